@@ -0,0 +1,299 @@
+package handler
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/sine-io/sinx/api/middleware"
+	"github.com/sine-io/sinx/application/access/dto"
+	"github.com/sine-io/sinx/application/access/service"
+	"github.com/sine-io/sinx/pkg/errorx"
+	"github.com/sine-io/sinx/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AccessHandler struct {
+	accessAppService *service.AccessApplicationService
+}
+
+func NewAccessHandler(accessAppService *service.AccessApplicationService) *AccessHandler {
+	return &AccessHandler{accessAppService: accessAppService}
+}
+
+// Submit 申请角色
+// @Summary 申请角色
+// @Description 向角色审批人提交申请，durationHours 为授权时长（小时），0 表示长期
+// @Tags 角色申请
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.SubmitRequest true "申请信息"
+// @Success 200 {object} response.Response{data=dto.AccessRequestItem}
+// @Router /api/access/request [post]
+func (h *AccessHandler) Submit(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	var req dto.SubmitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+
+	item, err := h.accessAppService.Submit(c.Request.Context(), userID, &req)
+	if err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+
+	response.Success(c, item)
+}
+
+// Approve 审批通过
+// @Summary 审批通过角色申请
+// @Description 仅该角色的审批人或超级管理员可操作，不能审批自己的申请；通过后立即绑定角色
+// @Tags 角色申请
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.DecisionRequest true "申请ID与审批意见"
+// @Success 200 {object} response.Response
+// @Router /api/access/approve [post]
+func (h *AccessHandler) Approve(c *gin.Context) {
+	h.decide(c, h.accessAppService.Approve)
+}
+
+// Reject 驳回
+// @Summary 驳回角色申请
+// @Tags 角色申请
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.DecisionRequest true "申请ID与审批意见"
+// @Success 200 {object} response.Response
+// @Router /api/access/reject [post]
+func (h *AccessHandler) Reject(c *gin.Context) {
+	h.decide(c, h.accessAppService.Reject)
+}
+
+// Cancel 撤回
+// @Summary 撤回自己待审批的角色申请
+// @Tags 角色申请
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.DecisionRequest true "申请ID与说明"
+// @Success 200 {object} response.Response
+// @Router /api/access/cancel [post]
+func (h *AccessHandler) Cancel(c *gin.Context) {
+	h.decide(c, h.accessAppService.Cancel)
+}
+
+func (h *AccessHandler) decide(c *gin.Context, fn func(ctx context.Context, actorID uint, req *dto.DecisionRequest) error) {
+	actorID, _ := middleware.GetUserID(c)
+
+	var req dto.DecisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+
+	if err := fn(c.Request.Context(), actorID, &req); err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// Comment 评论
+// @Summary 评论角色申请
+// @Tags 角色申请
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.CommentRequest true "申请ID与评论"
+// @Success 200 {object} response.Response
+// @Router /api/access/comment [post]
+func (h *AccessHandler) Comment(c *gin.Context) {
+	actorID, _ := middleware.GetUserID(c)
+
+	var req dto.CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+
+	if err := h.accessAppService.Comment(c.Request.Context(), actorID, &req); err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// Detail 申请详情
+// @Summary 角色申请详情
+// @Description 含全部状态变更与评论记录；申请人、该角色的审批人与超级管理员可见
+// @Tags 角色申请
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id query int true "申请ID"
+// @Success 200 {object} response.Response{data=dto.AccessRequestDetail}
+// @Router /api/access/detail [get]
+func (h *AccessHandler) Detail(c *gin.Context) {
+	actorID, _ := middleware.GetUserID(c)
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil || id <= 0 {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+
+	detail, err := h.accessAppService.Get(c.Request.Context(), actorID, uint(id))
+	if err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+
+	response.Success(c, detail)
+}
+
+// ListMine 我的申请
+// @Summary 当前用户提交的角色申请
+// @Tags 角色申请
+// @Produce json
+// @Security ApiKeyAuth
+// @Param pageNum query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Param status query string false "状态：pending / approved / rejected / cancelled"
+// @Success 200 {object} response.Response
+// @Router /api/access/mine [get]
+func (h *AccessHandler) ListMine(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	h.list(c, func(q *dto.ListQuery) (int64, []*dto.AccessRequestItem, error) {
+		return h.accessAppService.ListMine(c.Request.Context(), userID, q)
+	})
+}
+
+// ListPending 待我审批
+// @Summary 当前用户可审批的角色申请
+// @Description status 默认为 pending；超级管理员可见全部角色的申请
+// @Tags 角色申请
+// @Produce json
+// @Security ApiKeyAuth
+// @Param pageNum query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Param userId query int false "申请人"
+// @Param roleId query int false "角色"
+// @Param status query string false "状态"
+// @Success 200 {object} response.Response
+// @Router /api/access/pending [get]
+func (h *AccessHandler) ListPending(c *gin.Context) {
+	approverID, _ := middleware.GetUserID(c)
+	h.list(c, func(q *dto.ListQuery) (int64, []*dto.AccessRequestItem, error) {
+		return h.accessAppService.ListPending(c.Request.Context(), approverID, q)
+	})
+}
+
+// List 全部申请
+// @Summary 查询全部角色申请
+// @Tags 角色申请
+// @Produce json
+// @Security ApiKeyAuth
+// @Param pageNum query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Param userId query int false "申请人"
+// @Param roleId query int false "角色"
+// @Param status query string false "状态"
+// @Success 200 {object} response.Response
+// @Router /api/access/list [get]
+func (h *AccessHandler) List(c *gin.Context) {
+	h.list(c, func(q *dto.ListQuery) (int64, []*dto.AccessRequestItem, error) {
+		return h.accessAppService.List(c.Request.Context(), q)
+	})
+}
+
+func (h *AccessHandler) list(c *gin.Context, fn func(q *dto.ListQuery) (int64, []*dto.AccessRequestItem, error)) {
+	var q dto.ListQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+	total, list, err := fn(&q)
+	if err != nil {
+		response.InternalError(c, err)
+		return
+	}
+	response.Success(c, gin.H{"total": total, "data": list})
+}
+
+// SetApprovers 设置角色审批人
+// @Summary 设置角色审批人
+// @Description 以 userIds 整体替换；未设置审批人的角色不接受申请
+// @Tags 角色管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.ApproversRequest true "角色与审批人"
+// @Success 200 {object} response.Response
+// @Router /api/role/approvers [post]
+func (h *AccessHandler) SetApprovers(c *gin.Context) {
+	operatorID, _ := middleware.GetUserID(c)
+
+	var req dto.ApproversRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+
+	if err := h.accessAppService.SetApprovers(c.Request.Context(), operatorID, &req); err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// GetApprovers 角色审批人
+// @Summary 获取角色审批人
+// @Tags 角色管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id query int true "角色ID"
+// @Success 200 {object} response.Response
+// @Router /api/role/approvers [get]
+func (h *AccessHandler) GetApprovers(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil || id <= 0 {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+
+	ids, err := h.accessAppService.GetApprovers(c.Request.Context(), uint(id))
+	if err != nil {
+		response.InternalError(c, err)
+		return
+	}
+
+	response.Success(c, ids)
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"github.com/sine-io/sinx/application/dept/dto"
+	"github.com/sine-io/sinx/application/dept/service"
+	"github.com/sine-io/sinx/pkg/errorx"
+	"github.com/sine-io/sinx/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DeptHandler struct {
+	deptAppService *service.DeptApplicationService
+}
+
+func NewDeptHandler(deptAppService *service.DeptApplicationService) *DeptHandler {
+	return &DeptHandler{deptAppService: deptAppService}
+}
+
+// CreateDept 创建部门
+// @Summary 创建部门
+// @Tags 部门管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.DeptCreateOrUpdateRequest true "部门信息"
+// @Success 200 {object} response.Response
+// @Router /api/dept/create [post]
+func (h *DeptHandler) CreateDept(c *gin.Context) {
+	var req dto.DeptCreateOrUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+	if err := h.deptAppService.CreateOrUpdate(c.Request.Context(), &req); err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+	response.Success(c, nil)
+}
+
+// UpdateDept 更新部门
+// @Summary 更新部门
+// @Description 不能将部门移到自身或其下级部门之下
+// @Tags 部门管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.DeptCreateOrUpdateRequest true "部门信息"
+// @Success 200 {object} response.Response
+// @Router /api/dept/update [post]
+func (h *DeptHandler) UpdateDept(c *gin.Context) { h.CreateDept(c) }
+
+// DeleteDept 删除部门
+// @Summary 删除部门
+// @Description 存在下级部门（10006）或仍有用户归属时不允许删除
+// @Tags 部门管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.DeptDeleteRequest true "部门ID"
+// @Success 200 {object} response.Response
+// @Router /api/dept/delete [post]
+func (h *DeptHandler) DeleteDept(c *gin.Context) {
+	var req dto.DeptDeleteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+	if err := h.deptAppService.Delete(c.Request.Context(), req.ID); err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+	response.Success(c, nil)
+}
+
+// DeptTree 部门树
+// @Summary 获取部门树
+// @Tags 部门管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.Response{data=[]dto.DeptTreeNode}
+// @Router /api/dept/tree [get]
+func (h *DeptHandler) DeptTree(c *gin.Context) {
+	tree, err := h.deptAppService.Tree(c.Request.Context())
+	if err != nil {
+		response.InternalError(c, err)
+		return
+	}
+	response.Success(c, tree)
+}
@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/sine-io/sinx/api/middleware"
+	"github.com/sine-io/sinx/application/federation/dto"
+	"github.com/sine-io/sinx/application/federation/service"
+	"github.com/sine-io/sinx/pkg/errorx"
+	"github.com/sine-io/sinx/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// federationStateCookie 保存发起登录 / 绑定时的 state 摘要，仅回调路径可见
+const (
+	federationStateCookie = "sinx_federation_state"
+	federationCookiePath  = "/api/auth/federation/"
+)
+
+type FederationHandler struct {
+	federationAppService *service.FederationApplicationService
+	resultURL            string
+}
+
+// NewFederationHandler resultURL 为前端回调页，登录结果以 URL fragment 传递（不进入服务端日志与 Referer）
+func NewFederationHandler(federationAppService *service.FederationApplicationService, resultURL string) *FederationHandler {
+	return &FederationHandler{federationAppService: federationAppService, resultURL: resultURL}
+}
+
+// Providers 外部登录方式列表
+// @Summary 外部登录方式列表
+// @Tags 外部登录
+// @Produce json
+// @Success 200 {object} response.Response{data=[]dto.ProviderItem}
+// @Router /api/auth/federation/providers [get]
+func (h *FederationHandler) Providers(c *gin.Context) {
+	response.Success(c, h.federationAppService.Providers())
+}
+
+// Login 跳转到上游身份提供方
+// @Summary 外部登录
+// @Description 浏览器跳转入口，重定向到上游 IdP 授权页；redirect 为登录完成后前端需要回到的站内路径
+// @Tags 外部登录
+// @Param provider path string true "身份提供方"
+// @Param redirect query string false "站内相对路径"
+// @Success 302
+// @Router /api/auth/federation/{provider}/login [get]
+func (h *FederationHandler) Login(c *gin.Context) {
+	target, binding, err := h.federationAppService.BeginLogin(c.Request.Context(), c.Param("provider"), c.Query("redirect"), 0)
+	if err != nil {
+		h.redirectResult(c, errorValues(err, ""))
+		return
+	}
+	setStateCookie(c, binding, int(service.StateTTL.Seconds()))
+	c.Redirect(http.StatusFound, target)
+}
+
+// Callback 上游回调
+// @Summary 外部登录回调
+// @Description 校验授权结果后跳转到前端回调页：成功时 fragment 携带 token / refresh_token / expires_in（或 mfa_required / mfa_token），绑定时携带 linked，失败时携带 error
+// @Tags 外部登录
+// @Param provider path string true "身份提供方"
+// @Param state query string true "state"
+// @Param code query string false "授权码"
+// @Success 302
+// @Router /api/auth/federation/{provider}/callback [get]
+func (h *FederationHandler) Callback(c *gin.Context) {
+	state := c.Query("state")
+	binding, _ := c.Cookie(federationStateCookie)
+	setStateCookie(c, "", -1)
+	// 用户在上游拒绝授权等情况
+	if upstreamErr := c.Query("error"); upstreamErr != "" {
+		h.redirectResult(c, url.Values{"error": {upstreamErr}, "error_description": {c.Query("error_description")}})
+		return
+	}
+
+	res, err := h.federationAppService.Callback(c.Request.Context(), c.Param("provider"), state, binding, c.Query("code"), clientInfo(c))
+	redirect := ""
+	if res != nil {
+		redirect = res.Redirect
+	}
+	if err != nil {
+		h.redirectResult(c, errorValues(err, redirect))
+		return
+	}
+
+	v := url.Values{}
+	if redirect != "" {
+		v.Set("redirect", redirect)
+	}
+	switch {
+	case res.Linked:
+		v.Set("linked", res.Provider)
+	case res.Login.MFARequired:
+		v.Set("mfa_required", "true")
+		v.Set("mfa_token", res.Login.MFAToken)
+	default:
+		v.Set("token", res.Login.Token)
+		v.Set("refresh_token", res.Login.RefreshToken)
+		v.Set("expires_in", strconv.FormatInt(res.Login.ExpiresIn, 10))
+	}
+	h.redirectResult(c, v)
+}
+
+// ListIdentities 已绑定的外部身份
+// @Summary 已绑定的外部身份
+// @Tags 外部登录
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.Response{data=[]dto.IdentityItem}
+// @Router /api/user/identity/list [get]
+func (h *FederationHandler) ListIdentities(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.ErrorWithCode(c, errorx.ErrUnauthorized)
+		return
+	}
+
+	list, err := h.federationAppService.ListIdentities(c.Request.Context(), userID)
+	if err != nil {
+		response.InternalError(c, err)
+		return
+	}
+
+	response.Success(c, list)
+}
+
+// Link 绑定外部身份
+// @Summary 绑定外部身份
+// @Description 返回上游授权地址，前端跳转完成认证后回到回调页（fragment 携带 linked）
+// @Tags 外部登录
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.LinkRequest true "身份提供方"
+// @Success 200 {object} response.Response{data=dto.LinkResponse}
+// @Router /api/user/identity/link [post]
+func (h *FederationHandler) Link(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.ErrorWithCode(c, errorx.ErrUnauthorized)
+		return
+	}
+
+	var req dto.LinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+
+	target, binding, err := h.federationAppService.BeginLogin(c.Request.Context(), req.Provider, req.Redirect, userID)
+	if err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+	setStateCookie(c, binding, int(service.StateTTL.Seconds()))
+
+	response.Success(c, &dto.LinkResponse{RedirectTo: target})
+}
+
+// Unlink 解除外部身份绑定
+// @Summary 解除外部身份绑定
+// @Tags 外部登录
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.UnlinkRequest true "身份ID"
+// @Success 200 {object} response.Response
+// @Router /api/user/identity/unlink [post]
+func (h *FederationHandler) Unlink(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.ErrorWithCode(c, errorx.ErrUnauthorized)
+		return
+	}
+
+	var req dto.UnlinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+
+	if err := h.federationAppService.Unlink(c.Request.Context(), userID, &req); err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// setStateCookie 写入 / 清除 state 摘要 Cookie：HttpOnly，SameSite=Lax 以便上游跳转回来的顶层 GET 携带
+func setStateCookie(c *gin.Context, value string, maxAge int) {
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(federationStateCookie, value, maxAge, federationCookiePath, "", secure, true)
+}
+
+// redirectResult 跳转到前端回调页，结果放在 fragment
+func (h *FederationHandler) redirectResult(c *gin.Context, v url.Values) {
+	c.Header("Cache-Control", "no-store")
+	target := h.resultURL
+	if i := strings.IndexByte(target, '#'); i >= 0 {
+		target = target[:i]
+	}
+	c.Redirect(http.StatusFound, target+"#"+v.Encode())
+}
+
+// errorValues 内部错误不向前端暴露细节
+func errorValues(err error, redirect string) url.Values {
+	v := url.Values{"error": {"server_error"}}
+	if appErr, ok := err.(*errorx.Error); ok {
+		v.Set("error", strconv.Itoa(int(appErr.Code)))
+		v.Set("error_description", appErr.Message)
+	}
+	if redirect != "" {
+		v.Set("redirect", redirect)
+	}
+	return v
+}
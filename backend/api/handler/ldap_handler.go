@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"github.com/sine-io/sinx/application/ldap/dto"
+	"github.com/sine-io/sinx/application/ldap/service"
+	"github.com/sine-io/sinx/pkg/errorx"
+	"github.com/sine-io/sinx/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LDAPHandler struct {
+	ldapAppService *service.LDAPApplicationService
+}
+
+func NewLDAPHandler(ldapAppService *service.LDAPApplicationService) *LDAPHandler {
+	return &LDAPHandler{ldapAppService: ldapAppService}
+}
+
+// Sync 目录同步
+// @Summary LDAP 目录同步
+// @Description 导入目录用户、按目录组同步角色并禁用已从目录移除的用户；dryRun 为 true 时只返回变更报告
+// @Tags 安全管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.SyncRequest false "同步选项"
+// @Success 200 {object} response.Response{data=dto.SyncReport}
+// @Router /api/security/ldapSync [post]
+func (h *LDAPHandler) Sync(c *gin.Context) {
+	var req dto.SyncRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.ErrorWithCode(c, errorx.ErrInvalidParam)
+			return
+		}
+	}
+
+	report, err := h.ldapAppService.Sync(c.Request.Context(), req.DryRun)
+	if err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+
+	response.Success(c, report)
+}
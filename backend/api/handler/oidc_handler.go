@@ -0,0 +1,265 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sine-io/sinx/api/middleware"
+	"github.com/sine-io/sinx/application/oidc/dto"
+	"github.com/sine-io/sinx/application/oidc/service"
+	oidcService "github.com/sine-io/sinx/domain/oidc/service"
+	"github.com/sine-io/sinx/pkg/errorx"
+	"github.com/sine-io/sinx/pkg/logger"
+	"github.com/sine-io/sinx/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OIDCHandler struct {
+	oidcAppService *service.OIDCApplicationService
+	loginURL       string
+}
+
+// NewOIDCHandler loginURL 为前端登录 / 授权页，浏览器访问 /oauth2/authorize 时携带原始参数跳转过去
+func NewOIDCHandler(oidcAppService *service.OIDCApplicationService, loginURL string) *OIDCHandler {
+	return &OIDCHandler{oidcAppService: oidcAppService, loginURL: loginURL}
+}
+
+// Discovery OIDC 发现文档
+// @Summary OIDC 发现文档
+// @Tags OIDC
+// @Produce json
+// @Success 200 {object} dto.DiscoveryDocument
+// @Router /.well-known/openid-configuration [get]
+func (h *OIDCHandler) Discovery(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.oidcAppService.Discovery())
+}
+
+// AuthorizeRedirect 授权端点（浏览器）
+// @Summary 授权端点
+// @Description 浏览器跳转入口，携带原始查询参数重定向到前端登录 / 授权页，由前端登录后调用 POST /api/oauth2/authorize
+// @Tags OIDC
+// @Param response_type query string true "code"
+// @Param client_id query string true "客户端ID"
+// @Param redirect_uri query string true "回调地址"
+// @Param scope query string true "openid profile email"
+// @Param state query string false "state"
+// @Param nonce query string false "nonce"
+// @Param code_challenge query string true "PKCE challenge"
+// @Param code_challenge_method query string true "S256"
+// @Success 302
+// @Router /oauth2/authorize [get]
+func (h *OIDCHandler) AuthorizeRedirect(c *gin.Context) {
+	target := h.loginURL
+	if raw := c.Request.URL.RawQuery; raw != "" {
+		sep := "?"
+		if strings.Contains(target, "?") {
+			sep = "&"
+		}
+		target += sep + raw
+	}
+	c.Redirect(http.StatusFound, target)
+}
+
+// Authorize 授权（已登录用户）
+// @Summary 签发授权码
+// @Description 已登录用户同意授权后签发授权码，返回需跳转的客户端回调地址
+// @Tags OIDC
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.AuthorizeRequest true "授权请求参数"
+// @Success 200 {object} response.Response{data=dto.AuthorizeResponse}
+// @Failure 400 {object} response.Response
+// @Router /api/oauth2/authorize [post]
+func (h *OIDCHandler) Authorize(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.ErrorWithCode(c, errorx.ErrUnauthorized)
+		return
+	}
+
+	var req dto.AuthorizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+
+	authTime := time.Now()
+	if claims, ok := middleware.GetClaims(c); ok && claims.IssuedAt != nil {
+		authTime = claims.IssuedAt.Time
+	}
+
+	res, err := h.oidcAppService.Authorize(c.Request.Context(), userID, authTime, &req)
+	if err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+
+	response.Success(c, res)
+}
+
+// Token 令牌端点
+// @Summary 令牌端点
+// @Description 授权码 + PKCE 换取访问令牌与 ID 令牌；客户端认证支持 client_secret_basic / client_secret_post / none
+// @Tags OIDC
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "authorization_code"
+// @Param code formData string true "授权码"
+// @Param redirect_uri formData string true "回调地址"
+// @Param client_id formData string false "客户端ID"
+// @Param client_secret formData string false "客户端密钥"
+// @Param code_verifier formData string true "PKCE verifier"
+// @Success 200 {object} dto.TokenResponse
+// @Failure 400 {object} oidcService.OAuthError
+// @Router /oauth2/token [post]
+func (h *OIDCHandler) Token(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var req dto.TokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &oidcService.OAuthError{Code: "invalid_request"})
+		return
+	}
+	// client_secret_basic：凭据经过 form 编码
+	basicAuth := false
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		basicAuth = true
+		req.ClientID, _ = url.QueryUnescape(id)
+		req.ClientSecret, _ = url.QueryUnescape(secret)
+	}
+
+	res, err := h.oidcAppService.Token(c.Request.Context(), &req)
+	if err != nil {
+		oauthErr, ok := err.(*oidcService.OAuthError)
+		if !ok {
+			logger.Error("oidc_token_failed", "error", err)
+			c.JSON(http.StatusInternalServerError, &oidcService.OAuthError{Code: "server_error"})
+			return
+		}
+		status := http.StatusBadRequest
+		if oauthErr.Code == "invalid_client" {
+			status = http.StatusUnauthorized
+			if basicAuth {
+				c.Header("WWW-Authenticate", `Basic realm="sinx"`)
+			}
+		}
+		c.JSON(status, oauthErr)
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// UserInfo 用户信息端点
+// @Summary 用户信息端点
+// @Description 使用 OIDC 访问令牌获取用户信息（含角色与权限）
+// @Tags OIDC
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.UserInfoResponse
+// @Failure 401 {object} oidcService.OAuthError
+// @Router /oauth2/userinfo [get]
+func (h *OIDCHandler) UserInfo(c *gin.Context) {
+	authHeader := c.GetHeader(middleware.AuthorizationHeader)
+	if !strings.HasPrefix(authHeader, middleware.BearerPrefix) {
+		c.Header("WWW-Authenticate", `Bearer realm="sinx"`)
+		c.JSON(http.StatusUnauthorized, &oidcService.OAuthError{Code: "invalid_token"})
+		return
+	}
+
+	res, err := h.oidcAppService.UserInfo(c.Request.Context(), authHeader[len(middleware.BearerPrefix):])
+	if err != nil {
+		oauthErr, ok := err.(*oidcService.OAuthError)
+		if !ok {
+			logger.Error("oidc_userinfo_failed", "error", err)
+			c.JSON(http.StatusInternalServerError, &oidcService.OAuthError{Code: "server_error"})
+			return
+		}
+		c.Header("WWW-Authenticate", `Bearer error="`+oauthErr.Code+`"`)
+		c.JSON(http.StatusUnauthorized, oauthErr)
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// CreateClient 登记 OIDC 客户端
+// @Summary 登记 OIDC 客户端
+// @Description 返回的 clientSecret 仅显示一次；public=true 时为无密钥的公开客户端
+// @Tags OIDC
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.ClientCreateRequest true "客户端信息"
+// @Success 200 {object} response.Response{data=dto.ClientCreateResponse}
+// @Router /api/oidc/client/create [post]
+func (h *OIDCHandler) CreateClient(c *gin.Context) {
+	var req dto.ClientCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+
+	res, err := h.oidcAppService.CreateClient(c.Request.Context(), &req)
+	if err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+
+	response.Success(c, res)
+}
+
+// ListClients OIDC 客户端列表
+// @Summary OIDC 客户端列表
+// @Tags OIDC
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.Response{data=[]dto.ClientItem}
+// @Router /api/oidc/client/list [get]
+func (h *OIDCHandler) ListClients(c *gin.Context) {
+	list, err := h.oidcAppService.ListClients(c.Request.Context())
+	if err != nil {
+		response.InternalError(c, err)
+		return
+	}
+
+	response.Success(c, list)
+}
+
+// DeleteClient 删除 OIDC 客户端
+// @Summary 删除 OIDC 客户端
+// @Tags OIDC
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.ClientDeleteRequest true "客户端ID"
+// @Success 200 {object} response.Response
+// @Router /api/oidc/client/delete [post]
+func (h *OIDCHandler) DeleteClient(c *gin.Context) {
+	var req dto.ClientDeleteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+
+	if err := h.oidcAppService.DeleteClient(c.Request.Context(), &req); err != nil {
+		response.InternalError(c, err)
+		return
+	}
+
+	response.Success(c, nil)
+}
@@ -37,7 +37,11 @@ func (h *RBACHandler) CreateUser(c *gin.Context) {
 		return
 	}
 	if err := h.svc.CreateUser(c, &req); err != nil {
-		response.InternalError(c, err)
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
 		return
 	}
 	response.Success(c, nil)
@@ -58,8 +62,13 @@ func (h *RBACHandler) UpdateUser(c *gin.Context) {
 		response.ErrorWithCode(c, errorx.ErrInvalidParam)
 		return
 	}
-	if err := h.svc.UpdateUser(c, &req); err != nil {
-		response.InternalError(c, err)
+	operatorID, _ := middleware.GetUserID(c)
+	if err := h.svc.UpdateUser(c, operatorID, &req); err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
 		return
 	}
 	response.Success(c, nil)
@@ -80,8 +89,13 @@ func (h *RBACHandler) DeleteUser(c *gin.Context) {
 		response.ErrorWithCode(c, errorx.ErrInvalidParam)
 		return
 	}
-	if err := h.svc.DeleteUser(c, req.ID); err != nil {
-		response.InternalError(c, err)
+	operatorID, _ := middleware.GetUserID(c)
+	if err := h.svc.DeleteUser(c, operatorID, req.ID); err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
 		return
 	}
 	response.Success(c, nil)
@@ -99,7 +113,8 @@ func (h *RBACHandler) DeleteUser(c *gin.Context) {
 func (h *RBACHandler) UserList(c *gin.Context) {
 	pageNum, _ := strconv.Atoi(c.DefaultQuery("pageNum", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
-	total, list, err := h.svc.ListUsers(c, pageNum, pageSize)
+	operatorID, _ := middleware.GetUserID(c)
+	total, list, err := h.svc.ListUsers(c, operatorID, pageNum, pageSize)
 	if err != nil {
 		response.InternalError(c, err)
 		return
@@ -107,28 +122,6 @@ func (h *RBACHandler) UserList(c *gin.Context) {
 	response.Success(c, gin.H{"total": total, "data": list})
 }
 
-// ChangePassword 修改密码
-// @Summary 修改密码
-// @Tags 用户管理
-// @Accept json
-// @Produce json
-// @Security ApiKeyAuth
-// @Param request body rbacdto.ChangePasswordRequest true "修改密码"
-// @Success 200 {object} response.Response
-// @Router /api/user/changePassword [post]
-func (h *RBACHandler) ChangePassword(c *gin.Context) {
-	var req rbacdto.ChangePasswordRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		response.ErrorWithCode(c, errorx.ErrInvalidParam)
-		return
-	}
-	if err := h.svc.ChangePassword(c, &req); err != nil {
-		response.InternalError(c, err)
-		return
-	}
-	response.Success(c, nil)
-}
-
 // BindUserRole 绑定角色
 // @Summary 绑定用户角色
 // @Tags 用户管理
@@ -144,9 +137,13 @@ func (h *RBACHandler) BindUserRole(c *gin.Context) {
 		response.ErrorWithCode(c, errorx.ErrInvalidParam)
 		return
 	}
-	added, skipped, err := h.svc.BindUserRoles(c, req.UserID, req.RoleIDs)
+	added, skipped, err := h.svc.BindUserRoles(c, req.UserID, req.RoleIDs, req.ValidFrom, req.ValidUntil)
 	if err != nil {
-		response.InternalError(c, err)
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
 		return
 	}
 	response.Success(c, gin.H{"added": added, "skipped": skipped})
@@ -174,7 +171,7 @@ func (h *RBACHandler) UnbindUserRole(c *gin.Context) {
 	response.Success(c, nil)
 }
 
-// GetUserRoles 用户角色列表
+// GetUserRoles 用户角色列表（含有效期及尚未生效的绑定）
 // @Summary 获取用户角色列表
 // @Tags 用户管理
 // @Produce json
@@ -189,7 +186,7 @@ func (h *RBACHandler) GetUserRoles(c *gin.Context) {
 		return
 	}
 	id, _ := strconv.Atoi(idStr)
-	roles, err := h.svc.GetUserRoles(c, uint(id))
+	roles, err := h.svc.ListUserRoleBindings(c, uint(id))
 	if err != nil {
 		response.InternalError(c, err)
 		return
@@ -224,6 +221,28 @@ func (h *RBACHandler) GetUserMenus(c *gin.Context) {
 	response.Success(c, menus)
 }
 
+// GetUserDeniedPerms 用户因拒绝规则失去的权限
+// @Summary 获取用户因拒绝规则失去的权限及其来源角色与菜单
+// @Tags 用户管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param userId query int true "用户ID"
+// @Success 200 {object} response.Response{data=[]rbacdto.DeniedPerm}
+// @Router /api/user/deniedPerms [get]
+func (h *RBACHandler) GetUserDeniedPerms(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("userId"))
+	if err != nil || id <= 0 {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+	list, err := h.svc.GetUserDeniedPerms(c, uint(id))
+	if err != nil {
+		response.InternalError(c, err)
+		return
+	}
+	response.Success(c, list)
+}
+
 // 角色接口
 // CreateRole 创建或更新角色
 // @Summary 创建角色
@@ -241,7 +260,11 @@ func (h *RBACHandler) CreateRole(c *gin.Context) {
 		return
 	}
 	if err := h.svc.CreateOrUpdateRole(c, &req); err != nil {
-		response.InternalError(c, err)
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
 		return
 	}
 	response.Success(c, nil)
@@ -274,7 +297,11 @@ func (h *RBACHandler) DeleteRole(c *gin.Context) {
 		return
 	}
 	if err := h.svc.DeleteRole(c, req.ID); err != nil {
-		response.InternalError(c, err)
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
 		return
 	}
 	response.Success(c, nil)
@@ -315,7 +342,7 @@ func (h *RBACHandler) BindRoleMenu(c *gin.Context) {
 		response.ErrorWithCode(c, errorx.ErrInvalidParam)
 		return
 	}
-	added, skipped, err := h.svc.BindRoleMenus(c, req.RoleID, req.MenuIDs)
+	added, skipped, err := h.svc.BindRoleMenus(c, req.RoleID, req.MenuIDs, req.Deny)
 	if err != nil {
 		response.InternalError(c, err)
 		return
@@ -338,7 +365,7 @@ func (h *RBACHandler) UnbindRoleMenu(c *gin.Context) {
 		response.ErrorWithCode(c, errorx.ErrInvalidParam)
 		return
 	}
-	if err := h.svc.UnbindRoleMenus(c, req.RoleID, req.MenuIDs); err != nil {
+	if err := h.svc.UnbindRoleMenus(c, req.RoleID, req.MenuIDs, req.Deny); err != nil {
 		response.InternalError(c, err)
 		return
 	}
@@ -368,6 +395,32 @@ func (h *RBACHandler) GetRoleMenus(c *gin.Context) {
 	response.Success(c, menus)
 }
 
+// GetRolePerms 角色权限（自身与继承分开列出）
+// @Summary 获取角色自身权限与从祖先角色继承的权限
+// @Tags 角色管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id query int true "角色ID"
+// @Success 200 {object} response.Response{data=rbacdto.RolePermsResponse}
+// @Router /api/role/perms [get]
+func (h *RBACHandler) GetRolePerms(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil || id <= 0 {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+	res, err := h.svc.GetRolePerms(c, uint(id))
+	if err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+	response.Success(c, res)
+}
+
 // GetRoleUsers 拥有该角色的用户列表(占位)
 // @Summary 获取拥有该角色的用户列表
 // @Tags 角色管理
@@ -378,6 +431,88 @@ func (h *RBACHandler) GetRoleMenus(c *gin.Context) {
 // @Router /api/role/users [get]
 func (h *RBACHandler) GetRoleUsers(c *gin.Context) { response.Success(c, []any{}) }
 
+// SaveRoleConstraint 保存职责分离约束
+// @Summary 新增或更新职责分离约束
+// @Description roleIds 中的角色用户最多同时拥有 maxRoles 个（默认 1，即互斥）；id 非 0 时更新
+// @Tags 角色管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body rbacdto.RoleConstraintRequest true "约束"
+// @Success 200 {object} response.Response
+// @Router /api/role/sod/save [post]
+func (h *RBACHandler) SaveRoleConstraint(c *gin.Context) {
+	var req rbacdto.RoleConstraintRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+	if err := h.svc.SaveRoleConstraint(c, &req); err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+	response.Success(c, nil)
+}
+
+// DeleteRoleConstraint 删除职责分离约束
+// @Summary 删除职责分离约束
+// @Tags 角色管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body rbacdto.RoleDeleteRequest true "约束ID"
+// @Success 200 {object} response.Response
+// @Router /api/role/sod/delete [post]
+func (h *RBACHandler) DeleteRoleConstraint(c *gin.Context) {
+	var req rbacdto.RoleDeleteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+	if err := h.svc.DeleteRoleConstraint(c, req.ID); err != nil {
+		response.InternalError(c, err)
+		return
+	}
+	response.Success(c, nil)
+}
+
+// RoleConstraintList 职责分离约束列表
+// @Summary 获取职责分离约束列表
+// @Tags 角色管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.Response{data=[]rbacdto.RoleConstraintItem}
+// @Router /api/role/sod/list [get]
+func (h *RBACHandler) RoleConstraintList(c *gin.Context) {
+	list, err := h.svc.ListRoleConstraints(c)
+	if err != nil {
+		response.InternalError(c, err)
+		return
+	}
+	response.Success(c, list)
+}
+
+// SoDReport 职责分离违规报告
+// @Summary 列出当前违反职责分离约束的用户
+// @Description 用于发现约束添加前已存在的冲突绑定，含继承得到的角色与尚未生效的限时绑定
+// @Tags 角色管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.Response{data=[]rbacdto.SoDReportItem}
+// @Router /api/role/sod/report [get]
+func (h *RBACHandler) SoDReport(c *gin.Context) {
+	list, err := h.svc.SoDReport(c)
+	if err != nil {
+		response.InternalError(c, err)
+		return
+	}
+	response.Success(c, list)
+}
+
 // GetRoleMenuTree 角色菜单ID集合
 // @Summary 获取角色菜单树(返回菜单ID集合)
 // @Tags 菜单管理
@@ -418,7 +553,11 @@ func (h *RBACHandler) CreateMenu(c *gin.Context) {
 		return
 	}
 	if err := h.svc.CreateOrUpdateMenu(c, &req); err != nil {
-		response.InternalError(c, err)
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
 		return
 	}
 	response.Success(c, nil)
@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"strconv"
+
 	"github.com/sine-io/sinx/api/middleware"
 	"github.com/sine-io/sinx/application/user/dto"
 	"github.com/sine-io/sinx/application/user/service"
@@ -20,6 +22,9 @@ func NewUserHandler(userAppService *service.UserApplicationService) *UserHandler
 	}
 }
 
+// Service 暴露内部应用服务（供路由层构建令牌校验器使用）
+func (h *UserHandler) Service() *service.UserApplicationService { return h.userAppService }
+
 // Register 用户注册
 // @Summary 用户注册
 // @Description 创建新用户账户
@@ -59,6 +64,7 @@ func (h *UserHandler) Register(c *gin.Context) {
 // @Param request body dto.LoginRequest true "登录信息"
 // @Success 200 {object} response.Response{data=dto.LoginResponse}
 // @Failure 400 {object} response.Response
+// @Failure 429 {object} response.Response
 // @Router /api/auth/login [post]
 func (h *UserHandler) Login(c *gin.Context) {
 	var req dto.LoginRequest
@@ -67,9 +73,10 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	loginResp, err := h.userAppService.Login(c.Request.Context(), &req)
+	loginResp, err := h.userAppService.Login(c.Request.Context(), &req, clientInfo(c))
 	if err != nil {
 		if appErr, ok := err.(*errorx.Error); ok {
+			setRetryAfter(c, appErr)
 			response.Error(c, appErr)
 		} else {
 			response.InternalError(c, err)
@@ -80,6 +87,750 @@ func (h *UserHandler) Login(c *gin.Context) {
 	response.Success(c, loginResp)
 }
 
+// Refresh 刷新令牌
+// @Summary 刷新令牌
+// @Description 使用刷新令牌换取新的访问令牌与刷新令牌（旧刷新令牌立即失效）
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param request body dto.RefreshTokenRequest true "刷新令牌"
+// @Success 200 {object} response.Response{data=dto.TokenResponse}
+// @Failure 401 {object} response.Response
+// @Router /api/auth/refresh [post]
+func (h *UserHandler) Refresh(c *gin.Context) {
+	var req dto.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+
+	tokens, err := h.userAppService.Refresh(c.Request.Context(), &req)
+	if err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+
+	response.Success(c, tokens)
+}
+
+// Logout 退出登录
+// @Summary 退出登录
+// @Description 吊销当前访问令牌；可选携带刷新令牌一并吊销
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.LogoutRequest false "刷新令牌(可选)"
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /api/auth/logout [post]
+func (h *UserHandler) Logout(c *gin.Context) {
+	claims, exists := middleware.GetClaims(c)
+	if !exists {
+		response.ErrorWithCode(c, errorx.ErrUnauthorized)
+		return
+	}
+
+	var req dto.LogoutRequest
+	// 请求体可选
+	_ = c.ShouldBindJSON(&req)
+
+	if err := h.userAppService.Logout(c.Request.Context(), claims, &req); err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// LogoutAll 退出所有设备
+// @Summary 退出所有设备
+// @Description 吊销当前用户的全部访问令牌与刷新令牌
+// @Tags 用户管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /api/auth/logoutAll [post]
+func (h *UserHandler) LogoutAll(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.ErrorWithCode(c, errorx.ErrUnauthorized)
+		return
+	}
+
+	if err := h.userAppService.LogoutAll(c.Request.Context(), userID); err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// VerifyMFA 两步验证
+// @Summary 两步验证
+// @Description 使用登录返回的临时令牌与 TOTP 验证码（或恢复码）换取正式令牌
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param request body dto.MFAVerifyRequest true "临时令牌与验证码"
+// @Success 200 {object} response.Response{data=dto.LoginResponse}
+// @Failure 401 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Router /api/auth/mfa/verify [post]
+func (h *UserHandler) VerifyMFA(c *gin.Context) {
+	var req dto.MFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+
+	loginResp, err := h.userAppService.VerifyMFA(c.Request.Context(), &req, clientInfo(c))
+	if err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			setRetryAfter(c, appErr)
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+
+	response.Success(c, loginResp)
+}
+
+// EnrollMFA 登记两步验证
+// @Summary 登记两步验证
+// @Description 生成 TOTP 密钥与 otpauth URI，需调用激活接口校验验证码后生效
+// @Tags 用户管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.Response{data=dto.MFAEnrollResponse}
+// @Failure 409 {object} response.Response
+// @Router /api/user/mfa/enroll [post]
+func (h *UserHandler) EnrollMFA(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.ErrorWithCode(c, errorx.ErrUnauthorized)
+		return
+	}
+
+	res, err := h.userAppService.BeginMFAEnrollment(c.Request.Context(), userID)
+	if err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+
+	response.Success(c, res)
+}
+
+// ActivateMFA 激活两步验证
+// @Summary 激活两步验证
+// @Description 校验验证码完成登记，返回一次性恢复码（仅显示一次）
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.MFAActivateRequest true "验证码"
+// @Success 200 {object} response.Response{data=dto.MFAActivateResponse}
+// @Failure 401 {object} response.Response
+// @Router /api/user/mfa/activate [post]
+func (h *UserHandler) ActivateMFA(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.ErrorWithCode(c, errorx.ErrUnauthorized)
+		return
+	}
+
+	var req dto.MFAActivateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+
+	res, err := h.userAppService.ActivateMFA(c.Request.Context(), userID, &req)
+	if err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+
+	response.Success(c, res)
+}
+
+// GetMFAStatus 两步验证状态
+// @Summary 获取两步验证状态
+// @Tags 用户管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.Response{data=dto.MFAStatusResponse}
+// @Router /api/user/mfa/status [get]
+func (h *UserHandler) GetMFAStatus(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.ErrorWithCode(c, errorx.ErrUnauthorized)
+		return
+	}
+
+	res, err := h.userAppService.GetMFAStatus(c.Request.Context(), userID)
+	if err != nil {
+		response.InternalError(c, err)
+		return
+	}
+
+	response.Success(c, res)
+}
+
+// ResetMFA 重置用户两步验证
+// @Summary 重置用户两步验证
+// @Description 管理员清除指定用户的两步验证配置与恢复码
+// @Tags 安全管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.MFAResetRequest true "用户ID"
+// @Success 200 {object} response.Response
+// @Router /api/user/mfa/reset [post]
+func (h *UserHandler) ResetMFA(c *gin.Context) {
+	operatorID, _ := middleware.GetUserID(c)
+
+	var req dto.MFAResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+
+	if err := h.userAppService.ResetMFA(c.Request.Context(), operatorID, &req); err != nil {
+		response.InternalError(c, err)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// Impersonate 代登录
+// @Summary 以指定用户身份登录
+// @Description 签发目标用户的短期访问令牌（携带 act 声明），用于排查菜单 / 权限问题；不可刷新，不能修改密码、两步验证等账号设置，期间每个请求均记录审计日志
+// @Tags 安全管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.ImpersonateRequest true "目标用户ID与原因"
+// @Success 200 {object} response.Response{data=dto.ImpersonateResponse}
+// @Router /api/user/impersonate [post]
+func (h *UserHandler) Impersonate(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		response.ErrorWithCode(c, errorx.ErrUnauthorized)
+		return
+	}
+
+	var req dto.ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+
+	res, err := h.userAppService.Impersonate(c.Request.Context(), claims, &req)
+	if err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+
+	response.Success(c, res)
+}
+
+// ChangePassword 修改密码
+// @Summary 修改密码
+// @Description 修改当前用户的密码；成功后其他设备上的会话全部失效，并为当前会话返回新令牌
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.ChangePasswordRequest true "旧密码与新密码"
+// @Success 200 {object} response.Response{data=dto.TokenResponse}
+// @Failure 400 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Router /api/user/changePassword [post]
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		response.ErrorWithCode(c, errorx.ErrUnauthorized)
+		return
+	}
+
+	var req dto.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+
+	res, err := h.userAppService.ChangePassword(c.Request.Context(), userID, &req, clientInfo(c))
+	if err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			setRetryAfter(c, appErr)
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+
+	response.Success(c, res)
+}
+
+// AdminResetPassword 重置用户密码
+// @Summary 重置用户密码
+// @Description 管理员为用户设置临时密码（不传则随机生成并返回），用户的全部会话失效，下次登录须先修改密码
+// @Tags 安全管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.AdminResetPasswordRequest true "用户ID与临时密码"
+// @Success 200 {object} response.Response{data=dto.AdminResetPasswordResponse}
+// @Router /api/user/resetPassword [post]
+func (h *UserHandler) AdminResetPassword(c *gin.Context) {
+	operatorID, _ := middleware.GetUserID(c)
+
+	var req dto.AdminResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+
+	res, err := h.userAppService.AdminResetPassword(c.Request.Context(), operatorID, &req)
+	if err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+
+	response.Success(c, res)
+}
+
+// CreateAccessToken 创建个人访问令牌
+// @Summary 创建个人访问令牌
+// @Description 为当前用户创建带权限范围的 API 令牌（明文仅返回一次），请求头使用 Authorization: Bearer sinx_pat_...
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.AccessTokenCreateRequest true "令牌信息"
+// @Success 200 {object} response.Response{data=dto.AccessTokenCreateResponse}
+// @Failure 403 {object} response.Response
+// @Router /api/user/token/create [post]
+func (h *UserHandler) CreateAccessToken(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.ErrorWithCode(c, errorx.ErrUnauthorized)
+		return
+	}
+
+	var req dto.AccessTokenCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+
+	res, err := h.userAppService.CreateAccessToken(c.Request.Context(), userID, &req)
+	if err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+
+	response.Success(c, res)
+}
+
+// ListAccessTokens 个人访问令牌列表
+// @Summary 获取个人访问令牌列表
+// @Tags 用户管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.Response{data=[]dto.AccessTokenItem}
+// @Router /api/user/token/list [get]
+func (h *UserHandler) ListAccessTokens(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.ErrorWithCode(c, errorx.ErrUnauthorized)
+		return
+	}
+
+	list, err := h.userAppService.ListAccessTokens(c.Request.Context(), userID)
+	if err != nil {
+		response.InternalError(c, err)
+		return
+	}
+
+	response.Success(c, list)
+}
+
+// DeleteAccessToken 删除个人访问令牌
+// @Summary 删除个人访问令牌
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.AccessTokenDeleteRequest true "令牌ID"
+// @Success 200 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/user/token/delete [post]
+func (h *UserHandler) DeleteAccessToken(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.ErrorWithCode(c, errorx.ErrUnauthorized)
+		return
+	}
+
+	var req dto.AccessTokenDeleteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+
+	if err := h.userAppService.DeleteAccessToken(c.Request.Context(), userID, &req); err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// ListSessions 在线会话列表
+// @Summary 获取当前用户的在线会话
+// @Description 列出当前用户已登录的设备，current 标记本次请求所用的会话
+// @Tags 用户管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.Response{data=[]dto.SessionItem}
+// @Router /api/user/sessions [get]
+func (h *UserHandler) ListSessions(c *gin.Context) {
+	claims, exists := middleware.GetClaims(c)
+	if !exists {
+		response.ErrorWithCode(c, errorx.ErrUnauthorized)
+		return
+	}
+
+	list, err := h.userAppService.ListSessions(c.Request.Context(), claims)
+	if err != nil {
+		response.InternalError(c, err)
+		return
+	}
+
+	response.Success(c, list)
+}
+
+// RevokeSession 下线会话
+// @Summary 下线当前用户的指定会话
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.SessionRevokeRequest true "会话ID"
+// @Success 200 {object} response.Response
+// @Router /api/user/sessions/revoke [post]
+func (h *UserHandler) RevokeSession(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.ErrorWithCode(c, errorx.ErrUnauthorized)
+		return
+	}
+
+	var req dto.SessionRevokeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+
+	if err := h.userAppService.RevokeSession(c.Request.Context(), userID, &req); err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// ListOnlineSessions 在线用户
+// @Summary 获取在线用户会话列表
+// @Tags 安全管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param pageNum query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Success 200 {object} response.Response{data=[]dto.SessionItem}
+// @Router /api/security/sessions [get]
+func (h *UserHandler) ListOnlineSessions(c *gin.Context) {
+	pageNum, _ := strconv.Atoi(c.DefaultQuery("pageNum", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	total, list, err := h.userAppService.ListOnlineSessions(c.Request.Context(), pageNum, pageSize)
+	if err != nil {
+		response.InternalError(c, err)
+		return
+	}
+	response.Success(c, gin.H{"total": total, "data": list})
+}
+
+// ListLoginLogs 登录日志
+// @Summary 获取登录日志
+// @Description 记录每次登录尝试（成功与失败），按时间倒序；start / end 为 Unix 秒
+// @Tags 安全管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param pageNum query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Param user_id query int false "用户ID"
+// @Param username query string false "用户名"
+// @Param ip query string false "登录IP"
+// @Param success query bool false "是否成功"
+// @Param start query int false "开始时间"
+// @Param end query int false "结束时间"
+// @Success 200 {object} response.Response{data=[]dto.LoginLogItem}
+// @Router /api/security/loginLogs [get]
+func (h *UserHandler) ListLoginLogs(c *gin.Context) {
+	var q dto.LoginLogQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+	total, list, err := h.userAppService.ListLoginLogs(c.Request.Context(), &q)
+	if err != nil {
+		response.InternalError(c, err)
+		return
+	}
+	response.Success(c, gin.H{"total": total, "data": list})
+}
+
+// ForceLogout 强制下线
+// @Summary 强制下线
+// @Description 传 session_id 下线单个会话，否则下线 user_id 的全部会话
+// @Tags 安全管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.ForceLogoutRequest true "会话ID或用户ID"
+// @Success 200 {object} response.Response
+// @Router /api/security/forceLogout [post]
+func (h *UserHandler) ForceLogout(c *gin.Context) {
+	operatorID, _ := middleware.GetUserID(c)
+
+	var req dto.ForceLogoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+
+	if err := h.userAppService.ForceLogout(c.Request.Context(), operatorID, &req); err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// ListLockouts 登录锁定列表
+// @Summary 获取登录锁定列表
+// @Description 列出因登录失败过多而被临时锁定的账号与 IP
+// @Tags 安全管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.Response{data=[]dto.LockoutItem}
+// @Router /api/security/lockouts [get]
+func (h *UserHandler) ListLockouts(c *gin.Context) {
+	list, err := h.userAppService.ListLockouts(c.Request.Context())
+	if err != nil {
+		response.InternalError(c, err)
+		return
+	}
+
+	response.Success(c, list)
+}
+
+// ClearLockout 解除登录锁定
+// @Summary 解除登录锁定
+// @Description 解除账号或 IP 的锁定并清空失败计数
+// @Tags 安全管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.UnlockRequest true "解锁对象"
+// @Success 200 {object} response.Response
+// @Router /api/security/unlock [post]
+func (h *UserHandler) ClearLockout(c *gin.Context) {
+	var req dto.UnlockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+
+	if err := h.userAppService.ClearLockout(c.Request.Context(), &req); err != nil {
+		response.InternalError(c, err)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// ForgotPassword 找回密码
+// @Summary 找回密码
+// @Description 向该邮箱发送重置密码链接；邮箱是否存在均返回成功；按 IP 与邮箱限流
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param request body dto.ForgotPasswordRequest true "找回密码"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Router /api/auth/password/forgot [post]
+func (h *UserHandler) ForgotPassword(c *gin.Context) {
+	var req dto.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+
+	if err := h.userAppService.RequestPasswordReset(c.Request.Context(), &req, clientInfo(c)); err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			setRetryAfter(c, appErr)
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// ResetPassword 重置密码
+// @Summary 重置密码
+// @Description 使用邮件中的一次性令牌设置新密码，成功后该用户所有已登录会话失效
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param request body dto.ResetPasswordRequest true "重置密码"
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /api/auth/password/reset [post]
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	var req dto.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+
+	if err := h.userAppService.ResetPassword(c.Request.Context(), &req); err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// VerifyEmail 验证邮箱
+// @Summary 验证邮箱
+// @Description 使用邮件中的一次性令牌确认邮箱归属
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param request body dto.VerifyEmailRequest true "验证邮箱"
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /api/auth/email/verify [post]
+func (h *UserHandler) VerifyEmail(c *gin.Context) {
+	var req dto.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+
+	if err := h.userAppService.VerifyEmail(c.Request.Context(), &req); err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// ResendVerification 重发验证邮件
+// @Summary 重发验证邮件
+// @Description 向尚未验证的邮箱重新发送验证链接；邮箱是否存在均返回成功；按 IP 与邮箱限流
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param request body dto.ResendVerificationRequest true "重发验证邮件"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Router /api/auth/email/resend [post]
+func (h *UserHandler) ResendVerification(c *gin.Context) {
+	var req dto.ResendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithCode(c, errorx.ErrInvalidParam)
+		return
+	}
+
+	if err := h.userAppService.ResendVerification(c.Request.Context(), &req, clientInfo(c)); err != nil {
+		if appErr, ok := err.(*errorx.Error); ok {
+			setRetryAfter(c, appErr)
+			response.Error(c, appErr)
+		} else {
+			response.InternalError(c, err)
+		}
+		return
+	}
+
+	response.Success(c, nil)
+}
+
 // GetProfile 获取用户资料
 // @Summary 获取用户资料
 // @Description 获取当前登录用户的资料信息
@@ -106,6 +857,23 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 		}
 		return
 	}
+	if claims, ok := middleware.GetClaims(c); ok && claims.Actor != nil {
+		user.ImpersonatedBy = claims.Actor.Username
+	}
 
 	response.Success(c, user)
 }
+
+// clientInfo 提取请求来源信息
+func clientInfo(c *gin.Context) dto.ClientInfo {
+	return dto.ClientInfo{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+}
+
+// setRetryAfter 节流类错误附带 Retry-After 响应头
+func setRetryAfter(c *gin.Context, err *errorx.Error) {
+	if data, ok := err.Data.(map[string]int64); ok {
+		if v, ok := data["retryAfter"]; ok {
+			c.Header("Retry-After", strconv.FormatInt(v, 10))
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/sine-io/sinx/pkg/auth"
 	"github.com/sine-io/sinx/pkg/errorx"
+	"github.com/sine-io/sinx/pkg/logger"
 	"github.com/sine-io/sinx/pkg/response"
 
 	"github.com/gin-gonic/gin"
@@ -15,10 +16,18 @@ const (
 	BearerPrefix        = "Bearer "
 	UserIDKey           = "user_id"
 	UsernameKey         = "username"
+	ClaimsKey           = "claims"
+	TokenScopesKey      = "token_scopes"
 )
 
-// AuthMiddleware JWT认证中间件
-func AuthMiddleware() gin.HandlerFunc {
+// TokenValidator 在签名校验通过后进一步校验令牌（吊销 / 用户状态等）
+type TokenValidator func(c *gin.Context, claims *auth.Claims) error
+
+// APIKeyResolver 解析个人访问令牌，返回所属用户身份及令牌权限范围
+type APIKeyResolver func(c *gin.Context, raw string) (*auth.Claims, []string, error)
+
+// AuthMiddleware 认证中间件，同时接受 JWT 与个人访问令牌（以 auth.APIKeyPrefix 开头）
+func AuthMiddleware(validator TokenValidator, apiKeys APIKeyResolver) gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
 		authHeader := c.GetHeader(AuthorizationHeader)
 		if authHeader == "" {
@@ -34,18 +43,65 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 
 		tokenString := authHeader[len(BearerPrefix):]
+		if apiKeys != nil && strings.HasPrefix(tokenString, auth.APIKeyPrefix) {
+			claims, scopes, err := apiKeys(c, tokenString)
+			if err != nil {
+				if appErr, ok := err.(*errorx.Error); ok {
+					response.Error(c, appErr)
+				} else {
+					response.InternalError(c, err)
+				}
+				c.Abort()
+				return
+			}
+			c.Set(UserIDKey, claims.UserID)
+			c.Set(UsernameKey, claims.Username)
+			c.Set(ClaimsKey, claims)
+			c.Set(TokenScopesKey, scopes)
+			c.Next()
+			return
+		}
+
 		claims, err := auth.ParseToken(tokenString)
-		if err != nil {
+		if err != nil || claims.TokenUse != "" {
 			response.ErrorWithCode(c, errorx.ErrUserInvalidToken)
 			c.Abort()
 			return
 		}
 
+		if validator != nil {
+			if err := validator(c, claims); err != nil {
+				if appErr, ok := err.(*errorx.Error); ok {
+					response.Error(c, appErr)
+				} else {
+					response.InternalError(c, err)
+				}
+				c.Abort()
+				return
+			}
+		}
+
 		// 将用户信息设置到上下文中
 		c.Set(UserIDKey, claims.UserID)
 		c.Set(UsernameKey, claims.Username)
+		c.Set(ClaimsKey, claims)
 
 		c.Next()
+
+		// 代登录期间的每个请求都记录操作人与目标用户
+		if claims.Actor != nil {
+			logger.Info("audit:impersonation_request",
+				"actorId", claims.Actor.UserID,
+				"actor", claims.Actor.Username,
+				"userId", claims.UserID,
+				"username", claims.Username,
+				"jti", claims.ID,
+				"method", c.Request.Method,
+				"path", c.Request.URL.Path,
+				"status", c.Writer.Status(),
+				"client_ip", c.ClientIP(),
+			)
+		}
 	})
 }
 
@@ -70,3 +126,57 @@ func GetUsername(c *gin.Context) (string, bool) {
 	name, ok := username.(string)
 	return name, ok
 }
+
+// GetClaims 从上下文中获取当前访问令牌的声明
+func GetClaims(c *gin.Context) (*auth.Claims, bool) {
+	v, exists := c.Get(ClaimsKey)
+	if !exists {
+		return nil, false
+	}
+
+	claims, ok := v.(*auth.Claims)
+	return claims, ok
+}
+
+// GetTokenScopes 获取个人访问令牌的权限范围；JWT 认证时返回 false
+func GetTokenScopes(c *gin.Context) ([]string, bool) {
+	v, exists := c.Get(TokenScopesKey)
+	if !exists {
+		return nil, false
+	}
+
+	scopes, ok := v.([]string)
+	return scopes, ok
+}
+
+// IsImpersonating 当前请求是否使用代登录令牌
+func IsImpersonating(c *gin.Context) bool {
+	claims, ok := GetClaims(c)
+	return ok && claims.Actor != nil
+}
+
+// NoImpersonation 拒绝代登录令牌（个人访问令牌仍可调用）
+// 用于重置他人凭据、强制下线、角色授予等管理操作，避免借代登录身份扩大影响
+func NoImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if IsImpersonating(c) {
+			response.ErrorWithCode(c, errorx.ErrForbidden)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// InteractiveOnly 仅允许用户本人的交互式登录（JWT）访问，拒绝个人访问令牌与代登录令牌
+// 用于修改密码、两步验证、令牌管理等账号自身的敏感操作
+func InteractiveOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, isAPIKey := GetTokenScopes(c); isAPIKey || IsImpersonating(c) {
+			response.ErrorWithCode(c, errorx.ErrForbidden)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
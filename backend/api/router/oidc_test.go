@@ -0,0 +1,292 @@
+package router
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sine-io/sinx/api/handler"
+	"github.com/sine-io/sinx/api/middleware"
+	"github.com/sine-io/sinx/application/oidc/dto"
+	oidcAppService "github.com/sine-io/sinx/application/oidc/service"
+	rbacdto "github.com/sine-io/sinx/application/rbac/dto"
+	authService "github.com/sine-io/sinx/domain/auth/service"
+	"github.com/sine-io/sinx/domain/oidc/entity"
+	oidcService "github.com/sine-io/sinx/domain/oidc/service"
+	userEntity "github.com/sine-io/sinx/domain/user/entity"
+	"github.com/sine-io/sinx/domain/user/repository/usertest"
+	userService "github.com/sine-io/sinx/domain/user/service"
+	"github.com/sine-io/sinx/pkg/auth"
+	"github.com/sine-io/sinx/pkg/config"
+	"github.com/sine-io/sinx/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// In-memory implementations -------------------------------------------------
+
+type memClientRepo struct {
+	data map[string]*entity.OAuthClient
+}
+
+func (m *memClientRepo) Create(_ context.Context, c *entity.OAuthClient) error {
+	c.CreatedAt = time.Now()
+	m.data[c.ClientID] = c
+	return nil
+}
+func (m *memClientRepo) GetByClientID(_ context.Context, id string) (*entity.OAuthClient, error) {
+	return m.data[id], nil
+}
+func (m *memClientRepo) List(_ context.Context) ([]*entity.OAuthClient, error) {
+	var res []*entity.OAuthClient
+	for _, c := range m.data {
+		res = append(res, c)
+	}
+	return res, nil
+}
+func (m *memClientRepo) Delete(_ context.Context, id string) error { delete(m.data, id); return nil }
+
+type memCodeRepo struct {
+	data map[string]*entity.AuthorizationCode
+}
+
+func (m *memCodeRepo) Create(_ context.Context, c *entity.AuthorizationCode) error {
+	m.data[c.CodeHash] = c
+	return nil
+}
+func (m *memCodeRepo) Consume(_ context.Context, hash string) (*entity.AuthorizationCode, error) {
+	c, ok := m.data[hash]
+	if !ok || c.UsedAt != nil {
+		return nil, nil
+	}
+	now := time.Now()
+	c.UsedAt = &now
+	return c, nil
+}
+
+type stubClaims struct{}
+
+func (stubClaims) GetUserRoles(_ context.Context, _ uint) ([]*rbacdto.RoleSimple, error) {
+	return []*rbacdto.RoleSimple{{ID: 1, Name: "editor"}}, nil
+}
+func (stubClaims) GetUserPerms(_ context.Context, _ uint) (map[string]struct{}, error) {
+	return map[string]struct{}{"user:list": {}, "role:list": {}}, nil
+}
+
+// Test ----------------------------------------------------------------------
+
+// 授权码 + PKCE 全流程：发现文档 -> 授权 -> 换取令牌 -> 校验 ID 令牌 -> userinfo
+func TestOIDCAuthorizationCodeFlow(t *testing.T) {
+	_ = config.LoadEnv()
+	_ = logger.Init()
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	// Ed25519 签名密钥
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(priv)
+	keyPath := filepath.Join(t.TempDir(), "k1.pem")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ks, err := auth.LoadKeySet("k1:"+keyPath, "")
+	if err != nil {
+		t.Fatalf("load keys: %v", err)
+	}
+	auth.SetKeySet(ks)
+	defer auth.SetKeySet(nil)
+
+	users := usertest.NewUserRepo(&userEntity.User{ID: 1, Username: "alice", Email: "alice@example.com"})
+	oidcDomain := oidcService.NewOIDCDomainService(&memClientRepo{data: map[string]*entity.OAuthClient{}}, &memCodeRepo{data: map[string]*entity.AuthorizationCode{}})
+	revocations := auth.NewMemoryRevocationStore(time.Hour)
+	tokenService := authService.NewTokenDomainService(nil, nil, users, revocations, time.Hour)
+	svc := oidcAppService.NewOIDCApplicationService(oidcDomain, userService.NewUserDomainService(users, nil, "", nil), tokenService, stubClaims{}, "http://sinx.test")
+
+	r := gin.New()
+	setupOIDCRoutes(r, handler.NewOIDCHandler(svc, "https://console.test/login"), middleware.AuthMiddleware(nil, nil), middleware.InteractiveOnly())
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	httpClient := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	const redirectURI = "https://app.test/callback"
+	client, err := svc.CreateClient(ctx, &dto.ClientCreateRequest{Name: "app", RedirectURIs: []string{redirectURI}})
+	if err != nil {
+		t.Fatalf("create client: %v", err)
+	}
+
+	// 发现文档
+	var disco dto.DiscoveryDocument
+	getJSON(t, httpClient, srv.URL+"/.well-known/openid-configuration", "", &disco)
+	if disco.Issuer != "http://sinx.test" || disco.IDTokenSigningAlgValuesSupported[0] != "EdDSA" {
+		t.Fatalf("unexpected discovery: %+v", disco)
+	}
+
+	// 浏览器入口跳转到前端登录页并保留参数
+	resp, err := httpClient.Get(srv.URL + "/oauth2/authorize?client_id=" + client.ClientID + "&state=xyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if loc := resp.Header.Get("Location"); resp.StatusCode != http.StatusFound || !strings.HasPrefix(loc, "https://console.test/login?client_id=") {
+		t.Fatalf("unexpected authorize redirect: %d %s", resp.StatusCode, loc)
+	}
+
+	sessionToken, _ := auth.GenerateToken(1, "alice")
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	code := authorize(t, httpClient, srv.URL, sessionToken, client.ClientID, redirectURI, verifier)
+
+	// 授权码换令牌（client_secret_basic）
+	tokenResp := exchange(t, httpClient, srv.URL, client.ClientID, client.ClientSecret, code, redirectURI, verifier)
+	if tokenResp.status != http.StatusOK {
+		t.Fatalf("token exchange failed: %d %s", tokenResp.status, tokenResp.body)
+	}
+	var tokens dto.TokenResponse
+	_ = json.Unmarshal(tokenResp.body, &tokens)
+
+	idClaims := &oidcAppService.IDTokenClaims{}
+	if _, err := auth.ParseWithKeys(tokens.IDToken, idClaims); err != nil {
+		t.Fatalf("id token invalid: %v", err)
+	}
+	if idClaims.Subject != "1" || idClaims.Audience[0] != client.ClientID || idClaims.Nonce != "n-1" || idClaims.Email != "alice@example.com" {
+		t.Fatalf("unexpected id token claims: %+v", idClaims)
+	}
+	if len(idClaims.Roles) != 1 || idClaims.Roles[0] != "editor" || len(idClaims.Perms) != 2 || idClaims.Perms[0] != "role:list" {
+		t.Fatalf("roles/perms missing: %+v %+v", idClaims.Roles, idClaims.Perms)
+	}
+
+	var info dto.UserInfoResponse
+	getJSON(t, httpClient, srv.URL+"/oauth2/userinfo", tokens.AccessToken, &info)
+	if info.Sub != "1" || info.PreferredUsername != "alice" || len(info.Perms) != 2 {
+		t.Fatalf("unexpected userinfo: %+v", info)
+	}
+
+	// 授权码只能使用一次
+	if res := exchange(t, httpClient, srv.URL, client.ClientID, client.ClientSecret, code, redirectURI, verifier); res.status != http.StatusBadRequest || !strings.Contains(string(res.body), "invalid_grant") {
+		t.Fatalf("code replay accepted: %d %s", res.status, res.body)
+	}
+	// PKCE verifier 不匹配
+	code = authorize(t, httpClient, srv.URL, sessionToken, client.ClientID, redirectURI, verifier)
+	if res := exchange(t, httpClient, srv.URL, client.ClientID, client.ClientSecret, code, redirectURI, verifier+"x"); res.status != http.StatusBadRequest {
+		t.Fatalf("wrong verifier accepted: %d %s", res.status, res.body)
+	}
+	// 错误的客户端密钥
+	code = authorize(t, httpClient, srv.URL, sessionToken, client.ClientID, redirectURI, verifier)
+	if res := exchange(t, httpClient, srv.URL, client.ClientID, "wrong", code, redirectURI, verifier); res.status != http.StatusUnauthorized {
+		t.Fatalf("wrong client secret accepted: %d %s", res.status, res.body)
+	}
+	// OIDC 访问令牌不能调用 sinx 业务接口
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/oauth2/authorize", strings.NewReader("{}"))
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("oidc access token accepted by api: %d", resp.StatusCode)
+	}
+	// 用户令牌被吊销后 userinfo 拒绝已签发的 OIDC 访问令牌
+	if err := revocations.RevokeUser(ctx, 1, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	req, _ = http.NewRequest(http.MethodGet, srv.URL+"/oauth2/userinfo", nil)
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("revoked oidc access token accepted by userinfo: %d", resp.StatusCode)
+	}
+}
+
+type rawResponse struct {
+	status int
+	body   []byte
+}
+
+func authorize(t *testing.T, c *http.Client, base, sessionToken, clientID, redirectURI, verifier string) string {
+	t.Helper()
+	sum := sha256.Sum256([]byte(verifier))
+	body, _ := json.Marshal(dto.AuthorizeRequest{
+		ResponseType:        "code",
+		ClientID:            clientID,
+		RedirectURI:         redirectURI,
+		Scope:               "openid profile email",
+		State:               "xyz",
+		Nonce:               "n-1",
+		CodeChallenge:       base64.RawURLEncoding.EncodeToString(sum[:]),
+		CodeChallengeMethod: "S256",
+	})
+	req, _ := http.NewRequest(http.MethodPost, base+"/api/oauth2/authorize", strings.NewReader(string(body)))
+	req.Header.Set("Authorization", "Bearer "+sessionToken)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out struct {
+		Data dto.AuthorizeResponse `json:"data"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&out)
+	u, err := url.Parse(out.Data.RedirectTo)
+	if err != nil || !strings.HasPrefix(out.Data.RedirectTo, redirectURI) || u.Query().Get("state") != "xyz" || u.Query().Get("code") == "" {
+		t.Fatalf("unexpected authorize response: %d %q", resp.StatusCode, out.Data.RedirectTo)
+	}
+	return u.Query().Get("code")
+}
+
+func exchange(t *testing.T, c *http.Client, base, clientID, secret, code, redirectURI, verifier string) rawResponse {
+	t.Helper()
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	}
+	req, _ := http.NewRequest(http.MethodPost, base+"/oauth2/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(secret))
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return rawResponse{status: resp.StatusCode, body: body}
+}
+
+func getJSON(t *testing.T, c *http.Client, target, bearer string, out any) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, target, nil)
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s: status %d", target, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		t.Fatalf("decode %s: %v", target, err)
+	}
+}
@@ -1,115 +1,247 @@
 package router
 
 import (
+	"fmt"
+
 	"github.com/gin-gonic/gin"
 	"github.com/sine-io/sinx/api/handler"
 	"github.com/sine-io/sinx/api/middleware"
+	"github.com/sine-io/sinx/pkg/auth"
+	"github.com/sine-io/sinx/pkg/errorx"
 	"github.com/sine-io/sinx/pkg/permissions"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-func SetupRoutes(r *gin.Engine, userHandler *handler.UserHandler, rbacHandler *handler.RBACHandler) {
+// passwordExpiredAllowed 密码过期后仍可访问的接口
+var passwordExpiredAllowed = map[string]bool{
+	"/api/user/changePassword": true,
+	"/api/auth/logout":         true,
+	"/api/auth/logoutAll":      true,
+}
+
+// NewEngine 创建 gin 引擎；只有来自 trustedProxies 的请求才采信 X-Forwarded-For / X-Real-IP，
+// 为空时一律使用连接地址，防止客户端伪造来源 IP 绕过按 IP 的节流或写入虚假登录日志
+func NewEngine(trustedProxies []string) (*gin.Engine, error) {
+	r := gin.New()
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	return r, nil
+}
+
+func SetupRoutes(r *gin.Engine, userHandler *handler.UserHandler, rbacHandler *handler.RBACHandler, oidcHandler *handler.OIDCHandler, federationHandler *handler.FederationHandler, ldapHandler *handler.LDAPHandler, accessHandler *handler.AccessHandler, deptHandler *handler.DeptHandler) {
 	// 设置全局中间件
 	r.Use(middleware.RecoveryMiddleware())
 	r.Use(middleware.LoggerMiddleware())
 	r.Use(middleware.CORSMiddleware())
 
+	// 令牌校验：拒绝已吊销令牌及非正常状态用户的令牌；密码过期时只放行修改密码与登出
+	tokenValidator := func(c *gin.Context, claims *auth.Claims) error {
+		err := userHandler.Service().ValidateToken(c.Request.Context(), claims)
+		if appErr, ok := err.(*errorx.Error); ok && appErr.Code == errorx.ErrPasswordExpired && passwordExpiredAllowed[c.FullPath()] {
+			return nil
+		}
+		return err
+	}
+	// 个人访问令牌：供 CI / 内部服务使用，权限受令牌范围限制
+	apiKeyResolver := func(c *gin.Context, raw string) (*auth.Claims, []string, error) {
+		return userHandler.Service().AuthenticateAPIKey(c.Request.Context(), raw)
+	}
+	authMW := middleware.AuthMiddleware(tokenValidator, apiKeyResolver)
+	interactive := middleware.InteractiveOnly()
+	noImpersonation := middleware.NoImpersonation()
+
 	// API路由组
 	api := r.Group("/api")
 	{
-		// 认证相关路由（不需要JWT验证）
-		auth := api.Group("/auth")
+		// 认证相关路由（登录类接口不需要JWT验证）
+		authGroup := api.Group("/auth")
 		{
-			auth.POST("/register", userHandler.Register)
-			auth.POST("/login", userHandler.Login)
+			authGroup.POST("/register", userHandler.Register)
+			authGroup.POST("/login", userHandler.Login)
+			authGroup.POST("/refresh", userHandler.Refresh)
+			authGroup.POST("/mfa/verify", userHandler.VerifyMFA)
+			authGroup.POST("/password/forgot", userHandler.ForgotPassword)
+			authGroup.POST("/password/reset", userHandler.ResetPassword)
+			authGroup.POST("/email/verify", userHandler.VerifyEmail)
+			authGroup.POST("/email/resend", userHandler.ResendVerification)
+			authGroup.POST("/logout", authMW, interactive, userHandler.Logout)
+			authGroup.POST("/logoutAll", authMW, interactive, userHandler.LogoutAll)
+			// 外部身份提供方登录（浏览器跳转）
+			authGroup.GET("/federation/providers", federationHandler.Providers)
+			authGroup.GET("/federation/:provider/login", federationHandler.Login)
+			authGroup.GET("/federation/:provider/callback", federationHandler.Callback)
 		}
 
 		// 用户相关路由（需要JWT验证）
-		// 简单权限检查器（每次实时查询，可后续增加缓存）
-		permChecker := func(c *gin.Context, required string) bool {
+		// 当前请求实际生效的权限：用户权限；个人访问令牌再与令牌范围取交集（范围均为具体权限点）
+		effectivePerms := func(c *gin.Context) (*permissions.PermSet, error) {
 			uid, ok := middleware.GetUserID(c)
 			if !ok {
-				return false
+				return nil, errorx.NewWithCode(errorx.ErrUnauthorized)
+			}
+			perms, err := rbacHandler.Service().GetUserPermSet(c, uid)
+			if err != nil {
+				return nil, err
+			}
+			scopes, isAPIKey := middleware.GetTokenScopes(c)
+			if !isAPIKey {
+				return perms, nil
 			}
-			perms, err := rbacHandler.Service().GetUserPerms(c, uid)
+			scoped := make(map[string]struct{}, len(scopes))
+			for _, s := range scopes {
+				if perms.Has(s) {
+					scoped[s] = struct{}{}
+				}
+			}
+			return permissions.NewPermSet(scoped), nil
+		}
+
+		// 权限检查器：按段匹配，支持 user:*、*:list、* 等通配权限
+		permChecker := func(c *gin.Context, required string) bool {
+			perms, err := effectivePerms(c)
 			if err != nil {
 				return false
 			}
-			_, exist := perms[required]
-			return exist
+			return perms.Has(required)
 		}
 
 		user := api.Group("/user")
-		user.Use(middleware.AuthMiddleware())
+		user.Use(authMW)
 		{
 			user.GET("/profile", userHandler.GetProfile)
-			user.POST("/create", middleware.PermissionMiddleware("user:create", permChecker), rbacHandler.CreateUser)
+			user.POST("/create", noImpersonation, middleware.PermissionMiddleware("user:create", permChecker), rbacHandler.CreateUser)
 			user.GET("/list", middleware.PermissionMiddleware("user:list", permChecker), rbacHandler.UserList)
-			user.POST("/update", middleware.PermissionMiddleware("user:update", permChecker), rbacHandler.UpdateUser)
-			user.POST("/delete", middleware.PermissionMiddleware("user:delete", permChecker), rbacHandler.DeleteUser)
-			user.POST("/changePassword", rbacHandler.ChangePassword)
-			user.POST("/bindRole", middleware.PermissionMiddleware("user:bindRole", permChecker), rbacHandler.BindUserRole)
-			user.POST("/unbindRole", middleware.PermissionMiddleware("user:unbindRole", permChecker), rbacHandler.UnbindUserRole)
+			user.POST("/update", noImpersonation, middleware.PermissionMiddleware("user:update", permChecker), rbacHandler.UpdateUser)
+			user.POST("/delete", noImpersonation, middleware.PermissionMiddleware("user:delete", permChecker), rbacHandler.DeleteUser)
+			user.POST("/changePassword", interactive, userHandler.ChangePassword)
+			user.POST("/resetPassword", noImpersonation, middleware.PermissionMiddleware("user:resetPassword", permChecker), userHandler.AdminResetPassword)
+			user.POST("/bindRole", noImpersonation, middleware.PermissionMiddleware("user:bindRole", permChecker), rbacHandler.BindUserRole)
+			user.POST("/unbindRole", noImpersonation, middleware.PermissionMiddleware("user:unbindRole", permChecker), rbacHandler.UnbindUserRole)
 			user.GET("/roles", middleware.PermissionMiddleware("user:roles", permChecker), rbacHandler.GetUserRoles)
 			user.GET("/menus", rbacHandler.GetUserMenus)
+			user.GET("/deniedPerms", middleware.PermissionMiddleware("user:deniedPerms", permChecker), rbacHandler.GetUserDeniedPerms)
+			user.POST("/mfa/enroll", interactive, userHandler.EnrollMFA)
+			user.POST("/mfa/activate", interactive, userHandler.ActivateMFA)
+			user.GET("/mfa/status", interactive, userHandler.GetMFAStatus)
+			user.POST("/token/create", interactive, userHandler.CreateAccessToken)
+			user.GET("/token/list", interactive, userHandler.ListAccessTokens)
+			user.POST("/token/delete", interactive, userHandler.DeleteAccessToken)
+			user.GET("/sessions", interactive, userHandler.ListSessions)
+			user.POST("/sessions/revoke", interactive, userHandler.RevokeSession)
+			user.GET("/identity/list", interactive, federationHandler.ListIdentities)
+			user.POST("/identity/link", interactive, federationHandler.Link)
+			user.POST("/identity/unlink", interactive, federationHandler.Unlink)
+			user.POST("/mfa/reset", noImpersonation, middleware.PermissionMiddleware("user:resetMfa", permChecker), userHandler.ResetMFA)
+			user.POST("/impersonate", interactive, middleware.PermissionMiddleware("user:impersonate", permChecker), userHandler.Impersonate)
 		}
 
-		role := api.Group("/role").Use(middleware.AuthMiddleware())
+		role := api.Group("/role").Use(authMW)
 		{
-			role.POST("/create", middleware.PermissionMiddleware("role:create", permChecker), rbacHandler.CreateRole)
+			role.POST("/create", noImpersonation, middleware.PermissionMiddleware("role:create", permChecker), rbacHandler.CreateRole)
 			role.GET("/list", middleware.PermissionMiddleware("role:list", permChecker), rbacHandler.RoleList)
-			role.POST("/update", middleware.PermissionMiddleware("role:update", permChecker), rbacHandler.UpdateRole)
-			role.POST("/delete", middleware.PermissionMiddleware("role:delete", permChecker), rbacHandler.DeleteRole)
-			role.POST("/bindMenu", middleware.PermissionMiddleware("role:bindMenu", permChecker), rbacHandler.BindRoleMenu)
-			role.POST("/unbindMenu", middleware.PermissionMiddleware("role:unbindMenu", permChecker), rbacHandler.UnbindRoleMenu)
+			role.POST("/update", noImpersonation, middleware.PermissionMiddleware("role:update", permChecker), rbacHandler.UpdateRole)
+			role.POST("/delete", noImpersonation, middleware.PermissionMiddleware("role:delete", permChecker), rbacHandler.DeleteRole)
+			role.POST("/bindMenu", noImpersonation, middleware.PermissionMiddleware("role:bindMenu", permChecker), rbacHandler.BindRoleMenu)
+			role.POST("/unbindMenu", noImpersonation, middleware.PermissionMiddleware("role:unbindMenu", permChecker), rbacHandler.UnbindRoleMenu)
 			role.GET("/menus", middleware.PermissionMiddleware("role:menus", permChecker), rbacHandler.GetRoleMenus)
 			role.GET("/users", middleware.PermissionMiddleware("role:users", permChecker), rbacHandler.GetRoleUsers)
+			role.GET("/perms", middleware.PermissionMiddleware("role:perms", permChecker), rbacHandler.GetRolePerms)
+			role.POST("/approvers", noImpersonation, middleware.PermissionMiddleware("role:approvers", permChecker), accessHandler.SetApprovers)
+			role.GET("/approvers", middleware.PermissionMiddleware("role:approvers", permChecker), accessHandler.GetApprovers)
+			role.POST("/sod/save", noImpersonation, middleware.PermissionMiddleware("role:sod", permChecker), rbacHandler.SaveRoleConstraint)
+			role.POST("/sod/delete", noImpersonation, middleware.PermissionMiddleware("role:sod", permChecker), rbacHandler.DeleteRoleConstraint)
+			role.GET("/sod/list", middleware.PermissionMiddleware("role:sod", permChecker), rbacHandler.RoleConstraintList)
+			role.GET("/sod/report", middleware.PermissionMiddleware("role:sodReport", permChecker), rbacHandler.SoDReport)
 		}
 
-		menu := api.Group("/menu").Use(middleware.AuthMiddleware())
+		menu := api.Group("/menu").Use(authMW)
 		{
-			menu.POST("/create", middleware.PermissionMiddleware("menu:create", permChecker), rbacHandler.CreateMenu)
+			menu.POST("/create", noImpersonation, middleware.PermissionMiddleware("menu:create", permChecker), rbacHandler.CreateMenu)
 			menu.GET("/list", middleware.PermissionMiddleware("menu:list", permChecker), rbacHandler.MenuList)
-			menu.POST("/update", middleware.PermissionMiddleware("menu:update", permChecker), rbacHandler.UpdateMenu)
-			menu.POST("/delete", middleware.PermissionMiddleware("menu:delete", permChecker), rbacHandler.DeleteMenu)
+			menu.POST("/update", noImpersonation, middleware.PermissionMiddleware("menu:update", permChecker), rbacHandler.UpdateMenu)
+			menu.POST("/delete", noImpersonation, middleware.PermissionMiddleware("menu:delete", permChecker), rbacHandler.DeleteMenu)
 			menu.GET("/roles", middleware.PermissionMiddleware("menu:roles", permChecker), rbacHandler.MenuRoles)
 			menu.GET("/tree", rbacHandler.MenuTree)
 			menu.GET("/roleMenuTree", middleware.PermissionMiddleware("menu:roleMenuTree", permChecker), rbacHandler.GetRoleMenuTree)
 		}
 
+		dept := api.Group("/dept").Use(authMW)
+		{
+			dept.POST("/create", noImpersonation, middleware.PermissionMiddleware("dept:create", permChecker), deptHandler.CreateDept)
+			dept.POST("/update", noImpersonation, middleware.PermissionMiddleware("dept:update", permChecker), deptHandler.UpdateDept)
+			dept.POST("/delete", noImpersonation, middleware.PermissionMiddleware("dept:delete", permChecker), deptHandler.DeleteDept)
+			dept.GET("/tree", middleware.PermissionMiddleware("dept:list", permChecker), deptHandler.DeptTree)
+		}
+
+		// 角色申请：用户自助申请，由角色审批人审批（审批人身份在服务层校验）；代登录令牌不能代目标用户提交、撤回或评论
+		access := api.Group("/access").Use(authMW)
+		{
+			access.POST("/request", interactive, accessHandler.Submit)
+			access.GET("/mine", accessHandler.ListMine)
+			access.GET("/pending", accessHandler.ListPending)
+			access.GET("/detail", accessHandler.Detail)
+			access.POST("/approve", interactive, accessHandler.Approve)
+			access.POST("/reject", interactive, accessHandler.Reject)
+			access.POST("/cancel", noImpersonation, accessHandler.Cancel)
+			access.POST("/comment", noImpersonation, accessHandler.Comment)
+			access.GET("/list", middleware.PermissionMiddleware("access:list", permChecker), accessHandler.List)
+		}
+
+		// OIDC 客户端登记
+		oidcClient := api.Group("/oidc/client").Use(authMW)
+		{
+			oidcClient.POST("/create", noImpersonation, middleware.PermissionMiddleware("oidcClient:create", permChecker), oidcHandler.CreateClient)
+			oidcClient.GET("/list", middleware.PermissionMiddleware("oidcClient:list", permChecker), oidcHandler.ListClients)
+			oidcClient.POST("/delete", noImpersonation, middleware.PermissionMiddleware("oidcClient:delete", permChecker), oidcHandler.DeleteClient)
+		}
+
+		// 安全管理：登录锁定查询与解除、LDAP 目录同步
+		security := api.Group("/security").Use(authMW)
+		{
+			security.GET("/lockouts", middleware.PermissionMiddleware("security:lockouts", permChecker), userHandler.ListLockouts)
+			security.POST("/unlock", noImpersonation, middleware.PermissionMiddleware("security:unlock", permChecker), userHandler.ClearLockout)
+			security.POST("/ldapSync", noImpersonation, middleware.PermissionMiddleware("security:ldapSync", permChecker), ldapHandler.Sync)
+			security.GET("/sessions", middleware.PermissionMiddleware("security:sessions", permChecker), userHandler.ListOnlineSessions)
+			security.POST("/forceLogout", noImpersonation, middleware.PermissionMiddleware("security:forceLogout", permChecker), userHandler.ForceLogout)
+			security.GET("/loginLogs", middleware.PermissionMiddleware("security:loginLogs", permChecker), userHandler.ListLoginLogs)
+		}
+
 		// 仪表盘统计（仅需要登录，不做细粒度权限限制）
-		stats := api.Group("/stats").Use(middleware.AuthMiddleware())
+		stats := api.Group("/stats").Use(authMW)
 		{
 			stats.GET("/overview", rbacHandler.StatsOverview)
 		}
 
 		// 导出所有权限（需登录，便于前端动态渲染）
-		api.GET("/perms/all", middleware.AuthMiddleware(), func(c *gin.Context) {
+		api.GET("/perms/all", authMW, func(c *gin.Context) {
 			c.JSON(200, gin.H{"code": 0, "data": permissions.AllPerms})
 		})
 
 		// 返回当前用户拥有的权限标识集合（前端可用于按钮/接口按需请求）
-		api.GET("/perms/me", middleware.AuthMiddleware(), func(c *gin.Context) {
-			uid, ok := middleware.GetUserID(c)
-			if !ok {
+		api.GET("/perms/me", authMW, func(c *gin.Context) {
+			if _, ok := middleware.GetUserID(c); !ok {
 				c.JSON(401, gin.H{"code": 10003, "message": "未认证"})
 				return
 			}
-			perms, err := rbacHandler.Service().GetUserPerms(c, uid)
+			perms, err := effectivePerms(c)
 			if err != nil {
 				c.JSON(500, gin.H{"code": 1, "message": err.Error()})
 				return
 			}
-			// 转为 slice
-			list := make([]string, 0, len(perms))
-			for k := range perms {
+			// 转为 slice：已扣除拒绝规则；未被拒绝的通配权限原样返回（由前端按段匹配），并展开为内置权限点
+			effective := perms.Effective(permissions.AllPerms)
+			list := make([]string, 0, len(effective))
+			for k := range effective {
 				list = append(list, k)
 			}
 			c.JSON(200, gin.H{"code": 0, "data": list})
 		})
 	}
 
+	setupOIDCRoutes(r, oidcHandler, authMW, interactive)
+
 	// 健康检查路由
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -118,6 +250,23 @@ func SetupRoutes(r *gin.Engine, userHandler *handler.UserHandler, rbacHandler *h
 		})
 	})
 
+	// 公钥集合，供下游服务离线验证访问令牌（按 kid 选择公钥）
+	r.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(200, auth.CurrentJWKS())
+	})
+
 	// Swagger 文档路由 (/swagger/index.html)
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 }
+
+// setupOIDCRoutes OIDC Provider 协议端点（授权码 + PKCE）
+func setupOIDCRoutes(r *gin.Engine, oidcHandler *handler.OIDCHandler, authMW, interactive gin.HandlerFunc) {
+	r.GET("/.well-known/openid-configuration", oidcHandler.Discovery)
+	r.GET("/oauth2/authorize", oidcHandler.AuthorizeRedirect)
+	r.POST("/oauth2/token", oidcHandler.Token)
+	r.GET("/oauth2/userinfo", oidcHandler.UserInfo)
+	r.POST("/oauth2/userinfo", oidcHandler.UserInfo)
+	// 前端完成登录后提交授权请求
+	r.POST("/api/oauth2/authorize", authMW, interactive, oidcHandler.Authorize)
+}
@@ -0,0 +1,60 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sine-io/sinx/pkg/loginguard"
+)
+
+// 未配置可信代理时伪造的 X-Forwarded-For 不改变节流所用的客户端 IP
+func TestSpoofedForwardedForDoesNotChangeThrottleKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	guard := loginguard.NewGuard(loginguard.Policy{Window: time.Minute, MaxIPFailures: 2, LockoutDuration: time.Minute}, loginguard.NewMemoryStore())
+
+	post := func(r *gin.Engine, forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.RemoteAddr = "10.0.0.1:40000"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	newEngine := func(proxies []string) *gin.Engine {
+		r, err := NewEngine(proxies)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.POST("/login", func(c *gin.Context) {
+			if d, _ := guard.Check(c, "", c.ClientIP()); !d.Allowed {
+				c.String(http.StatusTooManyRequests, c.ClientIP())
+				return
+			}
+			_, _ = guard.RecordFailure(c, "", c.ClientIP())
+			c.String(http.StatusUnauthorized, c.ClientIP())
+		})
+		return r
+	}
+
+	r := newEngine(nil)
+	for i, ip := range []string{"203.0.113.1", "203.0.113.2"} {
+		if w := post(r, ip); w.Code != http.StatusUnauthorized || w.Body.String() != "10.0.0.1" {
+			t.Fatalf("attempt %d: got %d %q", i+1, w.Code, w.Body.String())
+		}
+	}
+	// 轮换伪造的来源 IP 无法绕过按 IP 的锁定
+	if w := post(r, "203.0.113.3"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected lockout of the connection address, got %d", w.Code)
+	}
+
+	// 来自可信代理时采信其转发的客户端地址
+	if w := post(newEngine([]string{"10.0.0.0/8"}), "198.51.100.7"); w.Body.String() != "198.51.100.7" {
+		t.Fatalf("expected forwarded address from trusted proxy, got %q", w.Body.String())
+	}
+	if _, err := NewEngine([]string{"not-an-ip"}); err == nil {
+		t.Fatal("invalid proxy must be rejected")
+	}
+}
@@ -0,0 +1,61 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/sine-io/sinx/domain/access/entity"
+)
+
+// SubmitRequest 申请角色
+type SubmitRequest struct {
+	RoleID        uint   `json:"roleId" binding:"required"`
+	Reason        string `json:"reason" binding:"required,max=500"`
+	DurationHours int    `json:"durationHours" binding:"min=0,max=8760"` // 授权时长（小时），0 表示长期
+}
+
+// DecisionRequest 审批通过 / 驳回 / 撤回
+type DecisionRequest struct {
+	ID      uint   `json:"id" binding:"required"`
+	Comment string `json:"comment" binding:"max=500"`
+}
+
+type CommentRequest struct {
+	ID      uint   `json:"id" binding:"required"`
+	Comment string `json:"comment" binding:"required,max=500"`
+}
+
+// ListQuery 申请列表查询条件
+type ListQuery struct {
+	PageNum  int    `form:"pageNum"`
+	PageSize int    `form:"pageSize"`
+	UserID   uint   `form:"userId"`
+	RoleID   uint   `form:"roleId"`
+	Status   string `form:"status" binding:"omitempty,oneof=pending approved rejected cancelled"`
+}
+
+type AccessRequestItem struct {
+	ID            uint       `json:"id"`
+	UserID        uint       `json:"userId"`
+	Username      string     `json:"username"`
+	RoleID        uint       `json:"roleId"`
+	RoleName      string     `json:"roleName"`
+	Reason        string     `json:"reason"`
+	Status        string     `json:"status"`
+	DurationHours int        `json:"durationHours"`
+	ValidUntil    *time.Time `json:"validUntil,omitempty"`
+	DeciderID     uint       `json:"deciderId"`
+	DecidedAt     *time.Time `json:"decidedAt,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+// AccessRequestDetail 申请详情及全部状态变更、评论记录
+type AccessRequestDetail struct {
+	*AccessRequestItem
+	Events []*entity.AccessRequestEvent `json:"events"`
+}
+
+// ApproversRequest 以 userIds 整体替换角色的审批人，传空数组表示清空
+type ApproversRequest struct {
+	RoleID  uint   `json:"roleId" binding:"required"`
+	UserIDs []uint `json:"userIds"`
+}
@@ -0,0 +1,440 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sine-io/sinx/application/access/dto"
+	rbacdto "github.com/sine-io/sinx/application/rbac/dto"
+	"github.com/sine-io/sinx/domain/access/entity"
+	"github.com/sine-io/sinx/domain/access/repository"
+	roleRepo "github.com/sine-io/sinx/domain/role/repository"
+	userRepo "github.com/sine-io/sinx/domain/user/repository"
+	"github.com/sine-io/sinx/pkg/errorx"
+	"github.com/sine-io/sinx/pkg/logger"
+	"github.com/sine-io/sinx/pkg/permissions"
+)
+
+// RoleGranter 角色绑定（由 RBAC 应用服务实现，负责刷新权限缓存）
+type RoleGranter interface {
+	GrantUserRoles(ctx context.Context, userID uint, roleIDs []uint, validFrom, validUntil *time.Time) (added, skipped int, err error)
+	GetUserRoles(ctx context.Context, userID uint) ([]*rbacdto.RoleSimple, error)
+	IsSuperAdmin(ctx context.Context, userID uint) bool
+	GetUserPermSet(ctx context.Context, userID uint) (*permissions.PermSet, error)
+	GetRolePerms(ctx context.Context, roleID uint) (*rbacdto.RolePermsResponse, error)
+}
+
+// Notification 申请状态变更或新评论
+type Notification struct {
+	Action     string // entity.AccessAction*
+	Request    *entity.AccessRequest
+	ActorID    uint
+	Comment    string
+	Recipients []uint // 提交、撤回及申请人的评论通知审批人，其余通知申请人
+}
+
+// Notifier 通知钩子；Notify 不应阻塞请求，失败只记录日志
+type Notifier interface {
+	Notify(ctx context.Context, n *Notification)
+}
+
+type AccessApplicationService struct {
+	repo      repository.AccessRequestRepository
+	roleRepo  roleRepo.RoleRepository
+	userRepo  userRepo.UserRepository
+	granter   RoleGranter
+	notifiers []Notifier
+}
+
+func NewAccessApplicationService(repo repository.AccessRequestRepository, roleRepo roleRepo.RoleRepository, userRepo userRepo.UserRepository, granter RoleGranter, notifiers ...Notifier) *AccessApplicationService {
+	return &AccessApplicationService{repo: repo, roleRepo: roleRepo, userRepo: userRepo, granter: granter, notifiers: notifiers}
+}
+
+// Submit 申请角色：角色须为正常状态且已指定审批人，同一角色同时只能有一个待审批申请
+func (s *AccessApplicationService) Submit(ctx context.Context, userID uint, req *dto.SubmitRequest) (*dto.AccessRequestItem, error) {
+	role, err := s.roleRepo.GetByID(ctx, req.RoleID)
+	if err != nil || role == nil {
+		return nil, errorx.New(errorx.ErrNotFound, "role not found")
+	}
+	if role.Status != 0 {
+		return nil, errorx.New(errorx.ErrInvalidParam, "role is disabled")
+	}
+	approvers, err := s.repo.GetApprovers(ctx, req.RoleID)
+	if err != nil {
+		return nil, err
+	}
+	if len(approvers) == 0 {
+		return nil, errorx.New(errorx.ErrInvalidParam, "role does not accept access requests")
+	}
+	current, err := s.granter.GetUserRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range current {
+		if r.ID == req.RoleID {
+			return nil, errorx.New(errorx.ErrInvalidParam, "role already granted")
+		}
+	}
+	pending, err := s.repo.HasPending(ctx, userID, req.RoleID)
+	if err != nil {
+		return nil, err
+	}
+	if pending {
+		return nil, errorx.New(errorx.ErrInvalidParam, "a pending request for this role already exists")
+	}
+
+	ar := &entity.AccessRequest{UserID: userID, RoleID: req.RoleID, Reason: req.Reason, Status: entity.AccessStatusPending, DurationHours: req.DurationHours}
+	event := &entity.AccessRequestEvent{ActorID: userID, Action: entity.AccessActionSubmit, ToStatus: entity.AccessStatusPending, Comment: req.Reason}
+	if err := s.repo.Create(ctx, ar, event); err != nil {
+		return nil, err
+	}
+	logger.Info("audit:access_request_submit", "requestId", ar.ID, "userId", userID, "roleId", req.RoleID, "durationHours", req.DurationHours)
+	s.notify(ctx, &Notification{Action: entity.AccessActionSubmit, Request: ar, ActorID: userID, Comment: req.Reason, Recipients: approvers})
+	return s.item(ctx, ar), nil
+}
+
+// Approve 审批通过并绑定角色；设置了授权时长时绑定到期后自动失效
+func (s *AccessApplicationService) Approve(ctx context.Context, actorID uint, req *dto.DecisionRequest) error {
+	ar, err := s.decidable(ctx, actorID, req.ID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	ar.Status, ar.DeciderID, ar.DecidedAt = entity.AccessStatusApproved, actorID, &now
+	if ar.DurationHours > 0 {
+		until := now.Add(time.Duration(ar.DurationHours) * time.Hour)
+		ar.ValidUntil = &until
+	}
+	// 先占用状态再绑定，避免与驳回 / 撤回并发时绑定了已结束的申请
+	if err := s.transition(ctx, ar, entity.AccessStatusPending, actorID, entity.AccessActionApprove, req.Comment); err != nil {
+		return err
+	}
+	if _, _, err := s.granter.GrantUserRoles(ctx, ar.UserID, []uint{ar.RoleID}, nil, ar.ValidUntil); err != nil {
+		logger.Warn("access_request_bind_failed", "requestId", ar.ID, "error", err)
+		ar.Status, ar.DeciderID, ar.DecidedAt, ar.ValidUntil = entity.AccessStatusPending, 0, nil, nil
+		if _, rerr := s.repo.Transition(ctx, ar, entity.AccessStatusApproved, &entity.AccessRequestEvent{
+			ActorID: actorID, Action: entity.AccessActionRevert, FromStatus: entity.AccessStatusApproved, ToStatus: entity.AccessStatusPending, Comment: "bind role failed",
+		}); rerr != nil {
+			logger.Warn("access_request_revert_failed", "requestId", ar.ID, "error", rerr)
+		}
+		return err
+	}
+	logger.Info("audit:access_request_approve", "requestId", ar.ID, "actorId", actorID, "userId", ar.UserID, "roleId", ar.RoleID, "validUntil", ar.ValidUntil)
+	s.notify(ctx, &Notification{Action: entity.AccessActionApprove, Request: ar, ActorID: actorID, Comment: req.Comment, Recipients: []uint{ar.UserID}})
+	return nil
+}
+
+// Reject 驳回申请
+func (s *AccessApplicationService) Reject(ctx context.Context, actorID uint, req *dto.DecisionRequest) error {
+	ar, err := s.decidable(ctx, actorID, req.ID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	ar.Status, ar.DeciderID, ar.DecidedAt = entity.AccessStatusRejected, actorID, &now
+	if err := s.transition(ctx, ar, entity.AccessStatusPending, actorID, entity.AccessActionReject, req.Comment); err != nil {
+		return err
+	}
+	logger.Info("audit:access_request_reject", "requestId", ar.ID, "actorId", actorID, "userId", ar.UserID, "roleId", ar.RoleID)
+	s.notify(ctx, &Notification{Action: entity.AccessActionReject, Request: ar, ActorID: actorID, Comment: req.Comment, Recipients: []uint{ar.UserID}})
+	return nil
+}
+
+// Cancel 申请人撤回待审批的申请
+func (s *AccessApplicationService) Cancel(ctx context.Context, actorID uint, req *dto.DecisionRequest) error {
+	ar, err := s.load(ctx, req.ID)
+	if err != nil {
+		return err
+	}
+	if ar.UserID != actorID {
+		return errorx.New(errorx.ErrForbidden, "only the requester can cancel the request")
+	}
+	if !ar.IsPending() {
+		return errorx.New(errorx.ErrInvalidParam, fmt.Sprintf("request is already %s", ar.Status))
+	}
+	now := time.Now()
+	ar.Status, ar.DecidedAt = entity.AccessStatusCancelled, &now
+	if err := s.transition(ctx, ar, entity.AccessStatusPending, actorID, entity.AccessActionCancel, req.Comment); err != nil {
+		return err
+	}
+	logger.Info("audit:access_request_cancel", "requestId", ar.ID, "userId", actorID, "roleId", ar.RoleID)
+	approvers, _ := s.repo.GetApprovers(ctx, ar.RoleID)
+	s.notify(ctx, &Notification{Action: entity.AccessActionCancel, Request: ar, ActorID: actorID, Comment: req.Comment, Recipients: approvers})
+	return nil
+}
+
+// Comment 申请人、审批人或超级管理员评论，不改变状态
+func (s *AccessApplicationService) Comment(ctx context.Context, actorID uint, req *dto.CommentRequest) error {
+	ar, err := s.visible(ctx, actorID, req.ID)
+	if err != nil {
+		return err
+	}
+	event := &entity.AccessRequestEvent{RequestID: ar.ID, ActorID: actorID, Action: entity.AccessActionComment, FromStatus: ar.Status, ToStatus: ar.Status, Comment: req.Comment}
+	if err := s.repo.AddEvent(ctx, event); err != nil {
+		return err
+	}
+	logger.Info("audit:access_request_comment", "requestId", ar.ID, "actorId", actorID)
+	recipients := []uint{ar.UserID}
+	if actorID == ar.UserID {
+		recipients, _ = s.repo.GetApprovers(ctx, ar.RoleID)
+	}
+	s.notify(ctx, &Notification{Action: entity.AccessActionComment, Request: ar, ActorID: actorID, Comment: req.Comment, Recipients: recipients})
+	return nil
+}
+
+// Get 申请详情：申请人、该角色的审批人与超级管理员可见
+func (s *AccessApplicationService) Get(ctx context.Context, actorID, id uint) (*dto.AccessRequestDetail, error) {
+	ar, err := s.visible(ctx, actorID, id)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.repo.ListEvents(ctx, ar.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.AccessRequestDetail{AccessRequestItem: s.item(ctx, ar), Events: events}, nil
+}
+
+// ListMine 当前用户提交的申请
+func (s *AccessApplicationService) ListMine(ctx context.Context, userID uint, q *dto.ListQuery) (int64, []*dto.AccessRequestItem, error) {
+	return s.list(ctx, repository.AccessRequestFilter{UserID: userID, Status: q.Status}, q)
+}
+
+// ListPending 当前用户可审批的申请（默认只看待审批的）；超级管理员可见全部角色
+func (s *AccessApplicationService) ListPending(ctx context.Context, approverID uint, q *dto.ListQuery) (int64, []*dto.AccessRequestItem, error) {
+	filter := repository.AccessRequestFilter{UserID: q.UserID, Status: q.Status}
+	if filter.Status == "" {
+		filter.Status = entity.AccessStatusPending
+	}
+	if !s.granter.IsSuperAdmin(ctx, approverID) {
+		roles, err := s.repo.GetApproverRoles(ctx, approverID)
+		if err != nil {
+			return 0, nil, err
+		}
+		if len(roles) == 0 {
+			return 0, []*dto.AccessRequestItem{}, nil
+		}
+		filter.RoleIDs = roles
+	}
+	if q.RoleID != 0 {
+		if filter.RoleIDs != nil && !containsID(filter.RoleIDs, q.RoleID) {
+			return 0, []*dto.AccessRequestItem{}, nil
+		}
+		filter.RoleIDs = []uint{q.RoleID}
+	}
+	return s.list(ctx, filter, q)
+}
+
+// List 管理端查询全部申请
+func (s *AccessApplicationService) List(ctx context.Context, q *dto.ListQuery) (int64, []*dto.AccessRequestItem, error) {
+	filter := repository.AccessRequestFilter{UserID: q.UserID, Status: q.Status}
+	if q.RoleID != 0 {
+		filter.RoleIDs = []uint{q.RoleID}
+	}
+	return s.list(ctx, filter, q)
+}
+
+// SetApprovers 设置角色审批人，审批人须为正常状态的用户
+// 指定审批人等同于转授该角色，操作人须为超级管理员或自身拥有该角色授予的全部权限
+func (s *AccessApplicationService) SetApprovers(ctx context.Context, operatorID uint, req *dto.ApproversRequest) error {
+	if role, err := s.roleRepo.GetByID(ctx, req.RoleID); err != nil || role == nil {
+		return errorx.New(errorx.ErrNotFound, "role not found")
+	}
+	if err := s.ensureCoversRole(ctx, operatorID, req.RoleID); err != nil {
+		return err
+	}
+	seen := map[uint]struct{}{}
+	ids := make([]uint, 0, len(req.UserIDs))
+	for _, id := range req.UserIDs {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		u, err := s.userRepo.GetByID(ctx, id)
+		if err != nil || u == nil || u.ID == 0 || u.Status != 0 {
+			return errorx.New(errorx.ErrInvalidParam, fmt.Sprintf("user %d not found or disabled", id))
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	if err := s.repo.SetApprovers(ctx, req.RoleID, ids); err != nil {
+		return err
+	}
+	logger.Info("audit:set_role_approvers", "operatorId", operatorID, "roleId", req.RoleID, "userIds", ids)
+	return nil
+}
+
+// ensureCoversRole operatorID 为 0 表示系统调用；通配权限按字面段比较，即操作人须覆盖整个模式
+func (s *AccessApplicationService) ensureCoversRole(ctx context.Context, operatorID, roleID uint) error {
+	if operatorID == 0 || s.granter.IsSuperAdmin(ctx, operatorID) {
+		return nil
+	}
+	granted, err := s.granter.GetUserPermSet(ctx, operatorID)
+	if err != nil {
+		return err
+	}
+	rolePerms, err := s.granter.GetRolePerms(ctx, roleID)
+	if err != nil {
+		return err
+	}
+	perms := rolePerms.Own
+	for _, p := range rolePerms.Inherited {
+		perms = append(perms, p.Perm)
+	}
+	for _, perm := range perms {
+		if !granted.Has(perm) {
+			return errorx.New(errorx.ErrForbidden, "role grants permissions you do not hold: "+perm)
+		}
+	}
+	return nil
+}
+
+func (s *AccessApplicationService) GetApprovers(ctx context.Context, roleID uint) ([]uint, error) {
+	return s.repo.GetApprovers(ctx, roleID)
+}
+
+func (s *AccessApplicationService) load(ctx context.Context, id uint) (*entity.AccessRequest, error) {
+	ar, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ar == nil {
+		return nil, errorx.New(errorx.ErrNotFound, "access request not found")
+	}
+	return ar, nil
+}
+
+// isApprover 是否为角色审批人；超级管理员可审批任意角色
+func (s *AccessApplicationService) isApprover(ctx context.Context, actorID, roleID uint) (bool, error) {
+	if s.granter.IsSuperAdmin(ctx, actorID) {
+		return true, nil
+	}
+	approvers, err := s.repo.GetApprovers(ctx, roleID)
+	if err != nil {
+		return false, err
+	}
+	return containsID(approvers, actorID), nil
+}
+
+// decidable 加载可由 actorID 审批的待审批申请；申请人不能审批自己的申请
+func (s *AccessApplicationService) decidable(ctx context.Context, actorID, id uint) (*entity.AccessRequest, error) {
+	ar, err := s.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ar.UserID == actorID {
+		return nil, errorx.New(errorx.ErrForbidden, "cannot decide on your own request")
+	}
+	ok, err := s.isApprover(ctx, actorID, ar.RoleID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errorx.New(errorx.ErrForbidden, "not an approver of this role")
+	}
+	if !ar.IsPending() {
+		return nil, errorx.New(errorx.ErrInvalidParam, fmt.Sprintf("request is already %s", ar.Status))
+	}
+	return ar, nil
+}
+
+// visible 加载 actorID 可查看的申请
+func (s *AccessApplicationService) visible(ctx context.Context, actorID, id uint) (*entity.AccessRequest, error) {
+	ar, err := s.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ar.UserID == actorID {
+		return ar, nil
+	}
+	ok, err := s.isApprover(ctx, actorID, ar.RoleID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errorx.New(errorx.ErrForbidden, "access request is not visible")
+	}
+	return ar, nil
+}
+
+// transition 按状态条件更新申请并记录事件；状态已被并发修改时返回错误
+func (s *AccessApplicationService) transition(ctx context.Context, ar *entity.AccessRequest, from string, actorID uint, action, comment string) error {
+	ok, err := s.repo.Transition(ctx, ar, from, &entity.AccessRequestEvent{ActorID: actorID, Action: action, FromStatus: from, ToStatus: ar.Status, Comment: comment})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errorx.New(errorx.ErrInvalidParam, "request is no longer pending")
+	}
+	return nil
+}
+
+func (s *AccessApplicationService) list(ctx context.Context, filter repository.AccessRequestFilter, q *dto.ListQuery) (int64, []*dto.AccessRequestItem, error) {
+	pageNum, pageSize := q.PageNum, q.PageSize
+	if pageNum <= 0 {
+		pageNum = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 10
+	}
+	list, total, err := s.repo.List(ctx, filter, (pageNum-1)*pageSize, pageSize)
+	if err != nil {
+		return 0, nil, err
+	}
+	res := make([]*dto.AccessRequestItem, 0, len(list))
+	for _, ar := range list {
+		res = append(res, s.item(ctx, ar))
+	}
+	return total, res, nil
+}
+
+// item 转换为列表项，补充用户名与角色名（查询失败时留空）
+func (s *AccessApplicationService) item(ctx context.Context, ar *entity.AccessRequest) *dto.AccessRequestItem {
+	it := &dto.AccessRequestItem{
+		ID:            ar.ID,
+		UserID:        ar.UserID,
+		RoleID:        ar.RoleID,
+		Reason:        ar.Reason,
+		Status:        ar.Status,
+		DurationHours: ar.DurationHours,
+		ValidUntil:    ar.ValidUntil,
+		DeciderID:     ar.DeciderID,
+		DecidedAt:     ar.DecidedAt,
+		CreatedAt:     ar.CreatedAt,
+	}
+	if u, err := s.userRepo.GetByID(ctx, ar.UserID); err == nil && u != nil {
+		it.Username = u.Username
+	}
+	if r, err := s.roleRepo.GetByID(ctx, ar.RoleID); err == nil && r != nil {
+		it.RoleName = r.Name
+	}
+	return it
+}
+
+// notify 通知除操作人以外的收件人
+func (s *AccessApplicationService) notify(ctx context.Context, n *Notification) {
+	recipients := make([]uint, 0, len(n.Recipients))
+	for _, id := range n.Recipients {
+		if id != n.ActorID {
+			recipients = append(recipients, id)
+		}
+	}
+	if len(recipients) == 0 {
+		return
+	}
+	n.Recipients = recipients
+	for _, notifier := range s.notifiers {
+		notifier.Notify(ctx, n)
+	}
+}
+
+func containsID(ids []uint, id uint) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
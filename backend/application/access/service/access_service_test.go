@@ -0,0 +1,307 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sine-io/sinx/application/access/dto"
+	rbacdto "github.com/sine-io/sinx/application/rbac/dto"
+	"github.com/sine-io/sinx/domain/access/entity"
+	"github.com/sine-io/sinx/domain/access/repository"
+	roleEntity "github.com/sine-io/sinx/domain/role/entity"
+	userEntity "github.com/sine-io/sinx/domain/user/entity"
+	"github.com/sine-io/sinx/domain/user/repository/usertest"
+	"github.com/sine-io/sinx/pkg/config"
+	"github.com/sine-io/sinx/pkg/errorx"
+	"github.com/sine-io/sinx/pkg/logger"
+	"github.com/sine-io/sinx/pkg/permissions"
+)
+
+// In-memory implementations -------------------------------------------------
+
+type memAccessRepo struct {
+	requests  map[uint]*entity.AccessRequest
+	events    []*entity.AccessRequestEvent
+	approvers map[uint][]uint
+}
+
+func newMemAccessRepo() *memAccessRepo {
+	return &memAccessRepo{requests: map[uint]*entity.AccessRequest{}, approvers: map[uint][]uint{}}
+}
+
+func (m *memAccessRepo) Create(_ context.Context, req *entity.AccessRequest, event *entity.AccessRequestEvent) error {
+	req.ID = uint(len(m.requests) + 1)
+	req.CreatedAt = time.Now()
+	cp := *req
+	m.requests[req.ID] = &cp
+	event.RequestID = req.ID
+	m.events = append(m.events, event)
+	return nil
+}
+func (m *memAccessRepo) GetByID(_ context.Context, id uint) (*entity.AccessRequest, error) {
+	r, ok := m.requests[id]
+	if !ok {
+		return nil, nil
+	}
+	cp := *r
+	return &cp, nil
+}
+func (m *memAccessRepo) List(_ context.Context, filter repository.AccessRequestFilter, offset, limit int) ([]*entity.AccessRequest, int64, error) {
+	var res []*entity.AccessRequest
+	for id := uint(len(m.requests)); id > 0; id-- {
+		r := m.requests[id]
+		if (filter.UserID != 0 && r.UserID != filter.UserID) || (filter.Status != "" && r.Status != filter.Status) {
+			continue
+		}
+		if filter.RoleIDs != nil && !containsID(filter.RoleIDs, r.RoleID) {
+			continue
+		}
+		res = append(res, r)
+	}
+	return res, int64(len(res)), nil
+}
+func (m *memAccessRepo) HasPending(_ context.Context, userID, roleID uint) (bool, error) {
+	for _, r := range m.requests {
+		if r.UserID == userID && r.RoleID == roleID && r.IsPending() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+func (m *memAccessRepo) Transition(_ context.Context, req *entity.AccessRequest, from string, event *entity.AccessRequestEvent) (bool, error) {
+	if m.requests[req.ID].Status != from {
+		return false, nil
+	}
+	cp := *req
+	m.requests[req.ID] = &cp
+	event.RequestID = req.ID
+	m.events = append(m.events, event)
+	return true, nil
+}
+func (m *memAccessRepo) AddEvent(_ context.Context, event *entity.AccessRequestEvent) error {
+	m.events = append(m.events, event)
+	return nil
+}
+func (m *memAccessRepo) ListEvents(_ context.Context, requestID uint) ([]*entity.AccessRequestEvent, error) {
+	var res []*entity.AccessRequestEvent
+	for _, e := range m.events {
+		if e.RequestID == requestID {
+			res = append(res, e)
+		}
+	}
+	return res, nil
+}
+func (m *memAccessRepo) SetApprovers(_ context.Context, roleID uint, userIDs []uint) error {
+	m.approvers[roleID] = userIDs
+	return nil
+}
+func (m *memAccessRepo) GetApprovers(_ context.Context, roleID uint) ([]uint, error) {
+	return m.approvers[roleID], nil
+}
+func (m *memAccessRepo) GetApproverRoles(_ context.Context, userID uint) ([]uint, error) {
+	var res []uint
+	for rid, ids := range m.approvers {
+		if containsID(ids, userID) {
+			res = append(res, rid)
+		}
+	}
+	return res, nil
+}
+
+type memRoleRepo struct{ data map[uint]*roleEntity.Role }
+
+func (m *memRoleRepo) Create(_ context.Context, r *roleEntity.Role) error {
+	r.ID = uint(len(m.data) + 1)
+	m.data[r.ID] = r
+	return nil
+}
+func (m *memRoleRepo) Update(_ context.Context, r *roleEntity.Role) error {
+	m.data[r.ID] = r
+	return nil
+}
+func (m *memRoleRepo) Delete(_ context.Context, id uint) error { delete(m.data, id); return nil }
+func (m *memRoleRepo) GetByID(_ context.Context, id uint) (*roleEntity.Role, error) {
+	return m.data[id], nil
+}
+func (m *memRoleRepo) List(_ context.Context, offset, limit int) ([]*roleEntity.Role, error) {
+	return nil, nil
+}
+func (m *memRoleRepo) Count(_ context.Context) (int64, error) { return int64(len(m.data)), nil }
+func (m *memRoleRepo) ListAll(_ context.Context) ([]*roleEntity.Role, error) {
+	return nil, nil
+}
+
+type binding struct {
+	userID, roleID uint
+	validUntil     *time.Time
+}
+
+type stubGranter struct {
+	bound     []binding
+	admins    map[uint]bool
+	perms     map[uint][]string // 用户拥有的权限
+	rolePerms map[uint][]string // 角色授予的权限
+}
+
+func (g *stubGranter) GrantUserRoles(_ context.Context, userID uint, roleIDs []uint, _, validUntil *time.Time) (int, int, error) {
+	for _, id := range roleIDs {
+		g.bound = append(g.bound, binding{userID, id, validUntil})
+	}
+	return len(roleIDs), 0, nil
+}
+func (g *stubGranter) GetUserRoles(_ context.Context, userID uint) ([]*rbacdto.RoleSimple, error) {
+	var res []*rbacdto.RoleSimple
+	for _, b := range g.bound {
+		if b.userID == userID {
+			res = append(res, &rbacdto.RoleSimple{ID: b.roleID})
+		}
+	}
+	return res, nil
+}
+func (g *stubGranter) IsSuperAdmin(_ context.Context, userID uint) bool { return g.admins[userID] }
+func (g *stubGranter) GetUserPermSet(_ context.Context, userID uint) (*permissions.PermSet, error) {
+	set := map[string]struct{}{}
+	for _, p := range g.perms[userID] {
+		set[p] = struct{}{}
+	}
+	return permissions.NewPermSet(set), nil
+}
+func (g *stubGranter) GetRolePerms(_ context.Context, roleID uint) (*rbacdto.RolePermsResponse, error) {
+	return &rbacdto.RolePermsResponse{RoleID: roleID, Own: g.rolePerms[roleID]}, nil
+}
+
+type recordNotifier struct{ sent []*Notification }
+
+func (n *recordNotifier) Notify(_ context.Context, note *Notification) { n.sent = append(n.sent, note) }
+
+// Tests ----------------------------------------------------------------------
+
+func code(err error) errorx.ErrorCode {
+	if appErr, ok := err.(*errorx.Error); ok {
+		return appErr.Code
+	}
+	return 0
+}
+
+// 申请 → 审批人通过 → 按申请时长绑定角色；每次状态变化都有事件记录与通知
+func TestAccessRequestWorkflow(t *testing.T) {
+	_ = config.LoadEnv()
+	_ = logger.Init()
+	ctx := context.Background()
+
+	repo := newMemAccessRepo()
+	roles := &memRoleRepo{data: map[uint]*roleEntity.Role{}}
+	users := usertest.NewUserRepo(
+		&userEntity.User{ID: 1, Username: "alice"},
+		&userEntity.User{ID: 2, Username: "bob"},
+		&userEntity.User{ID: 3, Username: "carol"},
+	)
+	granter := &stubGranter{admins: map[uint]bool{}, perms: map[uint][]string{3: {"deploy:run"}}, rolePerms: map[uint][]string{1: {"deploy:run", "deploy:rollback"}}}
+	notifier := &recordNotifier{}
+	svc := NewAccessApplicationService(repo, roles, users, granter, notifier)
+	_ = roles.Create(ctx, &roleEntity.Role{Name: "deployer"}) // id=1
+
+	if _, err := svc.Submit(ctx, 1, &dto.SubmitRequest{RoleID: 1, Reason: "release"}); code(err) != errorx.ErrInvalidParam {
+		t.Fatal("role without approvers must not accept requests")
+	}
+	// 设置审批人须为超级管理员或拥有该角色的全部权限
+	if err := svc.SetApprovers(ctx, 3, &dto.ApproversRequest{RoleID: 1, UserIDs: []uint{3}}); code(err) != errorx.ErrForbidden {
+		t.Fatalf("operator lacking role perms must be rejected, got %v", err)
+	}
+	granter.perms[3] = append(granter.perms[3], "deploy:rollback")
+	if err := svc.SetApprovers(ctx, 3, &dto.ApproversRequest{RoleID: 1, UserIDs: []uint{3}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.SetApprovers(ctx, 0, &dto.ApproversRequest{RoleID: 1, UserIDs: []uint{2, 2}}); err != nil {
+		t.Fatal(err)
+	}
+
+	item, err := svc.Submit(ctx, 1, &dto.SubmitRequest{RoleID: 1, Reason: "release", DurationHours: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Status != entity.AccessStatusPending || item.RoleName != "deployer" || item.Username != "alice" {
+		t.Fatalf("unexpected item: %+v", item)
+	}
+	if _, err := svc.Submit(ctx, 1, &dto.SubmitRequest{RoleID: 1, Reason: "again"}); err == nil {
+		t.Fatal("duplicate pending request must be rejected")
+	}
+	if len(notifier.sent) != 1 || notifier.sent[0].Recipients[0] != 2 {
+		t.Fatal("approvers should be notified on submit")
+	}
+
+	// 非审批人与申请人本人都不能审批
+	if err := svc.Approve(ctx, 3, &dto.DecisionRequest{ID: item.ID}); code(err) != errorx.ErrForbidden {
+		t.Fatal("non-approver must not approve")
+	}
+	if err := svc.Approve(ctx, 1, &dto.DecisionRequest{ID: item.ID}); code(err) != errorx.ErrForbidden {
+		t.Fatal("requester must not approve their own request")
+	}
+	if _, err := svc.Get(ctx, 3, item.ID); code(err) != errorx.ErrForbidden {
+		t.Fatal("unrelated user must not view the request")
+	}
+	if total, _, _ := svc.ListPending(ctx, 2, &dto.ListQuery{}); total != 1 {
+		t.Fatal("approver should see the pending request")
+	}
+	if err := svc.Comment(ctx, 2, &dto.CommentRequest{ID: item.ID, Comment: "which service?"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.Approve(ctx, 2, &dto.DecisionRequest{ID: item.ID, Comment: "ok"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(granter.bound) != 1 || granter.bound[0].validUntil == nil || time.Until(*granter.bound[0].validUntil) < 7*time.Hour {
+		t.Fatalf("approval should bind the role for the requested duration: %+v", granter.bound)
+	}
+	if err := svc.Reject(ctx, 2, &dto.DecisionRequest{ID: item.ID}); code(err) != errorx.ErrInvalidParam {
+		t.Fatal("decided request must not transition again")
+	}
+	if _, err := svc.Submit(ctx, 1, &dto.SubmitRequest{RoleID: 1, Reason: "again"}); err == nil {
+		t.Fatal("already granted role must not be requested")
+	}
+
+	detail, err := svc.Get(ctx, 1, item.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, e := range detail.Events {
+		actions = append(actions, e.Action)
+	}
+	if len(actions) != 3 || actions[0] != entity.AccessActionSubmit || actions[1] != entity.AccessActionComment || actions[2] != entity.AccessActionApprove {
+		t.Fatalf("unexpected audit trail: %v", actions)
+	}
+	if detail.Status != entity.AccessStatusApproved || detail.DeciderID != 2 {
+		t.Fatalf("unexpected decision: %+v", detail.AccessRequestItem)
+	}
+	if last := notifier.sent[len(notifier.sent)-1]; last.Action != entity.AccessActionApprove || last.Recipients[0] != 1 {
+		t.Fatal("requester should be notified of the decision")
+	}
+}
+
+// 申请人可撤回待审批的申请，撤回后不能再审批
+func TestAccessRequestCancel(t *testing.T) {
+	_ = config.LoadEnv()
+	_ = logger.Init()
+	ctx := context.Background()
+
+	repo := newMemAccessRepo()
+	roles := &memRoleRepo{data: map[uint]*roleEntity.Role{}}
+	users := usertest.NewUserRepo(&userEntity.User{ID: 1}, &userEntity.User{ID: 2})
+	granter := &stubGranter{admins: map[uint]bool{}}
+	svc := NewAccessApplicationService(repo, roles, users, granter)
+	_ = roles.Create(ctx, &roleEntity.Role{Name: "auditor"})
+	_ = svc.SetApprovers(ctx, 0, &dto.ApproversRequest{RoleID: 1, UserIDs: []uint{2}})
+
+	item, _ := svc.Submit(ctx, 1, &dto.SubmitRequest{RoleID: 1, Reason: "audit"})
+	if err := svc.Cancel(ctx, 2, &dto.DecisionRequest{ID: item.ID}); code(err) != errorx.ErrForbidden {
+		t.Fatal("only the requester can cancel")
+	}
+	if err := svc.Cancel(ctx, 1, &dto.DecisionRequest{ID: item.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Approve(ctx, 2, &dto.DecisionRequest{ID: item.ID}); err == nil || len(granter.bound) != 0 {
+		t.Fatal("cancelled request must not be approved")
+	}
+}
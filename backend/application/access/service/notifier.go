@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/sine-io/sinx/domain/access/entity"
+	roleRepo "github.com/sine-io/sinx/domain/role/repository"
+	userRepo "github.com/sine-io/sinx/domain/user/repository"
+	"github.com/sine-io/sinx/pkg/logger"
+	"github.com/sine-io/sinx/pkg/mailer"
+)
+
+// actionText 邮件中的事件描述
+var actionText = map[string]string{
+	entity.AccessActionSubmit:  "待审批",
+	entity.AccessActionApprove: "已通过",
+	entity.AccessActionReject:  "已驳回",
+	entity.AccessActionCancel:  "已撤回",
+	entity.AccessActionComment: "有新的评论",
+}
+
+type accessMailData struct {
+	AppName   string
+	Username  string
+	RequestID uint
+	Requester string
+	Role      string
+	Actor     string
+	Action    string
+	Comment   string
+}
+
+// MailNotifier 以邮件通知申请的收件人，未设置邮箱的用户跳过
+type MailNotifier struct {
+	mailer    mailer.Mailer
+	templates *mailer.Templates
+	users     userRepo.UserRepository
+	roles     roleRepo.RoleRepository
+	appName   string
+}
+
+func NewMailNotifier(m mailer.Mailer, templates *mailer.Templates, users userRepo.UserRepository, roles roleRepo.RoleRepository, appName string) *MailNotifier {
+	return &MailNotifier{mailer: m, templates: templates, users: users, roles: roles, appName: appName}
+}
+
+func (n *MailNotifier) Notify(ctx context.Context, note *Notification) {
+	data := accessMailData{AppName: n.appName, RequestID: note.Request.ID, Action: actionText[note.Action], Comment: note.Comment}
+	data.Requester = n.username(ctx, note.Request.UserID)
+	data.Actor = n.username(ctx, note.ActorID)
+	if r, err := n.roles.GetByID(ctx, note.Request.RoleID); err == nil && r != nil {
+		data.Role = r.Name
+	}
+	for _, id := range note.Recipients {
+		u, err := n.users.GetByID(ctx, id)
+		if err != nil || u == nil || u.Email == "" {
+			continue
+		}
+		data.Username = u.Username
+		msg, err := n.templates.Render(mailer.TemplateAccessRequest, u.Email, data)
+		if err != nil {
+			logger.Warn("access_request_mail_render_failed", "requestId", note.Request.ID, "error", err)
+			return
+		}
+		go func(userID uint) {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := n.mailer.Send(ctx, msg); err != nil {
+				logger.Error("mail_send_failed", "template", mailer.TemplateAccessRequest, "userId", userID, "error", err)
+			}
+		}(id)
+	}
+}
+
+func (n *MailNotifier) username(ctx context.Context, id uint) string {
+	if u, err := n.users.GetByID(ctx, id); err == nil && u != nil {
+		return u.Username
+	}
+	return ""
+}
@@ -5,26 +5,44 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/sine-io/sinx/api/handler"
 	"github.com/sine-io/sinx/api/router"
+	accessAppService "github.com/sine-io/sinx/application/access/service"
+	deptAppService "github.com/sine-io/sinx/application/dept/service"
+	federationAppService "github.com/sine-io/sinx/application/federation/service"
+	ldapAppService "github.com/sine-io/sinx/application/ldap/service"
+	oidcAppService "github.com/sine-io/sinx/application/oidc/service"
 	rbacAppService "github.com/sine-io/sinx/application/rbac/service"
 	userAppService "github.com/sine-io/sinx/application/user/service"
+	authRepo "github.com/sine-io/sinx/domain/auth/repository"
+	authDomainService "github.com/sine-io/sinx/domain/auth/service"
+	oidcDomainService "github.com/sine-io/sinx/domain/oidc/service"
 	userDomainService "github.com/sine-io/sinx/domain/user/service"
 	"github.com/sine-io/sinx/infra/cache"
 	"github.com/sine-io/sinx/infra/database"
 	"github.com/sine-io/sinx/infra/migration"
 	userRepoInfra "github.com/sine-io/sinx/infra/repository"
+	"github.com/sine-io/sinx/pkg/auth"
 	"github.com/sine-io/sinx/pkg/config"
+	"github.com/sine-io/sinx/pkg/federation"
+	"github.com/sine-io/sinx/pkg/geoip"
+	"github.com/sine-io/sinx/pkg/ldapdir"
 	"github.com/sine-io/sinx/pkg/logger"
+	"github.com/sine-io/sinx/pkg/loginguard"
+	"github.com/sine-io/sinx/pkg/mailer"
+	"github.com/sine-io/sinx/pkg/passwordpolicy"
+	"github.com/sine-io/sinx/pkg/utils"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 type Application struct {
-	server *http.Server
-	db     *gorm.DB
+	server   *http.Server
+	db       *gorm.DB
+	stopJobs context.CancelFunc
 }
 
 type Dependencies struct {
@@ -44,15 +62,24 @@ func Init(ctx context.Context) (*Application, error) {
 		return nil, fmt.Errorf("failed to init services: %w", err)
 	}
 
+	// 启动后台任务
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	startJobs(jobCtx, services)
+
 	// 初始化处理器
 	handlers := initHandlers(services)
 
 	// 初始化HTTP服务器
-	server := initHTTPServer(handlers)
+	server, err := initHTTPServer(handlers)
+	if err != nil {
+		stopJobs()
+		return nil, fmt.Errorf("failed to init http server: %w", err)
+	}
 
 	return &Application{
-		server: server,
-		db:     deps.DB,
+		server:   server,
+		db:       deps.DB,
+		stopJobs: stopJobs,
 	}, nil
 }
 
@@ -79,37 +106,282 @@ func initInfrastructure(ctx context.Context) (*Dependencies, error) {
 type Services struct {
 	UserAppService *userAppService.UserApplicationService
 	// 预留: Role/Menu/RBAC 服务
-	RBACAppService *rbacAppService.RBACApplicationService
+	RBACAppService       *rbacAppService.RBACApplicationService
+	OIDCAppService       *oidcAppService.OIDCApplicationService
+	FederationAppService *federationAppService.FederationApplicationService
+	LDAPAppService       *ldapAppService.LDAPApplicationService
+	AccessAppService     *accessAppService.AccessApplicationService
+	DeptAppService       *deptAppService.DeptApplicationService
 }
 
 func initServices(deps *Dependencies) (*Services, error) {
+	// 新密码使用的哈希算法
+	if err := utils.SetDefaultPasswordHasher(config.Get().PasswordHasher); err != nil {
+		return nil, err
+	}
+	// JWT 签名密钥（RS256 / EdDSA，未配置时使用 HS256）
+	if err := auth.InitSigningKeys(); err != nil {
+		return nil, err
+	}
+
+	// 邮件模板
+	templates, err := mailer.LoadTemplates(config.Get().MailTemplateDir)
+	if err != nil {
+		return nil, err
+	}
+	// 密码策略
+	passwordPolicy, err := newPasswordPolicy()
+	if err != nil {
+		return nil, err
+	}
+
 	// 初始化仓储层
 	userRepository := userRepoInfra.NewUserRepository(deps.DB)
 	roleRepository := userRepoInfra.NewRoleRepository(deps.DB)
 	menuRepository := userRepoInfra.NewMenuRepository(deps.DB)
 	rbacRepository := userRepoInfra.NewRBACRepository(deps.DB)
+	refreshTTL := time.Duration(config.Get().RefreshExpireHours) * time.Hour
+	refreshTokenRepository := newRefreshTokenRepository(deps.DB, refreshTTL)
+	revocationStore := newRevocationStore()
+	mfaRepository := userRepoInfra.NewMFARepository(deps.DB)
+	accessTokenRepository := userRepoInfra.NewAccessTokenRepository(deps.DB)
+	oauthClientRepository := userRepoInfra.NewOAuthClientRepository(deps.DB)
+	authCodeRepository := userRepoInfra.NewAuthCodeRepository(deps.DB)
+	identityRepository := userRepoInfra.NewIdentityRepository(deps.DB)
+	passwordHistoryRepository := userRepoInfra.NewPasswordHistoryRepository(deps.DB)
+	sessionRepository := userRepoInfra.NewSessionRepository(deps.DB)
+	loginLogRepository := userRepoInfra.NewLoginLogRepository(deps.DB)
+	accessRequestRepository := userRepoInfra.NewAccessRequestRepository(deps.DB)
+	departmentRepository := userRepoInfra.NewDepartmentRepository(deps.DB)
+
+	// LDAP 目录（未配置时为 nil）
+	var userDirectory userDomainService.Directory
+	var syncDirectory ldapAppService.Directory
+	if directory := newLDAPDirectory(); directory != nil {
+		userDirectory, syncDirectory = directory, directory
+	}
+	// IP 归属地库（未配置时为 nil）
+	var geoResolver userDomainService.GeoResolver
+	geoDB, err := newGeoIP()
+	if err != nil {
+		return nil, err
+	}
+	if geoDB != nil {
+		geoResolver = geoDB
+	}
 
 	// 初始化领域服务层
-	userDomainSvc := userDomainService.NewUserDomainService(userRepository)
+	passwordPolicySvc := userDomainService.NewPasswordPolicyService(passwordPolicy, passwordHistoryRepository)
+	userDomainSvc := userDomainService.NewUserDomainService(userRepository, userDirectory, config.Get().LDAPDefaultAuth, passwordPolicySvc)
+	tokenDomainSvc := authDomainService.NewTokenDomainService(refreshTokenRepository, sessionRepository, userRepository, revocationStore, refreshTTL)
+	mfaDomainSvc := userDomainService.NewMFADomainService(mfaRepository, config.Get().MFAIssuer)
+	accessTokenDomainSvc := authDomainService.NewAccessTokenDomainService(accessTokenRepository)
+	oidcDomainSvc := oidcDomainService.NewOIDCDomainService(oauthClientRepository, authCodeRepository)
+	loginLogDomainSvc := userDomainService.NewLoginLogDomainService(loginLogRepository, userRepository, geoResolver)
 
 	// 初始化应用服务层
-	userAppSvc := userAppService.NewUserApplicationService(userDomainSvc)
-	rbacSvc := rbacAppService.NewRBACApplicationService(userRepository, roleRepository, menuRepository, rbacRepository)
+	rbacSvc := rbacAppService.NewRBACApplicationService(userRepository, roleRepository, menuRepository, rbacRepository, departmentRepository, tokenDomainSvc, passwordPolicySvc)
+	if username := config.Get().BootstrapSuperAdmin; username != "" {
+		if err := rbacSvc.BootstrapSuperAdmin(context.Background(), username); err != nil {
+			return nil, fmt.Errorf("bootstrap super admin: %w", err)
+		}
+	}
+	guardStore := newGuardStore()
+	accountMail := newAccountMail(templates, guardStore)
+	userAppSvc := userAppService.NewUserApplicationService(userDomainSvc, tokenDomainSvc, mfaDomainSvc, accessTokenDomainSvc, loginLogDomainSvc, newLoginGuard(guardStore), rbacSvc, accountMail, time.Duration(config.Get().ImpersonationTTLMinutes)*time.Minute)
+	oidcSvc := oidcAppService.NewOIDCApplicationService(oidcDomainSvc, userDomainSvc, tokenDomainSvc, rbacSvc, config.Get().OIDCIssuer)
+	federationSvc := federationAppService.NewFederationApplicationService(config.Get().FederationProviders, config.Get().OIDCIssuer, identityRepository, userDomainSvc, rbacSvc, userAppSvc, newFederationStateStore())
+	ldapSvc := ldapAppService.NewLDAPApplicationService(syncDirectory, userRepository, userDomainSvc, rbacSvc, config.Get().LDAPGroupRoles, config.Get().LDAPDefaultRoleID, config.Get().LDAPDefaultAuth)
+	if auth.ActiveAlg() == "HS256" {
+		logger.Warn("OIDC ID tokens are signed with HS256 shared secret; configure JWT_SIGNING_KEYS for external clients")
+	}
+
+	accessNotifier := accessAppService.NewMailNotifier(accountMail.Mailer, templates, userRepository, roleRepository, config.Get().MFAIssuer)
+	accessSvc := accessAppService.NewAccessApplicationService(accessRequestRepository, roleRepository, userRepository, rbacSvc, accessNotifier)
+	deptSvc := deptAppService.NewDeptApplicationService(departmentRepository)
+
+	return &Services{UserAppService: userAppSvc, RBACAppService: rbacSvc, OIDCAppService: oidcSvc, FederationAppService: federationSvc, LDAPAppService: ldapSvc, AccessAppService: accessSvc, DeptAppService: deptSvc}, nil
+}
+
+// startJobs 启动定时任务，ctx 取消后退出
+func startJobs(ctx context.Context, services *Services) {
+	if minutes := config.Get().LDAPSyncIntervalMinutes; minutes > 0 && config.Get().LDAPURL != "" {
+		go services.LDAPAppService.Start(ctx, time.Duration(minutes)*time.Minute)
+	}
+	if seconds := config.Get().RoleSweepIntervalSeconds; seconds > 0 {
+		go services.RBACAppService.StartRoleSweeper(ctx, time.Duration(seconds)*time.Second)
+	}
+}
+
+// newRefreshTokenRepository 按配置选择刷新令牌存储，Redis 不可用时回退到 Postgres
+func newRefreshTokenRepository(db *gorm.DB, ttl time.Duration) authRepo.RefreshTokenRepository {
+	if config.Get().RefreshTokenStore == "redis" {
+		if cli := cache.GetRedis(); cli != nil {
+			return cache.NewRedisRefreshTokenStore(cli, ttl)
+		}
+		logger.Warn("refresh_token_store_fallback", "store", "postgres")
+	}
+	return userRepoInfra.NewRefreshTokenRepository(db)
+}
 
-	return &Services{UserAppService: userAppSvc, RBACAppService: rbacSvc}, nil
+// newRevocationStore 令牌吊销存储：优先 Redis（多实例共享），否则使用进程内存
+func newRevocationStore() auth.RevocationStore {
+	// 用户级吊销记录只需保留到此前签发的访问令牌全部过期
+	ttl := auth.AccessTokenTTL()
+	if cli := cache.GetRedis(); cli != nil {
+		return auth.NewRedisRevocationStore(cli, ttl)
+	}
+	return auth.NewMemoryRevocationStore(ttl)
+}
+
+// newGuardStore 登录节流与发信限流的计数存储：优先 Redis（多实例共享计数），否则使用进程内存
+func newGuardStore() loginguard.Store {
+	if cli := cache.GetRedis(); cli != nil {
+		return loginguard.NewRedisStore(cli)
+	}
+	return loginguard.NewMemoryStore()
+}
+
+// newLoginGuard 登录节流
+func newLoginGuard(store loginguard.Store) *loginguard.Guard {
+	cfg := config.Get()
+	policy := loginguard.Policy{
+		Window:          time.Duration(cfg.LoginWindowMinutes) * time.Minute,
+		MaxUserFailures: cfg.LoginMaxUserFailures,
+		MaxIPFailures:   cfg.LoginMaxIPFailures,
+		LockoutDuration: time.Duration(cfg.LoginLockoutMinutes) * time.Minute,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Duration(cfg.LoginBackoffMaxSeconds) * time.Second,
+	}
+	return loginguard.NewGuard(policy, store)
+}
+
+// newAccountMail 找回密码 / 邮箱验证邮件配置，按 MAIL_DRIVER 选择发送方式
+func newAccountMail(templates *mailer.Templates, store loginguard.Store) userAppService.AccountMail {
+	cfg := config.Get()
+	var m mailer.Mailer
+	switch cfg.MailDriver {
+	case "smtp":
+		m = mailer.NewSMTPMailer(mailer.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.MailFrom,
+			TLS:      cfg.SMTPTLS,
+		})
+	case "file":
+		m = mailer.NewFileMailer(cfg.MailOutboxDir, cfg.MailFrom)
+	default:
+		// 邮件正文（含一次性链接）仅写入日志，生产环境应配置 smtp
+		if cfg.AppEnv == "production" {
+			logger.Warn("mail_driver_log_in_production")
+		}
+		m = mailer.NewLogMailer()
+	}
+	return userAppService.AccountMail{
+		Mailer:               m,
+		Templates:            templates,
+		AppName:              cfg.MFAIssuer,
+		ResetURL:             cfg.PasswordResetURL,
+		ResetTTL:             time.Duration(cfg.PasswordResetTTLMinutes) * time.Minute,
+		VerifyURL:            cfg.EmailVerifyURL,
+		VerifyTTL:            time.Duration(cfg.EmailVerifyTTLHours) * time.Hour,
+		RequireVerifiedEmail: cfg.RequireEmailVerification,
+		Limiter:              loginguard.NewLimiter(store, "mail", time.Duration(cfg.MailRateWindowMinutes)*time.Minute, cfg.MailRateMaxPerIP, cfg.MailRateMaxPerEmail),
+	}
+}
+
+// newPasswordPolicy 按配置构建密码策略
+func newPasswordPolicy() (*passwordpolicy.Policy, error) {
+	cfg := config.Get()
+	for _, class := range cfg.PasswordRequireClasses {
+		if !passwordpolicy.ValidClass(class) {
+			return nil, fmt.Errorf("invalid PASSWORD_REQUIRE_CLASSES entry %q", class)
+		}
+	}
+	policy := &passwordpolicy.Policy{
+		MinLength:      cfg.PasswordMinLength,
+		RequireClasses: cfg.PasswordRequireClasses,
+		DenyCommon:     cfg.PasswordDenyCommon,
+		DenyUsername:   cfg.PasswordDenyUsername,
+		HistorySize:    cfg.PasswordHistory,
+		MaxAge:         time.Duration(cfg.PasswordMaxAgeDays) * 24 * time.Hour,
+	}
+	if cfg.PasswordDenyListFile != "" {
+		if err := policy.LoadDenyList(cfg.PasswordDenyListFile); err != nil {
+			return nil, fmt.Errorf("load password deny list: %w", err)
+		}
+	}
+	return policy, nil
+}
+
+// newGeoIP 打开 IP 归属地库，未配置 GEOIP_DB_PATH 时返回 nil
+func newGeoIP() (*geoip.DB, error) {
+	path := config.Get().GeoIPDBPath
+	if path == "" {
+		return nil, nil
+	}
+	db, err := geoip.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open geoip database: %w", err)
+	}
+	return db, nil
+}
+
+// newFederationStateStore 外部登录 state 存储：优先 Redis（回调可能落到其他实例），否则使用进程内存
+func newFederationStateStore() federation.StateStore {
+	if cli := cache.GetRedis(); cli != nil {
+		return federation.NewRedisStateStore(cli)
+	}
+	return federation.NewMemoryStateStore()
+}
+
+// newLDAPDirectory 按配置创建 LDAP 客户端，未配置 LDAP_URL 时返回 nil
+func newLDAPDirectory() *ldapdir.Client {
+	cfg := config.Get()
+	if cfg.LDAPURL == "" {
+		return nil
+	}
+	return ldapdir.New(ldapdir.Config{
+		URL:                cfg.LDAPURL,
+		StartTLS:           cfg.LDAPStartTLS,
+		InsecureSkipVerify: cfg.LDAPInsecureSkipVerify,
+		BindDN:             cfg.LDAPBindDN,
+		BindPassword:       cfg.LDAPBindPassword,
+		BaseDN:             cfg.LDAPBaseDN,
+		UserFilter:         cfg.LDAPUserFilter,
+		UsernameAttr:       cfg.LDAPUsernameAttr,
+		EmailAttr:          cfg.LDAPEmailAttr,
+		NameAttr:           cfg.LDAPNameAttr,
+		GroupAttr:          cfg.LDAPGroupAttr,
+	})
 }
 
 type Handlers struct {
 	UserHandler *handler.UserHandler
 	// 预留: Role/Menu/RBAC 处理器
-	RBACHandler *handler.RBACHandler
+	RBACHandler       *handler.RBACHandler
+	OIDCHandler       *handler.OIDCHandler
+	FederationHandler *handler.FederationHandler
+	LDAPHandler       *handler.LDAPHandler
+	AccessHandler     *handler.AccessHandler
+	DeptHandler       *handler.DeptHandler
 }
 
 func initHandlers(services *Services) *Handlers {
-	return &Handlers{UserHandler: handler.NewUserHandler(services.UserAppService), RBACHandler: handler.NewRBACHandler(services.RBACAppService)}
+	return &Handlers{
+		UserHandler:       handler.NewUserHandler(services.UserAppService),
+		RBACHandler:       handler.NewRBACHandler(services.RBACAppService),
+		OIDCHandler:       handler.NewOIDCHandler(services.OIDCAppService, config.Get().OIDCLoginURL),
+		FederationHandler: handler.NewFederationHandler(services.FederationAppService, config.Get().FederationRedirectURL),
+		LDAPHandler:       handler.NewLDAPHandler(services.LDAPAppService),
+		AccessHandler:     handler.NewAccessHandler(services.AccessAppService),
+		DeptHandler:       handler.NewDeptHandler(services.DeptAppService),
+	}
 }
 
-func initHTTPServer(handlers *Handlers) *http.Server {
+func initHTTPServer(handlers *Handlers) (*http.Server, error) {
 	cfg := config.Get()
 
 	// 设置Gin模式
@@ -117,15 +389,18 @@ func initHTTPServer(handlers *Handlers) *http.Server {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	r := gin.New()
+	r, err := router.NewEngine(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
 
 	// 设置路由
-	router.SetupRoutes(r, handlers.UserHandler, handlers.RBACHandler)
+	router.SetupRoutes(r, handlers.UserHandler, handlers.RBACHandler, handlers.OIDCHandler, handlers.FederationHandler, handlers.LDAPHandler, handlers.AccessHandler, handlers.DeptHandler)
 
 	return &http.Server{
 		Addr:    cfg.ListenAddr,
 		Handler: r,
-	}
+	}, nil
 }
 
 func (app *Application) StartHTTPServer() error {
@@ -142,6 +417,11 @@ func (app *Application) StartHTTPServer() error {
 func (app *Application) Shutdown(ctx context.Context) error {
 	logger.Info("Shutting down HTTP server...")
 
+	// 停止后台任务
+	if app.stopJobs != nil {
+		app.stopJobs()
+	}
+
 	// 关闭HTTP服务器
 	if err := app.server.Shutdown(ctx); err != nil {
 		logger.Error("Failed to shutdown HTTP server", "error", err)
@@ -0,0 +1,27 @@
+package dto
+
+// DeptCreateOrUpdateRequest 部门，id 为空时新增
+type DeptCreateOrUpdateRequest struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name" binding:"required,max=50"`
+	ParentID uint   `json:"parentId"` // 上级部门，0 表示顶级
+	OrderNum int    `json:"orderNum"`
+	Leader   string `json:"leader" binding:"max=50"`
+	Status   int16  `json:"status" binding:"oneof=0 1"` // 0正常 1禁用
+	Remark   string `json:"remark" binding:"max=100"`
+}
+
+type DeptDeleteRequest struct {
+	ID uint `json:"id" binding:"required"`
+}
+
+type DeptTreeNode struct {
+	ID       uint            `json:"id"`
+	Name     string          `json:"name"`
+	ParentID uint            `json:"parentId"`
+	OrderNum int             `json:"orderNum"`
+	Leader   string          `json:"leader,omitempty"`
+	Status   int16           `json:"status"`
+	Remark   string          `json:"remark,omitempty"`
+	Children []*DeptTreeNode `json:"children"`
+}
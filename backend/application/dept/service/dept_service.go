@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+
+	"github.com/sine-io/sinx/application/dept/dto"
+	"github.com/sine-io/sinx/domain/dept/entity"
+	"github.com/sine-io/sinx/domain/dept/repository"
+	deptService "github.com/sine-io/sinx/domain/dept/service"
+	"github.com/sine-io/sinx/pkg/errorx"
+	"github.com/sine-io/sinx/pkg/logger"
+)
+
+type DeptApplicationService struct {
+	deptRepository repository.DepartmentRepository
+}
+
+func NewDeptApplicationService(repo repository.DepartmentRepository) *DeptApplicationService {
+	return &DeptApplicationService{deptRepository: repo}
+}
+
+// CreateOrUpdate 上级部门须存在，且不能把部门移到自身或下级部门之下
+func (s *DeptApplicationService) CreateOrUpdate(ctx context.Context, req *dto.DeptCreateOrUpdateRequest) error {
+	tree, err := s.tree(ctx)
+	if err != nil {
+		return err
+	}
+	if req.ParentID != 0 && tree.Get(req.ParentID) == nil {
+		return errorx.New(errorx.ErrInvalidParam, "parent department not found")
+	}
+	if req.ID == 0 {
+		dept := &entity.Department{Name: req.Name, ParentID: req.ParentID, OrderNum: req.OrderNum, Leader: req.Leader, Status: req.Status, Remark: req.Remark}
+		if err := s.deptRepository.Create(ctx, dept); err != nil {
+			return err
+		}
+		logger.Info("audit:create_dept", "id", dept.ID, "name", dept.Name, "parentId", dept.ParentID)
+		return nil
+	}
+	dept := tree.Get(req.ID)
+	if dept == nil {
+		return errorx.New(errorx.ErrNotFound, "department not found")
+	}
+	if tree.WouldCycle(req.ID, req.ParentID) {
+		return errorx.New(errorx.ErrInvalidParam, "department hierarchy cycle")
+	}
+	dept.Name = req.Name
+	dept.ParentID = req.ParentID
+	dept.OrderNum = req.OrderNum
+	dept.Leader = req.Leader
+	dept.Status = req.Status
+	dept.Remark = req.Remark
+	if err := s.deptRepository.Update(ctx, dept); err != nil {
+		return err
+	}
+	logger.Info("audit:update_dept", "id", dept.ID, "parentId", dept.ParentID)
+	return nil
+}
+
+// Delete 存在下级部门或仍有用户归属时不允许删除
+func (s *DeptApplicationService) Delete(ctx context.Context, id uint) error {
+	tree, err := s.tree(ctx)
+	if err != nil {
+		return err
+	}
+	if tree.HasChildren(id) {
+		return errorx.NewWithCode(errorx.ErrHasChildren)
+	}
+	hasUsers, err := s.deptRepository.HasUsers(ctx, id)
+	if err != nil {
+		return err
+	}
+	if hasUsers {
+		return errorx.New(errorx.ErrInvalidParam, "department still has users")
+	}
+	if err := s.deptRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	logger.Info("audit:delete_dept", "id", id)
+	return nil
+}
+
+// Tree 完整部门树，同级按 orderNum 排序
+func (s *DeptApplicationService) Tree(ctx context.Context) ([]*dto.DeptTreeNode, error) {
+	list, err := s.deptRepository.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return buildDeptTree(list, 0), nil
+}
+
+func buildDeptTree(list []*entity.Department, parentID uint) []*dto.DeptTreeNode {
+	result := []*dto.DeptTreeNode{}
+	for _, d := range list {
+		if d.ParentID == parentID {
+			node := &dto.DeptTreeNode{ID: d.ID, Name: d.Name, ParentID: d.ParentID, OrderNum: d.OrderNum, Leader: d.Leader, Status: d.Status, Remark: d.Remark}
+			node.Children = buildDeptTree(list, d.ID)
+			result = append(result, node)
+		}
+	}
+	return result
+}
+
+// tree 加载全部部门构建层级关系
+func (s *DeptApplicationService) tree(ctx context.Context) (*deptService.DeptTree, error) {
+	list, err := s.deptRepository.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return deptService.NewDeptTree(list), nil
+}
@@ -0,0 +1,38 @@
+package dto
+
+import userdto "github.com/sine-io/sinx/application/user/dto"
+
+type ProviderItem struct {
+	Name        string `json:"name" example:"keycloak"`
+	DisplayName string `json:"display_name" example:"企业账号"`
+}
+
+type LinkRequest struct {
+	Provider string `json:"provider" binding:"required" example:"keycloak"`
+	Redirect string `json:"redirect" example:"/profile"`
+}
+
+type LinkResponse struct {
+	RedirectTo string `json:"redirect_to" example:"https://sso.example.com/realms/corp/protocol/openid-connect/auth?..."`
+}
+
+type UnlinkRequest struct {
+	ID uint `json:"id" binding:"required" example:"1"`
+}
+
+type IdentityItem struct {
+	ID          uint   `json:"id" example:"1"`
+	Provider    string `json:"provider" example:"keycloak"`
+	Subject     string `json:"subject" example:"f3a1c2d4-..."`
+	Email       string `json:"email" example:"alice@example.com"`
+	LastLoginAt int64  `json:"last_login_at,omitempty" example:"1710000000"`
+	CreatedAt   int64  `json:"created_at" example:"1710000000"`
+}
+
+// CallbackResult 回调处理结果：登录（Login 非空）或绑定（Linked 为 true）
+type CallbackResult struct {
+	Login    *userdto.LoginResponse
+	Linked   bool
+	Provider string
+	Redirect string
+}
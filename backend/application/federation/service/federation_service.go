@@ -0,0 +1,315 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+	"time"
+
+	"github.com/sine-io/sinx/application/federation/dto"
+	rbacdto "github.com/sine-io/sinx/application/rbac/dto"
+	rbacService "github.com/sine-io/sinx/application/rbac/service"
+	userdto "github.com/sine-io/sinx/application/user/dto"
+	"github.com/sine-io/sinx/domain/user/entity"
+	"github.com/sine-io/sinx/domain/user/repository"
+	userService "github.com/sine-io/sinx/domain/user/service"
+	"github.com/sine-io/sinx/pkg/config"
+	"github.com/sine-io/sinx/pkg/errorx"
+	"github.com/sine-io/sinx/pkg/federation"
+	"github.com/sine-io/sinx/pkg/logger"
+	"github.com/sine-io/sinx/pkg/utils"
+)
+
+// StateTTL 跳转上游到回调之间允许的最长时间
+const StateTTL = 10 * time.Minute
+
+// RoleBinder 按上游组同步本地角色（由 RBAC 应用服务实现，负责刷新权限缓存）
+type RoleBinder interface {
+	GetUserRoles(ctx context.Context, userID uint) ([]*rbacdto.RoleSimple, error)
+	GrantUserRoles(ctx context.Context, userID uint, roleIDs []uint, validFrom, validUntil *time.Time) (added, skipped int, err error)
+	UnbindUserRoles(ctx context.Context, userID uint, roleIDs []uint) error
+}
+
+// LoginIssuer 外部身份认证通过后签发本地令牌（由用户应用服务实现）
+type LoginIssuer interface {
+	CompleteExternalLogin(ctx context.Context, user *entity.User, client userdto.ClientInfo) (*userdto.LoginResponse, error)
+}
+
+type provider struct {
+	cfg config.FederationProvider
+	rp  *federation.Provider
+}
+
+type FederationApplicationService struct {
+	providers         map[string]*provider
+	order             []string
+	identityRepo      repository.IdentityRepository
+	userDomainService *userService.UserDomainService
+	roles             RoleBinder
+	logins            LoginIssuer
+	states            federation.StateStore
+}
+
+// NewFederationApplicationService baseURL 为本服务对外地址，回调地址为 <baseURL>/api/auth/federation/<name>/callback
+func NewFederationApplicationService(providers []config.FederationProvider, baseURL string, identityRepo repository.IdentityRepository, userDomainService *userService.UserDomainService, roles RoleBinder, logins LoginIssuer, states federation.StateStore) *FederationApplicationService {
+	s := &FederationApplicationService{
+		providers:         make(map[string]*provider, len(providers)),
+		identityRepo:      identityRepo,
+		userDomainService: userDomainService,
+		roles:             roles,
+		logins:            logins,
+		states:            states,
+	}
+	baseURL = strings.TrimRight(baseURL, "/")
+	for _, p := range providers {
+		s.providers[p.Name] = &provider{cfg: p, rp: federation.NewProvider(federation.Config{
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  baseURL + "/api/auth/federation/" + p.Name + "/callback",
+			Scopes:       p.Scopes,
+		})}
+		s.order = append(s.order, p.Name)
+	}
+	return s
+}
+
+// Providers 可用的外部登录方式
+func (s *FederationApplicationService) Providers() []*dto.ProviderItem {
+	res := make([]*dto.ProviderItem, 0, len(s.order))
+	for _, name := range s.order {
+		res = append(res, &dto.ProviderItem{Name: name, DisplayName: s.providers[name].cfg.DisplayName})
+	}
+	return res
+}
+
+// BeginLogin 生成 state / nonce / PKCE 并返回上游授权地址；linkUserID 非 0 表示为已登录用户绑定身份
+// binding 为 state 的摘要，由调用方写入发起方浏览器的 Cookie，回调时须原样带回
+func (s *FederationApplicationService) BeginLogin(ctx context.Context, name, redirect string, linkUserID uint) (target, binding string, err error) {
+	p, ok := s.providers[name]
+	if !ok {
+		return "", "", errorx.New(errorx.ErrNotFound, "unknown identity provider")
+	}
+	// 仅允许站内相对路径，防止开放重定向
+	if !strings.HasPrefix(redirect, "/") || strings.HasPrefix(redirect, "//") || strings.HasPrefix(redirect, "/\\") {
+		redirect = ""
+	}
+
+	state, err := utils.RandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := utils.RandomToken(16)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, challenge, err := federation.NewPKCE()
+	if err != nil {
+		return "", "", err
+	}
+	target, err = p.rp.AuthCodeURL(ctx, state, nonce, challenge)
+	if err != nil {
+		logger.Warn("federation_discovery_failed", "provider", name, "error", err)
+		return "", "", errorx.New(errorx.ErrFederationFailed, "identity provider is unavailable")
+	}
+	ls := &federation.LoginState{Provider: name, Nonce: nonce, Verifier: verifier, Redirect: redirect, LinkUserID: linkUserID}
+	if err := s.states.Save(ctx, state, ls, StateTTL); err != nil {
+		return "", "", err
+	}
+	return target, utils.SHA256Hex(state), nil
+}
+
+// Callback 处理上游回调：校验 state 与 ID 令牌，随后登录（必要时自动创建用户）或绑定身份
+// binding 须与 BeginLogin 返回的一致，防止攻击者把自己的授权结果塞给受害者的浏览器（登录 / 绑定 CSRF）
+func (s *FederationApplicationService) Callback(ctx context.Context, name, state, binding, code string, client userdto.ClientInfo) (*dto.CallbackResult, error) {
+	if subtle.ConstantTimeCompare([]byte(binding), []byte(utils.SHA256Hex(state))) != 1 {
+		return nil, errorx.New(errorx.ErrFederationFailed, "login state does not belong to this browser")
+	}
+	ls, err := s.states.Take(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+	if ls == nil || ls.Provider != name {
+		return nil, errorx.New(errorx.ErrFederationFailed, "login state is invalid or expired")
+	}
+	p, ok := s.providers[name]
+	if !ok {
+		return nil, errorx.New(errorx.ErrNotFound, "unknown identity provider")
+	}
+	res := &dto.CallbackResult{Provider: name, Redirect: ls.Redirect}
+
+	idToken, err := p.rp.Exchange(ctx, code, ls.Verifier, ls.Nonce)
+	if err != nil {
+		logger.Warn("federation_exchange_failed", "provider", name, "error", err)
+		return res, errorx.New(errorx.ErrFederationFailed, "identity provider rejected the login")
+	}
+
+	identity, err := s.identityRepo.GetByProviderSubject(ctx, name, idToken.Subject)
+	if err != nil {
+		return res, err
+	}
+
+	if ls.LinkUserID != 0 {
+		if err := s.link(ctx, ls.LinkUserID, name, idToken, identity); err != nil {
+			return res, err
+		}
+		res.Linked = true
+		return res, nil
+	}
+
+	user, err := s.resolveUser(ctx, p, idToken, identity)
+	if err != nil {
+		return res, err
+	}
+	if err := s.syncGroupRoles(ctx, p, user.ID, idToken); err != nil {
+		return res, err
+	}
+	login, err := s.logins.CompleteExternalLogin(ctx, user, client)
+	if err != nil {
+		return res, err
+	}
+	logger.Info("audit:federated_login", "userId", user.ID, "provider", name, "subject", idToken.Subject)
+	res.Login = login
+	return res, nil
+}
+
+// ListIdentities 当前用户绑定的外部身份
+func (s *FederationApplicationService) ListIdentities(ctx context.Context, userID uint) ([]*dto.IdentityItem, error) {
+	list, err := s.identityRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*dto.IdentityItem, 0, len(list))
+	for _, i := range list {
+		item := &dto.IdentityItem{ID: i.ID, Provider: i.Provider, Subject: i.Subject, Email: i.Email, CreatedAt: i.CreatedAt.Unix()}
+		if i.LastLoginAt != nil {
+			item.LastLoginAt = i.LastLoginAt.Unix()
+		}
+		res = append(res, item)
+	}
+	return res, nil
+}
+
+// Unlink 解除绑定
+func (s *FederationApplicationService) Unlink(ctx context.Context, userID uint, req *dto.UnlinkRequest) error {
+	ok, err := s.identityRepo.Delete(ctx, userID, req.ID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errorx.NewWithCode(errorx.ErrNotFound)
+	}
+	logger.Info("audit:identity_unlinked", "userId", userID, "identityId", req.ID)
+	return nil
+}
+
+// link 为已登录用户绑定外部身份；该身份已属于其他用户时拒绝
+func (s *FederationApplicationService) link(ctx context.Context, userID uint, name string, idToken *federation.IDToken, identity *entity.UserIdentity) error {
+	if identity != nil {
+		if identity.UserID != userID {
+			return errorx.NewWithCode(errorx.ErrIdentityLinked)
+		}
+		return nil
+	}
+	if _, err := s.userDomainService.GetUserByID(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.identityRepo.Create(ctx, &entity.UserIdentity{UserID: userID, Provider: name, Subject: idToken.Subject, Email: idToken.Email}); err != nil {
+		return err
+	}
+	logger.Info("audit:identity_linked", "userId", userID, "provider", name, "subject", idToken.Subject)
+	return nil
+}
+
+// resolveUser 按已绑定身份、已验证邮箱（可选，本地邮箱也须已验证）查找本地用户，均未命中时自动创建并绑定默认角色
+func (s *FederationApplicationService) resolveUser(ctx context.Context, p *provider, idToken *federation.IDToken, identity *entity.UserIdentity) (*entity.User, error) {
+	now := time.Now()
+	if identity != nil {
+		user, err := s.userDomainService.GetUserByID(ctx, identity.UserID)
+		if err != nil {
+			return nil, errorx.New(errorx.ErrFederationFailed, "account is unavailable")
+		}
+		if err := s.identityRepo.TouchLastLogin(ctx, identity.ID, now); err != nil {
+			logger.Warn("identity_touch_failed", "error", err)
+		}
+		return user, nil
+	}
+
+	var user *entity.User
+	if p.cfg.LinkByEmail && idToken.EmailVerified && idToken.Email != "" {
+		user, _ = s.userDomainService.GetUserByEmail(ctx, idToken.Email)
+		// 本地邮箱未经验证时可能是他人抢注的账号，超级管理员也不自动关联，均须登录后在个人资料中手动绑定
+		if user != nil && (user.EmailVerifiedAt == nil || user.IsSuperAdmin()) {
+			logger.Warn("audit:identity_link_refused", "userId", user.ID, "provider", p.cfg.Name, "subject", idToken.Subject)
+			return nil, errorx.New(errorx.ErrFederationFailed, "an account with this email already exists; sign in and link this identity from your profile")
+		}
+	}
+	created := false
+	if user == nil {
+		username := idToken.PreferredUsername
+		if username == "" {
+			username, _, _ = strings.Cut(idToken.Email, "@")
+		}
+		if username == "" {
+			username = p.cfg.Name + "_" + idToken.Subject
+		}
+		var err error
+		if user, err = s.userDomainService.CreateFederatedUser(ctx, username, idToken.Email, idToken.Name); err != nil {
+			return nil, err
+		}
+		created = true
+	}
+
+	if err := s.identityRepo.Create(ctx, &entity.UserIdentity{UserID: user.ID, Provider: p.cfg.Name, Subject: idToken.Subject, Email: idToken.Email, LastLoginAt: &now}); err != nil {
+		return nil, err
+	}
+	if created {
+		logger.Info("audit:federated_user_provisioned", "userId", user.ID, "username", user.Username, "provider", p.cfg.Name)
+		if p.cfg.DefaultRoleID != 0 {
+			if _, _, err := s.roles.GrantUserRoles(ctx, user.ID, []uint{p.cfg.DefaultRoleID}, nil, nil); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		logger.Info("audit:identity_linked", "userId", user.ID, "provider", p.cfg.Name, "subject", idToken.Subject, "by", "email")
+	}
+	return user, nil
+}
+
+// syncGroupRoles 按上游组声明同步映射角色：绑定组内对应角色，解绑已不在组内的映射角色；未映射的本地角色不受影响
+func (s *FederationApplicationService) syncGroupRoles(ctx context.Context, p *provider, userID uint, idToken *federation.IDToken) error {
+	if len(p.cfg.GroupRoles) == 0 {
+		return nil
+	}
+	want := map[uint]struct{}{}
+	for _, g := range idToken.StringList(p.cfg.GroupsClaim) {
+		if roleID, ok := p.cfg.GroupRoles[g]; ok {
+			want[roleID] = struct{}{}
+		}
+	}
+	mapped := map[uint]struct{}{}
+	for _, roleID := range p.cfg.GroupRoles {
+		mapped[roleID] = struct{}{}
+	}
+
+	// 默认角色不随组变化解绑
+	delete(mapped, p.cfg.DefaultRoleID)
+
+	current, err := s.roles.GetUserRoles(ctx, userID)
+	if err != nil {
+		return err
+	}
+	bind, unbind := rbacService.DiffManagedRoles(current, mapped, want)
+
+	if len(bind) > 0 {
+		if _, _, err := s.roles.GrantUserRoles(ctx, userID, bind, nil, nil); err != nil {
+			return err
+		}
+	}
+	if len(unbind) > 0 {
+		if err := s.roles.UnbindUserRoles(ctx, userID, unbind); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,331 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sine-io/sinx/application/federation/dto"
+	rbacdto "github.com/sine-io/sinx/application/rbac/dto"
+	userdto "github.com/sine-io/sinx/application/user/dto"
+	"github.com/sine-io/sinx/domain/user/entity"
+	"github.com/sine-io/sinx/domain/user/repository/usertest"
+	userService "github.com/sine-io/sinx/domain/user/service"
+	"github.com/sine-io/sinx/pkg/config"
+	"github.com/sine-io/sinx/pkg/errorx"
+	"github.com/sine-io/sinx/pkg/federation"
+	"github.com/sine-io/sinx/pkg/logger"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// In-memory implementations -------------------------------------------------
+
+type memIdentityRepo struct {
+	data   []*entity.UserIdentity
+	nextID uint
+}
+
+func (m *memIdentityRepo) GetByProviderSubject(_ context.Context, provider, subject string) (*entity.UserIdentity, error) {
+	for _, i := range m.data {
+		if i.Provider == provider && i.Subject == subject {
+			return i, nil
+		}
+	}
+	return nil, nil
+}
+func (m *memIdentityRepo) ListByUser(_ context.Context, userID uint) ([]*entity.UserIdentity, error) {
+	var res []*entity.UserIdentity
+	for _, i := range m.data {
+		if i.UserID == userID {
+			res = append(res, i)
+		}
+	}
+	return res, nil
+}
+func (m *memIdentityRepo) Create(_ context.Context, i *entity.UserIdentity) error {
+	m.nextID++
+	i.ID, i.CreatedAt = m.nextID, time.Now()
+	m.data = append(m.data, i)
+	return nil
+}
+func (m *memIdentityRepo) Delete(_ context.Context, userID, id uint) (bool, error) {
+	for k, i := range m.data {
+		if i.ID == id && i.UserID == userID {
+			m.data = append(m.data[:k], m.data[k+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+func (m *memIdentityRepo) TouchLastLogin(_ context.Context, id uint, at time.Time) error { return nil }
+
+type memRoles struct{ data map[uint]map[uint]bool }
+
+func (m *memRoles) GetUserRoles(_ context.Context, userID uint) ([]*rbacdto.RoleSimple, error) {
+	var res []*rbacdto.RoleSimple
+	for id := range m.data[userID] {
+		res = append(res, &rbacdto.RoleSimple{ID: id})
+	}
+	return res, nil
+}
+func (m *memRoles) GrantUserRoles(_ context.Context, userID uint, roleIDs []uint, _, _ *time.Time) (int, int, error) {
+	if m.data[userID] == nil {
+		m.data[userID] = map[uint]bool{}
+	}
+	for _, id := range roleIDs {
+		m.data[userID][id] = true
+	}
+	return len(roleIDs), 0, nil
+}
+func (m *memRoles) UnbindUserRoles(_ context.Context, userID uint, roleIDs []uint) error {
+	for _, id := range roleIDs {
+		delete(m.data[userID], id)
+	}
+	return nil
+}
+
+type stubLogins struct{}
+
+func (stubLogins) CompleteExternalLogin(_ context.Context, u *entity.User, _ userdto.ClientInfo) (*userdto.LoginResponse, error) {
+	return &userdto.LoginResponse{Token: "token-" + u.Username, User: userdto.UserResponse{ID: u.ID, Username: u.Username}}, nil
+}
+
+// Stub IdP ------------------------------------------------------------------
+
+type idpGrant struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+// stubIdP 最小化的上游 OIDC 提供方：发现文档、JWKS、token 端点（校验 PKCE）
+type stubIdP struct {
+	srv    *httptest.Server
+	key    *rsa.PrivateKey
+	mu     sync.Mutex
+	grants map[string]idpGrant
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &stubIdP{key: key, grants: map[string]idpGrant{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.srv.URL,
+			"authorization_endpoint": idp.srv.URL + "/authorize",
+			"token_endpoint":         idp.srv.URL + "/token",
+			"jwks_uri":               idp.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "idp-1", "use": "sig", "alg": "RS256",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		_ = r.ParseForm()
+		idp.mu.Lock()
+		g, ok := idp.grants[r.PostForm.Get("code")]
+		delete(idp.grants, r.PostForm.Get("code"))
+		idp.mu.Unlock()
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if id != "sinx" || secret != "s3cret" || !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, g.claims)
+		token.Header["kid"] = "idp-1"
+		raw, _ := token.SignedString(key)
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "at", "token_type": "Bearer", "id_token": raw})
+	})
+	idp.srv = httptest.NewServer(mux)
+	return idp
+}
+
+// authorize 模拟用户在 IdP 完成登录：记录授权码并返回回调参数
+func (idp *stubIdP) authorize(t *testing.T, authURL, code, sub string, groups []string, nonceOverride string) string {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("client_id") != "sinx" {
+		t.Fatalf("unexpected authorize request: %s", authURL)
+	}
+	nonce := q.Get("nonce")
+	if nonceOverride != "" {
+		nonce = nonceOverride
+	}
+	now := time.Now()
+	idp.mu.Lock()
+	idp.grants[code] = idpGrant{challenge: q.Get("code_challenge"), claims: jwt.MapClaims{
+		"iss": idp.srv.URL, "aud": "sinx", "sub": sub, "nonce": nonce,
+		"iat": now.Unix(), "exp": now.Add(time.Minute).Unix(),
+		"preferred_username": "alice", "email": "alice@corp.test", "email_verified": true, "name": "Alice",
+		"groups": groups,
+	}}
+	idp.mu.Unlock()
+	return q.Get("state")
+}
+
+// Test ----------------------------------------------------------------------
+
+// 外部登录全流程：跳转 -> 回调自动创建用户 -> 组映射角色同步 -> state 防重放 / nonce 校验 -> 身份绑定冲突
+func TestFederatedLogin(t *testing.T) {
+	_ = config.LoadEnv()
+	_ = logger.Init()
+	ctx := context.Background()
+
+	idp := newStubIdP(t)
+	defer idp.srv.Close()
+
+	users := usertest.NewUserRepo()
+	users.Create(ctx, &entity.User{Username: "bob", Email: "bob@corp.test"})
+	identities := &memIdentityRepo{}
+	roles := &memRoles{data: map[uint]map[uint]bool{}}
+	svc := NewFederationApplicationService([]config.FederationProvider{{
+		Name: "corp", DisplayName: "Corp SSO", Issuer: idp.srv.URL, ClientID: "sinx", ClientSecret: "s3cret",
+		GroupsClaim: "groups", DefaultRoleID: 3, GroupRoles: map[string]uint{"admins": 5, "auditors": 6},
+	}}, "http://sinx.test", identities, userService.NewUserDomainService(users, nil, "", nil), roles, stubLogins{}, federation.NewMemoryStateStore())
+
+	// 首次登录：自动创建用户，绑定默认角色与组映射角色
+	authURL, binding, err := svc.BeginLogin(ctx, "corp", "/dashboard", 0)
+	if err != nil {
+		t.Fatalf("begin login: %v", err)
+	}
+	if u, _ := url.Parse(authURL); u.Query().Get("redirect_uri") != "http://sinx.test/api/auth/federation/corp/callback" {
+		t.Fatalf("unexpected redirect_uri in %s", authURL)
+	}
+	state := idp.authorize(t, authURL, "code-1", "sub-alice", []string{"admins", "unmapped"}, "")
+	res, err := svc.Callback(ctx, "corp", state, binding, "code-1", userdto.ClientInfo{})
+	if err != nil {
+		t.Fatalf("callback: %v", err)
+	}
+	if res.Login == nil || res.Login.User.Username != "alice" || res.Redirect != "/dashboard" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	aliceID := res.Login.User.ID
+	if !roles.data[aliceID][3] || !roles.data[aliceID][5] || len(roles.data[aliceID]) != 2 {
+		t.Fatalf("expected default + mapped roles, got %v", roles.data[aliceID])
+	}
+
+	// state 仅可使用一次
+	if _, err := svc.Callback(ctx, "corp", state, binding, "code-1", userdto.ClientInfo{}); err == nil {
+		t.Fatal("replayed state must be rejected")
+	}
+
+	// 再次登录：复用同一用户，组变化后解绑映射角色，默认角色保留
+	authURL, binding, _ = svc.BeginLogin(ctx, "corp", "", 0)
+	state = idp.authorize(t, authURL, "code-2", "sub-alice", []string{"auditors"}, "")
+	// Cookie 中的 state 摘要不匹配（攻击者把自己的回调地址发给受害者）时拒绝，且不消耗 state
+	_, otherBinding, _ := svc.BeginLogin(ctx, "corp", "", 0)
+	for _, forged := range []string{"", otherBinding} {
+		_, err := svc.Callback(ctx, "corp", state, forged, "code-2", userdto.ClientInfo{})
+		if appErr, ok := err.(*errorx.Error); !ok || appErr.Code != errorx.ErrFederationFailed {
+			t.Fatalf("mismatched state cookie must be rejected, got %v", err)
+		}
+	}
+	res, err = svc.Callback(ctx, "corp", state, binding, "code-2", userdto.ClientInfo{})
+	if err != nil {
+		t.Fatalf("second callback: %v", err)
+	}
+	if res.Login.User.ID != aliceID || len(users.Data) != 2 {
+		t.Fatalf("expected existing user to be reused")
+	}
+	if !roles.data[aliceID][3] || roles.data[aliceID][5] || !roles.data[aliceID][6] {
+		t.Fatalf("expected roles {3,6}, got %v", roles.data[aliceID])
+	}
+
+	// nonce 不匹配的 ID 令牌被拒绝
+	authURL, binding, _ = svc.BeginLogin(ctx, "corp", "", 0)
+	state = idp.authorize(t, authURL, "code-3", "sub-alice", nil, "forged")
+	if _, err := svc.Callback(ctx, "corp", state, binding, "code-3", userdto.ClientInfo{}); err == nil {
+		t.Fatal("nonce mismatch must be rejected")
+	}
+
+	// 已绑定到 alice 的身份不能再绑定给 bob；bob 可绑定新的身份
+	authURL, binding, _ = svc.BeginLogin(ctx, "corp", "", 1)
+	state = idp.authorize(t, authURL, "code-4", "sub-alice", nil, "")
+	_, err = svc.Callback(ctx, "corp", state, binding, "code-4", userdto.ClientInfo{})
+	if appErr, ok := err.(*errorx.Error); !ok || appErr.Code != errorx.ErrIdentityLinked {
+		t.Fatalf("expected ErrIdentityLinked, got %v", err)
+	}
+	authURL, binding, _ = svc.BeginLogin(ctx, "corp", "", 1)
+	state = idp.authorize(t, authURL, "code-5", "sub-bob", nil, "")
+	if res, err = svc.Callback(ctx, "corp", state, binding, "code-5", userdto.ClientInfo{}); err != nil || !res.Linked {
+		t.Fatalf("link: %v %+v", err, res)
+	}
+	if list, _ := svc.ListIdentities(ctx, 1); len(list) != 1 || list[0].Subject != "sub-bob" {
+		t.Fatalf("unexpected identities for bob: %+v", list)
+	}
+}
+
+// 按邮箱自动关联只针对邮箱已验证的本地账号，且不关联超级管理员
+func TestFederatedLinkByEmail(t *testing.T) {
+	_ = config.LoadEnv()
+	_ = logger.Init()
+	ctx := context.Background()
+
+	idp := newStubIdP(t)
+	defer idp.srv.Close()
+
+	// 他人以 alice 的邮箱抢注的本地账号，邮箱未验证
+	local := &entity.User{Username: "squatter", Email: "alice@corp.test"}
+	users := usertest.NewUserRepo()
+	users.Create(ctx, local)
+	identities := &memIdentityRepo{}
+	svc := NewFederationApplicationService([]config.FederationProvider{{
+		Name: "corp", Issuer: idp.srv.URL, ClientID: "sinx", ClientSecret: "s3cret", LinkByEmail: true,
+	}}, "http://sinx.test", identities, userService.NewUserDomainService(users, nil, "", nil), &memRoles{data: map[uint]map[uint]bool{}}, stubLogins{}, federation.NewMemoryStateStore())
+
+	login := func(code string) (*dto.CallbackResult, error) {
+		authURL, binding, err := svc.BeginLogin(ctx, "corp", "", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		state := idp.authorize(t, authURL, code, "sub-alice", nil, "")
+		return svc.Callback(ctx, "corp", state, binding, code, userdto.ClientInfo{})
+	}
+	refused := func(name string, err error) {
+		t.Helper()
+		if appErr, ok := err.(*errorx.Error); !ok || appErr.Code != errorx.ErrFederationFailed {
+			t.Fatalf("%s: expected ErrFederationFailed, got %v", name, err)
+		}
+		if list, _ := svc.ListIdentities(ctx, local.ID); len(list) != 0 {
+			t.Fatalf("%s: identity must not be linked", name)
+		}
+	}
+
+	_, err := login("code-1")
+	refused("unverified local email", err)
+
+	now := time.Now()
+	local.EmailVerifiedAt = &now
+	local.UserType = entity.UserTypeSuperAdmin
+	_, err = login("code-2")
+	refused("super admin", err)
+
+	local.UserType = entity.UserTypeNormal
+	res, err := login("code-3")
+	if err != nil || res.Login == nil || res.Login.User.ID != local.ID {
+		t.Fatalf("verified local email must be linked: %v %+v", err, res)
+	}
+}
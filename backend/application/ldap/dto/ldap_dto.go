@@ -0,0 +1,25 @@
+package dto
+
+type SyncRequest struct {
+	DryRun bool `json:"dryRun" example:"true"`
+}
+
+// SyncReport 同步结果；dryRun 为 true 时仅为预览，未做任何修改
+type SyncReport struct {
+	DryRun      bool          `json:"dryRun"`
+	Total       int           `json:"total"`     // 目录中的有效用户数
+	Created     []string      `json:"created"`   // 新导入的用户
+	Updated     []string      `json:"updated"`   // 邮箱 / 昵称 / 认证来源有变化的用户
+	Disabled    []string      `json:"disabled"`  // 已从目录移除（或在 AD 中禁用）而被禁用的本地用户
+	Conflicts   []string      `json:"conflicts"` // 同名的本地认证用户，未接管
+	RoleChanges []*RoleChange `json:"roleChanges"`
+	Errors      []string      `json:"errors"`
+	StartedAt   int64         `json:"startedAt"`
+	FinishedAt  int64         `json:"finishedAt"`
+}
+
+type RoleChange struct {
+	Username string `json:"username"`
+	Bound    []uint `json:"bound"`
+	Unbound  []uint `json:"unbound"`
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sine-io/sinx/application/ldap/dto"
+	rbacdto "github.com/sine-io/sinx/application/rbac/dto"
+	rbacService "github.com/sine-io/sinx/application/rbac/service"
+	"github.com/sine-io/sinx/domain/user/entity"
+	"github.com/sine-io/sinx/domain/user/repository"
+	userService "github.com/sine-io/sinx/domain/user/service"
+	"github.com/sine-io/sinx/pkg/errorx"
+	"github.com/sine-io/sinx/pkg/ldapdir"
+	"github.com/sine-io/sinx/pkg/logger"
+)
+
+// listPageSize 扫描本地用户时的分页大小
+const listPageSize = 200
+
+// Directory 目录用户来源
+type Directory interface {
+	ListUsers(ctx context.Context) ([]*ldapdir.Entry, error)
+}
+
+// UserManager 用户资料与角色维护（由 RBAC 应用服务实现，负责吊销令牌与刷新权限缓存）
+type UserManager interface {
+	UpdateUser(ctx context.Context, operatorID uint, req *rbacdto.UserUpdateRequest) error
+	GetUserRoles(ctx context.Context, userID uint) ([]*rbacdto.RoleSimple, error)
+	GrantUserRoles(ctx context.Context, userID uint, roleIDs []uint, validFrom, validUntil *time.Time) (added, skipped int, err error)
+	UnbindUserRoles(ctx context.Context, userID uint, roleIDs []uint) error
+}
+
+type LDAPApplicationService struct {
+	directory         Directory
+	userRepo          repository.UserRepository
+	userDomainService *userService.UserDomainService
+	users             UserManager
+	groupRoles        map[string]uint
+	defaultRoleID     uint
+	defaultAuth       string
+	mu                sync.Mutex
+}
+
+// NewLDAPApplicationService directory 为 nil 表示未启用 LDAP；defaultAuth 为 ldap 时同步会接管未指定认证来源的同名用户
+func NewLDAPApplicationService(directory Directory, userRepo repository.UserRepository, userDomainService *userService.UserDomainService, users UserManager, groupRoles map[string]uint, defaultRoleID uint, defaultAuth string) *LDAPApplicationService {
+	return &LDAPApplicationService{
+		directory:         directory,
+		userRepo:          userRepo,
+		userDomainService: userDomainService,
+		users:             users,
+		groupRoles:        groupRoles,
+		defaultRoleID:     defaultRoleID,
+		defaultAuth:       defaultAuth,
+	}
+}
+
+// Sync 以目录为准同步用户与映射角色；dryRun 时只生成报告不做修改。
+// 仅禁用认证来源为 ldap 的本地用户，已禁用的本地用户不会被重新启用。
+func (s *LDAPApplicationService) Sync(ctx context.Context, dryRun bool) (*dto.SyncReport, error) {
+	if s.directory == nil {
+		return nil, errorx.New(errorx.ErrInvalidParam, "ldap is not configured")
+	}
+	if !s.mu.TryLock() {
+		return nil, errorx.New(errorx.ErrTooManyRequest, "ldap sync is already running")
+	}
+	defer s.mu.Unlock()
+
+	report := &dto.SyncReport{DryRun: dryRun, StartedAt: time.Now().Unix()}
+	entries, err := s.directory.ListUsers(ctx)
+	if err != nil {
+		logger.Warn("ldap_sync_list_failed", "error", err)
+		return nil, errorx.New(errorx.ErrInternalServer, "directory service unavailable")
+	}
+	// 目录返回空结果多半是配置或权限问题，避免误禁用全部用户
+	if len(entries) == 0 {
+		return nil, errorx.New(errorx.ErrInternalServer, "directory returned no users, sync aborted")
+	}
+
+	active := make(map[string]struct{}, len(entries))
+	for _, e := range entries {
+		if e.Disabled {
+			continue
+		}
+		active[e.Username] = struct{}{}
+		report.Total++
+		if err := s.syncEntry(ctx, e, report); err != nil {
+			report.Errors = append(report.Errors, e.Username+": "+err.Error())
+		}
+	}
+	if err := s.disableRemoved(ctx, active, report); err != nil {
+		return nil, err
+	}
+
+	report.FinishedAt = time.Now().Unix()
+	logger.Info("audit:ldap_sync", "dryRun", dryRun, "created", len(report.Created), "updated", len(report.Updated),
+		"disabled", len(report.Disabled), "roleChanges", len(report.RoleChanges), "errors", len(report.Errors))
+	return report, nil
+}
+
+// Start 按固定间隔执行同步，ctx 取消后退出
+func (s *LDAPApplicationService) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Sync(ctx, false); err != nil {
+				logger.Warn("ldap_sync_failed", "error", err)
+			}
+		}
+	}
+}
+
+// syncEntry 导入或更新单个目录用户并同步映射角色
+func (s *LDAPApplicationService) syncEntry(ctx context.Context, e *ldapdir.Entry, report *dto.SyncReport) error {
+	user, _ := s.userRepo.GetByUsername(ctx, e.Username)
+	if user == nil {
+		report.Created = append(report.Created, e.Username)
+		if report.DryRun {
+			var current []*rbacdto.RoleSimple
+			if s.defaultRoleID != 0 {
+				current = []*rbacdto.RoleSimple{{ID: s.defaultRoleID}}
+			}
+			s.diffRoles(e, current, report)
+			return nil
+		}
+		created, err := s.userDomainService.CreateDirectoryUser(ctx, e.Username, e.Email, e.Name)
+		if err != nil {
+			return err
+		}
+		logger.Info("audit:ldap_user_imported", "userId", created.ID, "username", created.Username)
+		if s.defaultRoleID != 0 {
+			if _, _, err := s.users.GrantUserRoles(ctx, created.ID, []uint{s.defaultRoleID}, nil, nil); err != nil {
+				return err
+			}
+		}
+		return s.syncRoles(ctx, created.ID, e, report)
+	}
+
+	switch {
+	case user.AuthSource == entity.AuthSourceLDAP:
+	case user.AuthSource == "" && s.defaultAuth == entity.AuthSourceLDAP:
+		// 已按全局配置走目录认证，显式标记后纳入同步管理
+	default:
+		report.Conflicts = append(report.Conflicts, e.Username)
+		return nil
+	}
+	if user.Status != 0 {
+		return nil
+	}
+
+	req := &rbacdto.UserUpdateRequest{ID: user.ID}
+	changed := false
+	if user.AuthSource != entity.AuthSourceLDAP {
+		req.AuthSource, changed = entity.AuthSourceLDAP, true
+	}
+	if e.Email != "" && e.Email != user.Email {
+		req.Email, changed = e.Email, true
+	}
+	if e.Name != "" && e.Name != user.Nickname {
+		req.Nickname, changed = e.Name, true
+	}
+	if changed {
+		report.Updated = append(report.Updated, e.Username)
+		if !report.DryRun {
+			if err := s.users.UpdateUser(ctx, 0, req); err != nil {
+				return err
+			}
+		}
+	}
+	return s.syncRoles(ctx, user.ID, e, report)
+}
+
+// syncRoles 按目录组同步映射角色；默认角色与未映射的本地角色不受影响
+func (s *LDAPApplicationService) syncRoles(ctx context.Context, userID uint, e *ldapdir.Entry, report *dto.SyncReport) error {
+	if len(s.groupRoles) == 0 {
+		return nil
+	}
+	current, err := s.users.GetUserRoles(ctx, userID)
+	if err != nil {
+		return err
+	}
+	change := s.diffRoles(e, current, report)
+	if change == nil || report.DryRun {
+		return nil
+	}
+	if len(change.Bound) > 0 {
+		if _, _, err := s.users.GrantUserRoles(ctx, userID, change.Bound, nil, nil); err != nil {
+			return err
+		}
+	}
+	if len(change.Unbound) > 0 {
+		if err := s.users.UnbindUserRoles(ctx, userID, change.Unbound); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diffRoles 计算角色变更并写入报告，无变化时返回 nil
+func (s *LDAPApplicationService) diffRoles(e *ldapdir.Entry, current []*rbacdto.RoleSimple, report *dto.SyncReport) *dto.RoleChange {
+	if len(s.groupRoles) == 0 {
+		return nil
+	}
+	want := map[uint]struct{}{}
+	for _, g := range e.Groups {
+		if roleID, ok := s.groupRoles[g]; ok {
+			want[roleID] = struct{}{}
+		}
+	}
+	managed := map[uint]struct{}{}
+	for _, roleID := range s.groupRoles {
+		managed[roleID] = struct{}{}
+	}
+	delete(managed, s.defaultRoleID)
+
+	bind, unbind := rbacService.DiffManagedRoles(current, managed, want)
+	if len(bind) == 0 && len(unbind) == 0 {
+		return nil
+	}
+	change := &dto.RoleChange{Username: e.Username, Bound: bind, Unbound: unbind}
+	report.RoleChanges = append(report.RoleChanges, change)
+	return change
+}
+
+// disableRemoved 禁用目录中已不存在（或已禁用）的 ldap 用户，禁用会同时吊销其令牌
+func (s *LDAPApplicationService) disableRemoved(ctx context.Context, active map[string]struct{}, report *dto.SyncReport) error {
+	// 先收集再修改，避免边分页边禁用导致跳过记录
+	var removed []*entity.User
+	for offset := 0; ; offset += listPageSize {
+		list, err := s.userRepo.List(ctx, offset, listPageSize)
+		if err != nil {
+			return err
+		}
+		for _, u := range list {
+			if u.Status != 0 || u.AuthSource != entity.AuthSourceLDAP {
+				continue
+			}
+			if _, ok := active[u.Username]; !ok {
+				removed = append(removed, u)
+			}
+		}
+		if len(list) < listPageSize {
+			break
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool { return removed[i].Username < removed[j].Username })
+
+	status := int16(1)
+	for _, u := range removed {
+		report.Disabled = append(report.Disabled, u.Username)
+		if report.DryRun {
+			continue
+		}
+		if err := s.users.UpdateUser(ctx, 0, &rbacdto.UserUpdateRequest{ID: u.ID, Status: &status}); err != nil {
+			report.Errors = append(report.Errors, u.Username+": "+err.Error())
+			continue
+		}
+		logger.Info("audit:ldap_user_disabled", "userId", u.ID, "username", u.Username)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	rbacdto "github.com/sine-io/sinx/application/rbac/dto"
+	"github.com/sine-io/sinx/domain/user/entity"
+	"github.com/sine-io/sinx/domain/user/repository/usertest"
+	userService "github.com/sine-io/sinx/domain/user/service"
+	"github.com/sine-io/sinx/pkg/config"
+	"github.com/sine-io/sinx/pkg/ldapdir"
+	"github.com/sine-io/sinx/pkg/ldapdir/ldaptest"
+	"github.com/sine-io/sinx/pkg/logger"
+)
+
+// In-memory implementations -------------------------------------------------
+
+// memUsers 简化的 UserManager：直接修改内存仓储
+type memUsers struct {
+	repo  *usertest.UserRepo
+	roles map[uint]map[uint]bool
+}
+
+func (m *memUsers) UpdateUser(_ context.Context, _ uint, req *rbacdto.UserUpdateRequest) error {
+	u := m.repo.Data[req.ID]
+	if req.Email != "" {
+		u.Email = req.Email
+	}
+	if req.Nickname != "" {
+		u.Nickname = req.Nickname
+	}
+	if req.AuthSource != "" {
+		u.AuthSource = req.AuthSource
+	}
+	if req.Status != nil {
+		u.Status = *req.Status
+	}
+	return nil
+}
+func (m *memUsers) GetUserRoles(_ context.Context, userID uint) ([]*rbacdto.RoleSimple, error) {
+	var res []*rbacdto.RoleSimple
+	for id := range m.roles[userID] {
+		res = append(res, &rbacdto.RoleSimple{ID: id})
+	}
+	return res, nil
+}
+func (m *memUsers) GrantUserRoles(_ context.Context, userID uint, roleIDs []uint, _, _ *time.Time) (int, int, error) {
+	if m.roles[userID] == nil {
+		m.roles[userID] = map[uint]bool{}
+	}
+	for _, id := range roleIDs {
+		m.roles[userID][id] = true
+	}
+	return len(roleIDs), 0, nil
+}
+func (m *memUsers) UnbindUserRoles(_ context.Context, userID uint, roleIDs []uint) error {
+	for _, id := range roleIDs {
+		delete(m.roles[userID], id)
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+
+const (
+	roleMember = 1 // 默认角色
+	roleAdmin  = 2
+	roleDev    = 3
+)
+
+func newTestSync(t *testing.T) (*ldaptest.Server, *usertest.UserRepo, *memUsers, *LDAPApplicationService) {
+	_ = config.LoadEnv()
+	_ = logger.Init()
+
+	srv, err := ldaptest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	srv.Add("cn=svc,dc=corp,dc=test", map[string][]string{"userPassword": {"svc-pass"}})
+	srv.Add("uid=alice,ou=people,dc=corp,dc=test", map[string][]string{
+		"objectClass": {"person"}, "uid": {"alice"}, "mail": {"alice@corp.test"}, "cn": {"Alice"},
+		"memberOf": {"cn=admins,ou=groups,dc=corp,dc=test"},
+	})
+	srv.Add("uid=carol,ou=people,dc=corp,dc=test", map[string][]string{
+		"objectClass": {"person"}, "uid": {"carol"}, "mail": {"carol@corp.test"}, "cn": {"Carol"},
+		"memberOf": {"cn=dev,ou=groups,dc=corp,dc=test"},
+	})
+	srv.Add("uid=admin,ou=people,dc=corp,dc=test", map[string][]string{"objectClass": {"person"}, "uid": {"admin"}})
+
+	repo := usertest.NewUserRepo()
+	// 本地管理员与目录同名但显式使用本地认证，不应被接管
+	_ = repo.Create(context.Background(), &entity.User{Username: "admin", AuthSource: entity.AuthSourceLocal})
+	// 已从目录移除的导入用户
+	_ = repo.Create(context.Background(), &entity.User{Username: "dave", AuthSource: entity.AuthSourceLDAP})
+	// 已导入的 carol 目前持有 admin 映射角色
+	_ = repo.Create(context.Background(), &entity.User{Username: "carol", AuthSource: entity.AuthSourceLDAP})
+	users := &memUsers{repo: repo, roles: map[uint]map[uint]bool{3: {roleMember: true, roleAdmin: true}}}
+
+	directory := ldapdir.New(ldapdir.Config{URL: srv.URL, BindDN: "cn=svc,dc=corp,dc=test", BindPassword: "svc-pass", BaseDN: "ou=people,dc=corp,dc=test"})
+	svc := NewLDAPApplicationService(directory, repo, userService.NewUserDomainService(repo, nil, "", nil), users,
+		map[string]uint{"admins": roleAdmin, "dev": roleDev, "everyone": roleMember}, roleMember, entity.AuthSourceLocal)
+	return srv, repo, users, svc
+}
+
+func TestSyncDryRun(t *testing.T) {
+	_, repo, users, svc := newTestSync(t)
+
+	report, err := svc.Sync(context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Created) != 1 || report.Created[0] != "alice" {
+		t.Fatalf("created = %v", report.Created)
+	}
+	if len(report.Disabled) != 1 || report.Disabled[0] != "dave" {
+		t.Fatalf("disabled = %v", report.Disabled)
+	}
+	if len(report.Conflicts) != 1 || report.Conflicts[0] != "admin" {
+		t.Fatalf("conflicts = %v", report.Conflicts)
+	}
+	if len(report.RoleChanges) != 2 {
+		t.Fatalf("roleChanges = %+v", report.RoleChanges)
+	}
+
+	// 预览不得产生任何修改
+	if len(repo.Data) != 3 || repo.Data[2].Status != 0 || !users.roles[3][roleAdmin] {
+		t.Fatal("dry run must not modify users or roles")
+	}
+}
+
+func TestSyncApply(t *testing.T) {
+	srv, repo, users, svc := newTestSync(t)
+	ctx := context.Background()
+
+	if _, err := svc.Sync(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+	alice, _ := repo.GetByUsername(ctx, "alice")
+	if alice == nil || alice.AuthSource != entity.AuthSourceLDAP || alice.Email != "alice@corp.test" {
+		t.Fatalf("alice not imported: %+v", alice)
+	}
+	if !users.roles[alice.ID][roleMember] || !users.roles[alice.ID][roleAdmin] {
+		t.Fatalf("alice roles = %v", users.roles[alice.ID])
+	}
+	// carol：解绑 admin、绑定 dev，默认角色保留，并补齐资料
+	carol := repo.Data[3]
+	if users.roles[3][roleAdmin] || !users.roles[3][roleDev] || !users.roles[3][roleMember] || carol.Nickname != "Carol" {
+		t.Fatalf("carol = %+v roles = %v", carol, users.roles[3])
+	}
+	if repo.Data[2].Status != 1 {
+		t.Fatal("dave should be disabled")
+	}
+	if repo.Data[1].AuthSource != entity.AuthSourceLocal || repo.Data[1].Status != 0 {
+		t.Fatal("local admin must not be touched")
+	}
+
+	// 再次同步应无变化
+	report, err := svc.Sync(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Created)+len(report.Updated)+len(report.Disabled)+len(report.RoleChanges) != 0 {
+		t.Fatalf("second sync should be a no-op: %+v", report)
+	}
+
+	// 目录为空时中止，避免误禁用全部用户
+	for _, dn := range []string{"uid=alice,ou=people,dc=corp,dc=test", "uid=carol,ou=people,dc=corp,dc=test", "uid=admin,ou=people,dc=corp,dc=test"} {
+		srv.Delete(dn)
+	}
+	if _, err := svc.Sync(ctx, false); err == nil {
+		t.Fatal("expected sync to abort on empty directory")
+	}
+	if alice.Status != 0 {
+		t.Fatal("alice must stay enabled after aborted sync")
+	}
+}
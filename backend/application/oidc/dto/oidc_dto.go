@@ -0,0 +1,86 @@
+package dto
+
+// AuthorizeRequest 授权请求（前端在用户登录后携带 JWT 提交 /authorize 的原始查询参数）
+type AuthorizeRequest struct {
+	ResponseType        string `json:"response_type" form:"response_type" binding:"required" example:"code"`
+	ClientID            string `json:"client_id" form:"client_id" binding:"required" example:"Zx8v..."`
+	RedirectURI         string `json:"redirect_uri" form:"redirect_uri" binding:"required" example:"https://app.internal/callback"`
+	Scope               string `json:"scope" form:"scope" example:"openid profile email"`
+	State               string `json:"state" form:"state" example:"af0ifjsldkj"`
+	Nonce               string `json:"nonce" form:"nonce" example:"n-0S6_WzA2Mj"`
+	CodeChallenge       string `json:"code_challenge" form:"code_challenge" example:"E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"`
+	CodeChallengeMethod string `json:"code_challenge_method" form:"code_challenge_method" example:"S256"`
+}
+
+type AuthorizeResponse struct {
+	RedirectTo string `json:"redirect_to" example:"https://app.internal/callback?code=...&state=af0ifjsldkj"`
+}
+
+// TokenRequest token 端点表单参数（application/x-www-form-urlencoded）
+type TokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+	CodeVerifier string `form:"code_verifier"`
+}
+
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type" example:"Bearer"`
+	ExpiresIn   int64  `json:"expires_in" example:"900"`
+	IDToken     string `json:"id_token"`
+	Scope       string `json:"scope" example:"openid profile email"`
+}
+
+type UserInfoResponse struct {
+	Sub               string   `json:"sub" example:"1"`
+	PreferredUsername string   `json:"preferred_username,omitempty" example:"john_doe"`
+	Name              string   `json:"name,omitempty" example:"John"`
+	Picture           string   `json:"picture,omitempty"`
+	Email             string   `json:"email,omitempty" example:"john@example.com"`
+	Roles             []string `json:"roles" example:"管理员"`
+	Perms             []string `json:"perms" example:"user:list"`
+}
+
+// DiscoveryDocument /.well-known/openid-configuration
+type DiscoveryDocument struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JwksURI                           string   `json:"jwks_uri"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+type ClientCreateRequest struct {
+	Name         string   `json:"name" binding:"required,max=100" example:"wiki"`
+	RedirectURIs []string `json:"redirectUris" binding:"required,min=1,dive,required,max=512" example:"https://wiki.internal/oidc/callback"`
+	Public       bool     `json:"public" example:"false"` // 公开客户端（SPA），无密钥
+}
+
+type ClientDeleteRequest struct {
+	ClientID string `json:"clientId" binding:"required" example:"Zx8v..."`
+}
+
+type ClientItem struct {
+	ClientID     string   `json:"clientId"`
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirectUris"`
+	Public       bool     `json:"public"`
+	CreatedAt    int64    `json:"createdAt"`
+}
+
+// ClientCreateResponse ClientSecret 仅在创建时返回一次
+type ClientCreateResponse struct {
+	ClientItem
+	ClientSecret string `json:"clientSecret,omitempty"`
+}
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sine-io/sinx/application/oidc/dto"
+	rbacdto "github.com/sine-io/sinx/application/rbac/dto"
+	authService "github.com/sine-io/sinx/domain/auth/service"
+	"github.com/sine-io/sinx/domain/oidc/entity"
+	oidcService "github.com/sine-io/sinx/domain/oidc/service"
+	userEntity "github.com/sine-io/sinx/domain/user/entity"
+	userService "github.com/sine-io/sinx/domain/user/service"
+	"github.com/sine-io/sinx/pkg/auth"
+	"github.com/sine-io/sinx/pkg/logger"
+	"github.com/sine-io/sinx/pkg/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// UserClaimsProvider 提供写入 ID 令牌的角色与权限
+type UserClaimsProvider interface {
+	GetUserRoles(ctx context.Context, userID uint) ([]*rbacdto.RoleSimple, error)
+	GetUserPerms(ctx context.Context, userID uint) (map[string]struct{}, error)
+}
+
+// IDTokenClaims OIDC ID 令牌声明；roles / perms 为 sinx 扩展声明
+type IDTokenClaims struct {
+	Nonce             string   `json:"nonce,omitempty"`
+	AuthTime          int64    `json:"auth_time,omitempty"`
+	PreferredUsername string   `json:"preferred_username,omitempty"`
+	Name              string   `json:"name,omitempty"`
+	Email             string   `json:"email,omitempty"`
+	Roles             []string `json:"roles"`
+	Perms             []string `json:"perms"`
+	jwt.RegisteredClaims
+}
+
+type OIDCApplicationService struct {
+	oidcDomainService *oidcService.OIDCDomainService
+	userDomainService *userService.UserDomainService
+	tokenService      *authService.TokenDomainService
+	claims            UserClaimsProvider
+	issuer            string
+}
+
+func NewOIDCApplicationService(oidcDomainService *oidcService.OIDCDomainService, userDomainService *userService.UserDomainService, tokenService *authService.TokenDomainService, claims UserClaimsProvider, issuer string) *OIDCApplicationService {
+	return &OIDCApplicationService{
+		oidcDomainService: oidcDomainService,
+		userDomainService: userDomainService,
+		tokenService:      tokenService,
+		claims:            claims,
+		issuer:            strings.TrimRight(issuer, "/"),
+	}
+}
+
+// Discovery OIDC 发现文档
+func (s *OIDCApplicationService) Discovery() *dto.DiscoveryDocument {
+	return &dto.DiscoveryDocument{
+		Issuer:                            s.issuer,
+		AuthorizationEndpoint:             s.issuer + "/oauth2/authorize",
+		TokenEndpoint:                     s.issuer + "/oauth2/token",
+		UserinfoEndpoint:                  s.issuer + "/oauth2/userinfo",
+		JwksURI:                           s.issuer + "/.well-known/jwks.json",
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{auth.ActiveAlg()},
+		ScopesSupported:                   []string{"openid", "profile", "email"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "preferred_username", "name", "email", "roles", "perms"},
+	}
+}
+
+// Authorize 为已登录用户处理授权请求，返回需跳转的回调地址
+// 客户端或回调地址非法时直接返回错误；其余协议错误通过回调地址的 error 参数告知客户端
+func (s *OIDCApplicationService) Authorize(ctx context.Context, userID uint, authTime time.Time, req *dto.AuthorizeRequest) (*dto.AuthorizeResponse, error) {
+	client, err := s.oidcDomainService.ResolveClient(ctx, req.ClientID, req.RedirectURI)
+	if err != nil {
+		return nil, err
+	}
+
+	q := url.Values{}
+	if req.ResponseType != "code" {
+		q.Set("error", "unsupported_response_type")
+	} else {
+		code, err := s.oidcDomainService.IssueCode(ctx, client, userID, oidcService.AuthorizeParams{
+			RedirectURI:         req.RedirectURI,
+			Scope:               req.Scope,
+			Nonce:               req.Nonce,
+			CodeChallenge:       req.CodeChallenge,
+			CodeChallengeMethod: req.CodeChallengeMethod,
+			AuthTime:            authTime,
+		})
+		if oauthErr, ok := err.(*oidcService.OAuthError); ok {
+			q.Set("error", oauthErr.Code)
+			q.Set("error_description", oauthErr.Description)
+		} else if err != nil {
+			return nil, err
+		} else {
+			q.Set("code", code)
+			logger.Info("audit:oidc_authorize", "userId", userID, "clientId", client.ClientID)
+		}
+	}
+	if req.State != "" {
+		q.Set("state", req.State)
+	}
+
+	sep := "?"
+	if strings.Contains(req.RedirectURI, "?") {
+		sep = "&"
+	}
+	return &dto.AuthorizeResponse{RedirectTo: req.RedirectURI + sep + q.Encode()}, nil
+}
+
+// Token 授权码换取访问令牌与 ID 令牌；失败时返回 *OAuthError
+func (s *OIDCApplicationService) Token(ctx context.Context, req *dto.TokenRequest) (*dto.TokenResponse, error) {
+	if req.GrantType != "authorization_code" {
+		return nil, &oidcService.OAuthError{Code: "unsupported_grant_type"}
+	}
+	client, err := s.oidcDomainService.AuthenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	code, err := s.oidcDomainService.ExchangeCode(ctx, client, req.Code, req.RedirectURI, req.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userDomainService.GetUserByID(ctx, code.UserID)
+	if err != nil {
+		return nil, &oidcService.OAuthError{Code: "invalid_grant", Description: "user is unavailable"}
+	}
+	roles, perms, err := s.rolesAndPerms(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	ttl := auth.AccessTokenTTL()
+	jti, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	registered := jwt.RegisteredClaims{
+		ID:        jti,
+		Issuer:    s.issuer,
+		Subject:   strconv.FormatUint(uint64(user.ID), 10),
+		Audience:  jwt.ClaimStrings{client.ClientID},
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	}
+	accessToken, err := auth.SignClaims(&auth.Claims{
+		UserID:           user.ID,
+		Username:         user.Username,
+		TokenUse:         auth.TokenUseOIDC,
+		Scope:            code.Scope,
+		IssuedAtNano:     now.UnixNano(),
+		RegisteredClaims: registered,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	idClaims := &IDTokenClaims{Nonce: code.Nonce, Roles: roles, Perms: perms, RegisteredClaims: registered}
+	if !code.AuthTime.IsZero() {
+		idClaims.AuthTime = code.AuthTime.Unix()
+	}
+	s.fillProfile(code.Scope, user, &idClaims.PreferredUsername, &idClaims.Name, &idClaims.Email)
+	idToken, err := auth.SignClaims(idClaims)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("audit:oidc_token", "userId", user.ID, "clientId", client.ClientID)
+	return &dto.TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(ttl.Seconds()),
+		IDToken:     idToken,
+		Scope:       code.Scope,
+	}, nil
+}
+
+// UserInfo 使用 OIDC 访问令牌查询用户信息；用户令牌被吊销（如修改密码、禁用）后同样失效
+func (s *OIDCApplicationService) UserInfo(ctx context.Context, rawToken string) (*dto.UserInfoResponse, error) {
+	claims, err := auth.ParseToken(rawToken)
+	if err != nil || claims.TokenUse != auth.TokenUseOIDC {
+		return nil, &oidcService.OAuthError{Code: "invalid_token"}
+	}
+	revoked, err := s.tokenService.IsRevoked(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, &oidcService.OAuthError{Code: "invalid_token", Description: "token has been revoked"}
+	}
+	user, err := s.userDomainService.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, &oidcService.OAuthError{Code: "invalid_token", Description: "user is unavailable"}
+	}
+	roles, perms, err := s.rolesAndPerms(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	res := &dto.UserInfoResponse{Sub: claims.Subject, Roles: roles, Perms: perms}
+	s.fillProfile(claims.Scope, user, &res.PreferredUsername, &res.Name, &res.Email)
+	if oidcService.HasScope(claims.Scope, "profile") {
+		res.Picture = user.Avatar
+	}
+	return res, nil
+}
+
+// CreateClient 登记客户端
+func (s *OIDCApplicationService) CreateClient(ctx context.Context, req *dto.ClientCreateRequest) (*dto.ClientCreateResponse, error) {
+	client, secret, err := s.oidcDomainService.RegisterClient(ctx, req.Name, req.RedirectURIs, req.Public)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("audit:oidc_client_created", "clientId", client.ClientID, "name", client.Name)
+	return &dto.ClientCreateResponse{ClientItem: *clientToItem(client), ClientSecret: secret}, nil
+}
+
+func (s *OIDCApplicationService) ListClients(ctx context.Context) ([]*dto.ClientItem, error) {
+	clients, err := s.oidcDomainService.ListClients(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*dto.ClientItem, 0, len(clients))
+	for _, c := range clients {
+		res = append(res, clientToItem(c))
+	}
+	return res, nil
+}
+
+func (s *OIDCApplicationService) DeleteClient(ctx context.Context, req *dto.ClientDeleteRequest) error {
+	if err := s.oidcDomainService.DeleteClient(ctx, req.ClientID); err != nil {
+		return err
+	}
+	logger.Info("audit:oidc_client_deleted", "clientId", req.ClientID)
+	return nil
+}
+
+// rolesAndPerms 用户角色名与权限标识（排序后输出，便于客户端比对）
+func (s *OIDCApplicationService) rolesAndPerms(ctx context.Context, userID uint) ([]string, []string, error) {
+	roleList, err := s.claims.GetUserRoles(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	roles := make([]string, 0, len(roleList))
+	for _, r := range roleList {
+		roles = append(roles, r.Name)
+	}
+	permSet, err := s.claims.GetUserPerms(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	perms := make([]string, 0, len(permSet))
+	for p := range permSet {
+		perms = append(perms, p)
+	}
+	sort.Strings(roles)
+	sort.Strings(perms)
+	return roles, perms, nil
+}
+
+// fillProfile 按 scope 填充 profile / email 声明
+func (s *OIDCApplicationService) fillProfile(scope string, user *userEntity.User, username, name, email *string) {
+	if oidcService.HasScope(scope, "profile") {
+		*username = user.Username
+		*name = user.Nickname
+	}
+	if oidcService.HasScope(scope, "email") {
+		*email = user.Email
+	}
+}
+
+func clientToItem(c *entity.OAuthClient) *dto.ClientItem {
+	return &dto.ClientItem{ClientID: c.ClientID, Name: c.Name, RedirectURIs: c.RedirectURIList(), Public: c.IsPublic(), CreatedAt: c.CreatedAt.Unix()}
+}
@@ -1,5 +1,7 @@
 package dto
 
+import "time"
+
 // 通用分页请求
 type PageRequest struct {
 	PageNum  int `form:"pageNum" json:"pageNum"`
@@ -19,6 +21,7 @@ type UserCreateRequest struct {
 	Email    string `json:"email"`
 	Mobile   string `json:"mobile"`
 	Avatar   string `json:"avatar"`
+	DeptID   uint   `json:"deptId"` // 所属部门，0 表示未分配
 }
 
 type UserUpdateRequest struct {
@@ -28,21 +31,23 @@ type UserUpdateRequest struct {
 	Email    string `json:"email"`
 	Mobile   string `json:"mobile"`
 	Status   *int16 `json:"status"`
+	// UserType 0 普通 1 超级管理员，仅超级管理员可修改
+	UserType *int16 `json:"userType" binding:"omitempty,oneof=0 1"`
+	// AuthSource 认证来源：local / ldap；default 表示清空，跟随全局配置
+	AuthSource string `json:"authSource" binding:"omitempty,oneof=default local ldap"`
+	// DeptID 所属部门，不传表示不修改，0 表示移出部门
+	DeptID *uint `json:"deptId"`
 }
 
 type UserDeleteRequest struct {
 	ID uint `json:"id" binding:"required"`
 }
 
-type ChangePasswordRequest struct {
-	UserID      uint   `json:"userId" binding:"required"`
-	OldPassword string `json:"oldPassword" binding:"required"`
-	NewPassword string `json:"newPassword" binding:"required"`
-}
-
 type BindUserRoleRequest struct {
-	UserID  uint   `json:"userId" binding:"required"`
-	RoleIDs []uint `json:"roleIds" binding:"required"`
+	UserID     uint       `json:"userId" binding:"required"`
+	RoleIDs    []uint     `json:"roleIds" binding:"required"`
+	ValidFrom  *time.Time `json:"validFrom"`  // 可选，生效时间
+	ValidUntil *time.Time `json:"validUntil"` // 可选，失效时间，到期后绑定被自动清理
 }
 
 type UnbindUserRoleRequest struct {
@@ -52,10 +57,14 @@ type UnbindUserRoleRequest struct {
 
 // 角色相关
 type RoleCreateOrUpdateRequest struct {
-	ID     uint   `json:"id"`
-	Name   string `json:"name" binding:"required"`
-	Remark string `json:"remark"`
-	Status int16  `json:"status" binding:"required"`
+	ID       uint   `json:"id"`
+	Name     string `json:"name" binding:"required"`
+	Remark   string `json:"remark"`
+	ParentID uint   `json:"parentId"`                   // 父角色，0 表示顶级；继承全部祖先角色的菜单
+	Status   int16  `json:"status" binding:"oneof=0 1"` // 0正常 1禁用
+	// DataScope 数据范围：1 全部 2 自定义部门 3 本部门 4 本部门及以下 5 仅本人；新建默认 1，更新时不传则保持不变
+	DataScope int16  `json:"dataScope" binding:"omitempty,oneof=1 2 3 4 5"`
+	DeptIDs   []uint `json:"deptIds"` // 自定义数据范围的部门，DataScope 为 2 时必填
 }
 
 type RoleDeleteRequest struct {
@@ -65,11 +74,13 @@ type RoleDeleteRequest struct {
 type BindRoleMenuRequest struct {
 	RoleID  uint   `json:"roleId" binding:"required"`
 	MenuIDs []uint `json:"menuIds" binding:"required"`
+	Deny    bool   `json:"deny"` // true 绑定为拒绝菜单，优先于任何授予
 }
 
 type UnbindRoleMenuRequest struct {
 	RoleID  uint   `json:"roleId" binding:"required"`
 	MenuIDs []uint `json:"menuIds" binding:"required"`
+	Deny    bool   `json:"deny"` // true 解绑拒绝菜单
 }
 
 // 菜单相关
@@ -87,7 +98,7 @@ type MenuCreateOrUpdateRequest struct {
 	IsHidden  int16  `json:"isHidden" binding:"required"`
 	Perms     string `json:"perms"`
 	Icon      string `json:"icon"`
-	Status    int16  `json:"status" binding:"required"`
+	Status    int16  `json:"status" binding:"oneof=0 1"` // 0正常 1禁用
 	Remark    string `json:"remark"`
 }
 
@@ -97,18 +108,41 @@ type MenuDeleteRequest struct {
 
 // 输出结构
 type UserSimple struct {
-	ID       uint   `json:"id"`
-	Username string `json:"username"`
-	Nickname string `json:"nickname"`
-	Email    string `json:"email"`
-	Status   int16  `json:"status"`
+	ID         uint   `json:"id"`
+	Username   string `json:"username"`
+	Nickname   string `json:"nickname"`
+	Email      string `json:"email"`
+	Status     int16  `json:"status"`
+	UserType   int16  `json:"userType"`
+	AuthSource string `json:"authSource"`
+	DeptID     uint   `json:"deptId"`
 }
 
 type RoleSimple struct {
-	ID     uint   `json:"id"`
-	Name   string `json:"name"`
-	Remark string `json:"remark"`
-	Status int16  `json:"status"`
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Remark     string     `json:"remark"`
+	ParentID   uint       `json:"parentId"`
+	Status     int16      `json:"status"`
+	DataScope  int16      `json:"dataScope,omitempty"`
+	DeptIDs    []uint     `json:"deptIds,omitempty"`
+	ValidFrom  *time.Time `json:"validFrom,omitempty"`
+	ValidUntil *time.Time `json:"validUntil,omitempty"`
+}
+
+// RolePermsResponse 角色自身权限、继承权限与生效的拒绝规则
+type RolePermsResponse struct {
+	RoleID    uint          `json:"roleId"`
+	Own       []string      `json:"own"`
+	Inherited []*PermSource `json:"inherited"`
+	Denied    []*PermSource `json:"denied"`
+}
+
+// PermSource 权限标识及其来源角色
+type PermSource struct {
+	Perm     string `json:"perm"`
+	RoleID   uint   `json:"roleId"`
+	RoleName string `json:"roleName"`
 }
 
 type MenuSimple struct {
@@ -131,9 +165,52 @@ type MenuTreeNode struct {
 	Path      string          `json:"path,omitempty"`
 	Component string          `json:"component,omitempty"`
 	Icon      string          `json:"icon,omitempty"`
+	Denied    bool            `json:"denied,omitempty"` // 权限被拒绝规则收回
 	Children  []*MenuTreeNode `json:"children"`
 }
 
 type RoleMenuTreeResponse struct {
-	MenuIDs []uint `json:"menuIds"`
+	MenuIDs       []uint `json:"menuIds"`
+	DeniedMenuIDs []uint `json:"deniedMenuIds"`
+}
+
+// DeniedPerm 用户因拒绝规则失去的权限及其来源
+type DeniedPerm struct {
+	Perm     string `json:"perm"`
+	MenuID   uint   `json:"menuId"`
+	MenuName string `json:"menuName"`
+	RoleID   uint   `json:"roleId"`
+	RoleName string `json:"roleName"`
+}
+
+// RoleConstraintRequest 职责分离约束，id 为空时新增
+type RoleConstraintRequest struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name" binding:"required,max=100"`
+	RoleIDs  []uint `json:"roleIds" binding:"required,min=2"`
+	MaxRoles int    `json:"maxRoles" binding:"min=0"` // 用户最多可同时拥有的角色数，0 按 1（互斥）处理
+}
+
+type RoleConstraintItem struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	RoleIDs   []uint    `json:"roleIds"`
+	MaxRoles  int       `json:"maxRoles"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// SoDViolation 违反的职责分离约束：用户同时拥有的约束内角色超过上限
+type SoDViolation struct {
+	ConstraintID   uint     `json:"constraintId"`
+	ConstraintName string   `json:"constraintName"`
+	MaxRoles       int      `json:"maxRoles"`
+	RoleIDs        []uint   `json:"roleIds"` // 用户拥有的约束内角色（含经子角色继承的）
+	RoleNames      []string `json:"roleNames"`
+}
+
+// SoDReportItem 存在违规的用户
+type SoDReportItem struct {
+	UserID     uint            `json:"userId"`
+	Username   string          `json:"username"`
+	Violations []*SoDViolation `json:"violations"`
 }
@@ -2,34 +2,57 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	rbacdto "github.com/sine-io/sinx/application/rbac/dto"
+	deptRepo "github.com/sine-io/sinx/domain/dept/repository"
+	deptService "github.com/sine-io/sinx/domain/dept/service"
 	menuEntity "github.com/sine-io/sinx/domain/menu/entity"
 	menuRepo "github.com/sine-io/sinx/domain/menu/repository"
+	rbacEntity "github.com/sine-io/sinx/domain/rbac/entity"
 	rbacRepo "github.com/sine-io/sinx/domain/rbac/repository"
 	roleEntity "github.com/sine-io/sinx/domain/role/entity"
 	roleRepo "github.com/sine-io/sinx/domain/role/repository"
+	roleService "github.com/sine-io/sinx/domain/role/service"
 	userEntity "github.com/sine-io/sinx/domain/user/entity"
 	userRepo "github.com/sine-io/sinx/domain/user/repository"
+	userService "github.com/sine-io/sinx/domain/user/service"
 	"github.com/sine-io/sinx/infra/cache"
+	"github.com/sine-io/sinx/pkg/datascope"
 	"github.com/sine-io/sinx/pkg/errorx"
 	"github.com/sine-io/sinx/pkg/logger"
 	"github.com/sine-io/sinx/pkg/permissions"
 	"github.com/sine-io/sinx/pkg/utils"
 )
 
+// TokenRevoker 吊销用户全部令牌（禁用 / 删除用户时使已签发令牌立即失效）
+type TokenRevoker interface {
+	RevokeUserTokens(ctx context.Context, userID uint) error
+}
+
 type RBACApplicationService struct {
 	userRepository userRepo.UserRepository
 	roleRepository roleRepo.RoleRepository
 	menuRepository menuRepo.MenuRepository
 	rbacRepository rbacRepo.RBACRepository
+	deptRepository deptRepo.DepartmentRepository
+	tokenRevoker   TokenRevoker
+	passwords      *userService.PasswordPolicyService
 	permCache      *permissions.UserPermCache
-	redisPermCache *permissions.RedisUserPermCache
+	redisPermCache sharedPermCache
+}
+
+// sharedPermCache 多实例共享的权限缓存版本戳，由 permissions.RedisUserPermCache 实现
+type sharedPermCache interface {
+	Version(ctx context.Context, userID uint) (int64, error)
+	InvalidateUsers(ctx context.Context, userIDs []uint) error
 }
 
-func NewRBACApplicationService(u userRepo.UserRepository, r roleRepo.RoleRepository, m menuRepo.MenuRepository, rb rbacRepo.RBACRepository) *RBACApplicationService {
-	svc := &RBACApplicationService{userRepository: u, roleRepository: r, menuRepository: m, rbacRepository: rb, permCache: permissions.NewUserPermCache(5 * time.Minute)}
+func NewRBACApplicationService(u userRepo.UserRepository, r roleRepo.RoleRepository, m menuRepo.MenuRepository, rb rbacRepo.RBACRepository, d deptRepo.DepartmentRepository, tr TokenRevoker, pw *userService.PasswordPolicyService) *RBACApplicationService {
+	svc := &RBACApplicationService{userRepository: u, roleRepository: r, menuRepository: m, rbacRepository: rb, deptRepository: d, tokenRevoker: tr, passwords: pw, permCache: permissions.NewUserPermCache(5 * time.Minute)}
 	if cli := cache.GetRedis(); cli != nil {
 		svc.redisPermCache = permissions.NewRedisUserPermCache(cli, 5*time.Minute)
 	}
@@ -38,57 +61,326 @@ func NewRBACApplicationService(u userRepo.UserRepository, r roleRepo.RoleReposit
 
 // 用户管理
 func (s *RBACApplicationService) CreateUser(ctx context.Context, req *rbacdto.UserCreateRequest) error {
-	hashed, _ := utils.HashPassword(req.Password)
-	user := &userEntity.User{Username: req.Username, Password: hashed, Nickname: req.Nickname, Email: req.Email, Mobile: req.Mobile, Avatar: req.Avatar}
+	if err := s.passwords.Validate(ctx, "password", nil, req.Username, req.Password); err != nil {
+		return err
+	}
+	if err := s.ensureDept(ctx, req.DeptID); err != nil {
+		return err
+	}
+	hashed, err := utils.HashPassword(req.Password)
+	if err != nil {
+		return errorx.New(errorx.ErrInternalServer, "failed to hash password")
+	}
+	now := time.Now()
+	user := &userEntity.User{Username: req.Username, Password: hashed, PasswordChangedAt: &now, Nickname: req.Nickname, Email: req.Email, Mobile: req.Mobile, Avatar: req.Avatar, DeptID: req.DeptID}
 	if err := s.userRepository.Create(ctx, user); err != nil {
 		return err
 	}
+	s.passwords.Remember(ctx, user.ID, hashed)
 	logger.Info("audit:create_user", "username", req.Username, "nickname", req.Nickname)
 	return nil
 }
 
-func (s *RBACApplicationService) UpdateUser(ctx context.Context, req *rbacdto.UserUpdateRequest) error {
+// UpdateUser 更新用户；operatorID 为 0 表示系统调用（如目录同步）
+// 超级管理员只能由超级管理员修改，用户类型也只能由超级管理员变更
+func (s *RBACApplicationService) UpdateUser(ctx context.Context, operatorID uint, req *rbacdto.UserUpdateRequest) error {
 	user, err := s.userRepository.GetByID(ctx, req.ID)
 	if err != nil {
 		return err
 	}
+	if err := s.EnsureInDataScope(ctx, operatorID, user); err != nil {
+		return err
+	}
+	if user.IsSuperAdmin() || (req.UserType != nil && *req.UserType != user.UserType) {
+		if err := s.requireSuperAdmin(ctx, operatorID); err != nil {
+			return err
+		}
+	}
+	// 降级或禁用超级管理员时至少保留一个：校验与保存在同一事务中持锁执行，避免并发请求各自通过校验
+	demoted := req.UserType != nil && *req.UserType != userEntity.UserTypeSuperAdmin
+	disabling := req.Status != nil && *req.Status != 0
+	if user.IsSuperAdmin() && user.Status == 0 && (demoted || disabling) {
+		err = s.userRepository.LockSuperAdmins(ctx, func(ctx context.Context) error {
+			if err := s.ensureOtherSuperAdmin(ctx); err != nil {
+				return err
+			}
+			return s.saveUser(ctx, operatorID, user, req)
+		})
+		return err
+	}
+	return s.saveUser(ctx, operatorID, user, req)
+}
+
+// saveUser 将更新请求写入用户并处理令牌吊销、权限缓存失效
+func (s *RBACApplicationService) saveUser(ctx context.Context, operatorID uint, user *userEntity.User, req *rbacdto.UserUpdateRequest) error {
+	typeChanged := req.UserType != nil && *req.UserType != user.UserType
 	if req.Username != "" {
 		user.Username = req.Username
 	}
 	if req.Nickname != "" {
 		user.Nickname = req.Nickname
 	}
-	if req.Email != "" {
+	if req.Email != "" && req.Email != user.Email {
 		user.Email = req.Email
+		user.EmailVerifiedAt = nil
 	}
 	if req.Mobile != "" {
 		user.Mobile = req.Mobile
 	}
+	switch req.AuthSource {
+	case "":
+	case "default":
+		user.AuthSource = ""
+	default:
+		user.AuthSource = req.AuthSource
+	}
+	if req.UserType != nil {
+		user.UserType = *req.UserType
+	}
+	if req.DeptID != nil {
+		if err := s.ensureDept(ctx, *req.DeptID); err != nil {
+			return err
+		}
+		user.DeptID = *req.DeptID
+	}
+	disabled, statusChanged := false, false
 	if req.Status != nil {
+		disabled = user.Status == 0 && *req.Status != 0
+		statusChanged = user.Status != *req.Status
 		user.Status = *req.Status
 	}
 	if err := s.userRepository.Update(ctx, user); err != nil {
 		return err
 	}
+	if disabled {
+		s.revokeUserTokens(ctx, user.ID)
+	}
+	if typeChanged || statusChanged {
+		s.invalidatePermCache([]uint{user.ID})
+	}
+	if typeChanged {
+		logger.Info("audit:change_user_type", "id", user.ID, "userType", user.UserType, "operatorId", operatorID)
+	}
 	logger.Info("audit:update_user", "id", user.ID)
 	return nil
 }
 
-func (s *RBACApplicationService) DeleteUser(ctx context.Context, id uint) error {
-	if err := s.userRepository.Delete(ctx, id); err != nil {
+// DeleteUser 删除用户；超级管理员只能由超级管理员删除，且不能删除最后一个
+func (s *RBACApplicationService) DeleteUser(ctx context.Context, operatorID uint, id uint) error {
+	user, err := s.userRepository.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.EnsureInDataScope(ctx, operatorID, user); err != nil {
+		return err
+	}
+	if user.IsSuperAdmin() {
+		if err := s.requireSuperAdmin(ctx, operatorID); err != nil {
+			return err
+		}
+	}
+	if user.IsSuperAdmin() && user.Status == 0 {
+		err = s.userRepository.LockSuperAdmins(ctx, func(ctx context.Context) error {
+			if err := s.ensureOtherSuperAdmin(ctx); err != nil {
+				return err
+			}
+			return s.userRepository.Delete(ctx, id)
+		})
+	} else {
+		err = s.userRepository.Delete(ctx, id)
+	}
+	if err != nil {
+		return err
+	}
+	s.revokeUserTokens(ctx, id)
+	s.invalidatePermCache([]uint{id})
+	logger.Info("audit:delete_user", "id", id, "operatorId", operatorID)
+	return nil
+}
+
+// ensureDept 部门须存在，0 表示未分配
+func (s *RBACApplicationService) ensureDept(ctx context.Context, deptID uint) error {
+	if deptID == 0 {
+		return nil
+	}
+	dept, err := s.deptRepository.GetByID(ctx, deptID)
+	if err != nil {
+		return err
+	}
+	if dept == nil {
+		return errorx.New(errorx.ErrInvalidParam, "department not found")
+	}
+	return nil
+}
+
+// DataScope 汇总用户当前生效角色的数据范围（取并集，不沿角色继承）
+// 超级管理员不受限；没有任何可用范围时仅可见本人数据
+func (s *RBACApplicationService) DataScope(ctx context.Context, userID uint) (*datascope.Scope, error) {
+	user, err := s.userRepository.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return &datascope.Scope{UserID: userID}, nil
+	}
+	if user.IsSuperAdmin() {
+		return &datascope.Scope{All: true}, nil
+	}
+	// 与权限一致：按生效角色（含继承的祖先，排除已删除与禁用的角色）取并集
+	roleIDs, h, err := s.effectiveRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	scope := &datascope.Scope{}
+	depts := map[uint]struct{}{}
+	var tree *deptService.DeptTree
+	for _, id := range roleIDs {
+		r := h.Get(id)
+		switch r.DataScope {
+		case roleEntity.DataScopeAll:
+			return &datascope.Scope{All: true}, nil
+		case roleEntity.DataScopeCustom:
+			for _, id := range r.ScopeDeptIDs() {
+				depts[id] = struct{}{}
+			}
+		case roleEntity.DataScopeDept:
+			if user.DeptID != 0 {
+				depts[user.DeptID] = struct{}{}
+			}
+		case roleEntity.DataScopeDeptAndChild:
+			if user.DeptID == 0 {
+				continue
+			}
+			if tree == nil {
+				list, err := s.deptRepository.ListAll(ctx)
+				if err != nil {
+					return nil, err
+				}
+				tree = deptService.NewDeptTree(list)
+			}
+			for _, id := range tree.Descendants(user.DeptID) {
+				depts[id] = struct{}{}
+			}
+		case roleEntity.DataScopeSelf:
+			scope.UserID = userID
+		}
+	}
+	for id := range depts {
+		scope.DeptIDs = append(scope.DeptIDs, id)
+	}
+	sort.Slice(scope.DeptIDs, func(i, j int) bool { return scope.DeptIDs[i] < scope.DeptIDs[j] })
+	if len(scope.DeptIDs) == 0 {
+		scope.UserID = userID
+	}
+	return scope, nil
+}
+
+// WithDataScope 将用户的数据范围附加到 ctx，之后的仓储列表查询按该范围过滤
+func (s *RBACApplicationService) WithDataScope(ctx context.Context, userID uint) (context.Context, error) {
+	scope, err := s.DataScope(ctx, userID)
+	if err != nil {
+		return ctx, err
+	}
+	return datascope.WithScope(ctx, scope), nil
+}
+
+// EnsureInDataScope 目标用户须在操作人的数据范围内（与 ListUsers 可见的用户一致）；operatorID 为 0 表示系统调用，不做限制
+func (s *RBACApplicationService) EnsureInDataScope(ctx context.Context, operatorID uint, target *userEntity.User) error {
+	if operatorID == 0 {
+		return nil
+	}
+	scope, err := s.DataScope(ctx, operatorID)
+	if err != nil {
+		return err
+	}
+	if !scope.Allows(target.DeptID, target.ID) {
+		return errorx.New(errorx.ErrForbidden, "user is outside your data scope")
+	}
+	return nil
+}
+
+// IsSuperAdmin 用户是否为状态正常的超级管理员
+func (s *RBACApplicationService) IsSuperAdmin(ctx context.Context, userID uint) bool {
+	user := s.activeUser(ctx, userID)
+	return user != nil && user.IsSuperAdmin()
+}
+
+// activeUser 返回状态正常的用户，不存在或已禁用 / 删除时返回 nil
+func (s *RBACApplicationService) activeUser(ctx context.Context, userID uint) *userEntity.User {
+	if userID == 0 {
+		return nil
+	}
+	user, err := s.userRepository.GetByID(ctx, userID)
+	if err != nil || user == nil || user.Status != 0 {
+		return nil
+	}
+	return user
+}
+
+// requireSuperAdmin 操作人须为超级管理员；operatorID 为 0 的系统调用不受限
+func (s *RBACApplicationService) requireSuperAdmin(ctx context.Context, operatorID uint) error {
+	if operatorID == 0 || s.IsSuperAdmin(ctx, operatorID) {
+		return nil
+	}
+	return errorx.New(errorx.ErrForbidden, "only super admins can modify super admins")
+}
+
+// ensureOtherSuperAdmin 移除一个超级管理员前确认还有其他超级管理员
+func (s *RBACApplicationService) ensureOtherSuperAdmin(ctx context.Context) error {
+	n, err := s.userRepository.CountSuperAdmins(ctx)
+	if err != nil {
+		return err
+	}
+	if n <= 1 {
+		return errorx.NewWithCode(errorx.ErrLastSuperAdmin)
+	}
+	return nil
+}
+
+// BootstrapSuperAdmin 系统中没有超级管理员时，将指定用户提升为超级管理员（全新安装的初始化入口）
+func (s *RBACApplicationService) BootstrapSuperAdmin(ctx context.Context, username string) error {
+	n, err := s.userRepository.CountSuperAdmins(ctx)
+	if err != nil || n > 0 {
+		return err
+	}
+	user, err := s.userRepository.GetByUsername(ctx, username)
+	if err != nil || user == nil || user.ID == 0 {
+		logger.Warn("bootstrap_super_admin_user_not_found", "username", username)
+		return nil
+	}
+	user.UserType = userEntity.UserTypeSuperAdmin
+	if err := s.userRepository.Update(ctx, user); err != nil {
 		return err
 	}
-	logger.Info("audit:delete_user", "id", id)
+	s.invalidatePermCache([]uint{user.ID})
+	logger.Info("audit:bootstrap_super_admin", "id", user.ID, "username", username)
 	return nil
 }
 
-func (s *RBACApplicationService) ListUsers(ctx context.Context, pageNum, pageSize int) (int64, []*rbacdto.UserSimple, error) {
+// revokeUserTokens 尽力吊销用户令牌；失败时仍有中间件的用户状态校验兜底
+func (s *RBACApplicationService) revokeUserTokens(ctx context.Context, userID uint) {
+	if s.tokenRevoker == nil {
+		return
+	}
+	if err := s.tokenRevoker.RevokeUserTokens(ctx, userID); err != nil {
+		logger.Warn("revoke_user_tokens_failed", "userId", userID, "error", err)
+	}
+}
+
+// ListUsers 按操作人的数据范围过滤；operatorID 为 0 表示系统调用，不做限制
+func (s *RBACApplicationService) ListUsers(ctx context.Context, operatorID uint, pageNum, pageSize int) (int64, []*rbacdto.UserSimple, error) {
 	if pageNum <= 0 {
 		pageNum = 1
 	}
 	if pageSize <= 0 {
 		pageSize = 10
 	}
+	if operatorID != 0 {
+		var err error
+		if ctx, err = s.WithDataScope(ctx, operatorID); err != nil {
+			return 0, nil, err
+		}
+	}
 	offset := (pageNum - 1) * pageSize
 	users, err := s.userRepository.List(ctx, offset, pageSize)
 	if err != nil {
@@ -97,56 +389,112 @@ func (s *RBACApplicationService) ListUsers(ctx context.Context, pageNum, pageSiz
 	total, _ := s.userRepository.Count(ctx)
 	res := make([]*rbacdto.UserSimple, 0, len(users))
 	for _, u := range users {
-		res = append(res, &rbacdto.UserSimple{ID: u.ID, Username: u.Username, Nickname: u.Nickname, Email: u.Email, Status: u.Status})
+		res = append(res, &rbacdto.UserSimple{ID: u.ID, Username: u.Username, Nickname: u.Nickname, Email: u.Email, Status: u.Status, UserType: u.UserType, AuthSource: u.AuthSource, DeptID: u.DeptID})
 	}
 	return total, res, nil
 }
 
-func (s *RBACApplicationService) ChangePassword(ctx context.Context, req *rbacdto.ChangePasswordRequest) error {
-	user, err := s.userRepository.GetByID(ctx, req.UserID)
+// 角色管理
+// CreateOrUpdateRole 父角色须存在，且不能形成继承环
+func (s *RBACApplicationService) CreateOrUpdateRole(ctx context.Context, req *rbacdto.RoleCreateOrUpdateRequest) error {
+	h, err := s.roleHierarchy(ctx)
 	if err != nil {
 		return err
 	}
-	// 旧密码校验
-	if !utils.CheckPassword(req.OldPassword, user.Password) {
-		return errorx.NewWithCode(errorx.ErrUserInvalidPassword)
+	if req.ParentID != 0 && h.Get(req.ParentID) == nil {
+		return errorx.New(errorx.ErrInvalidParam, "parent role not found")
 	}
-	hashed, _ := utils.HashPassword(req.NewPassword)
-	user.Password = hashed
-	if err := s.userRepository.Update(ctx, user); err != nil {
+	if h.WouldCycle(req.ID, req.ParentID) {
+		return errorx.New(errorx.ErrInvalidParam, "role hierarchy cycle")
+	}
+	// 更新时未指定数据范围则保持原值，避免只改名称 / 备注就放宽为全部数据；新建默认全部数据
+	dataScope := req.DataScope
+	if dataScope == 0 && req.ID == 0 {
+		dataScope = roleEntity.DataScopeAll
+	}
+	if err := s.ensureScopeDepts(ctx, dataScope, req.DeptIDs); err != nil {
 		return err
 	}
-	logger.Info("audit:change_password", "userId", req.UserID)
-	return nil
-}
-
-// 角色管理
-func (s *RBACApplicationService) CreateOrUpdateRole(ctx context.Context, req *rbacdto.RoleCreateOrUpdateRequest) error {
 	if req.ID == 0 {
-		if err := s.roleRepository.Create(ctx, &roleEntity.Role{Name: req.Name, Remark: req.Remark, Status: req.Status}); err != nil {
+		role := &roleEntity.Role{Name: req.Name, Remark: req.Remark, ParentID: req.ParentID, Status: req.Status, DataScope: dataScope}
+		if dataScope == roleEntity.DataScopeCustom {
+			role.SetScopeDeptIDs(req.DeptIDs)
+		}
+		if err := s.roleRepository.Create(ctx, role); err != nil {
 			return err
 		}
-		logger.Info("audit:create_role", "name", req.Name)
+		logger.Info("audit:create_role", "name", req.Name, "parentId", req.ParentID, "dataScope", dataScope)
 		return nil
 	}
 	role, err := s.roleRepository.GetByID(ctx, req.ID)
 	if err != nil {
 		return err
 	}
-	role.Name = req.Name
-	role.Remark = req.Remark
-	role.Status = req.Status
-	if err := s.roleRepository.Update(ctx, role); err != nil {
+	changed := role.Status != req.Status || role.ParentID != req.ParentID
+	save := func(ctx context.Context) error {
+		role.Name = req.Name
+		role.Remark = req.Remark
+		role.ParentID = req.ParentID
+		role.Status = req.Status
+		if dataScope != 0 {
+			role.DataScope = dataScope
+			role.ScopeDept = ""
+			if dataScope == roleEntity.DataScopeCustom {
+				role.SetScopeDeptIDs(req.DeptIDs)
+			}
+		}
+		return s.roleRepository.Update(ctx, role)
+	}
+	if role.ParentID != req.ParentID {
+		// 父角色变更的职责分离校验与保存在同一事务中持锁执行，避免与并发的绑定 / 约束保存共同造成违规
+		err = s.rbacRepository.LockSoD(ctx, 0, func(ctx context.Context) error {
+			if err := s.checkReparentSoD(ctx, role.ID, req.ParentID); err != nil {
+				return err
+			}
+			return save(ctx)
+		})
+	} else {
+		err = save(ctx)
+	}
+	if err != nil {
 		return err
 	}
-	logger.Info("audit:update_role", "id", role.ID)
+	if changed {
+		s.invalidateRoleUsers(ctx, role.ID)
+	}
+	logger.Info("audit:update_role", "id", role.ID, "parentId", role.ParentID, "dataScope", role.DataScope)
 	return nil
 }
 
+// ensureScopeDepts 自定义数据范围须指定部门，且部门均存在
+func (s *RBACApplicationService) ensureScopeDepts(ctx context.Context, dataScope int16, deptIDs []uint) error {
+	if dataScope != roleEntity.DataScopeCustom {
+		return nil
+	}
+	if len(deptIDs) == 0 {
+		return errorx.New(errorx.ErrInvalidParam, "deptIds is required for custom data scope")
+	}
+	for _, id := range deptIDs {
+		if err := s.ensureDept(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteRole 存在子角色时不允许删除
 func (s *RBACApplicationService) DeleteRole(ctx context.Context, id uint) error {
+	h, err := s.roleHierarchy(ctx)
+	if err != nil {
+		return err
+	}
+	if h.HasChildren(id) {
+		return errorx.NewWithCode(errorx.ErrHasChildren)
+	}
 	if err := s.roleRepository.Delete(ctx, id); err != nil {
 		return err
 	}
+	s.invalidateRoleUsers(ctx, id)
 	logger.Info("audit:delete_role", "id", id)
 	return nil
 }
@@ -166,13 +514,78 @@ func (s *RBACApplicationService) ListRoles(ctx context.Context, pageNum, pageSiz
 	total, _ := s.roleRepository.Count(ctx)
 	list := make([]*rbacdto.RoleSimple, 0, len(roles))
 	for _, r := range roles {
-		list = append(list, &rbacdto.RoleSimple{ID: r.ID, Name: r.Name, Remark: r.Remark, Status: r.Status})
+		list = append(list, &rbacdto.RoleSimple{ID: r.ID, Name: r.Name, Remark: r.Remark, ParentID: r.ParentID, Status: r.Status, DataScope: r.DataScope, DeptIDs: r.ScopeDeptIDs()})
 	}
 	return total, list, nil
 }
 
+// roleHierarchy 加载全部角色构建继承关系
+func (s *RBACApplicationService) roleHierarchy(ctx context.Context) (*roleService.RoleHierarchy, error) {
+	roles, err := s.roleRepository.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return roleService.NewRoleHierarchy(roles), nil
+}
+
+// GetRolePerms 分别列出角色自身绑定的权限、从祖先角色继承的权限（来源为最近的祖先）及生效的拒绝规则
+func (s *RBACApplicationService) GetRolePerms(ctx context.Context, roleID uint) (*rbacdto.RolePermsResponse, error) {
+	h, err := s.roleHierarchy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	role := h.Get(roleID)
+	if role == nil {
+		return nil, errorx.NewWithCode(errorx.ErrNotFound)
+	}
+	res := &rbacdto.RolePermsResponse{RoleID: roleID, Own: []string{}, Inherited: []*rbacdto.PermSource{}, Denied: []*rbacdto.PermSource{}}
+	seen := map[string]struct{}{}
+	denied := map[string]struct{}{}
+	chain := []*roleEntity.Role{role}
+	for _, a := range h.Ancestors(roleID) {
+		if a.Status != 0 {
+			break
+		}
+		chain = append(chain, a)
+	}
+	for i, r := range chain {
+		menus, err := s.rbacRepository.GetRolesMenus(ctx, []uint{r.ID})
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range menus {
+			if _, ok := seen[m.Perms]; m.Perms == "" || ok {
+				continue
+			}
+			seen[m.Perms] = struct{}{}
+			if i == 0 {
+				res.Own = append(res.Own, m.Perms)
+			} else {
+				res.Inherited = append(res.Inherited, &rbacdto.PermSource{Perm: m.Perms, RoleID: r.ID, RoleName: r.Name})
+			}
+		}
+		denyMenus, err := s.rbacRepository.GetRolesDenyMenus(ctx, []uint{r.ID})
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range denyMenus {
+			if _, ok := denied[m.Perms]; m.Perms == "" || ok {
+				continue
+			}
+			denied[m.Perms] = struct{}{}
+			res.Denied = append(res.Denied, &rbacdto.PermSource{Perm: m.Perms, RoleID: r.ID, RoleName: r.Name})
+		}
+	}
+	sort.Strings(res.Own)
+	return res, nil
+}
+
 // 菜单管理
+// CreateOrUpdateMenu 权限标识可使用通配段，如 user:*、*:list、*
 func (s *RBACApplicationService) CreateOrUpdateMenu(ctx context.Context, req *rbacdto.MenuCreateOrUpdateRequest) error {
+	if req.Perms != "" && !permissions.ValidPattern(req.Perms) {
+		return errorx.New(errorx.ErrInvalidParam, "invalid perms: "+req.Perms)
+	}
 	if req.ID == 0 {
 		if err := s.menuRepository.Create(ctx, &menuEntity.Menu{Name: req.Name, ParentID: req.ParentID, OrderNum: req.OrderNum, Path: req.Path, Component: req.Component, Query: req.Query, IsFrame: req.IsFrame, MenuType: req.MenuType, IsCatch: req.IsCatch, IsHidden: req.IsHidden, Perms: req.Perms, Icon: req.Icon, Status: req.Status, Remark: req.Remark}); err != nil {
 			return err
@@ -184,6 +597,7 @@ func (s *RBACApplicationService) CreateOrUpdateMenu(ctx context.Context, req *rb
 	if err != nil {
 		return err
 	}
+	permsChanged := menu.Status != req.Status || menu.Perms != req.Perms
 	menu.Name = req.Name
 	menu.ParentID = req.ParentID
 	menu.OrderNum = req.OrderNum
@@ -201,6 +615,9 @@ func (s *RBACApplicationService) CreateOrUpdateMenu(ctx context.Context, req *rb
 	if err := s.menuRepository.Update(ctx, menu); err != nil {
 		return err
 	}
+	if permsChanged {
+		s.invalidateMenuUsers(ctx, menu.ID)
+	}
 	logger.Info("audit:update_menu", "id", menu.ID)
 	return nil
 }
@@ -216,6 +633,7 @@ func (s *RBACApplicationService) DeleteMenu(ctx context.Context, id uint) error
 	if err := s.menuRepository.Delete(ctx, id); err != nil {
 		return err
 	}
+	s.invalidateMenuUsers(ctx, id)
 	logger.Info("audit:delete_menu", "id", id)
 	return nil
 }
@@ -246,15 +664,17 @@ func (s *RBACApplicationService) MenuTree(ctx context.Context) ([]*rbacdto.MenuT
 	if err != nil {
 		return nil, err
 	}
-	return buildMenuTree(menus, 0), nil
+	return buildMenuTree(menus, 0, nil), nil
 }
 
-func buildMenuTree(menus []*menuEntity.Menu, parentID uint) []*rbacdto.MenuTreeNode {
+// buildMenuTree denied 中的菜单标记为被拒绝
+func buildMenuTree(menus []*menuEntity.Menu, parentID uint, denied map[uint]struct{}) []*rbacdto.MenuTreeNode {
 	var result []*rbacdto.MenuTreeNode
 	for _, m := range menus {
 		if m.ParentID == parentID {
-			node := &rbacdto.MenuTreeNode{ID: m.ID, Name: m.Name, ParentID: m.ParentID, Path: m.Path, Component: m.Component, Icon: m.Icon}
-			node.Children = buildMenuTree(menus, m.ID)
+			_, isDenied := denied[m.ID]
+			node := &rbacdto.MenuTreeNode{ID: m.ID, Name: m.Name, ParentID: m.ParentID, Path: m.Path, Component: m.Component, Icon: m.Icon, Denied: isDenied}
+			node.Children = buildMenuTree(menus, m.ID, denied)
 			result = append(result, node)
 		}
 	}
@@ -262,13 +682,39 @@ func buildMenuTree(menus []*menuEntity.Menu, parentID uint) []*rbacdto.MenuTreeN
 }
 
 // 绑定解绑
-func (s *RBACApplicationService) BindUserRoles(ctx context.Context, userID uint, roleIDs []uint) (added, skipped int, err error) {
-	added, skipped, err = s.rbacRepository.BindUserRoles(ctx, userID, roleIDs)
+// BindUserRoles validFrom / validUntil 为可选的有效期，为空表示不限；已绑定的角色按新的有效期更新（管理员显式绑定）
+func (s *RBACApplicationService) BindUserRoles(ctx context.Context, userID uint, roleIDs []uint, validFrom, validUntil *time.Time) (added, skipped int, err error) {
+	return s.bindUserRoles(ctx, userID, roleIDs, validFrom, validUntil, false)
+}
+
+// GrantUserRoles 供目录 / 外部身份同步与申请审批使用：已绑定的角色只放宽有效期，不会缩短管理员设置的绑定
+func (s *RBACApplicationService) GrantUserRoles(ctx context.Context, userID uint, roleIDs []uint, validFrom, validUntil *time.Time) (added, skipped int, err error) {
+	return s.bindUserRoles(ctx, userID, roleIDs, validFrom, validUntil, true)
+}
+
+func (s *RBACApplicationService) bindUserRoles(ctx context.Context, userID uint, roleIDs []uint, validFrom, validUntil *time.Time, widen bool) (added, skipped int, err error) {
+	if validUntil != nil && (!validUntil.After(time.Now()) || (validFrom != nil && !validUntil.After(*validFrom))) {
+		err = errorx.New(errorx.ErrInvalidParam, "validUntil must be in the future and after validFrom")
+		return
+	}
+	// 职责分离校验与绑定在同一事务中持有该用户的锁，多实例下并发请求也不会各自通过校验后共同造成违规
+	err = s.rbacRepository.LockSoD(ctx, userID, func(ctx context.Context) error {
+		if err := s.checkSoD(ctx, userID, roleIDs); err != nil {
+			return err
+		}
+		var err error
+		if widen {
+			added, skipped, err = s.rbacRepository.GrantUserRoles(ctx, userID, roleIDs, validFrom, validUntil)
+		} else {
+			added, skipped, err = s.rbacRepository.BindUserRoles(ctx, userID, roleIDs, validFrom, validUntil)
+		}
+		return err
+	})
 	if err != nil {
 		return
 	}
 	s.invalidatePermCache([]uint{userID})
-	logger.Info("audit:bind_user_roles", "userId", userID, "roleIds", roleIDs, "added", added, "skipped", skipped)
+	logger.Info("audit:bind_user_roles", "userId", userID, "roleIds", roleIDs, "validFrom", validFrom, "validUntil", validUntil, "widen", widen, "added", added, "skipped", skipped)
 	return
 }
 func (s *RBACApplicationService) UnbindUserRoles(ctx context.Context, userID uint, roleIDs []uint) error {
@@ -279,26 +725,310 @@ func (s *RBACApplicationService) UnbindUserRoles(ctx context.Context, userID uin
 	logger.Info("audit:unbind_user_roles", "userId", userID, "roleIds", roleIDs)
 	return nil
 }
-func (s *RBACApplicationService) BindRoleMenus(ctx context.Context, roleID uint, menuIDs []uint) (added, skipped int, err error) {
-	added, skipped, err = s.rbacRepository.BindRoleMenus(ctx, roleID, menuIDs)
+
+// checkSoD 绑定 roleIDs 后新增或加重的职责分离违规返回 ErrRoleConflict（Data 为违规列表）
+// 约束添加前已存在的违规不影响无关角色的绑定，由 SoDReport 列出
+func (s *RBACApplicationService) checkSoD(ctx context.Context, userID uint, roleIDs []uint) error {
+	constraints, err := s.rbacRepository.ListRoleConstraints(ctx)
+	if err != nil || len(constraints) == 0 {
+		return err
+	}
+	bindings, err := s.rbacRepository.GetUserRoleBindings(ctx, userID)
+	if err != nil {
+		return err
+	}
+	h, err := s.roleHierarchy(ctx)
+	if err != nil {
+		return err
+	}
+	held := make([]uint, 0, len(bindings)+len(roleIDs))
+	for _, b := range bindings {
+		held = append(held, b.RoleID)
+	}
+	conflicts, descs := sodIncrease(constraints, h, held, h, append(held, roleIDs...))
+	if len(conflicts) == 0 {
+		return nil
+	}
+	logger.Warn("sod_violation_rejected", "userId", userID, "roleIds", roleIDs, "conflicts", descs)
+	return errorx.New(errorx.ErrRoleConflict, "role assignment violates separation of duties: "+strings.Join(descs, "; "), conflicts)
+}
+
+// checkReparentSoD 将角色的父角色改为 parentID 前校验：约束内的角色不能因此互相继承，
+// 拥有该角色或其后代角色的用户也不能因继承链变化新增或加重违规
+func (s *RBACApplicationService) checkReparentSoD(ctx context.Context, roleID, parentID uint) error {
+	constraints, err := s.rbacRepository.ListRoleConstraints(ctx)
+	if err != nil || len(constraints) == 0 {
+		return err
+	}
+	h, err := s.roleHierarchy(ctx)
+	if err != nil {
+		return err
+	}
+	next := h.WithParent(roleID, parentID)
+	for _, c := range constraints {
+		if id, other, ok := inheritedPair(next, c.RoleIDList()); ok {
+			return errorx.New(errorx.ErrRoleConflict, fmt.Sprintf("role %d would inherit role %d in constraint %s", id, other, c.Name))
+		}
+	}
+	bindings, err := s.rbacRepository.GetUserRolesByRoles(ctx, h.Descendants(roleID))
+	if err != nil {
+		return err
+	}
+	userIDs := make([]uint, 0, len(bindings))
+	seen := map[uint]struct{}{}
+	for _, b := range bindings {
+		if _, ok := seen[b.UserID]; !ok {
+			seen[b.UserID] = struct{}{}
+			userIDs = append(userIDs, b.UserID)
+		}
+	}
+	sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+	for _, userID := range userIDs {
+		all, err := s.rbacRepository.GetUserRoleBindings(ctx, userID)
+		if err != nil {
+			return err
+		}
+		held := make([]uint, 0, len(all))
+		for _, b := range all {
+			held = append(held, b.RoleID)
+		}
+		if conflicts, descs := sodIncrease(constraints, h, held, next, held); len(conflicts) > 0 {
+			logger.Warn("sod_violation_rejected", "userId", userID, "roleId", roleID, "parentId", parentID, "conflicts", descs)
+			return errorx.New(errorx.ErrRoleConflict, fmt.Sprintf("role hierarchy change violates separation of duties for user %d: %s", userID, strings.Join(descs, "; ")), conflicts)
+		}
+	}
+	return nil
+}
+
+// sodIncrease 返回由 (h, held) 变为 (next, nextHeld) 后新增或加重的违规及其描述
+func sodIncrease(constraints []*rbacEntity.RoleConstraint, h *roleService.RoleHierarchy, held []uint, next *roleService.RoleHierarchy, nextHeld []uint) ([]*rbacdto.SoDViolation, []string) {
+	before := map[uint]int{}
+	for _, v := range sodViolations(constraints, h, held) {
+		before[v.ConstraintID] = len(v.RoleIDs)
+	}
+	var conflicts []*rbacdto.SoDViolation
+	var descs []string
+	for _, v := range sodViolations(constraints, next, nextHeld) {
+		if len(v.RoleIDs) > before[v.ConstraintID] {
+			conflicts = append(conflicts, v)
+			descs = append(descs, fmt.Sprintf("%s allows at most %d of [%s]", v.ConstraintName, v.MaxRoles, strings.Join(v.RoleNames, ", ")))
+		}
+	}
+	return conflicts, descs
+}
+
+// inheritedPair 返回 ids 中继承了另一角色的一对角色（id 继承 other）
+func inheritedPair(h *roleService.RoleHierarchy, ids []uint) (id, other uint, ok bool) {
+	for _, id := range ids {
+		for _, a := range h.Ancestors(id) {
+			for _, other := range ids {
+				if a.ID == other {
+					return id, other, true
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// sodViolations held 为用户直接绑定的角色，经子角色继承的祖先角色同样计入；已删除的角色不计入
+func sodViolations(constraints []*rbacEntity.RoleConstraint, h *roleService.RoleHierarchy, held []uint) []*rbacdto.SoDViolation {
+	implied := map[uint]struct{}{}
+	for _, id := range held {
+		if h.Get(id) == nil {
+			continue
+		}
+		implied[id] = struct{}{}
+		for _, a := range h.Ancestors(id) {
+			implied[a.ID] = struct{}{}
+		}
+	}
+	var res []*rbacdto.SoDViolation
+	for _, c := range constraints {
+		v := &rbacdto.SoDViolation{ConstraintID: c.ID, ConstraintName: c.Name, MaxRoles: c.MaxRoles}
+		for _, id := range c.RoleIDList() {
+			if _, ok := implied[id]; ok {
+				v.RoleIDs = append(v.RoleIDs, id)
+				v.RoleNames = append(v.RoleNames, h.Get(id).Name)
+			}
+		}
+		if len(v.RoleIDs) > c.MaxRoles {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// SaveRoleConstraint 新增或更新职责分离约束；约束内的角色不能互为祖先（否则拥有子角色即违规）
+func (s *RBACApplicationService) SaveRoleConstraint(ctx context.Context, req *rbacdto.RoleConstraintRequest) error {
+	c := &rbacEntity.RoleConstraint{}
+	if req.ID != 0 {
+		existing, err := s.rbacRepository.GetRoleConstraint(ctx, req.ID)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			return errorx.New(errorx.ErrNotFound, "constraint not found")
+		}
+		c = existing
+	}
+	c.Name = req.Name
+	c.SetRoleIDs(req.RoleIDs)
+	c.MaxRoles = req.MaxRoles
+	if c.MaxRoles == 0 {
+		c.MaxRoles = 1
+	}
+	ids := c.RoleIDList()
+	if len(ids) < 2 {
+		return errorx.New(errorx.ErrInvalidParam, "constraint needs at least two roles")
+	}
+	if c.MaxRoles >= len(ids) {
+		return errorx.New(errorx.ErrInvalidParam, "maxRoles must be less than the number of roles")
+	}
+	err := s.rbacRepository.LockSoD(ctx, 0, func(ctx context.Context) error {
+		h, err := s.roleHierarchy(ctx)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if h.Get(id) == nil {
+				return errorx.New(errorx.ErrInvalidParam, fmt.Sprintf("role %d not found", id))
+			}
+		}
+		if id, other, ok := inheritedPair(h, ids); ok {
+			return errorx.New(errorx.ErrInvalidParam, fmt.Sprintf("role %d inherits role %d in the same constraint", id, other))
+		}
+		return s.rbacRepository.SaveRoleConstraint(ctx, c)
+	})
+	if err != nil {
+		return err
+	}
+	logger.Info("audit:save_role_constraint", "id", c.ID, "name", c.Name, "roleIds", ids, "maxRoles", c.MaxRoles)
+	return nil
+}
+
+func (s *RBACApplicationService) DeleteRoleConstraint(ctx context.Context, id uint) error {
+	if err := s.rbacRepository.DeleteRoleConstraint(ctx, id); err != nil {
+		return err
+	}
+	logger.Info("audit:delete_role_constraint", "id", id)
+	return nil
+}
+
+func (s *RBACApplicationService) ListRoleConstraints(ctx context.Context) ([]*rbacdto.RoleConstraintItem, error) {
+	list, err := s.rbacRepository.ListRoleConstraints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*rbacdto.RoleConstraintItem, 0, len(list))
+	for _, c := range list {
+		res = append(res, &rbacdto.RoleConstraintItem{ID: c.ID, Name: c.Name, RoleIDs: c.RoleIDList(), MaxRoles: c.MaxRoles, CreatedAt: c.CreatedAt})
+	}
+	return res, nil
+}
+
+// SoDReport 列出当前违反职责分离约束的用户（如约束添加前已存在的绑定），含尚未生效的限时绑定
+func (s *RBACApplicationService) SoDReport(ctx context.Context) ([]*rbacdto.SoDReportItem, error) {
+	constraints, err := s.rbacRepository.ListRoleConstraints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := []*rbacdto.SoDReportItem{}
+	if len(constraints) == 0 {
+		return res, nil
+	}
+	h, err := s.roleHierarchy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// 拥有约束角色或其后代角色的绑定
+	scope := map[uint]struct{}{}
+	for _, c := range constraints {
+		for _, id := range c.RoleIDList() {
+			for _, d := range h.Descendants(id) {
+				scope[d] = struct{}{}
+			}
+		}
+	}
+	roleIDs := make([]uint, 0, len(scope))
+	for id := range scope {
+		roleIDs = append(roleIDs, id)
+	}
+	bindings, err := s.rbacRepository.GetUserRolesByRoles(ctx, roleIDs)
+	if err != nil {
+		return nil, err
+	}
+	held := map[uint][]uint{}
+	for _, b := range bindings {
+		held[b.UserID] = append(held[b.UserID], b.RoleID)
+	}
+	for userID, ids := range held {
+		violations := sodViolations(constraints, h, ids)
+		if len(violations) == 0 {
+			continue
+		}
+		item := &rbacdto.SoDReportItem{UserID: userID, Violations: violations}
+		if u, err := s.userRepository.GetByID(ctx, userID); err == nil && u != nil {
+			item.Username = u.Username
+		}
+		res = append(res, item)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].UserID < res[j].UserID })
+	return res, nil
+}
+
+// DiffManagedRoles 计算受管角色集合内的变更：绑定 want 中缺少的角色，解绑 managed 中不在 want 的角色
+// 用于按外部组（IdP / LDAP）同步角色，managed 之外的本地角色不受影响
+func DiffManagedRoles(current []*rbacdto.RoleSimple, managed, want map[uint]struct{}) (bind, unbind []uint) {
+	has := make(map[uint]struct{}, len(current))
+	for _, r := range current {
+		has[r.ID] = struct{}{}
+		_, isManaged := managed[r.ID]
+		_, wanted := want[r.ID]
+		if isManaged && !wanted {
+			unbind = append(unbind, r.ID)
+		}
+	}
+	for id := range want {
+		if _, ok := has[id]; !ok {
+			bind = append(bind, id)
+		}
+	}
+	sort.Slice(bind, func(i, j int) bool { return bind[i] < bind[j] })
+	sort.Slice(unbind, func(i, j int) bool { return unbind[i] < unbind[j] })
+	return bind, unbind
+}
+
+// BindRoleMenus deny 为 true 时绑定为拒绝菜单（与授予分开存储），优先于任何授予
+func (s *RBACApplicationService) BindRoleMenus(ctx context.Context, roleID uint, menuIDs []uint, deny bool) (added, skipped int, err error) {
+	if deny {
+		added, skipped, err = s.rbacRepository.BindRoleDenyMenus(ctx, roleID, menuIDs)
+	} else {
+		added, skipped, err = s.rbacRepository.BindRoleMenus(ctx, roleID, menuIDs)
+	}
 	if err != nil {
 		return
 	}
-	userIDs, _ := s.rbacRepository.GetRoleUsers(ctx, roleID)
-	s.invalidatePermCache(userIDs)
-	logger.Info("audit:bind_role_menus", "roleId", roleID, "menuIds", menuIDs, "added", added, "skipped", skipped)
+	s.invalidateRoleUsers(ctx, roleID)
+	logger.Info("audit:bind_role_menus", "roleId", roleID, "menuIds", menuIDs, "deny", deny, "added", added, "skipped", skipped)
 	return
 }
-func (s *RBACApplicationService) UnbindRoleMenus(ctx context.Context, roleID uint, menuIDs []uint) error {
-	if err := s.rbacRepository.UnbindRoleMenus(ctx, roleID, menuIDs); err != nil {
+func (s *RBACApplicationService) UnbindRoleMenus(ctx context.Context, roleID uint, menuIDs []uint, deny bool) error {
+	var err error
+	if deny {
+		err = s.rbacRepository.UnbindRoleDenyMenus(ctx, roleID, menuIDs)
+	} else {
+		err = s.rbacRepository.UnbindRoleMenus(ctx, roleID, menuIDs)
+	}
+	if err != nil {
 		return err
 	}
-	userIDs, _ := s.rbacRepository.GetRoleUsers(ctx, roleID)
-	s.invalidatePermCache(userIDs)
-	logger.Info("audit:unbind_role_menus", "roleId", roleID, "menuIds", menuIDs)
+	s.invalidateRoleUsers(ctx, roleID)
+	logger.Info("audit:unbind_role_menus", "roleId", roleID, "menuIds", menuIDs, "deny", deny)
 	return nil
 }
 
+// GetUserRoles 返回当前处于有效期内的直接绑定角色
 func (s *RBACApplicationService) GetUserRoles(ctx context.Context, userID uint) ([]*rbacdto.RoleSimple, error) {
 	roles, err := s.rbacRepository.GetUserRoles(ctx, userID)
 	if err != nil {
@@ -306,11 +1036,69 @@ func (s *RBACApplicationService) GetUserRoles(ctx context.Context, userID uint)
 	}
 	res := make([]*rbacdto.RoleSimple, 0, len(roles))
 	for _, r := range roles {
-		res = append(res, &rbacdto.RoleSimple{ID: r.ID, Name: r.Name, Remark: r.Remark, Status: r.Status})
+		res = append(res, &rbacdto.RoleSimple{ID: r.ID, Name: r.Name, Remark: r.Remark, ParentID: r.ParentID, Status: r.Status})
+	}
+	return res, nil
+}
+
+// ListUserRoleBindings 返回用户全部直接绑定角色及其有效期（含尚未生效的绑定）
+func (s *RBACApplicationService) ListUserRoleBindings(ctx context.Context, userID uint) ([]*rbacdto.RoleSimple, error) {
+	bindings, err := s.rbacRepository.GetUserRoleBindings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	h, err := s.roleHierarchy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*rbacdto.RoleSimple, 0, len(bindings))
+	for _, b := range bindings {
+		r := h.Get(b.RoleID)
+		if r == nil {
+			continue
+		}
+		res = append(res, &rbacdto.RoleSimple{ID: r.ID, Name: r.Name, Remark: r.Remark, ParentID: r.ParentID, Status: r.Status, ValidFrom: b.ValidFrom, ValidUntil: b.ValidUntil})
 	}
 	return res, nil
 }
 
+// SweepRoleBindings 删除已过期的限时角色绑定，并失效过期或在 (since, now] 内开始生效的绑定所涉用户的权限缓存
+func (s *RBACApplicationService) SweepRoleBindings(ctx context.Context, since, now time.Time) error {
+	expired, err := s.rbacRepository.DeleteExpiredUserRoles(ctx, now)
+	if err != nil {
+		return err
+	}
+	activated, err := s.rbacRepository.GetUsersActivatedBetween(ctx, since, now)
+	if err != nil {
+		return err
+	}
+	if len(expired) > 0 || len(activated) > 0 {
+		s.invalidatePermCache(append(expired, activated...))
+		logger.Info("audit:sweep_role_bindings", "expiredUsers", expired, "activatedUsers", activated)
+	}
+	return nil
+}
+
+// StartRoleSweeper 按 interval 周期清理限时角色绑定，ctx 取消后退出
+func (s *RBACApplicationService) StartRoleSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var since time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := time.Now()
+			if err := s.SweepRoleBindings(ctx, since, now); err != nil {
+				logger.Warn("role_sweep_failed", "error", err)
+				continue
+			}
+			since = now
+		}
+	}
+}
+
 func (s *RBACApplicationService) GetRoleMenus(ctx context.Context, roleID uint) ([]*rbacdto.MenuSimple, error) {
 	menus, err := s.rbacRepository.GetRoleMenus(ctx, roleID)
 	if err != nil {
@@ -323,17 +1111,129 @@ func (s *RBACApplicationService) GetRoleMenus(ctx context.Context, roleID uint)
 	return res, nil
 }
 
+// GetUserMenus 用户菜单树；被拒绝规则收回的菜单标记为 denied
 func (s *RBACApplicationService) GetUserMenus(ctx context.Context, userID uint) ([]*rbacdto.MenuTreeNode, error) {
-	menus, err := s.rbacRepository.GetUserMenus(ctx, userID)
+	user := s.activeUser(ctx, userID)
+	if user == nil {
+		return []*rbacdto.MenuTreeNode{}, nil
+	}
+	menus, denyMenus, err := s.userGrants(ctx, user)
 	if err != nil {
 		return nil, err
 	}
+	// 拒绝规则完整覆盖其权限标识的菜单视为被收回；通配菜单仅部分被拒绝时仍可用
+	denies := permissions.NewPermSet(grantSet(user, menus, denyMenus).Denies())
+	denied := map[uint]struct{}{}
+	for _, m := range denyMenus {
+		denied[m.ID] = struct{}{}
+	}
+	for _, m := range menus {
+		if m.Perms != "" && denies.Has(m.Perms) {
+			denied[m.ID] = struct{}{}
+		}
+	}
 	// 构建树：复用 buildMenuTree 需要全部菜单; 这里简化：先转 slice -> tree (仅包含授权的)
-	return buildMenuTree(convertMenus(menus), 0), nil
+	return buildMenuTree(convertMenus(menus), 0, denied), nil
 }
 
 func convertMenus(ms []*menuEntity.Menu) []*menuEntity.Menu { return ms }
 
+// GetUserDeniedPerms 列出用户因拒绝规则而失去的权限（拒绝规则与其授予有重叠）及其来源角色与菜单
+func (s *RBACApplicationService) GetUserDeniedPerms(ctx context.Context, userID uint) ([]*rbacdto.DeniedPerm, error) {
+	res := []*rbacdto.DeniedPerm{}
+	user := s.activeUser(ctx, userID)
+	if user == nil || user.IsSuperAdmin() {
+		return res, nil
+	}
+	roleIDs, h, err := s.effectiveRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	menus, err := s.rbacRepository.GetRolesMenus(ctx, roleIDs)
+	if err != nil {
+		return nil, err
+	}
+	granted := grantSet(user, menus, nil)
+	for _, id := range roleIDs {
+		denyMenus, err := s.rbacRepository.GetRolesDenyMenus(ctx, []uint{id})
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range denyMenus {
+			if m.Perms != "" && granted.Overlaps(m.Perms) {
+				res = append(res, &rbacdto.DeniedPerm{Perm: m.Perms, MenuID: m.ID, MenuName: m.Name, RoleID: id, RoleName: h.Get(id).Name})
+			}
+		}
+	}
+	return res, nil
+}
+
+// effectiveRoles 用户的生效角色 ID：直接绑定的正常状态角色及其继承的祖先
+func (s *RBACApplicationService) effectiveRoles(ctx context.Context, userID uint) ([]uint, *roleService.RoleHierarchy, error) {
+	roles, err := s.rbacRepository.GetUserRoles(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	h, err := s.roleHierarchy(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := make([]uint, 0, len(roles))
+	for _, r := range roles {
+		ids = append(ids, r.ID)
+	}
+	return h.Effective(ids), h, nil
+}
+
+// userGrants 用户经生效角色获得的正常状态菜单与被拒绝的菜单
+// 超级管理员返回全部正常状态菜单，且不受拒绝规则约束
+func (s *RBACApplicationService) userGrants(ctx context.Context, user *userEntity.User) (menus, denied []*menuEntity.Menu, err error) {
+	if !user.IsSuperAdmin() {
+		roleIDs, _, err := s.effectiveRoles(ctx, user.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if menus, err = s.rbacRepository.GetRolesMenus(ctx, roleIDs); err != nil {
+			return nil, nil, err
+		}
+		denied, err = s.rbacRepository.GetRolesDenyMenus(ctx, roleIDs)
+		return menus, denied, err
+	}
+	all, err := s.menuRepository.ListAll(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	menus = make([]*menuEntity.Menu, 0, len(all))
+	for _, m := range all {
+		if m.Status == 0 {
+			menus = append(menus, m)
+		}
+	}
+	return menus, nil, nil
+}
+
+// grantSet 由授予菜单与拒绝菜单编译权限集合；超级管理员额外拥有全部权限点
+func grantSet(user *userEntity.User, menus, denied []*menuEntity.Menu) *permissions.PermSet {
+	perms := make(map[string]struct{})
+	for _, m := range menus {
+		if m.Perms != "" {
+			perms[m.Perms] = struct{}{}
+		}
+	}
+	if user.IsSuperAdmin() {
+		for _, p := range permissions.AllPerms {
+			perms[p] = struct{}{}
+		}
+	}
+	denies := make(map[string]struct{})
+	for _, m := range denied {
+		if m.Perms != "" {
+			denies[m.Perms] = struct{}{}
+		}
+	}
+	return permissions.NewPermSetWithDenies(perms, denies)
+}
+
 func (s *RBACApplicationService) GetMenuRoles(ctx context.Context, menuID uint) ([]*rbacdto.RoleSimple, error) {
 	roles, err := s.rbacRepository.GetMenuRoles(ctx, menuID)
 	if err != nil {
@@ -341,7 +1241,7 @@ func (s *RBACApplicationService) GetMenuRoles(ctx context.Context, menuID uint)
 	}
 	res := make([]*rbacdto.RoleSimple, 0, len(roles))
 	for _, r := range roles {
-		res = append(res, &rbacdto.RoleSimple{ID: r.ID, Name: r.Name, Remark: r.Remark, Status: r.Status})
+		res = append(res, &rbacdto.RoleSimple{ID: r.ID, Name: r.Name, Remark: r.Remark, ParentID: r.ParentID, Status: r.Status})
 	}
 	return res, nil
 }
@@ -351,44 +1251,117 @@ func (s *RBACApplicationService) GetRoleMenuTree(ctx context.Context, roleID uin
 	if err != nil {
 		return nil, err
 	}
-	return &rbacdto.RoleMenuTreeResponse{MenuIDs: ids}, nil
+	denyMenus, err := s.rbacRepository.GetRolesDenyMenus(ctx, []uint{roleID})
+	if err != nil {
+		return nil, err
+	}
+	deniedIDs := make([]uint, 0, len(denyMenus))
+	for _, m := range denyMenus {
+		deniedIDs = append(deniedIDs, m.ID)
+	}
+	return &rbacdto.RoleMenuTreeResponse{MenuIDs: ids, DeniedMenuIDs: deniedIDs}, nil
 }
 
-// GetUserPerms 返回用户拥有的权限标识集合
+// GetUserPerms 返回用户实际生效的权限标识集合（已扣除拒绝规则）；禁用的用户、角色与菜单不授予任何权限
 func (s *RBACApplicationService) GetUserPerms(ctx context.Context, userID uint) (map[string]struct{}, error) {
+	set, err := s.GetUserPermSet(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return set.Effective(permissions.AllPerms), nil
+}
+
+// GetUserPermSet 返回用户预编译的权限集合（含拒绝规则），权限检查应使用其 Has 以支持通配符与拒绝
+// 编译好的集合缓存在内存；启用 Redis 时只读取版本戳判断其他实例是否已使其失效
+func (s *RBACApplicationService) GetUserPermSet(ctx context.Context, userID uint) (*permissions.PermSet, error) {
 	if userID == 0 { // 未登录或匿名
-		return map[string]struct{}{}, nil
+		return permissions.NewPermSet(map[string]struct{}{}), nil
 	}
-	if s.redisPermCache != nil {
-		if cached, err := s.redisPermCache.Get(ctx, userID); err == nil && cached != nil {
+	// 版本戳须在计算前读取：计算期间发生的失效会更新版本戳，下次检查即可发现
+	version, cacheable := s.permCacheVersion(ctx, userID)
+	if cacheable {
+		if cached := s.permCache.Get(userID, version); cached != nil {
 			return cached, nil
 		}
-	} else if cached := s.permCache.Get(userID); cached != nil {
-		return cached, nil
 	}
-	menus, err := s.rbacRepository.GetUserMenus(ctx, userID)
+	user := s.activeUser(ctx, userID)
+	if user == nil {
+		set := permissions.NewPermSet(map[string]struct{}{})
+		if cacheable {
+			s.permCache.Set(userID, set, version)
+		}
+		return set, nil
+	}
+	menus, denied, err := s.userGrants(ctx, user)
 	if err != nil {
 		return nil, err
 	}
-	perms := make(map[string]struct{})
-	for _, m := range menus {
-		if m.Perms != "" {
-			perms[m.Perms] = struct{}{}
+	set := grantSet(user, menus, denied)
+	// 超级管理员拥有全部权限点，无需绑定菜单；其权限随任意菜单变化，不写缓存
+	if cacheable && !user.IsSuperAdmin() {
+		s.permCache.Set(userID, set, version)
+	}
+	return set, nil
+}
+
+// permCacheVersion 返回用户权限缓存的版本戳；Redis 不可用时无法得知其他实例的失效，不使用缓存
+func (s *RBACApplicationService) permCacheVersion(ctx context.Context, userID uint) (int64, bool) {
+	if s.redisPermCache == nil {
+		return 0, true
+	}
+	version, err := s.redisPermCache.Version(ctx, userID)
+	if err != nil {
+		logger.Warn("perm_version_get_failed", "userId", userID, "error", err)
+		return 0, false
+	}
+	return version, true
+}
+
+// invalidateRoleUsers 角色变化后失效该角色及其全部后代角色用户的权限缓存
+func (s *RBACApplicationService) invalidateRoleUsers(ctx context.Context, roleID uint) {
+	roleIDs := []uint{roleID}
+	if h, err := s.roleHierarchy(ctx); err == nil {
+		roleIDs = h.Descendants(roleID)
+	} else {
+		logger.Warn("perm_cache_invalidate_failed", "roleId", roleID, "error", err)
+	}
+	for _, id := range roleIDs {
+		userIDs, err := s.rbacRepository.GetRoleUsers(ctx, id)
+		if err != nil {
+			logger.Warn("perm_cache_invalidate_failed", "roleId", id, "error", err)
+			continue
 		}
+		s.invalidatePermCache(userIDs)
 	}
-	// 写入缓存（双写策略：内存+redis）
-	s.permCache.Set(userID, perms)
-	if s.redisPermCache != nil {
-		_ = s.redisPermCache.Set(ctx, userID, perms)
+}
+
+// invalidateMenuUsers 菜单变化后失效所有经角色授予或拒绝该菜单的用户的权限缓存
+func (s *RBACApplicationService) invalidateMenuUsers(ctx context.Context, menuID uint) {
+	roles, err := s.rbacRepository.GetMenuRoles(ctx, menuID)
+	if err != nil {
+		logger.Warn("perm_cache_invalidate_failed", "menuId", menuID, "error", err)
+		return
+	}
+	for _, r := range roles {
+		s.invalidateRoleUsers(ctx, r.ID)
+	}
+	denyRoleIDs, err := s.rbacRepository.GetMenuDenyRoles(ctx, menuID)
+	if err != nil {
+		logger.Warn("perm_cache_invalidate_failed", "menuId", menuID, "error", err)
+		return
+	}
+	for _, id := range denyRoleIDs {
+		s.invalidateRoleUsers(ctx, id)
 	}
-	return perms, nil
 }
 
-// invalidatePermCache 统一失效（内存+redis）
+// invalidatePermCache 失效本实例内存缓存，并更新 Redis 版本戳通知其他实例
 func (s *RBACApplicationService) invalidatePermCache(userIDs []uint) {
 	s.permCache.InvalidateUsers(userIDs)
 	if s.redisPermCache != nil {
-		s.redisPermCache.InvalidateUsers(context.Background(), userIDs)
+		if err := s.redisPermCache.InvalidateUsers(context.Background(), userIDs); err != nil {
+			logger.Warn("perm_version_bump_failed", "error", err)
+		}
 	}
 }
 
@@ -2,17 +2,26 @@ package service
 
 import (
 	"context"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	rbacdto "github.com/sine-io/sinx/application/rbac/dto"
+	deptEntity "github.com/sine-io/sinx/domain/dept/entity"
 	menuEntity "github.com/sine-io/sinx/domain/menu/entity"
 	menuRepo "github.com/sine-io/sinx/domain/menu/repository"
+	rbacEntity "github.com/sine-io/sinx/domain/rbac/entity"
 	rbacRepo "github.com/sine-io/sinx/domain/rbac/repository"
 	roleEntity "github.com/sine-io/sinx/domain/role/entity"
 	roleRepo "github.com/sine-io/sinx/domain/role/repository"
 	userEntity "github.com/sine-io/sinx/domain/user/entity"
-	userRepo "github.com/sine-io/sinx/domain/user/repository"
+	"github.com/sine-io/sinx/domain/user/repository/usertest"
 	"github.com/sine-io/sinx/pkg/config"
+	"github.com/sine-io/sinx/pkg/errorx"
 	"github.com/sine-io/sinx/pkg/logger"
+	"github.com/sine-io/sinx/pkg/permissions"
+	"github.com/sine-io/sinx/pkg/utils"
 )
 
 // 简单内存自增ID
@@ -22,56 +31,34 @@ func (g *idGen) nextID() uint { g.next++; return g.next }
 
 // In-memory implementations -------------------------------------------------
 
-type memUserRepo struct {
+type memDeptRepo struct {
 	idg  idGen
-	data map[uint]*userEntity.User
+	data map[uint]*deptEntity.Department
 }
 
-func newMemUserRepo() userRepo.UserRepository { return &memUserRepo{data: map[uint]*userEntity.User{}} }
-func (m *memUserRepo) Create(_ context.Context, u *userEntity.User) error {
-	u.ID = m.idg.nextID()
-	m.data[u.ID] = u
+func (m *memDeptRepo) Create(_ context.Context, d *deptEntity.Department) error {
+	d.ID = m.idg.nextID()
+	m.data[d.ID] = d
 	return nil
 }
-func (m *memUserRepo) GetByID(_ context.Context, id uint) (*userEntity.User, error) {
-	return m.data[id], nil
+func (m *memDeptRepo) Update(_ context.Context, d *deptEntity.Department) error {
+	m.data[d.ID] = d
+	return nil
 }
-func (m *memUserRepo) GetByUsername(_ context.Context, username string) (*userEntity.User, error) {
-	for _, u := range m.data {
-		if u.Username == username {
-			return u, nil
-		}
-	}
-	return &userEntity.User{}, nil
+func (m *memDeptRepo) Delete(_ context.Context, id uint) error { delete(m.data, id); return nil }
+func (m *memDeptRepo) GetByID(_ context.Context, id uint) (*deptEntity.Department, error) {
+	return m.data[id], nil
 }
-func (m *memUserRepo) GetByEmail(_ context.Context, email string) (*userEntity.User, error) {
-	for _, u := range m.data {
-		if u.Email == email {
-			return u, nil
+func (m *memDeptRepo) ListAll(_ context.Context) ([]*deptEntity.Department, error) {
+	res := []*deptEntity.Department{}
+	for i := uint(1); i <= m.idg.next; i++ {
+		if d, ok := m.data[i]; ok {
+			res = append(res, d)
 		}
 	}
-	return &userEntity.User{}, nil
-}
-func (m *memUserRepo) Update(_ context.Context, u *userEntity.User) error {
-	m.data[u.ID] = u
-	return nil
-}
-func (m *memUserRepo) Delete(_ context.Context, id uint) error { delete(m.data, id); return nil }
-func (m *memUserRepo) List(_ context.Context, offset, limit int) ([]*userEntity.User, error) {
-	res := []*userEntity.User{}
-	for _, u := range m.data {
-		res = append(res, u)
-	}
-	if offset > len(res) {
-		return []*userEntity.User{}, nil
-	}
-	end := offset + limit
-	if end > len(res) {
-		end = len(res)
-	}
-	return res[offset:end], nil
+	return res, nil
 }
-func (m *memUserRepo) Count(_ context.Context) (int64, error) { return int64(len(m.data)), nil }
+func (m *memDeptRepo) HasUsers(_ context.Context, id uint) (bool, error) { return false, nil }
 
 type memRoleRepo struct {
 	idg  idGen
@@ -107,6 +94,13 @@ func (m *memRoleRepo) List(_ context.Context, offset, limit int) ([]*roleEntity.
 	return res[offset:end], nil
 }
 func (m *memRoleRepo) Count(_ context.Context) (int64, error) { return int64(len(m.data)), nil }
+func (m *memRoleRepo) ListAll(_ context.Context) ([]*roleEntity.Role, error) {
+	res := []*roleEntity.Role{}
+	for _, r := range m.data {
+		res = append(res, r)
+	}
+	return res, nil
+}
 
 type memMenuRepo struct {
 	idg  idGen
@@ -189,27 +183,48 @@ func indexOf(s, sub string) int {
 }
 
 type memRBACRepo struct {
-	userRoles map[uint]map[uint]struct{}
+	userRoles map[uint]map[uint]*rbacEntity.UserRole
 	roleMenus map[uint]map[uint]struct{}
+	roleDeny  map[uint]map[uint]struct{}
 	roles     map[uint]*roleEntity.Role
 	menus     map[uint]*menuEntity.Menu
+	sod       map[uint]*rbacEntity.RoleConstraint
+	sodIDs    idGen
+	sodMu     sync.Mutex
 }
 
 func newMemRBACRepo(rr *memRoleRepo, mr *memMenuRepo) rbacRepo.RBACRepository {
-	return &memRBACRepo{userRoles: map[uint]map[uint]struct{}{}, roleMenus: map[uint]map[uint]struct{}{}, roles: rr.data, menus: mr.data}
+	return &memRBACRepo{userRoles: map[uint]map[uint]*rbacEntity.UserRole{}, roleMenus: map[uint]map[uint]struct{}{}, roleDeny: map[uint]map[uint]struct{}{}, roles: rr.data, menus: mr.data, sod: map[uint]*rbacEntity.RoleConstraint{}}
 }
 
-func (r *memRBACRepo) BindUserRoles(_ context.Context, userID uint, roleIDs []uint) (int, int, error) {
+func (r *memRBACRepo) BindUserRoles(_ context.Context, userID uint, roleIDs []uint, validFrom, validUntil *time.Time) (int, int, error) {
+	if _, ok := r.userRoles[userID]; !ok {
+		r.userRoles[userID] = map[uint]*rbacEntity.UserRole{}
+	}
+	added, skipped := 0, 0
+	for _, id := range roleIDs {
+		if ur, exists := r.userRoles[userID][id]; exists {
+			ur.ValidFrom, ur.ValidUntil = validFrom, validUntil
+			skipped++
+			continue
+		}
+		r.userRoles[userID][id] = &rbacEntity.UserRole{UserID: userID, RoleID: id, ValidFrom: validFrom, ValidUntil: validUntil}
+		added++
+	}
+	return added, skipped, nil
+}
+func (r *memRBACRepo) GrantUserRoles(_ context.Context, userID uint, roleIDs []uint, validFrom, validUntil *time.Time) (int, int, error) {
 	if _, ok := r.userRoles[userID]; !ok {
-		r.userRoles[userID] = map[uint]struct{}{}
+		r.userRoles[userID] = map[uint]*rbacEntity.UserRole{}
 	}
 	added, skipped := 0, 0
 	for _, id := range roleIDs {
-		if _, exists := r.userRoles[userID][id]; exists {
+		if ur, exists := r.userRoles[userID][id]; exists {
+			ur.Widen(validFrom, validUntil)
 			skipped++
 			continue
 		}
-		r.userRoles[userID][id] = struct{}{}
+		r.userRoles[userID][id] = &rbacEntity.UserRole{UserID: userID, RoleID: id, ValidFrom: validFrom, ValidUntil: validUntil}
 		added++
 	}
 	return added, skipped, nil
@@ -224,13 +239,49 @@ func (r *memRBACRepo) UnbindUserRoles(_ context.Context, userID uint, roleIDs []
 }
 func (r *memRBACRepo) GetUserRoles(_ context.Context, userID uint) ([]*roleEntity.Role, error) {
 	res := []*roleEntity.Role{}
-	for id := range r.userRoles[userID] {
-		if rl, ok := r.roles[id]; ok {
+	now := time.Now()
+	for id, ur := range r.userRoles[userID] {
+		if rl, ok := r.roles[id]; ok && ur.ActiveAt(now) {
 			res = append(res, rl)
 		}
 	}
 	return res, nil
 }
+func (r *memRBACRepo) GetUserRoleBindings(_ context.Context, userID uint) ([]*rbacEntity.UserRole, error) {
+	res := []*rbacEntity.UserRole{}
+	for _, ur := range r.userRoles[userID] {
+		res = append(res, ur)
+	}
+	return res, nil
+}
+func (r *memRBACRepo) DeleteExpiredUserRoles(_ context.Context, now time.Time) ([]uint, error) {
+	res := []uint{}
+	for uid, rids := range r.userRoles {
+		hit := false
+		for rid, ur := range rids {
+			if ur.ValidUntil != nil && !ur.ValidUntil.After(now) {
+				delete(rids, rid)
+				hit = true
+			}
+		}
+		if hit {
+			res = append(res, uid)
+		}
+	}
+	return res, nil
+}
+func (r *memRBACRepo) GetUsersActivatedBetween(_ context.Context, from, to time.Time) ([]uint, error) {
+	res := []uint{}
+	for uid, rids := range r.userRoles {
+		for _, ur := range rids {
+			if ur.ValidFrom != nil && ur.ValidFrom.After(from) && !ur.ValidFrom.After(to) {
+				res = append(res, uid)
+				break
+			}
+		}
+	}
+	return res, nil
+}
 func (r *memRBACRepo) BindRoleMenus(_ context.Context, roleID uint, menuIDs []uint) (int, int, error) {
 	if _, ok := r.roleMenus[roleID]; !ok {
 		r.roleMenus[roleID] = map[uint]struct{}{}
@@ -263,11 +314,16 @@ func (r *memRBACRepo) GetRoleMenus(_ context.Context, roleID uint) ([]*menuEntit
 	}
 	return res, nil
 }
-func (r *memRBACRepo) GetUserMenus(_ context.Context, userID uint) ([]*menuEntity.Menu, error) {
+func (r *memRBACRepo) GetRolesMenus(_ context.Context, roleIDs []uint) ([]*menuEntity.Menu, error) {
 	res := []*menuEntity.Menu{}
-	for rid := range r.userRoles[userID] {
+	seen := map[uint]struct{}{}
+	for _, rid := range roleIDs {
 		for mid := range r.roleMenus[rid] {
-			if m, ok := r.menus[mid]; ok {
+			if _, ok := seen[mid]; ok {
+				continue
+			}
+			if m, ok := r.menus[mid]; ok && m.Status == 0 {
+				seen[mid] = struct{}{}
 				res = append(res, m)
 			}
 		}
@@ -301,6 +357,113 @@ func (r *memRBACRepo) GetMenuIDsByRole(_ context.Context, roleID uint) ([]uint,
 	}
 	return res, nil
 }
+func (r *memRBACRepo) BindRoleDenyMenus(_ context.Context, roleID uint, menuIDs []uint) (int, int, error) {
+	if _, ok := r.roleDeny[roleID]; !ok {
+		r.roleDeny[roleID] = map[uint]struct{}{}
+	}
+	added, skipped := 0, 0
+	for _, id := range menuIDs {
+		if _, ex := r.roleDeny[roleID][id]; ex {
+			skipped++
+			continue
+		}
+		r.roleDeny[roleID][id] = struct{}{}
+		added++
+	}
+	return added, skipped, nil
+}
+func (r *memRBACRepo) UnbindRoleDenyMenus(_ context.Context, roleID uint, menuIDs []uint) error {
+	for _, id := range menuIDs {
+		delete(r.roleDeny[roleID], id)
+	}
+	return nil
+}
+func (r *memRBACRepo) GetRolesDenyMenus(_ context.Context, roleIDs []uint) ([]*menuEntity.Menu, error) {
+	res := []*menuEntity.Menu{}
+	seen := map[uint]struct{}{}
+	for _, rid := range roleIDs {
+		for mid := range r.roleDeny[rid] {
+			if _, ok := seen[mid]; ok {
+				continue
+			}
+			if m, ok := r.menus[mid]; ok {
+				seen[mid] = struct{}{}
+				res = append(res, m)
+			}
+		}
+	}
+	return res, nil
+}
+func (r *memRBACRepo) GetMenuDenyRoles(_ context.Context, menuID uint) ([]uint, error) {
+	res := []uint{}
+	for rid, mids := range r.roleDeny {
+		if _, ok := mids[menuID]; ok {
+			res = append(res, rid)
+		}
+	}
+	return res, nil
+}
+func (r *memRBACRepo) GetUserRolesByRoles(_ context.Context, roleIDs []uint) ([]*rbacEntity.UserRole, error) {
+	res := []*rbacEntity.UserRole{}
+	for _, m := range r.userRoles {
+		for _, rid := range roleIDs {
+			if ur, ok := m[rid]; ok {
+				res = append(res, ur)
+			}
+		}
+	}
+	return res, nil
+}
+func (r *memRBACRepo) SaveRoleConstraint(_ context.Context, c *rbacEntity.RoleConstraint) error {
+	if c.ID == 0 {
+		c.ID = r.sodIDs.nextID()
+	}
+	r.sod[c.ID] = c
+	return nil
+}
+func (r *memRBACRepo) DeleteRoleConstraint(_ context.Context, id uint) error {
+	delete(r.sod, id)
+	return nil
+}
+func (r *memRBACRepo) GetRoleConstraint(_ context.Context, id uint) (*rbacEntity.RoleConstraint, error) {
+	return r.sod[id], nil
+}
+func (r *memRBACRepo) ListRoleConstraints(_ context.Context) ([]*rbacEntity.RoleConstraint, error) {
+	res := []*rbacEntity.RoleConstraint{}
+	for i := uint(1); i <= r.sodIDs.next; i++ {
+		if c, ok := r.sod[i]; ok {
+			res = append(res, c)
+		}
+	}
+	return res, nil
+}
+func (r *memRBACRepo) LockSoD(ctx context.Context, _ uint, fn func(ctx context.Context) error) error {
+	r.sodMu.Lock()
+	defer r.sodMu.Unlock()
+	return fn(ctx)
+}
+
+// memSharedPermCache 替代 Redis 的共享版本戳
+type memSharedPermCache struct {
+	mu   sync.Mutex
+	ver  map[uint]int64
+	next int64
+}
+
+func (c *memSharedPermCache) Version(_ context.Context, userID uint) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ver[userID], nil
+}
+func (c *memSharedPermCache) InvalidateUsers(_ context.Context, userIDs []uint) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.next++
+	for _, id := range userIDs {
+		c.ver[id] = c.next
+	}
+	return nil
+}
 
 // Test ----------------------------------------------------------------------
 func TestBindAndPerms_InMemory(t *testing.T) {
@@ -308,17 +471,17 @@ func TestBindAndPerms_InMemory(t *testing.T) {
 	_ = config.LoadEnv()
 	_ = logger.Init()
 	ctx := context.Background()
-	ur := newMemUserRepo().(*memUserRepo)
+	ur := usertest.NewUserRepo()
 	rr := newMemRoleRepo().(*memRoleRepo)
 	mr := newMemMenuRepo().(*memMenuRepo)
 	rb := newMemRBACRepo(rr, mr)
-	svc := NewRBACApplicationService(ur, rr, mr, rb)
+	svc := NewRBACApplicationService(ur, rr, mr, rb, nil, nil, nil)
 
 	_ = ur.Create(ctx, &userEntity.User{Username: "u1", Password: "p"})                                                        // id=1
-	_ = rr.Create(ctx, &roleEntity.Role{Name: "r1", Status: 1})                                                                // id=1
-	_ = mr.Create(ctx, &menuEntity.Menu{Name: "m1", ParentID: 0, OrderNum: 1, MenuType: "B", Perms: "user:create", Status: 1}) // id=1
-	_, _, _ = svc.BindRoleMenus(ctx, 1, []uint{1})
-	_, _, _ = svc.BindUserRoles(ctx, 1, []uint{1})
+	_ = rr.Create(ctx, &roleEntity.Role{Name: "r1", Status: 0})                                                                // id=1
+	_ = mr.Create(ctx, &menuEntity.Menu{Name: "m1", ParentID: 0, OrderNum: 1, MenuType: "B", Perms: "user:create", Status: 0}) // id=1
+	_, _, _ = svc.BindRoleMenus(ctx, 1, []uint{1}, false)
+	_, _, _ = svc.BindUserRoles(ctx, 1, []uint{1}, nil, nil)
 	perms, err := svc.GetUserPerms(ctx, 1)
 	if err != nil {
 		t.Fatalf("get perms: %v", err)
@@ -332,3 +495,606 @@ func TestBindAndPerms_InMemory(t *testing.T) {
 		t.Fatalf("cache mismatch")
 	}
 }
+
+// 一个实例上的失效更新共享版本戳，另一实例内存中编译好的权限集合随之失效
+func TestPermCacheCrossInstance_InMemory(t *testing.T) {
+	_ = config.LoadEnv()
+	_ = logger.Init()
+	ctx := context.Background()
+	ur := usertest.NewUserRepo()
+	rr := newMemRoleRepo().(*memRoleRepo)
+	mr := newMemMenuRepo().(*memMenuRepo)
+	rb := newMemRBACRepo(rr, mr)
+	shared := &memSharedPermCache{ver: map[uint]int64{}}
+	a := NewRBACApplicationService(ur, rr, mr, rb, nil, nil, nil)
+	b := NewRBACApplicationService(ur, rr, mr, rb, nil, nil, nil)
+	a.redisPermCache, b.redisPermCache = shared, shared
+
+	_ = ur.Create(ctx, &userEntity.User{Username: "u1", Password: "p"})
+	_ = rr.Create(ctx, &roleEntity.Role{Name: "r1"})
+	_ = mr.Create(ctx, &menuEntity.Menu{Name: "m1", MenuType: "B", Perms: "user:create"})
+	_, _, _ = a.BindRoleMenus(ctx, 1, []uint{1}, false)
+	_, _, _ = a.BindUserRoles(ctx, 1, []uint{1}, nil, nil)
+	if set, _ := b.GetUserPermSet(ctx, 1); !set.Has("user:create") {
+		t.Fatal("instance b should see the bound permission")
+	}
+
+	// 绕过服务直接改库：版本戳未变，b 继续使用内存中的集合
+	rb.(*memRBACRepo).userRoles[1] = map[uint]*rbacEntity.UserRole{}
+	if set, _ := b.GetUserPermSet(ctx, 1); !set.Has("user:create") {
+		t.Fatal("instance b should serve the compiled set from memory while the version is unchanged")
+	}
+
+	// 经实例 a 失效后，b 重新计算
+	if err := a.UnbindUserRoles(ctx, 1, []uint{1}); err != nil {
+		t.Fatal(err)
+	}
+	if set, _ := b.GetUserPermSet(ctx, 1); set.Has("user:create") {
+		t.Fatal("invalidation on instance a must drop instance b's cached set")
+	}
+}
+
+func TestSuperAdmin_InMemory(t *testing.T) {
+	_ = config.LoadEnv()
+	_ = logger.Init()
+	ctx := context.Background()
+	ur := usertest.NewUserRepo()
+	rr := newMemRoleRepo().(*memRoleRepo)
+	mr := newMemMenuRepo().(*memMenuRepo)
+	svc := NewRBACApplicationService(ur, rr, mr, newMemRBACRepo(rr, mr), nil, nil, nil)
+
+	_ = ur.Create(ctx, &userEntity.User{Username: "root"})  // id=1
+	_ = ur.Create(ctx, &userEntity.User{Username: "admin"}) // id=2
+	_ = mr.Create(ctx, &menuEntity.Menu{Name: "m1", MenuType: "B", Perms: "report:export"})
+
+	// 没有超级管理员时按用户名初始化
+	if err := svc.BootstrapSuperAdmin(ctx, "root"); err != nil || !ur.Data[1].IsSuperAdmin() {
+		t.Fatalf("bootstrap failed: %v", err)
+	}
+	// 未绑定任何菜单也拥有全部权限与菜单
+	perms, _ := svc.GetUserPerms(ctx, 1)
+	if _, ok := perms["report:export"]; !ok {
+		t.Fatal("super admin should hold menu perms")
+	}
+	if _, ok := perms["user:delete"]; !ok {
+		t.Fatal("super admin should hold all built-in perms")
+	}
+	if menus, _ := svc.GetUserMenus(ctx, 1); len(menus) != 1 {
+		t.Fatalf("super admin menus = %d", len(menus))
+	}
+
+	// 普通管理员不能修改 / 删除超级管理员，也不能自行提权
+	if err := svc.DeleteUser(ctx, 2, 1); err == nil {
+		t.Fatal("non-super admin must not delete a super admin")
+	}
+	superType := userEntity.UserTypeSuperAdmin
+	if err := svc.UpdateUser(ctx, 2, &rbacdto.UserUpdateRequest{ID: 2, UserType: &superType}); err == nil {
+		t.Fatal("non-super admin must not promote users")
+	}
+
+	// 最后一个超级管理员不能被删除或降级
+	if err := svc.DeleteUser(ctx, 1, 1); err == nil {
+		t.Fatal("last super admin must not be deleted")
+	}
+	if err := svc.UpdateUser(ctx, 1, &rbacdto.UserUpdateRequest{ID: 2, UserType: &superType}); err != nil {
+		t.Fatalf("promote: %v", err)
+	}
+	normal := userEntity.UserTypeNormal
+	if err := svc.UpdateUser(ctx, 2, &rbacdto.UserUpdateRequest{ID: 1, UserType: &normal}); err != nil {
+		t.Fatalf("demote with another super admin left: %v", err)
+	}
+	if err := svc.UpdateUser(ctx, 2, &rbacdto.UserUpdateRequest{ID: 2, UserType: &normal}); err == nil {
+		t.Fatal("last super admin must not demote themselves")
+	}
+}
+
+// 禁用角色 / 菜单 / 用户后权限立即失效（缓存随状态变化失效）
+func TestDisabledStatus_InMemory(t *testing.T) {
+	_ = config.LoadEnv()
+	_ = logger.Init()
+	ctx := context.Background()
+	ur := usertest.NewUserRepo()
+	rr := newMemRoleRepo().(*memRoleRepo)
+	mr := newMemMenuRepo().(*memMenuRepo)
+	svc := NewRBACApplicationService(ur, rr, mr, newMemRBACRepo(rr, mr), nil, nil, nil)
+
+	_ = ur.Create(ctx, &userEntity.User{Username: "u1"})
+	_ = rr.Create(ctx, &roleEntity.Role{Name: "r1"})
+	_ = mr.Create(ctx, &menuEntity.Menu{Name: "m1", MenuType: "C", Perms: "user:list"})
+	_, _, _ = svc.BindRoleMenus(ctx, 1, []uint{1}, false)
+	_, _, _ = svc.BindUserRoles(ctx, 1, []uint{1}, nil, nil)
+
+	has := func() bool {
+		perms, err := svc.GetUserPerms(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, ok := perms["user:list"]
+		return ok
+	}
+	if !has() {
+		t.Fatal("expected perm before disabling")
+	}
+
+	_ = svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{ID: 1, Name: "r1", Status: 1})
+	if has() {
+		t.Fatal("disabled role must not grant perms")
+	}
+	_ = svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{ID: 1, Name: "r1", Status: 0})
+	if !has() {
+		t.Fatal("re-enabled role should grant perms again")
+	}
+
+	_ = svc.CreateOrUpdateMenu(ctx, &rbacdto.MenuCreateOrUpdateRequest{ID: 1, Name: "m1", MenuType: "C", Perms: "user:list", Status: 1})
+	if has() {
+		t.Fatal("disabled menu must not grant perms")
+	}
+	if menus, _ := svc.GetUserMenus(ctx, 1); len(menus) != 0 {
+		t.Fatal("disabled menu must not be listed")
+	}
+	_ = svc.CreateOrUpdateMenu(ctx, &rbacdto.MenuCreateOrUpdateRequest{ID: 1, Name: "m1", MenuType: "C", Perms: "user:list", Status: 0})
+	if !has() {
+		t.Fatal("re-enabled menu should grant perms again")
+	}
+
+	disabled := int16(1)
+	_ = svc.UpdateUser(ctx, 0, &rbacdto.UserUpdateRequest{ID: 1, Status: &disabled})
+	if has() {
+		t.Fatal("disabled user must not hold perms")
+	}
+}
+
+// 角色继承祖先角色的菜单；成环的父角色被拒绝；祖先变化时后代角色用户的缓存失效
+func TestRoleHierarchy_InMemory(t *testing.T) {
+	_ = config.LoadEnv()
+	_ = logger.Init()
+	ctx := context.Background()
+	ur := usertest.NewUserRepo()
+	rr := newMemRoleRepo().(*memRoleRepo)
+	mr := newMemMenuRepo().(*memMenuRepo)
+	svc := NewRBACApplicationService(ur, rr, mr, newMemRBACRepo(rr, mr), nil, nil, nil)
+
+	_ = ur.Create(ctx, &userEntity.User{Username: "u1"})
+	_ = svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{Name: "base"})                // id=1
+	_ = svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{Name: "editor", ParentID: 1}) // id=2
+	_ = svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{Name: "chief", ParentID: 2})  // id=3
+	_ = mr.Create(ctx, &menuEntity.Menu{Name: "m1", MenuType: "C", Perms: "user:list"})
+	_ = mr.Create(ctx, &menuEntity.Menu{Name: "m2", MenuType: "C", Perms: "role:list"})
+	_, _, _ = svc.BindRoleMenus(ctx, 1, []uint{1}, false)
+	_, _, _ = svc.BindRoleMenus(ctx, 3, []uint{2}, false)
+	_, _, _ = svc.BindUserRoles(ctx, 1, []uint{3}, nil, nil)
+
+	has := func(p string) bool {
+		perms, err := svc.GetUserPerms(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, ok := perms[p]
+		return ok
+	}
+	if !has("user:list") || !has("role:list") {
+		t.Fatal("expected own and inherited perms")
+	}
+	rp, err := svc.GetRolePerms(ctx, 3)
+	if err != nil || len(rp.Own) != 1 || rp.Own[0] != "role:list" || len(rp.Inherited) != 1 || rp.Inherited[0].RoleID != 1 {
+		t.Fatalf("unexpected role perms: %+v %v", rp, err)
+	}
+
+	// 自身、后代作为父角色都会成环
+	if err := svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{ID: 1, Name: "base", ParentID: 3}); err == nil {
+		t.Fatal("cycle must be rejected")
+	}
+	if err := svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{ID: 2, Name: "editor", ParentID: 2}); err == nil {
+		t.Fatal("self parent must be rejected")
+	}
+	if err := svc.DeleteRole(ctx, 1); err == nil {
+		t.Fatal("role with children must not be deleted")
+	}
+
+	// 祖先菜单变化与禁用都传递到后代角色的用户
+	_ = svc.UnbindRoleMenus(ctx, 1, []uint{1}, false)
+	if has("user:list") {
+		t.Fatal("unbinding an ancestor menu must reach descendant users")
+	}
+	_, _, _ = svc.BindRoleMenus(ctx, 1, []uint{1}, false)
+	if !has("user:list") {
+		t.Fatal("binding an ancestor menu must reach descendant users")
+	}
+	_ = svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{ID: 2, Name: "editor", ParentID: 1, Status: 1})
+	if has("user:list") || !has("role:list") {
+		t.Fatal("a disabled ancestor must stop inheritance")
+	}
+}
+
+// 拒绝规则优先于授予（含通配授予与继承），变化后缓存随即失效，并能列出失去的权限
+func TestDenyRules_InMemory(t *testing.T) {
+	_ = config.LoadEnv()
+	_ = logger.Init()
+	ctx := context.Background()
+	ur := usertest.NewUserRepo()
+	rr := newMemRoleRepo().(*memRoleRepo)
+	mr := newMemMenuRepo().(*memMenuRepo)
+	svc := NewRBACApplicationService(ur, rr, mr, newMemRBACRepo(rr, mr), nil, nil, nil)
+
+	_ = ur.Create(ctx, &userEntity.User{Username: "auditor"})
+	_ = svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{Name: "auditor"})             // id=1
+	_ = svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{Name: "junior", ParentID: 1}) // id=2
+	_ = mr.Create(ctx, &menuEntity.Menu{Name: "all users", MenuType: "C", Perms: "user:*"})          // id=1
+	_ = mr.Create(ctx, &menuEntity.Menu{Name: "delete user", MenuType: "B", Perms: "user:delete"})   // id=2
+	_, _, _ = svc.BindRoleMenus(ctx, 1, []uint{1}, false)
+	_, _, _ = svc.BindUserRoles(ctx, 1, []uint{2}, nil, nil)
+
+	set := func() *permissions.PermSet {
+		s, err := svc.GetUserPermSet(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	if !set().Has("user:delete") {
+		t.Fatal("expected inherited wildcard grant")
+	}
+
+	// 祖先角色上的拒绝同样收回后代角色用户的权限
+	_, _, _ = svc.BindRoleMenus(ctx, 1, []uint{2}, true)
+	if s := set(); s.Has("user:delete") || !s.Has("user:list") {
+		t.Fatal("deny must override the wildcard grant only for user:delete")
+	}
+	perms, _ := svc.GetUserPerms(ctx, 1)
+	if _, ok := perms["user:delete"]; ok {
+		t.Fatal("effective perms must not list denied perms")
+	}
+	if _, ok := perms["user:list"]; !ok {
+		t.Fatal("effective perms should expand the rest of the wildcard")
+	}
+	lost, _ := svc.GetUserDeniedPerms(ctx, 1)
+	if len(lost) != 1 || lost[0].Perm != "user:delete" || lost[0].RoleID != 1 {
+		t.Fatalf("unexpected denied perms: %+v", lost)
+	}
+	if menus, _ := svc.GetUserMenus(ctx, 1); len(menus) != 1 || menus[0].Denied {
+		t.Fatal("the granted wildcard menu stays visible")
+	}
+	_, _, _ = svc.BindRoleMenus(ctx, 1, []uint{2}, false)
+	if menus, _ := svc.GetUserMenus(ctx, 1); len(menus) != 2 || !menus[0].Denied && !menus[1].Denied {
+		t.Fatal("a granted menu whose perm is denied must be flagged")
+	}
+	_ = svc.UnbindRoleMenus(ctx, 1, []uint{2}, false)
+
+	_ = svc.UnbindRoleMenus(ctx, 1, []uint{2}, true)
+	if !set().Has("user:delete") {
+		t.Fatal("removing the deny must restore the grant")
+	}
+}
+
+// 限时角色只在有效期内生效；清理任务删除过期绑定，并在绑定过期或开始生效时失效缓存
+func TestTimeBoundRoles_InMemory(t *testing.T) {
+	_ = config.LoadEnv()
+	_ = logger.Init()
+	ctx := context.Background()
+	ur := usertest.NewUserRepo()
+	rr := newMemRoleRepo().(*memRoleRepo)
+	mr := newMemMenuRepo().(*memMenuRepo)
+	repo := newMemRBACRepo(rr, mr).(*memRBACRepo)
+	svc := NewRBACApplicationService(ur, rr, mr, repo, nil, nil, nil)
+
+	_ = ur.Create(ctx, &userEntity.User{Username: "oncall"})
+	_ = svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{Name: "oncall"})    // id=1
+	_ = mr.Create(ctx, &menuEntity.Menu{Name: "users", MenuType: "C", Perms: "user:list"}) // id=1
+	_, _, _ = svc.BindRoleMenus(ctx, 1, []uint{1}, false)
+
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	if _, _, err := svc.BindUserRoles(ctx, 1, []uint{1}, &future, &past); err == nil {
+		t.Fatal("validUntil before validFrom must be rejected")
+	}
+	if _, _, err := svc.BindUserRoles(ctx, 1, []uint{1}, nil, &past); err == nil {
+		t.Fatal("already expired binding must be rejected")
+	}
+	has := func() bool {
+		s, err := svc.GetUserPermSet(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s.Has("user:list")
+	}
+
+	// 尚未生效：不授予权限，但管理端能看到该绑定
+	_, _, _ = svc.BindUserRoles(ctx, 1, []uint{1}, &future, nil)
+	if has() {
+		t.Fatal("binding must not apply before validFrom")
+	}
+	if list, _ := svc.ListUserRoleBindings(ctx, 1); len(list) != 1 || list[0].ValidFrom == nil {
+		t.Fatalf("unexpected bindings: %+v", list)
+	}
+	if active, _ := svc.GetUserRoles(ctx, 1); len(active) != 0 {
+		t.Fatal("pending binding must not be listed as active")
+	}
+
+	// 模拟时间推移到生效时间之后：清理任务发现新生效的绑定并失效缓存
+	activeAt := now.Add(-time.Minute)
+	repo.userRoles[1][1].ValidFrom = &activeAt
+	if has() {
+		t.Fatal("cached perms are expected before the sweep")
+	}
+	if err := svc.SweepRoleBindings(ctx, now.Add(-2*time.Minute), now); err != nil {
+		t.Fatal(err)
+	}
+	if !has() {
+		t.Fatal("binding should apply once active")
+	}
+
+	// 模拟到期：清理任务删除绑定并失效缓存
+	repo.userRoles[1][1].ValidUntil = &past
+	if err := svc.SweepRoleBindings(ctx, now, now); err != nil {
+		t.Fatal(err)
+	}
+	if has() {
+		t.Fatal("expired binding must no longer grant perms")
+	}
+	if list, _ := svc.ListUserRoleBindings(ctx, 1); len(list) != 0 {
+		t.Fatal("expired binding should be deleted")
+	}
+
+	// 同步 / 审批授予只放宽有效期：永久绑定不会被限时授予缩短，限时绑定可被延长
+	later := now.Add(2 * time.Hour)
+	_, _, _ = svc.BindUserRoles(ctx, 1, []uint{1}, nil, nil)
+	if _, skipped, err := svc.GrantUserRoles(ctx, 1, []uint{1}, nil, &future); err != nil || skipped != 1 {
+		t.Fatalf("grant: %v skipped=%d", err, skipped)
+	}
+	if b := repo.userRoles[1][1]; b.ValidFrom != nil || b.ValidUntil != nil {
+		t.Fatalf("permanent binding must stay permanent: %+v", b)
+	}
+	_, _, _ = svc.BindUserRoles(ctx, 1, []uint{1}, nil, &future)
+	_, _, _ = svc.GrantUserRoles(ctx, 1, []uint{1}, nil, &later)
+	_, _, _ = svc.GrantUserRoles(ctx, 1, []uint{1}, nil, &future)
+	if b := repo.userRoles[1][1]; b.ValidUntil == nil || !b.ValidUntil.Equal(later) {
+		t.Fatalf("grant should extend to the later expiry only: %+v", b)
+	}
+	_, _, _ = svc.GrantUserRoles(ctx, 1, []uint{1}, nil, nil)
+	if b := repo.userRoles[1][1]; b.ValidUntil != nil {
+		t.Fatalf("system grants are permanent: %+v", b)
+	}
+}
+
+// 职责分离：互斥与基数约束拒绝冲突绑定（含继承得到的角色），报告列出约束添加前已存在的违规
+func TestSoDConstraints_InMemory(t *testing.T) {
+	_ = config.LoadEnv()
+	_ = logger.Init()
+	ctx := context.Background()
+	ur := usertest.NewUserRepo()
+	rr := newMemRoleRepo().(*memRoleRepo)
+	mr := newMemMenuRepo().(*memMenuRepo)
+	svc := NewRBACApplicationService(ur, rr, mr, newMemRBACRepo(rr, mr), nil, nil, nil)
+
+	_ = ur.Create(ctx, &userEntity.User{Username: "alice"})                                        // id=1
+	_ = ur.Create(ctx, &userEntity.User{Username: "bob"})                                          // id=2
+	_ = svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{Name: "requester"})         // id=1
+	_ = svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{Name: "approver"})          // id=2
+	_ = svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{Name: "lead", ParentID: 2}) // id=3
+	_ = svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{Name: "auditor"})           // id=4
+	_ = svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{Name: "operator"})          // id=5
+	_ = svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{Name: "deployer"})          // id=6
+
+	// 约束添加前已存在的冲突绑定
+	_, _, _ = svc.BindUserRoles(ctx, 2, []uint{1, 2}, nil, nil)
+
+	if err := svc.SaveRoleConstraint(ctx, &rbacdto.RoleConstraintRequest{Name: "bad", RoleIDs: []uint{2, 3}}); err == nil {
+		t.Fatal("roles inheriting each other must be rejected")
+	}
+	if err := svc.SaveRoleConstraint(ctx, &rbacdto.RoleConstraintRequest{Name: "bad", RoleIDs: []uint{4, 5}, MaxRoles: 2}); err == nil {
+		t.Fatal("maxRoles must be less than the number of roles")
+	}
+	if err := svc.SaveRoleConstraint(ctx, &rbacdto.RoleConstraintRequest{Name: "maker-checker", RoleIDs: []uint{1, 2}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.SaveRoleConstraint(ctx, &rbacdto.RoleConstraintRequest{Name: "ops", RoleIDs: []uint{4, 5, 6}, MaxRoles: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 子角色 lead 继承 approver，与 requester 冲突
+	_, _, _ = svc.BindUserRoles(ctx, 1, []uint{1}, nil, nil)
+	_, _, err := svc.BindUserRoles(ctx, 1, []uint{3}, nil, nil)
+	appErr, ok := err.(*errorx.Error)
+	if !ok || appErr.Code != errorx.ErrRoleConflict || !contains(appErr.Message, "maker-checker") {
+		t.Fatalf("expected a role conflict naming the constraint, got %v", err)
+	}
+	if roles, _ := svc.GetUserRoles(ctx, 1); len(roles) != 1 {
+		t.Fatal("rejected roles must not be bound")
+	}
+
+	// 基数约束：三选二
+	if _, _, err := svc.BindUserRoles(ctx, 1, []uint{4, 5}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := svc.BindUserRoles(ctx, 1, []uint{6}, nil, nil); err == nil {
+		t.Fatal("exceeding maxRoles must be rejected")
+	}
+
+	// 已有违规不影响无关角色的绑定，但会出现在报告中
+	if _, _, err := svc.BindUserRoles(ctx, 2, []uint{4}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	report, err := svc.SoDReport(ctx)
+	if err != nil || len(report) != 1 || report[0].UserID != 2 || report[0].Username != "bob" || len(report[0].Violations) != 1 {
+		t.Fatalf("unexpected report: %+v %v", report, err)
+	}
+	if v := report[0].Violations[0]; v.ConstraintName != "maker-checker" || len(v.RoleNames) != 2 {
+		t.Fatalf("unexpected violation: %+v", v)
+	}
+
+	// 变更父角色：alice 持有 requester 与 auditor，auditor 改为继承 approver 将新增违规
+	err = svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{ID: 4, Name: "auditor", ParentID: 2})
+	if appErr, ok := err.(*errorx.Error); !ok || appErr.Code != errorx.ErrRoleConflict || !contains(appErr.Message, "maker-checker") {
+		t.Fatalf("re-parenting into a conflict must be rejected, got %v", err)
+	}
+	// 约束内的角色不能因变更父角色而互相继承
+	err = svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{ID: 5, Name: "operator", ParentID: 4})
+	if appErr, ok := err.(*errorx.Error); !ok || appErr.Code != errorx.ErrRoleConflict || !contains(appErr.Message, "ops") {
+		t.Fatalf("re-parenting inside a constraint must be rejected, got %v", err)
+	}
+	if r, _ := rr.GetByID(ctx, 4); r.ParentID != 0 {
+		t.Fatal("rejected re-parenting must not be saved")
+	}
+	if err := svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{ID: 6, Name: "deployer", ParentID: 1}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 数据范围：按角色汇总可见部门，用户列表只返回范围内的用户
+func TestDataScope_InMemory(t *testing.T) {
+	_ = config.LoadEnv()
+	_ = logger.Init()
+	ctx := context.Background()
+	ur := usertest.NewUserRepo()
+	rr := newMemRoleRepo().(*memRoleRepo)
+	mr := newMemMenuRepo().(*memMenuRepo)
+	dr := &memDeptRepo{data: map[uint]*deptEntity.Department{}}
+	svc := NewRBACApplicationService(ur, rr, mr, newMemRBACRepo(rr, mr), dr, nil, nil)
+
+	_ = dr.Create(ctx, &deptEntity.Department{Name: "hq"})                                          // id=1
+	_ = dr.Create(ctx, &deptEntity.Department{Name: "sales", ParentID: 1})                          // id=2
+	_ = dr.Create(ctx, &deptEntity.Department{Name: "east", ParentID: 2})                           // id=3
+	_ = dr.Create(ctx, &deptEntity.Department{Name: "rnd", ParentID: 1})                            // id=4
+	_ = ur.Create(ctx, &userEntity.User{Username: "root", UserType: userEntity.UserTypeSuperAdmin}) // id=1
+	_ = ur.Create(ctx, &userEntity.User{Username: "manager", DeptID: 2})                            // id=2
+	_ = ur.Create(ctx, &userEntity.User{Username: "rep", DeptID: 3})                                // id=3
+	_ = ur.Create(ctx, &userEntity.User{Username: "dev", DeptID: 4})                                // id=4
+
+	if err := svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{Name: "bad", DataScope: roleEntity.DataScopeCustom}); err == nil {
+		t.Fatal("custom data scope without departments must be rejected")
+	}
+	if err := svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{Name: "bad", DataScope: roleEntity.DataScopeCustom, DeptIDs: []uint{99}}); err == nil {
+		t.Fatal("unknown departments must be rejected")
+	}
+	_ = svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{Name: "dept", DataScope: roleEntity.DataScopeDept})                      // id=1
+	_ = svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{Name: "tree", DataScope: roleEntity.DataScopeDeptAndChild})              // id=2
+	_ = svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{Name: "rnd", DataScope: roleEntity.DataScopeCustom, DeptIDs: []uint{4}}) // id=3
+	_ = svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{Name: "self", DataScope: roleEntity.DataScopeSelf})                      // id=4
+	_ = svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{Name: "default"})                                                        // id=5
+
+	names := func(operatorID uint) []string {
+		total, list, err := svc.ListUsers(ctx, operatorID, 1, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := []string{}
+		for _, u := range list {
+			res = append(res, u.Username)
+		}
+		if int(total) != len(res) {
+			t.Fatalf("count %d does not match list %v", total, res)
+		}
+		return res
+	}
+	same := func(got []string, want ...string) bool {
+		if len(got) != len(want) {
+			return false
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	if got := names(1); len(got) != 4 {
+		t.Fatalf("super admin sees everything, got %v", got)
+	}
+	if got := names(0); len(got) != 4 {
+		t.Fatalf("system calls are not scoped, got %v", got)
+	}
+	if got := names(2); !same(got, "manager") {
+		t.Fatalf("no roles falls back to self, got %v", got)
+	}
+	_, _, _ = svc.BindUserRoles(ctx, 2, []uint{1}, nil, nil)
+	if got := names(2); !same(got, "manager") {
+		t.Fatalf("own department only, got %v", got)
+	}
+	_, _, _ = svc.BindUserRoles(ctx, 2, []uint{2}, nil, nil)
+	if got := names(2); !same(got, "manager", "rep") {
+		t.Fatalf("own department and below, got %v", got)
+	}
+	_, _, _ = svc.BindUserRoles(ctx, 2, []uint{3}, nil, nil)
+	if got := names(2); !same(got, "manager", "rep", "dev") {
+		t.Fatalf("custom departments are added to the union, got %v", got)
+	}
+	_, _, _ = svc.BindUserRoles(ctx, 3, []uint{4}, nil, nil)
+	if got := names(3); !same(got, "rep") {
+		t.Fatalf("self only, got %v", got)
+	}
+	// 未设置数据范围的角色默认为全部数据
+	_, _, _ = svc.BindUserRoles(ctx, 3, []uint{5}, nil, nil)
+	if got := names(3); len(got) != 4 {
+		t.Fatalf("default role sees everything, got %v", got)
+	}
+
+	// 更新角色时未指定数据范围则保持原值，不会被放宽为全部数据
+	if err := svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{ID: 3, Name: "rnd-renamed", Remark: "r&d"}); err != nil {
+		t.Fatal(err)
+	}
+	if r, _ := rr.GetByID(ctx, 3); r.Name != "rnd-renamed" || r.DataScope != roleEntity.DataScopeCustom || r.ScopeDept != "4" {
+		t.Fatalf("data scope must be kept on update, got %+v", r)
+	}
+	if got := names(2); !same(got, "manager", "rep", "dev") {
+		t.Fatalf("renamed custom role must keep its departments, got %v", got)
+	}
+	_ = svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{ID: 1, Name: "dept-renamed"})
+	if r, _ := rr.GetByID(ctx, 1); r.DataScope != roleEntity.DataScopeDept {
+		t.Fatalf("dept scope must be kept on update, got %d", r.DataScope)
+	}
+
+	// 子角色继承祖先角色的数据范围；已删除的角色不再生效
+	_ = ur.Create(ctx, &userEntity.User{Username: "intern", DeptID: 2})                                                                  // id=5
+	_ = svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{Name: "child", ParentID: 2, DataScope: roleEntity.DataScopeSelf}) // id=6
+	_ = svc.CreateOrUpdateRole(ctx, &rbacdto.RoleCreateOrUpdateRequest{Name: "temp"})                                                    // id=7
+	_, _, _ = svc.BindUserRoles(ctx, 5, []uint{6, 7}, nil, nil)
+	if got := names(5); len(got) != 5 {
+		t.Fatalf("temp role sees everything, got %v", got)
+	}
+	_ = svc.DeleteRole(ctx, 7)
+	if got := names(5); !same(got, "manager", "rep", "intern") {
+		t.Fatalf("deleted role must not apply and parent scope is inherited, got %v", got)
+	}
+
+	// 修改 / 删除用户同样受数据范围限制
+	forbidden := func(name string, err error) {
+		t.Helper()
+		if appErr, ok := err.(*errorx.Error); !ok || appErr.Code != errorx.ErrForbidden {
+			t.Fatalf("%s: expected forbidden, got %v", name, err)
+		}
+	}
+	forbidden("update outside scope", svc.UpdateUser(ctx, 5, &rbacdto.UserUpdateRequest{ID: 4, Nickname: "x"}))
+	forbidden("delete outside scope", svc.DeleteUser(ctx, 5, 4))
+	if u, _ := ur.GetByID(ctx, 4); u.Nickname != "" || u.Status != 0 {
+		t.Fatalf("user outside scope must stay untouched: %+v", u)
+	}
+	if err := svc.UpdateUser(ctx, 5, &rbacdto.UserUpdateRequest{ID: 3, Nickname: "Rep"}); err != nil {
+		t.Fatalf("update inside scope: %v", err)
+	}
+	if err := svc.UpdateUser(ctx, 0, &rbacdto.UserUpdateRequest{ID: 4, Nickname: "Dev"}); err != nil {
+		t.Fatalf("system calls are not scoped: %v", err)
+	}
+}
+
+// 密码哈希失败时不能以空哈希创建用户（bcrypt 不支持超过 72 字节的密码）
+func TestCreateUserHashFailure_InMemory(t *testing.T) {
+	_ = config.LoadEnv()
+	_ = logger.Init()
+	if err := utils.SetDefaultPasswordHasher("bcrypt"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = utils.SetDefaultPasswordHasher("argon2id") })
+	ur := usertest.NewUserRepo()
+	rr := newMemRoleRepo().(*memRoleRepo)
+	mr := newMemMenuRepo().(*memMenuRepo)
+	svc := NewRBACApplicationService(ur, rr, mr, newMemRBACRepo(rr, mr), nil, nil, nil)
+
+	err := svc.CreateUser(context.Background(), &rbacdto.UserCreateRequest{Username: "u1", Password: strings.Repeat("a", 100)})
+	if appErr, ok := err.(*errorx.Error); !ok || appErr.Code != errorx.ErrInternalServer {
+		t.Fatalf("expected internal error, got %v", err)
+	}
+	if len(ur.Data) != 0 {
+		t.Fatalf("user must not be created, got %d", len(ur.Data))
+	}
+}
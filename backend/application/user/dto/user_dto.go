@@ -3,7 +3,13 @@ package dto
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required,min=3,max=50" example:"john_doe"`
 	Email    string `json:"email" binding:"required,email,max=100" example:"john@example.com"`
-	Password string `json:"password" binding:"required,min=6,max=50" example:"password123"`
+	Password string `json:"password" binding:"required,max=128" example:"password123"`
+}
+
+// ClientInfo 请求来源信息（由处理器从 HTTP 请求中提取）
+type ClientInfo struct {
+	IP        string
+	UserAgent string
 }
 
 type LoginRequest struct {
@@ -11,14 +17,199 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required" example:"password123"`
 }
 
+// UserResponse ImpersonatedBy 仅在代登录时返回操作人用户名，前端据此显示代登录提示
 type UserResponse struct {
-	ID       uint   `json:"id" example:"1"`
-	Username string `json:"username" example:"john_doe"`
-	Email    string `json:"email" example:"john@example.com"`
-	IsActive bool   `json:"is_active" example:"true"`
+	ID             uint   `json:"id" example:"1"`
+	Username       string `json:"username" example:"john_doe"`
+	Email          string `json:"email" example:"john@example.com"`
+	EmailVerified  bool   `json:"email_verified" example:"true"`
+	IsActive       bool   `json:"is_active" example:"true"`
+	ImpersonatedBy string `json:"impersonated_by,omitempty" example:"admin"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email,max=100" example:"john@example.com"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	NewPassword string `json:"new_password" binding:"required,max=128" example:"newPassword123"`
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required" example:"password123"`
+	NewPassword string `json:"new_password" binding:"required,max=128" example:"newPassword123"`
+}
+
+// AdminResetPasswordRequest Password 为空时随机生成临时密码
+type AdminResetPasswordRequest struct {
+	UserID   uint   `json:"user_id" binding:"required" example:"1"`
+	Password string `json:"password" binding:"max=128" example:"Temp@2024!"`
+}
+
+// AdminResetPasswordResponse 仅在随机生成时返回临时密码（只返回一次）
+type AdminResetPasswordResponse struct {
+	TemporaryPassword string `json:"temporary_password,omitempty" example:"x7#Kq2mPz9!Rw4tB"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email,max=100" example:"john@example.com"`
 }
 
+// LoginResponse 启用两步验证的用户仅返回 MFARequired 与 MFAToken，需调用 /api/auth/mfa/verify 换取正式令牌；
+// PasswordExpired 为 true 时须先调用 /api/user/changePassword，其余接口返回 20017
 type LoginResponse struct {
-	Token string       `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
-	User  UserResponse `json:"user"`
+	Token           string       `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	RefreshToken    string       `json:"refresh_token" example:"q8m2ZbJ0c4sX..."`
+	ExpiresIn       int64        `json:"expires_in" example:"900"`
+	MFARequired     bool         `json:"mfa_required,omitempty" example:"false"`
+	MFAToken        string       `json:"mfa_token,omitempty" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	PasswordExpired bool         `json:"password_expired,omitempty" example:"false"`
+	User            UserResponse `json:"user"`
+}
+
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	Code     string `json:"code" binding:"required,max=20" example:"123456"`
+}
+
+type MFAEnrollResponse struct {
+	Secret     string `json:"secret" example:"JBSWY3DPEHPK3PXP"`
+	OtpauthURI string `json:"otpauth_uri" example:"otpauth://totp/Sinx:john_doe?secret=JBSWY3DPEHPK3PXP&issuer=Sinx"`
+}
+
+type MFAActivateRequest struct {
+	Code string `json:"code" binding:"required,len=6" example:"123456"`
+}
+
+type MFAActivateResponse struct {
+	RecoveryCodes []string `json:"recovery_codes" example:"abcde-fghjk"`
+}
+
+type MFAStatusResponse struct {
+	Enabled                bool  `json:"enabled" example:"true"`
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining" example:"10"`
+}
+
+type MFAResetRequest struct {
+	UserID uint `json:"user_id" binding:"required" example:"1"`
+}
+
+type ImpersonateRequest struct {
+	UserID uint   `json:"user_id" binding:"required" example:"2"`
+	Reason string `json:"reason" binding:"required,max=200" example:"排查菜单不可见问题"`
+}
+
+// ImpersonateResponse 代登录令牌，不附带刷新令牌
+type ImpersonateResponse struct {
+	Token     string       `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	ExpiresIn int64        `json:"expires_in" example:"1800"`
+	User      UserResponse `json:"user"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required" example:"q8m2ZbJ0c4sX..."`
+}
+
+type TokenResponse struct {
+	Token        string `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	RefreshToken string `json:"refresh_token" example:"q8m2ZbJ0c4sX..."`
+	ExpiresIn    int64  `json:"expires_in" example:"900"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token" example:"q8m2ZbJ0c4sX..."`
+}
+
+// SessionItem 登录会话；UserID / Username 仅在管理员在线用户列表中返回
+type SessionItem struct {
+	ID         string `json:"id" example:"kq2mPz9Rw4tBx7Kq"`
+	UserID     uint   `json:"user_id,omitempty" example:"1"`
+	Username   string `json:"username,omitempty" example:"john_doe"`
+	Device     string `json:"device" example:"Chrome on Windows"`
+	IP         string `json:"ip" example:"203.0.113.7"`
+	UserAgent  string `json:"user_agent" example:"Mozilla/5.0 ..."`
+	CreatedAt  int64  `json:"created_at" example:"1760000000"`
+	LastSeenAt int64  `json:"last_seen_at" example:"1760600000"`
+	ExpiresAt  int64  `json:"expires_at" example:"1760604800"`
+	Current    bool   `json:"current,omitempty" example:"true"`
+}
+
+type SessionRevokeRequest struct {
+	ID string `json:"id" binding:"required,max=64" example:"kq2mPz9Rw4tBx7Kq"`
+}
+
+// ForceLogoutRequest 指定 SessionID 时下线单个会话，否则下线 UserID 的全部会话
+type ForceLogoutRequest struct {
+	SessionID string `json:"session_id" binding:"max=64" example:"kq2mPz9Rw4tBx7Kq"`
+	UserID    uint   `json:"user_id" example:"1"`
+}
+
+// LoginLogQuery 登录日志查询条件，start / end 为 Unix 秒
+type LoginLogQuery struct {
+	PageNum  int    `form:"pageNum"`
+	PageSize int    `form:"pageSize"`
+	UserID   uint   `form:"user_id"`
+	Username string `form:"username" binding:"max=50"`
+	IP       string `form:"ip" binding:"max=64"`
+	Success  *bool  `form:"success"`
+	Start    int64  `form:"start"`
+	End      int64  `form:"end"`
+}
+
+type LoginLogItem struct {
+	ID        uint   `json:"id" example:"1"`
+	UserID    uint   `json:"user_id" example:"1"`
+	Username  string `json:"username" example:"john_doe"`
+	Method    string `json:"method" example:"password"`
+	Success   bool   `json:"success" example:"false"`
+	Reason    string `json:"reason" example:"invalid_password"`
+	IP        string `json:"ip" example:"203.0.113.7"`
+	UserAgent string `json:"user_agent" example:"Mozilla/5.0 ..."`
+	Nation    string `json:"nation" example:"中国"`
+	Province  string `json:"province" example:"广东"`
+	City      string `json:"city" example:"深圳"`
+	CreatedAt int64  `json:"created_at" example:"1760000000"`
+}
+
+type LockoutItem struct {
+	Kind     string `json:"kind" example:"user"`
+	Key      string `json:"key" example:"john_doe"`
+	Failures int    `json:"failures" example:"5"`
+	Until    int64  `json:"until" example:"1760600000"`
+}
+
+type UnlockRequest struct {
+	Kind string `json:"kind" binding:"required,oneof=user ip" example:"user"`
+	Key  string `json:"key" binding:"required" example:"john_doe"`
+}
+
+type AccessTokenCreateRequest struct {
+	Name          string   `json:"name" binding:"required,max=64" example:"ci-deploy"`
+	Scopes        []string `json:"scopes" binding:"required,min=1,dive,required" example:"user:list"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"min=0,max=3650" example:"90"` // 0 表示永不过期
+}
+
+type AccessTokenDeleteRequest struct {
+	ID uint `json:"id" binding:"required" example:"1"`
+}
+
+type AccessTokenItem struct {
+	ID         uint     `json:"id" example:"1"`
+	Name       string   `json:"name" example:"ci-deploy"`
+	Prefix     string   `json:"prefix" example:"sinx_pat_Ab3x"`
+	Scopes     []string `json:"scopes" example:"user:list"`
+	ExpiresAt  int64    `json:"expires_at,omitempty" example:"1768000000"`
+	LastUsedAt int64    `json:"last_used_at,omitempty" example:"1760600000"`
+	CreatedAt  int64    `json:"created_at" example:"1760000000"`
+}
+
+// AccessTokenCreateResponse 明文令牌仅在创建时返回一次
+type AccessTokenCreateResponse struct {
+	Token string `json:"token" example:"sinx_pat_Ab3x..."`
+	AccessTokenItem
 }
@@ -2,21 +2,79 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"time"
 
 	"github.com/sine-io/sinx/application/user/dto"
+	authEntity "github.com/sine-io/sinx/domain/auth/entity"
+	authService "github.com/sine-io/sinx/domain/auth/service"
 	"github.com/sine-io/sinx/domain/user/entity"
+	"github.com/sine-io/sinx/domain/user/repository"
 	"github.com/sine-io/sinx/domain/user/service"
 	"github.com/sine-io/sinx/pkg/auth"
 	"github.com/sine-io/sinx/pkg/errorx"
+	"github.com/sine-io/sinx/pkg/logger"
+	"github.com/sine-io/sinx/pkg/loginguard"
+	"github.com/sine-io/sinx/pkg/mailer"
+	"github.com/sine-io/sinx/pkg/permissions"
+	"github.com/sine-io/sinx/pkg/utils"
 )
 
+// PermissionProvider 查询用户当前拥有的权限（用于校验个人访问令牌的权限范围与管理操作的目标）
+type PermissionProvider interface {
+	GetUserPermSet(ctx context.Context, userID uint) (*permissions.PermSet, error)
+	IsSuperAdmin(ctx context.Context, userID uint) bool
+	// EnsureInDataScope 目标用户须在操作人的数据范围内
+	EnsureInDataScope(ctx context.Context, operatorID uint, target *entity.User) error
+}
+
+// AccountMail 找回密码与邮箱验证邮件；链接为 <URL>?token=...
+type AccountMail struct {
+	Mailer               mailer.Mailer
+	Templates            *mailer.Templates
+	AppName              string
+	ResetURL             string
+	ResetTTL             time.Duration
+	VerifyURL            string
+	VerifyTTL            time.Duration
+	RequireVerifiedEmail bool                // 邮箱未验证的本地账号禁止密码登录
+	Limiter              *loginguard.Limiter // 找回密码 / 重发验证邮件按 IP 与邮箱限流，nil 表示不限
+}
+
+// mailData 邮件模板数据
+type mailData struct {
+	AppName   string
+	Username  string
+	Email     string
+	Link      string
+	ExpiresIn string
+}
+
 type UserApplicationService struct {
-	userDomainService *service.UserDomainService
+	userDomainService        *service.UserDomainService
+	tokenDomainService       *authService.TokenDomainService
+	mfaDomainService         *service.MFADomainService
+	accessTokenDomainService *authService.AccessTokenDomainService
+	loginLogDomainService    *service.LoginLogDomainService
+	loginGuard               *loginguard.Guard
+	perms                    PermissionProvider
+	account                  AccountMail
+	impersonationTTL         time.Duration
 }
 
-func NewUserApplicationService(userDomainService *service.UserDomainService) *UserApplicationService {
+func NewUserApplicationService(userDomainService *service.UserDomainService, tokenDomainService *authService.TokenDomainService, mfaDomainService *service.MFADomainService, accessTokenDomainService *authService.AccessTokenDomainService, loginLogDomainService *service.LoginLogDomainService, loginGuard *loginguard.Guard, perms PermissionProvider, account AccountMail, impersonationTTL time.Duration) *UserApplicationService {
 	return &UserApplicationService{
-		userDomainService: userDomainService,
+		userDomainService:        userDomainService,
+		tokenDomainService:       tokenDomainService,
+		mfaDomainService:         mfaDomainService,
+		accessTokenDomainService: accessTokenDomainService,
+		loginLogDomainService:    loginLogDomainService,
+		loginGuard:               loginGuard,
+		perms:                    perms,
+		account:                  account,
+		impersonationTTL:         impersonationTTL,
 	}
 }
 
@@ -26,18 +84,40 @@ func (s *UserApplicationService) Register(ctx context.Context, req *dto.Register
 	if err != nil {
 		return nil, err
 	}
+	if err := s.sendVerification(user); err != nil {
+		logger.Warn("email_verification_send_failed", "userId", user.ID, "error", err)
+	}
 
 	return s.entityToResponse(user), nil
 }
 
 // Login 用户登录
-func (s *UserApplicationService) Login(ctx context.Context, req *dto.LoginRequest) (*dto.LoginResponse, error) {
+func (s *UserApplicationService) Login(ctx context.Context, req *dto.LoginRequest, client dto.ClientInfo) (*dto.LoginResponse, error) {
+	// 暴力破解防护：账号 / IP 被锁定或处于退避期时直接拒绝
+	decision, err := s.loginGuard.Check(ctx, req.Username, client.IP)
+	if err != nil {
+		logger.Warn("login_guard_check_failed", "error", err)
+	}
+	if !decision.Allowed {
+		s.recordLogin(ctx, entity.LoginMethodPassword, req.Username, nil, client, throttleReason(decision))
+		return nil, throttleError(decision)
+	}
+
 	user, err := s.userDomainService.AuthenticateUser(ctx, req.Username, req.Password)
 	if err != nil {
+		s.recordLogin(ctx, entity.LoginMethodPassword, req.Username, nil, client, failureReason(err))
 		// Hide specific reasons during login to avoid user enumeration
 		if appErr, ok := err.(*errorx.Error); ok {
 			switch appErr.Code {
 			case errorx.ErrUserNotFound, errorx.ErrUserInvalidPassword:
+				decision, gerr := s.loginGuard.RecordFailure(ctx, req.Username, client.IP)
+				if gerr != nil {
+					logger.Warn("login_guard_record_failed", "error", gerr)
+				}
+				if decision.Locked {
+					logger.Warn("audit:login_locked", "username", req.Username, "ip", client.IP)
+					return nil, throttleError(decision)
+				}
 				// Keep HTTP 401 but return a friendly, localized message
 				return nil, errorx.New(errorx.ErrUnauthorized, "用户名或密码错误")
 			}
@@ -45,18 +125,705 @@ func (s *UserApplicationService) Login(ctx context.Context, req *dto.LoginReques
 		return nil, err
 	}
 
-	// 生成JWT令牌
-	token, err := auth.GenerateToken(user.ID, user.Username)
+	// 开启强制验证时，邮箱未验证的本地账号不能登录（目录账号的邮箱由目录维护）
+	if s.account.RequireVerifiedEmail && user.EmailVerifiedAt == nil && !s.userDomainService.IsDirectoryUser(user) {
+		s.recordLogin(ctx, entity.LoginMethodPassword, user.Username, user, client, entity.LoginReasonEmailNotVerified)
+		return nil, errorx.NewWithCode(errorx.ErrEmailNotVerified)
+	}
+
+	// 已启用两步验证：仅返回临时令牌，失败计数待验证码通过后再清零；登录结果在验证码校验后记录
+	mfaEnabled, err := s.mfaDomainService.IsEnabled(ctx, user.ID)
 	if err != nil {
-		return nil, errorx.New(errorx.ErrInternalServer, "failed to generate token")
+		return nil, err
+	}
+	if mfaEnabled {
+		mfaToken, err := auth.GenerateMFAToken(user.ID, user.Username)
+		if err != nil {
+			return nil, err
+		}
+		return &dto.LoginResponse{MFARequired: true, MFAToken: mfaToken, User: *s.entityToResponse(user)}, nil
 	}
 
-	return &dto.LoginResponse{
-		Token: token,
-		User:  *s.entityToResponse(user),
+	if err := s.loginGuard.RecordSuccess(ctx, req.Username); err != nil {
+		logger.Warn("login_guard_reset_failed", "error", err)
+	}
+	return s.issueLogin(ctx, user, client, entity.LoginMethodPassword)
+}
+
+// VerifyMFA 使用两步验证临时令牌 + TOTP 验证码（或恢复码）换取正式令牌
+func (s *UserApplicationService) VerifyMFA(ctx context.Context, req *dto.MFAVerifyRequest, client dto.ClientInfo) (*dto.LoginResponse, error) {
+	claims, err := auth.ParseToken(req.MFAToken)
+	if err != nil || claims.TokenUse != auth.TokenUseMFAPending {
+		return nil, errorx.NewWithCode(errorx.ErrUserInvalidToken)
+	}
+	revoked, err := s.tokenDomainService.IsRevoked(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errorx.NewWithCode(errorx.ErrUserTokenRevoked)
+	}
+
+	// 验证码错误与密码错误共用同一套失败计数
+	decision, err := s.loginGuard.Check(ctx, claims.Username, client.IP)
+	if err != nil {
+		logger.Warn("login_guard_check_failed", "error", err)
+	}
+	if !decision.Allowed {
+		s.recordLogin(ctx, entity.LoginMethodMFA, claims.Username, &entity.User{ID: claims.UserID, Username: claims.Username}, client, throttleReason(decision))
+		return nil, throttleError(decision)
+	}
+
+	if err := s.mfaDomainService.Verify(ctx, claims.UserID, req.Code); err != nil {
+		if appErr, ok := err.(*errorx.Error); ok && appErr.Code == errorx.ErrMFAInvalidCode {
+			s.recordLogin(ctx, entity.LoginMethodMFA, claims.Username, &entity.User{ID: claims.UserID, Username: claims.Username}, client, entity.LoginReasonInvalidMFACode)
+			decision, gerr := s.loginGuard.RecordFailure(ctx, claims.Username, client.IP)
+			if gerr != nil {
+				logger.Warn("login_guard_record_failed", "error", gerr)
+			}
+			if decision.Locked {
+				logger.Warn("audit:login_locked", "username", claims.Username, "ip", client.IP)
+				return nil, throttleError(decision)
+			}
+		}
+		return nil, err
+	}
+
+	user, err := s.userDomainService.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, errorx.NewWithCode(errorx.ErrUserInvalidToken)
+	}
+	// 临时令牌仅可使用一次
+	if err := s.tokenDomainService.RevokeAccessToken(ctx, claims); err != nil {
+		return nil, err
+	}
+	if err := s.loginGuard.RecordSuccess(ctx, claims.Username); err != nil {
+		logger.Warn("login_guard_reset_failed", "error", err)
+	}
+	return s.issueLogin(ctx, user, client, entity.LoginMethodMFA)
+}
+
+// BeginMFAEnrollment 开始登记两步验证，返回密钥与 otpauth URI
+func (s *UserApplicationService) BeginMFAEnrollment(ctx context.Context, userID uint) (*dto.MFAEnrollResponse, error) {
+	user, err := s.userDomainService.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	secret, uri, err := s.mfaDomainService.BeginEnrollment(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.MFAEnrollResponse{Secret: secret, OtpauthURI: uri}, nil
+}
+
+// ActivateMFA 校验验证码完成登记，返回一次性恢复码
+func (s *UserApplicationService) ActivateMFA(ctx context.Context, userID uint, req *dto.MFAActivateRequest) (*dto.MFAActivateResponse, error) {
+	codes, err := s.mfaDomainService.ConfirmEnrollment(ctx, userID, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("audit:mfa_enabled", "userId", userID)
+	return &dto.MFAActivateResponse{RecoveryCodes: codes}, nil
+}
+
+// GetMFAStatus 查询两步验证状态
+func (s *UserApplicationService) GetMFAStatus(ctx context.Context, userID uint) (*dto.MFAStatusResponse, error) {
+	enabled, err := s.mfaDomainService.IsEnabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	res := &dto.MFAStatusResponse{Enabled: enabled}
+	if enabled {
+		if res.RecoveryCodesRemaining, err = s.mfaDomainService.RemainingRecoveryCodes(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// ResetMFA 管理员重置用户的两步验证
+func (s *UserApplicationService) ResetMFA(ctx context.Context, operatorID uint, req *dto.MFAResetRequest) error {
+	if err := s.guardTarget(ctx, operatorID, req.UserID); err != nil {
+		return err
+	}
+	if err := s.mfaDomainService.Reset(ctx, req.UserID); err != nil {
+		return err
+	}
+	logger.Info("audit:mfa_reset", "userId", req.UserID, "operatorId", operatorID)
+	return nil
+}
+
+// Refresh 使用刷新令牌换取新的令牌对
+func (s *UserApplicationService) Refresh(ctx context.Context, req *dto.RefreshTokenRequest) (*dto.TokenResponse, error) {
+	pair, err := s.tokenDomainService.Refresh(ctx, req.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.TokenResponse{Token: pair.AccessToken, RefreshToken: pair.RefreshToken, ExpiresIn: pair.ExpiresIn}, nil
+}
+
+// Logout 注销当前访问令牌及其所属会话；若携带刷新令牌则一并吊销其令牌族
+func (s *UserApplicationService) Logout(ctx context.Context, claims *auth.Claims, req *dto.LogoutRequest) error {
+	if err := s.tokenDomainService.RevokeAccessToken(ctx, claims); err != nil {
+		return err
+	}
+	if claims.SessionID != "" {
+		if _, err := s.tokenDomainService.RevokeSession(ctx, claims.UserID, claims.SessionID); err != nil {
+			logger.Warn("revoke_session_failed", "userId", claims.UserID, "error", err)
+		}
+	}
+	if req.RefreshToken != "" {
+		if err := s.tokenDomainService.RevokeRefreshToken(ctx, claims.UserID, req.RefreshToken); err != nil {
+			return err
+		}
+	}
+	logger.Info("audit:logout", "userId", claims.UserID)
+	return nil
+}
+
+// LogoutAll 注销用户在所有设备上的令牌
+func (s *UserApplicationService) LogoutAll(ctx context.Context, userID uint) error {
+	if err := s.tokenDomainService.RevokeUserTokens(ctx, userID); err != nil {
+		return err
+	}
+	logger.Info("audit:logout_all", "userId", userID)
+	return nil
+}
+
+// ListSessions 当前用户的在线会话，current 标记发起请求的会话
+func (s *UserApplicationService) ListSessions(ctx context.Context, claims *auth.Claims) ([]*dto.SessionItem, error) {
+	list, err := s.tokenDomainService.ListSessions(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*dto.SessionItem, 0, len(list))
+	for _, session := range list {
+		item := sessionToItem(session)
+		item.Current = session.SessionID == claims.SessionID
+		res = append(res, item)
+	}
+	return res, nil
+}
+
+// RevokeSession 下线当前用户的指定会话
+func (s *UserApplicationService) RevokeSession(ctx context.Context, userID uint, req *dto.SessionRevokeRequest) error {
+	if _, err := s.tokenDomainService.RevokeSession(ctx, userID, req.ID); err != nil {
+		return err
+	}
+	logger.Info("audit:revoke_session", "userId", userID, "sessionId", req.ID)
+	return nil
+}
+
+// ListOnlineSessions 管理员查看全部在线会话
+func (s *UserApplicationService) ListOnlineSessions(ctx context.Context, pageNum, pageSize int) (int64, []*dto.SessionItem, error) {
+	if pageNum <= 0 {
+		pageNum = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 10
+	}
+	list, total, err := s.tokenDomainService.ListOnlineSessions(ctx, (pageNum-1)*pageSize, pageSize)
+	if err != nil {
+		return 0, nil, err
+	}
+	res := make([]*dto.SessionItem, 0, len(list))
+	for _, session := range list {
+		item := sessionToItem(session)
+		item.UserID, item.Username = session.UserID, session.Username
+		res = append(res, item)
+	}
+	return total, res, nil
+}
+
+// ListLoginLogs 管理员分页查询登录日志
+func (s *UserApplicationService) ListLoginLogs(ctx context.Context, q *dto.LoginLogQuery) (int64, []*dto.LoginLogItem, error) {
+	pageNum, pageSize := q.PageNum, q.PageSize
+	if pageNum <= 0 {
+		pageNum = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 10
+	}
+	filter := repository.LoginLogFilter{UserID: q.UserID, Username: q.Username, IP: q.IP, Success: q.Success}
+	if q.Start > 0 {
+		filter.Since = time.Unix(q.Start, 0)
+	}
+	if q.End > 0 {
+		filter.Until = time.Unix(q.End, 0)
+	}
+	list, total, err := s.loginLogDomainService.List(ctx, filter, (pageNum-1)*pageSize, pageSize)
+	if err != nil {
+		return 0, nil, err
+	}
+	res := make([]*dto.LoginLogItem, 0, len(list))
+	for _, l := range list {
+		res = append(res, &dto.LoginLogItem{
+			ID:        l.ID,
+			UserID:    l.UserID,
+			Username:  l.Username,
+			Method:    l.Method,
+			Success:   l.Success,
+			Reason:    l.Reason,
+			IP:        l.IP,
+			UserAgent: l.UserAgent,
+			Nation:    l.Nation,
+			Province:  l.Province,
+			City:      l.City,
+			CreatedAt: l.CreatedAt.Unix(),
+		})
+	}
+	return total, res, nil
+}
+
+// ForceLogout 管理员强制下线单个会话或用户的全部会话
+func (s *UserApplicationService) ForceLogout(ctx context.Context, operatorID uint, req *dto.ForceLogoutRequest) error {
+	if req.SessionID != "" {
+		session, err := s.tokenDomainService.GetSession(ctx, req.SessionID)
+		if err != nil {
+			return err
+		}
+		if session == nil {
+			return errorx.New(errorx.ErrNotFound, "session not found")
+		}
+		if err := s.guardTarget(ctx, operatorID, session.UserID); err != nil {
+			return err
+		}
+		if _, err := s.tokenDomainService.RevokeSession(ctx, 0, req.SessionID); err != nil {
+			return err
+		}
+		logger.Info("audit:force_logout", "userId", session.UserID, "sessionId", req.SessionID, "operatorId", operatorID)
+		return nil
+	}
+	if req.UserID == 0 {
+		return errorx.New(errorx.ErrInvalidParam, "session_id or user_id is required")
+	}
+	if err := s.guardTarget(ctx, operatorID, req.UserID); err != nil {
+		return err
+	}
+	if err := s.tokenDomainService.RevokeUserTokens(ctx, req.UserID); err != nil {
+		return err
+	}
+	logger.Info("audit:force_logout", "userId", req.UserID, "operatorId", operatorID)
+	return nil
+}
+
+// guardTarget 管理员对其他用户的操作（重置密码 / 两步验证、强制下线、代登录）：
+// 目标须在操作人的数据范围内；目标为超级管理员时，操作人也须为超级管理员
+func (s *UserApplicationService) guardTarget(ctx context.Context, operatorID, targetID uint) error {
+	target, err := s.userDomainService.GetUserByID(ctx, targetID)
+	if err != nil {
+		return err
+	}
+	if err := s.perms.EnsureInDataScope(ctx, operatorID, target); err != nil {
+		return err
+	}
+	if s.perms.IsSuperAdmin(ctx, targetID) && !s.perms.IsSuperAdmin(ctx, operatorID) {
+		return errorx.New(errorx.ErrForbidden, "only super admins can manage super admins")
+	}
+	return nil
+}
+
+// ValidateToken 校验已通过签名验证的访问令牌：未被吊销且用户仍处于正常状态；密码过期时返回 ErrPasswordExpired
+func (s *UserApplicationService) ValidateToken(ctx context.Context, claims *auth.Claims) error {
+	revoked, err := s.tokenDomainService.IsRevoked(ctx, claims)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return errorx.NewWithCode(errorx.ErrUserTokenRevoked)
+	}
+	active, err := s.tokenDomainService.SessionActive(ctx, claims)
+	if err != nil {
+		return err
+	}
+	if !active {
+		return errorx.NewWithCode(errorx.ErrUserTokenRevoked)
+	}
+	user, err := s.userDomainService.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		return errorx.NewWithCode(errorx.ErrUserInvalidToken)
+	}
+	if claims.Actor != nil {
+		return s.validateActor(ctx, claims.Actor)
+	}
+	if s.userDomainService.PasswordExpired(user) {
+		return errorx.NewWithCode(errorx.ErrPasswordExpired)
+	}
+	return nil
+}
+
+// validateActor 代登录令牌要求操作人仍为正常状态且仍持有代登录权限
+func (s *UserApplicationService) validateActor(ctx context.Context, actor *auth.Actor) error {
+	if _, err := s.userDomainService.GetUserByID(ctx, actor.UserID); err != nil {
+		return errorx.NewWithCode(errorx.ErrUserInvalidToken)
+	}
+	perms, err := s.perms.GetUserPermSet(ctx, actor.UserID)
+	if err != nil {
+		return err
+	}
+	if !perms.Has(permissions.PermUserImpersonate) {
+		return errorx.NewWithCode(errorx.ErrUserTokenRevoked)
+	}
+	return nil
+}
+
+// Impersonate 管理员以目标用户身份登录（用于排查菜单 / 权限问题）
+// 目标用户的权限不得超出操作人自身权限，避免借代登录提权；令牌不可刷新，到期即结束
+func (s *UserApplicationService) Impersonate(ctx context.Context, operator *auth.Claims, req *dto.ImpersonateRequest) (*dto.ImpersonateResponse, error) {
+	if req.UserID == operator.UserID {
+		return nil, errorx.New(errorx.ErrInvalidParam, "cannot impersonate yourself")
+	}
+	target, err := s.userDomainService.GetUserByID(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.guardTarget(ctx, operator.UserID, target.ID); err != nil {
+		return nil, err
+	}
+	granted, err := s.perms.GetUserPermSet(ctx, operator.UserID)
+	if err != nil {
+		return nil, err
+	}
+	targetPerms, err := s.perms.GetUserPermSet(ctx, target.ID)
+	if err != nil {
+		return nil, err
+	}
+	// 通配权限按字面段比较，即操作人须覆盖整个模式
+	for perm := range targetPerms.Perms() {
+		if !granted.Has(perm) {
+			return nil, errorx.New(errorx.ErrForbidden, "target user has permissions you do not hold: "+perm)
+		}
+	}
+
+	actor := auth.Actor{UserID: operator.UserID, Username: operator.Username}
+	token, claims, err := auth.GenerateImpersonationToken(target.ID, target.Username, actor, s.impersonationTTL)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("audit:impersonate", "userId", target.ID, "username", target.Username, "operatorId", operator.UserID, "jti", claims.ID, "reason", req.Reason)
+	return &dto.ImpersonateResponse{
+		Token:     token,
+		ExpiresIn: int64(s.impersonationTTL.Seconds()),
+		User:      *s.entityToResponse(target),
 	}, nil
 }
 
+// AuthenticateAPIKey 校验个人访问令牌，返回所属用户身份与令牌权限范围
+func (s *UserApplicationService) AuthenticateAPIKey(ctx context.Context, raw string) (*auth.Claims, []string, error) {
+	token, err := s.accessTokenDomainService.Authenticate(ctx, raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	user, err := s.userDomainService.GetUserByID(ctx, token.UserID)
+	if err != nil {
+		return nil, nil, errorx.NewWithCode(errorx.ErrUserInvalidToken)
+	}
+	// 与交互式登录一致：密码过期或须修改密码时，个人访问令牌同样不可用
+	if s.userDomainService.PasswordExpired(user) {
+		return nil, nil, errorx.NewWithCode(errorx.ErrPasswordExpired)
+	}
+	claims := &auth.Claims{UserID: user.ID, Username: user.Username, TokenUse: auth.TokenUseAPIKey}
+	return claims, token.ScopeList(), nil
+}
+
+// CreateAccessToken 创建个人访问令牌，权限范围不得超出当前用户拥有的权限
+func (s *UserApplicationService) CreateAccessToken(ctx context.Context, userID uint, req *dto.AccessTokenCreateRequest) (*dto.AccessTokenCreateResponse, error) {
+	granted, err := s.perms.GetUserPermSet(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	raw, token, err := s.accessTokenDomainService.Create(ctx, userID, req.Name, req.Scopes, ttl, granted)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("audit:access_token_created", "userId", userID, "tokenId", token.ID, "scopes", token.Scopes)
+	return &dto.AccessTokenCreateResponse{Token: raw, AccessTokenItem: *accessTokenToItem(token)}, nil
+}
+
+// ListAccessTokens 列出当前用户的个人访问令牌（不含明文）
+func (s *UserApplicationService) ListAccessTokens(ctx context.Context, userID uint) ([]*dto.AccessTokenItem, error) {
+	list, err := s.accessTokenDomainService.List(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*dto.AccessTokenItem, 0, len(list))
+	for _, t := range list {
+		res = append(res, accessTokenToItem(t))
+	}
+	return res, nil
+}
+
+// DeleteAccessToken 删除当前用户的个人访问令牌
+func (s *UserApplicationService) DeleteAccessToken(ctx context.Context, userID uint, req *dto.AccessTokenDeleteRequest) error {
+	if err := s.accessTokenDomainService.Delete(ctx, userID, req.ID); err != nil {
+		return err
+	}
+	logger.Info("audit:access_token_deleted", "userId", userID, "tokenId", req.ID)
+	return nil
+}
+
+// ListLockouts 列出当前被锁定的账号与 IP
+func (s *UserApplicationService) ListLockouts(ctx context.Context) ([]*dto.LockoutItem, error) {
+	lockouts, err := s.loginGuard.Lockouts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*dto.LockoutItem, 0, len(lockouts))
+	for _, l := range lockouts {
+		res = append(res, &dto.LockoutItem{Kind: l.Kind, Key: l.Key, Failures: l.Failures, Until: l.Until.Unix()})
+	}
+	return res, nil
+}
+
+// ClearLockout 解除账号或 IP 的锁定
+func (s *UserApplicationService) ClearLockout(ctx context.Context, req *dto.UnlockRequest) error {
+	if err := s.loginGuard.Unlock(ctx, req.Kind, req.Key); err != nil {
+		return err
+	}
+	logger.Info("audit:clear_lockout", "kind", req.Kind, "key", req.Key)
+	return nil
+}
+
+// RequestPasswordReset 发送找回密码邮件；邮箱不存在时同样返回成功，避免枚举账号
+func (s *UserApplicationService) RequestPasswordReset(ctx context.Context, req *dto.ForgotPasswordRequest, client dto.ClientInfo) error {
+	if err := s.limitMail(ctx, "password_reset", req.Email, client); err != nil {
+		return err
+	}
+	user, err := s.userDomainService.GetUserByEmail(ctx, req.Email)
+	if err != nil || s.userDomainService.IsDirectoryUser(user) {
+		return nil
+	}
+	// 令牌绑定当前密码哈希：密码一经修改，此前签发的全部找回链接失效
+	token, err := auth.GenerateActionToken(user.ID, user.Username, auth.TokenUsePasswordReset, fingerprint(user.Password), s.account.ResetTTL)
+	if err != nil {
+		return err
+	}
+	link, err := withToken(s.account.ResetURL, token)
+	if err != nil {
+		return err
+	}
+	if err := s.sendMail(mailer.TemplatePasswordReset, user, link, s.account.ResetTTL); err != nil {
+		return err
+	}
+	logger.Info("audit:password_reset_requested", "userId", user.ID)
+	return nil
+}
+
+// ResetPassword 使用找回密码令牌设置新密码，并吊销该用户全部令牌、解除登录锁定
+func (s *UserApplicationService) ResetPassword(ctx context.Context, req *dto.ResetPasswordRequest) error {
+	// 先校验令牌并设置密码，成功后再作废令牌：新密码不符合策略时邮件链接仍可继续使用
+	user, claims, err := s.verifyActionToken(ctx, req.Token, auth.TokenUsePasswordReset, func(u *entity.User) string { return u.Password })
+	if err != nil {
+		return err
+	}
+	if err := s.userDomainService.ResetPassword(ctx, user, req.NewPassword); err != nil {
+		return err
+	}
+	// 令牌绑定的密码哈希已变化，作废失败也不能再次使用，仅记录日志
+	if err := s.tokenDomainService.RevokeAccessToken(ctx, claims); err != nil {
+		logger.Warn("revoke_action_token_failed", "userId", user.ID, "error", err)
+	}
+	// 能收到找回邮件即证明邮箱归属
+	if user.EmailVerifiedAt == nil {
+		if err := s.userDomainService.MarkEmailVerified(ctx, user); err != nil {
+			logger.Warn("mark_email_verified_failed", "userId", user.ID, "error", err)
+		}
+	}
+	// 找回密码通常意味着账号可能已泄露，旧会话未能吊销时必须报错
+	if err := s.tokenDomainService.RevokeUserTokens(ctx, user.ID); err != nil {
+		return err
+	}
+	if err := s.loginGuard.Unlock(ctx, loginguard.KindUser, user.Username); err != nil {
+		logger.Warn("login_guard_reset_failed", "error", err)
+	}
+	logger.Info("audit:password_reset", "userId", user.ID)
+	return nil
+}
+
+// ChangePassword 用户修改自己的密码：校验旧密码，吊销其余会话并为当前会话签发新令牌
+func (s *UserApplicationService) ChangePassword(ctx context.Context, userID uint, req *dto.ChangePasswordRequest, client dto.ClientInfo) (*dto.TokenResponse, error) {
+	user, err := s.userDomainService.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if s.userDomainService.IsDirectoryUser(user) {
+		return nil, errorx.New(errorx.ErrInvalidParam, "password is managed by the directory")
+	}
+	// 旧密码校验与登录共用失败计数，防止借已登录会话暴力猜测密码
+	decision, err := s.loginGuard.Check(ctx, user.Username, client.IP)
+	if err != nil {
+		logger.Warn("login_guard_check_failed", "error", err)
+	}
+	if !decision.Allowed {
+		return nil, throttleError(decision)
+	}
+	if !utils.CheckPassword(req.OldPassword, user.Password) {
+		decision, gerr := s.loginGuard.RecordFailure(ctx, user.Username, client.IP)
+		if gerr != nil {
+			logger.Warn("login_guard_record_failed", "error", gerr)
+		}
+		if decision.Locked {
+			logger.Warn("audit:login_locked", "username", user.Username, "ip", client.IP)
+			return nil, throttleError(decision)
+		}
+		return nil, errorx.NewWithCode(errorx.ErrUserInvalidPassword)
+	}
+	if err := s.userDomainService.SetPassword(ctx, user, "new_password", req.NewPassword); err != nil {
+		return nil, err
+	}
+	if err := s.loginGuard.RecordSuccess(ctx, user.Username); err != nil {
+		logger.Warn("login_guard_reset_failed", "error", err)
+	}
+	// 先吊销全部令牌再签发新令牌对，其他设备需重新登录
+	if err := s.tokenDomainService.RevokeUserTokens(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	pair, err := s.tokenDomainService.IssueTokenPair(ctx, user, client.IP, client.UserAgent)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("audit:change_password", "userId", user.ID)
+	return &dto.TokenResponse{Token: pair.AccessToken, RefreshToken: pair.RefreshToken, ExpiresIn: pair.ExpiresIn}, nil
+}
+
+// AdminResetPassword 管理员为用户设置临时密码：吊销其全部令牌、解除登录锁定，下次登录须先修改密码
+func (s *UserApplicationService) AdminResetPassword(ctx context.Context, operatorID uint, req *dto.AdminResetPasswordRequest) (*dto.AdminResetPasswordResponse, error) {
+	user, err := s.userDomainService.GetUserByID(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.guardTarget(ctx, operatorID, user.ID); err != nil {
+		return nil, err
+	}
+	password, err := s.userDomainService.SetTemporaryPassword(ctx, user, req.Password)
+	if err != nil {
+		return nil, err
+	}
+	// 临时密码已生效，旧会话未能吊销时须告知管理员重试
+	if err := s.tokenDomainService.RevokeUserTokens(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	if err := s.loginGuard.Unlock(ctx, loginguard.KindUser, user.Username); err != nil {
+		logger.Warn("login_guard_reset_failed", "error", err)
+	}
+	logger.Info("audit:admin_reset_password", "userId", user.ID, "operatorId", operatorID)
+
+	res := &dto.AdminResetPasswordResponse{}
+	if req.Password == "" {
+		res.TemporaryPassword = password
+	}
+	return res, nil
+}
+
+// ResendVerification 重新发送邮箱验证邮件；邮箱不存在或已验证时同样返回成功
+func (s *UserApplicationService) ResendVerification(ctx context.Context, req *dto.ResendVerificationRequest, client dto.ClientInfo) error {
+	if err := s.limitMail(ctx, "email_verify", req.Email, client); err != nil {
+		return err
+	}
+	user, err := s.userDomainService.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		return nil
+	}
+	return s.sendVerification(user)
+}
+
+// VerifyEmail 使用邮箱验证令牌确认邮箱归属
+func (s *UserApplicationService) VerifyEmail(ctx context.Context, req *dto.VerifyEmailRequest) error {
+	user, err := s.consumeActionToken(ctx, req.Token, auth.TokenUseEmailVerify, func(u *entity.User) string { return u.Email })
+	if err != nil {
+		return err
+	}
+	if err := s.userDomainService.MarkEmailVerified(ctx, user); err != nil {
+		return err
+	}
+	logger.Info("audit:email_verified", "userId", user.ID)
+	return nil
+}
+
+// limitMail 发信类接口限流：邮箱是否存在均计数，避免借此枚举账号或对他人邮箱刷信
+func (s *UserApplicationService) limitMail(ctx context.Context, action, email string, client dto.ClientInfo) error {
+	decision, err := s.account.Limiter.Allow(ctx, email, client.IP)
+	if err != nil {
+		logger.Warn("mail_limiter_failed", "error", err)
+	}
+	if !decision.Allowed {
+		logger.Warn("audit:mail_throttled", "action", action, "email", email, "ip", client.IP)
+		return throttleError(decision)
+	}
+	return nil
+}
+
+// sendVerification 为邮箱尚未验证的用户发送验证邮件；令牌绑定当前邮箱，邮箱变更后失效
+func (s *UserApplicationService) sendVerification(user *entity.User) error {
+	if user.Email == "" || user.EmailVerifiedAt != nil {
+		return nil
+	}
+	token, err := auth.GenerateActionToken(user.ID, user.Username, auth.TokenUseEmailVerify, fingerprint(user.Email), s.account.VerifyTTL)
+	if err != nil {
+		return err
+	}
+	link, err := withToken(s.account.VerifyURL, token)
+	if err != nil {
+		return err
+	}
+	return s.sendMail(mailer.TemplateVerifyEmail, user, link, s.account.VerifyTTL)
+}
+
+// consumeActionToken 校验邮件令牌（签名、用途、是否已使用、账号状态摘要）并将其作废；state 取签发时参与摘要的字段
+func (s *UserApplicationService) consumeActionToken(ctx context.Context, raw, use string, state func(*entity.User) string) (*entity.User, error) {
+	user, claims, err := s.verifyActionToken(ctx, raw, use, state)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.tokenDomainService.RevokeAccessToken(ctx, claims); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// verifyActionToken 校验邮件令牌但不作废，由调用方在操作成功后作废
+func (s *UserApplicationService) verifyActionToken(ctx context.Context, raw, use string, state func(*entity.User) string) (*entity.User, *auth.Claims, error) {
+	claims, err := auth.ParseToken(raw)
+	if err != nil || claims.TokenUse != use {
+		return nil, nil, errorx.NewWithCode(errorx.ErrUserInvalidToken)
+	}
+	revoked, err := s.tokenDomainService.IsRevoked(ctx, claims)
+	if err != nil {
+		return nil, nil, err
+	}
+	if revoked {
+		return nil, nil, errorx.NewWithCode(errorx.ErrUserInvalidToken)
+	}
+	user, err := s.userDomainService.GetUserByID(ctx, claims.UserID)
+	if err != nil || claims.Fingerprint != fingerprint(state(user)) {
+		return nil, nil, errorx.NewWithCode(errorx.ErrUserInvalidToken)
+	}
+	return user, claims, nil
+}
+
+// sendMail 渲染模板后异步发送，避免响应耗时暴露账号是否存在
+func (s *UserApplicationService) sendMail(template string, user *entity.User, link string, ttl time.Duration) error {
+	msg, err := s.account.Templates.Render(template, user.Email, mailData{
+		AppName:   s.account.AppName,
+		Username:  user.Username,
+		Email:     user.Email,
+		Link:      link,
+		ExpiresIn: humanDuration(ttl),
+	})
+	if err != nil {
+		return err
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := s.account.Mailer.Send(ctx, msg); err != nil {
+			logger.Error("mail_send_failed", "template", template, "userId", user.ID, "error", err)
+		}
+	}()
+	return nil
+}
+
 // GetProfile 获取用户资料
 func (s *UserApplicationService) GetProfile(ctx context.Context, userID uint) (*dto.UserResponse, error) {
 	user, err := s.userDomainService.GetUserByID(ctx, userID)
@@ -67,7 +834,129 @@ func (s *UserApplicationService) GetProfile(ctx context.Context, userID uint) (*
 	return s.entityToResponse(user), nil
 }
 
+// CompleteExternalLogin 外部身份认证通过后完成登录；已启用两步验证时同样只返回临时令牌
+func (s *UserApplicationService) CompleteExternalLogin(ctx context.Context, user *entity.User, client dto.ClientInfo) (*dto.LoginResponse, error) {
+	mfaEnabled, err := s.mfaDomainService.IsEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if mfaEnabled {
+		mfaToken, err := auth.GenerateMFAToken(user.ID, user.Username)
+		if err != nil {
+			return nil, err
+		}
+		return &dto.LoginResponse{MFARequired: true, MFAToken: mfaToken, User: *s.entityToResponse(user)}, nil
+	}
+	return s.issueLogin(ctx, user, client, entity.LoginMethodFederated)
+}
+
+// issueLogin 签发访问令牌 + 刷新令牌，记录登录会话与登录日志
+func (s *UserApplicationService) issueLogin(ctx context.Context, user *entity.User, client dto.ClientInfo, method string) (*dto.LoginResponse, error) {
+	pair, err := s.tokenDomainService.IssueTokenPair(ctx, user, client.IP, client.UserAgent)
+	if err != nil {
+		return nil, err
+	}
+	s.recordLogin(ctx, method, user.Username, user, client, "")
+
+	return &dto.LoginResponse{
+		Token:           pair.AccessToken,
+		RefreshToken:    pair.RefreshToken,
+		ExpiresIn:       pair.ExpiresIn,
+		PasswordExpired: s.userDomainService.PasswordExpired(user),
+		User:            *s.entityToResponse(user),
+	}, nil
+}
+
+// recordLogin 写入登录日志，reason 为空表示成功
+func (s *UserApplicationService) recordLogin(ctx context.Context, method, username string, user *entity.User, client dto.ClientInfo, reason string) {
+	s.loginLogDomainService.Record(ctx, service.LoginAttempt{
+		Username:  username,
+		Method:    method,
+		Success:   reason == "",
+		Reason:    reason,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+	}, user)
+}
+
+// failureReason 认证失败对应的登录日志原因
+func failureReason(err error) string {
+	if appErr, ok := err.(*errorx.Error); ok {
+		switch appErr.Code {
+		case errorx.ErrUserNotFound:
+			return entity.LoginReasonUserNotFound
+		case errorx.ErrUserInvalidPassword:
+			return entity.LoginReasonInvalidPassword
+		}
+	}
+	return entity.LoginReasonError
+}
+
+func throttleReason(d loginguard.Decision) string {
+	if d.Locked {
+		return entity.LoginReasonLocked
+	}
+	return entity.LoginReasonThrottled
+}
+
+// throttleError 锁定 / 退避统一返回 429，并在 Data 中携带需等待的秒数
+func throttleError(d loginguard.Decision) *errorx.Error {
+	retryAfter := map[string]int64{"retryAfter": int64(math.Ceil(d.RetryAfter.Seconds()))}
+	if d.Locked {
+		return errorx.NewWithCode(errorx.ErrUserLocked, retryAfter)
+	}
+	return errorx.NewWithCode(errorx.ErrTooManyRequest, retryAfter)
+}
+
 // entityToResponse 将实体转换为响应DTO
 func (s *UserApplicationService) entityToResponse(user *entity.User) *dto.UserResponse {
-	return &dto.UserResponse{ID: user.ID, Username: user.Username, Email: user.Email, IsActive: user.Status == 0}
+	return &dto.UserResponse{ID: user.ID, Username: user.Username, Email: user.Email, EmailVerified: user.EmailVerifiedAt != nil, IsActive: user.Status == 0}
+}
+
+// fingerprint 账号状态摘要，写入邮件令牌
+func fingerprint(value string) string {
+	return utils.SHA256Hex(value)[:16]
+}
+
+// withToken 在前端页面地址上追加 token 查询参数
+func withToken(page, token string) (string, error) {
+	u, err := url.Parse(page)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// humanDuration 邮件中展示的有效期
+func humanDuration(d time.Duration) string {
+	if d < time.Hour {
+		return fmt.Sprintf("%d 分钟", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%d 小时", int(d.Hours()))
+}
+
+func accessTokenToItem(t *authEntity.PersonalAccessToken) *dto.AccessTokenItem {
+	item := &dto.AccessTokenItem{ID: t.ID, Name: t.Name, Prefix: t.Prefix, Scopes: t.ScopeList(), CreatedAt: t.CreatedAt.Unix()}
+	if t.ExpiresAt != nil {
+		item.ExpiresAt = t.ExpiresAt.Unix()
+	}
+	if t.LastUsedAt != nil {
+		item.LastUsedAt = t.LastUsedAt.Unix()
+	}
+	return item
+}
+
+func sessionToItem(s *authEntity.Session) *dto.SessionItem {
+	return &dto.SessionItem{
+		ID:         s.SessionID,
+		Device:     s.Device,
+		IP:         s.IP,
+		UserAgent:  s.UserAgent,
+		CreatedAt:  s.CreatedAt.Unix(),
+		LastSeenAt: s.LastSeenAt.Unix(),
+		ExpiresAt:  s.ExpiresAt.Unix(),
+	}
 }
@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sine-io/sinx/application/user/dto"
+	authEntity "github.com/sine-io/sinx/domain/auth/entity"
+	authService "github.com/sine-io/sinx/domain/auth/service"
+	"github.com/sine-io/sinx/domain/user/entity"
+	"github.com/sine-io/sinx/domain/user/repository"
+	"github.com/sine-io/sinx/domain/user/repository/usertest"
+	"github.com/sine-io/sinx/domain/user/service"
+	"github.com/sine-io/sinx/pkg/auth"
+	"github.com/sine-io/sinx/pkg/config"
+	"github.com/sine-io/sinx/pkg/datascope"
+	"github.com/sine-io/sinx/pkg/errorx"
+	"github.com/sine-io/sinx/pkg/logger"
+	"github.com/sine-io/sinx/pkg/loginguard"
+	"github.com/sine-io/sinx/pkg/passwordpolicy"
+	"github.com/sine-io/sinx/pkg/permissions"
+	"github.com/sine-io/sinx/pkg/totp"
+	"github.com/sine-io/sinx/pkg/utils"
+)
+
+// stubPerms 以用户表中的 UserType 判定超级管理员；scopes 为操作人的数据范围，未设置时不限制
+type stubPerms struct {
+	repo   *usertest.UserRepo
+	scopes map[uint]*datascope.Scope
+}
+
+func (p *stubPerms) GetUserPermSet(_ context.Context, _ uint) (*permissions.PermSet, error) {
+	return permissions.NewPermSet(nil), nil
+}
+
+func (p *stubPerms) IsSuperAdmin(_ context.Context, userID uint) bool {
+	u, ok := p.repo.Data[userID]
+	return ok && u.Status == 0 && u.IsSuperAdmin()
+}
+
+func (p *stubPerms) EnsureInDataScope(_ context.Context, operatorID uint, target *entity.User) error {
+	if !p.scopes[operatorID].Allows(target.DeptID, target.ID) {
+		return errorx.New(errorx.ErrForbidden, "user is outside your data scope")
+	}
+	return nil
+}
+
+// 目标用户不在操作人的数据范围内时，重置密码 / 两步验证、强制下线与代登录均被拒绝
+func TestTargetOutsideDataScope(t *testing.T) {
+	ctx := context.Background()
+	admin := &entity.User{ID: 1, Username: "admin", DeptID: 10}
+	peer := &entity.User{ID: 2, Username: "peer", Password: "hash", DeptID: 10}
+	other := &entity.User{ID: 3, Username: "other", Password: "hash", DeptID: 20}
+	svc, repo, _ := newAccountService(t, admin, peer, other)
+	svc.perms = &stubPerms{repo: repo, scopes: map[uint]*datascope.Scope{admin.ID: {DeptIDs: []uint{10}}}}
+
+	forbidden := func(name string, err error) {
+		t.Helper()
+		if appErr, ok := err.(*errorx.Error); !ok || appErr.Code != errorx.ErrForbidden {
+			t.Fatalf("%s: expected forbidden, got %v", name, err)
+		}
+	}
+	_, err := svc.AdminResetPassword(ctx, admin.ID, &dto.AdminResetPasswordRequest{UserID: other.ID, Password: "Temp@2024!x"})
+	forbidden("reset password", err)
+	if repo.Data[other.ID].Password != "hash" {
+		t.Fatal("password outside scope must stay untouched")
+	}
+	forbidden("reset mfa", svc.ResetMFA(ctx, admin.ID, &dto.MFAResetRequest{UserID: other.ID}))
+	forbidden("force logout", svc.ForceLogout(ctx, admin.ID, &dto.ForceLogoutRequest{UserID: other.ID}))
+	_, err = svc.Impersonate(ctx, &auth.Claims{UserID: admin.ID, Username: admin.Username}, &dto.ImpersonateRequest{UserID: other.ID})
+	forbidden("impersonate", err)
+
+	if _, err := svc.AdminResetPassword(ctx, admin.ID, &dto.AdminResetPasswordRequest{UserID: peer.ID, Password: "Temp@2024!xyz"}); err != nil {
+		t.Fatalf("reset password inside scope: %v", err)
+	}
+}
+
+func TestSuperAdminTargetRequiresSuperAdmin(t *testing.T) {
+	ctx := context.Background()
+	root := &entity.User{Username: "root", Password: "hash", UserType: entity.UserTypeSuperAdmin}
+	root.ID = 1
+	admin := &entity.User{Username: "admin", Password: "hash", UserType: entity.UserTypeNormal}
+	admin.ID = 2
+	repo := usertest.NewUserRepo(root, admin)
+	svc := NewUserApplicationService(service.NewUserDomainService(repo, nil, "", nil), nil, nil, nil, nil, nil, &stubPerms{repo: repo}, AccountMail{}, 0)
+
+	assertForbidden := func(name string, err error) {
+		t.Helper()
+		if appErr, ok := err.(*errorx.Error); !ok || appErr.Code != errorx.ErrForbidden {
+			t.Fatalf("%s: expected forbidden, got %v", name, err)
+		}
+	}
+
+	_, err := svc.AdminResetPassword(ctx, admin.ID, &dto.AdminResetPasswordRequest{UserID: root.ID, Password: "Temp@2024!x"})
+	assertForbidden("reset password", err)
+	if repo.Data[root.ID].Password != "hash" || repo.Data[root.ID].MustChangePassword {
+		t.Fatalf("super admin password must stay untouched")
+	}
+	assertForbidden("reset mfa", svc.ResetMFA(ctx, admin.ID, &dto.MFAResetRequest{UserID: root.ID}))
+	assertForbidden("force logout", svc.ForceLogout(ctx, admin.ID, &dto.ForceLogoutRequest{UserID: root.ID}))
+	_, err = svc.Impersonate(ctx, &auth.Claims{UserID: admin.ID, Username: admin.Username}, &dto.ImpersonateRequest{UserID: root.ID})
+	assertForbidden("impersonate", err)
+}
+
+type memAccessTokenRepo struct {
+	tokens map[string]*authEntity.PersonalAccessToken
+}
+
+func (m *memAccessTokenRepo) Create(_ context.Context, t *authEntity.PersonalAccessToken) error {
+	m.tokens[t.TokenHash] = t
+	return nil
+}
+func (m *memAccessTokenRepo) GetByHash(_ context.Context, hash string) (*authEntity.PersonalAccessToken, error) {
+	return m.tokens[hash], nil
+}
+func (m *memAccessTokenRepo) ListByUser(_ context.Context, _ uint) ([]*authEntity.PersonalAccessToken, error) {
+	return nil, nil
+}
+func (m *memAccessTokenRepo) Delete(_ context.Context, _, _ uint) (bool, error) { return false, nil }
+func (m *memAccessTokenRepo) TouchLastUsed(_ context.Context, _ uint, _ time.Time) error {
+	return nil
+}
+
+// 外部身份创建的用户不受本地密码有效期约束；密码过期的用户不能使用个人访问令牌
+func TestPasswordExpiryGate(t *testing.T) {
+	ctx := context.Background()
+	created := time.Now().Add(-48 * time.Hour)
+	repo := usertest.NewUserRepo()
+	passwords := service.NewPasswordPolicyService(&passwordpolicy.Policy{MaxAge: 24 * time.Hour}, nil)
+	users := service.NewUserDomainService(repo, nil, "", passwords)
+	tokens := &memAccessTokenRepo{tokens: map[string]*authEntity.PersonalAccessToken{}}
+	svc := NewUserApplicationService(users, nil, nil, authService.NewAccessTokenDomainService(tokens), nil, nil, &stubPerms{repo: repo}, AccountMail{}, 0)
+
+	federated, err := users.CreateFederatedUser(ctx, "alice", "alice@corp.test", "Alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	federated.CreatedAt = created
+	if users.PasswordExpired(federated) {
+		t.Fatal("federated user without a local password must not expire")
+	}
+	// 设置本地密码后按本地用户处理
+	if _, err := users.SetTemporaryPassword(ctx, federated, "Temp@2024!x"); err != nil {
+		t.Fatal(err)
+	}
+	if federated.AuthSource != entity.AuthSourceLocal || !users.PasswordExpired(federated) {
+		t.Fatalf("local password must be subject to the policy, got source %q", federated.AuthSource)
+	}
+
+	local := &entity.User{Username: "bob", Password: "hash", CreatedAt: created}
+	_ = repo.Create(ctx, local)
+	raw, _, err := svc.accessTokenDomainService.Create(ctx, local.ID, "ci", []string{"user:list"}, 0, permissions.NewPermSet(map[string]struct{}{"user:list": {}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = svc.AuthenticateAPIKey(ctx, raw)
+	if appErr, ok := err.(*errorx.Error); !ok || appErr.Code != errorx.ErrPasswordExpired {
+		t.Fatalf("expected ErrPasswordExpired, got %v", err)
+	}
+	now := time.Now()
+	local.PasswordChangedAt = &now
+	if claims, _, err := svc.AuthenticateAPIKey(ctx, raw); err != nil || claims.UserID != local.ID {
+		t.Fatalf("fresh password must allow the token: %v", err)
+	}
+}
+
+type memRefreshRepo struct {
+	data map[string]*authEntity.RefreshToken
+}
+
+func (m *memRefreshRepo) Create(_ context.Context, t *authEntity.RefreshToken) error {
+	m.data[t.TokenHash] = t
+	return nil
+}
+func (m *memRefreshRepo) GetByHash(_ context.Context, hash string) (*authEntity.RefreshToken, error) {
+	return m.data[hash], nil
+}
+func (m *memRefreshRepo) MarkUsed(_ context.Context, _ string) (bool, error) { return true, nil }
+func (m *memRefreshRepo) RevokeFamily(_ context.Context, _ string) error     { return nil }
+func (m *memRefreshRepo) RevokeUser(_ context.Context, _ uint) error         { return nil }
+
+// memSessionRepo 会话存储，revokeErr 非空时模拟吊销失败
+type memSessionRepo struct {
+	data      map[string]*authEntity.Session
+	revokeErr error
+}
+
+func (m *memSessionRepo) Create(_ context.Context, s *authEntity.Session) error {
+	m.data[s.SessionID] = s
+	return nil
+}
+func (m *memSessionRepo) GetBySessionID(_ context.Context, sid string) (*authEntity.Session, error) {
+	return m.data[sid], nil
+}
+func (m *memSessionRepo) ListActiveByUser(_ context.Context, _ uint, _ time.Time) ([]*authEntity.Session, error) {
+	return nil, nil
+}
+func (m *memSessionRepo) ListActive(_ context.Context, _ time.Time, _, _ int) ([]*authEntity.Session, int64, error) {
+	return nil, 0, nil
+}
+func (m *memSessionRepo) Touch(_ context.Context, _ string, _, _ time.Time) error { return nil }
+func (m *memSessionRepo) Revoke(_ context.Context, _ string) error                { return nil }
+func (m *memSessionRepo) RevokeUser(_ context.Context, _ uint) error              { return m.revokeErr }
+
+// newAccountService 带令牌、会话与登录节流的用户应用服务，密码至少 12 位
+func newAccountService(t *testing.T, users ...*entity.User) (*UserApplicationService, *usertest.UserRepo, *memSessionRepo) {
+	t.Helper()
+	_ = config.LoadEnv()
+	_ = logger.Init()
+	repo := usertest.NewUserRepo(users...)
+	sessions := &memSessionRepo{data: map[string]*authEntity.Session{}}
+	tokens := authService.NewTokenDomainService(&memRefreshRepo{data: map[string]*authEntity.RefreshToken{}}, sessions, repo, auth.NewMemoryRevocationStore(time.Hour), time.Hour)
+	passwords := service.NewPasswordPolicyService(&passwordpolicy.Policy{MinLength: 12}, nil)
+	guard := loginguard.NewGuard(loginguard.Policy{Window: time.Minute, MaxUserFailures: 3, LockoutDuration: time.Minute, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}, loginguard.NewMemoryStore())
+	mfa := service.NewMFADomainService(usertest.NewMFARepo(), "sinx")
+	logs := service.NewLoginLogDomainService(memLoginLogRepo{}, repo, nil)
+	svc := NewUserApplicationService(service.NewUserDomainService(repo, nil, "", passwords), tokens, mfa, nil, logs, guard, &stubPerms{repo: repo}, AccountMail{}, 0)
+	return svc, repo, sessions
+}
+
+type memLoginLogRepo struct{}
+
+func (memLoginLogRepo) Create(_ context.Context, _ *entity.LoginLog) error { return nil }
+func (memLoginLogRepo) List(_ context.Context, _ repository.LoginLogFilter, _, _ int) ([]*entity.LoginLog, int64, error) {
+	return nil, 0, nil
+}
+
+// 启用两步验证后登录只返回临时令牌；以验证码换取正式令牌，临时令牌仅可使用一次
+func TestLoginWithMFA(t *testing.T) {
+	ctx := context.Background()
+	hash, _ := utils.HashPassword("Correct-Horse-42")
+	alice := &entity.User{ID: 1, Username: "alice", Password: hash}
+	svc, _, _ := newAccountService(t, alice)
+	client := dto.ClientInfo{IP: "10.0.0.1"}
+
+	enroll, err := svc.BeginMFAEnrollment(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := totp.CodeAt(enroll.Secret, totp.Step(time.Now()))
+	if _, err := svc.ActivateMFA(ctx, alice.ID, &dto.MFAActivateRequest{Code: code}); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := svc.Login(ctx, &dto.LoginRequest{Username: "alice", Password: "Correct-Horse-42"}, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.MFARequired || res.MFAToken == "" || res.Token != "" || res.RefreshToken != "" {
+		t.Fatalf("login must return only an mfa token: %+v", res)
+	}
+	if _, err := auth.ParseToken(res.MFAToken); err != nil {
+		t.Fatal(err)
+	}
+
+	// 已用于激活的验证码不能再次使用
+	_, err = svc.VerifyMFA(ctx, &dto.MFAVerifyRequest{MFAToken: res.MFAToken, Code: code}, client)
+	if appErr, ok := err.(*errorx.Error); !ok || appErr.Code != errorx.ErrMFAInvalidCode {
+		t.Fatalf("replayed code must be rejected, got %v", err)
+	}
+	time.Sleep(5 * time.Millisecond) // 等待失败后的退避延迟
+	next, _ := totp.CodeAt(enroll.Secret, totp.Step(time.Now())+1)
+	out, err := svc.VerifyMFA(ctx, &dto.MFAVerifyRequest{MFAToken: res.MFAToken, Code: next}, client)
+	if err != nil || out.Token == "" || out.RefreshToken == "" || out.MFARequired {
+		t.Fatalf("verify mfa: %v %+v", err, out)
+	}
+	_, err = svc.VerifyMFA(ctx, &dto.MFAVerifyRequest{MFAToken: res.MFAToken, Code: next}, client)
+	if appErr, ok := err.(*errorx.Error); !ok || appErr.Code != errorx.ErrUserTokenRevoked {
+		t.Fatalf("mfa token must be single use, got %v", err)
+	}
+
+	// 管理员重置后直接以密码登录
+	if err := svc.ResetMFA(ctx, 2, &dto.MFAResetRequest{UserID: alice.ID}); err != nil {
+		t.Fatal(err)
+	}
+	res, err = svc.Login(ctx, &dto.LoginRequest{Username: "alice", Password: "Correct-Horse-42"}, client)
+	if err != nil || res.MFARequired || res.Token == "" {
+		t.Fatalf("login after reset must issue tokens: %v %+v", err, res)
+	}
+}
+
+// 新密码不符合策略时找回链接仍可使用；成功后链接作废，吊销旧会话失败须报错
+func TestResetPasswordKeepsTokenOnPolicyFailure(t *testing.T) {
+	ctx := context.Background()
+	alice := &entity.User{ID: 1, Username: "alice", Password: "old-hash"}
+	svc, repo, sessions := newAccountService(t, alice)
+	token, err := auth.GenerateActionToken(alice.ID, alice.Username, auth.TokenUsePasswordReset, fingerprint(alice.Password), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.ResetPassword(ctx, &dto.ResetPasswordRequest{Token: token, NewPassword: "short"}); err == nil {
+		t.Fatal("weak password must be rejected")
+	}
+	if repo.Data[1].Password != "old-hash" {
+		t.Fatal("password must stay unchanged")
+	}
+	if err := svc.ResetPassword(ctx, &dto.ResetPasswordRequest{Token: token, NewPassword: "Correct-Horse-42"}); err != nil {
+		t.Fatalf("link must survive a policy failure: %v", err)
+	}
+	err = svc.ResetPassword(ctx, &dto.ResetPasswordRequest{Token: token, NewPassword: "Another-Horse-42"})
+	if appErr, ok := err.(*errorx.Error); !ok || appErr.Code != errorx.ErrUserInvalidToken {
+		t.Fatalf("used link must be rejected, got %v", err)
+	}
+
+	sessions.revokeErr = errors.New("redis down")
+	token, _ = auth.GenerateActionToken(alice.ID, alice.Username, auth.TokenUsePasswordReset, fingerprint(repo.Data[1].Password), time.Hour)
+	if err := svc.ResetPassword(ctx, &dto.ResetPasswordRequest{Token: token, NewPassword: "Third-Horse-42!"}); err == nil {
+		t.Fatal("failing to revoke sessions must surface as an error")
+	}
+}
+
+// 修改密码时旧密码错误计入登录失败次数，达到上限后锁定
+func TestChangePasswordThrottlesOldPassword(t *testing.T) {
+	ctx := context.Background()
+	hash, _ := utils.HashPassword("Correct-Horse-42")
+	alice := &entity.User{ID: 1, Username: "alice", Password: hash}
+	svc, repo, _ := newAccountService(t, alice)
+	client := dto.ClientInfo{IP: "10.0.0.1"}
+	req := &dto.ChangePasswordRequest{OldPassword: "wrong", NewPassword: "Another-Horse-42"}
+
+	var err error
+	for i := 0; i < 3; i++ {
+		_, err = svc.ChangePassword(ctx, alice.ID, req, client)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if appErr, ok := err.(*errorx.Error); !ok || appErr.Code != errorx.ErrUserLocked {
+		t.Fatalf("third failure must lock the account, got %v", err)
+	}
+	req.OldPassword = "Correct-Horse-42"
+	if _, err := svc.ChangePassword(ctx, alice.ID, req, client); err == nil {
+		t.Fatal("locked account must not change its password")
+	}
+	if repo.Data[1].Password != hash {
+		t.Fatal("password must stay unchanged while locked")
+	}
+}
+
+// 管理员重置密码后吊销旧会话失败须报错
+func TestAdminResetPasswordSurfacesRevokeError(t *testing.T) {
+	ctx := context.Background()
+	alice := &entity.User{ID: 1, Username: "alice", Password: "old-hash"}
+	svc, _, sessions := newAccountService(t, alice)
+	sessions.revokeErr = errors.New("redis down")
+	if _, err := svc.AdminResetPassword(ctx, 2, &dto.AdminResetPasswordRequest{UserID: alice.ID}); err == nil {
+		t.Fatal("failing to revoke sessions must surface as an error")
+	}
+}
@@ -0,0 +1,65 @@
+package entity
+
+import "time"
+
+// AccessRequest 角色申请：用户自助申请角色，由该角色的审批人审批，通过后绑定角色
+type AccessRequest struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	UserID        uint       `json:"userId" gorm:"index;not null"`
+	RoleID        uint       `json:"roleId" gorm:"index;not null"`
+	Reason        string     `json:"reason" gorm:"size:500"`
+	Status        string     `json:"status" gorm:"size:20;index"`
+	DurationHours int        `json:"durationHours"`        // 申请的授权时长，0 表示长期
+	ValidUntil    *time.Time `json:"validUntil,omitempty"` // 审批通过后角色绑定的失效时间
+	DeciderID     uint       `json:"deciderId"`            // 审批人
+	DecidedAt     *time.Time `json:"decidedAt,omitempty"`  // 审批 / 撤回时间
+	CreatedAt     time.Time  `json:"createdAt" gorm:"index"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+}
+
+func (AccessRequest) TableName() string { return "access_requests" }
+
+// 申请状态：pending 可流转到其余任一状态，其余状态为终态
+const (
+	AccessStatusPending   = "pending"
+	AccessStatusApproved  = "approved"
+	AccessStatusRejected  = "rejected"
+	AccessStatusCancelled = "cancelled"
+)
+
+// IsPending 是否仍待审批
+func (r *AccessRequest) IsPending() bool { return r.Status == AccessStatusPending }
+
+// AccessRequestEvent 申请的状态变更与评论记录，只追加不修改
+type AccessRequestEvent struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	RequestID  uint      `json:"requestId" gorm:"index;not null"`
+	ActorID    uint      `json:"actorId"`
+	Action     string    `json:"action" gorm:"size:20"` // 见 AccessAction*
+	FromStatus string    `json:"fromStatus" gorm:"size:20"`
+	ToStatus   string    `json:"toStatus" gorm:"size:20"`
+	Comment    string    `json:"comment" gorm:"size:500"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+func (AccessRequestEvent) TableName() string { return "access_request_events" }
+
+// 申请事件
+const (
+	AccessActionSubmit  = "submit"
+	AccessActionApprove = "approve"
+	AccessActionReject  = "reject"
+	AccessActionCancel  = "cancel"
+	AccessActionComment = "comment"
+	AccessActionRevert  = "revert" // 审批通过后绑定角色失败，退回待审批
+)
+
+// RoleApprover 角色审批人
+type RoleApprover struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	RoleID    uint      `json:"roleId" gorm:"uniqueIndex:idx_role_approver;not null"`
+	UserID    uint      `json:"userId" gorm:"uniqueIndex:idx_role_approver;index;not null"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (RoleApprover) TableName() string { return "role_approvers" }
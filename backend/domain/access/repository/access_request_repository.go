@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/sine-io/sinx/domain/access/entity"
+)
+
+// AccessRequestFilter 申请查询条件，零值字段不参与过滤
+type AccessRequestFilter struct {
+	UserID  uint
+	RoleIDs []uint // 限定角色（审批人视角）
+	Status  string
+}
+
+type AccessRequestRepository interface {
+	// Create 创建申请并记录提交事件
+	Create(ctx context.Context, req *entity.AccessRequest, event *entity.AccessRequestEvent) error
+	// GetByID 不存在时返回 nil, nil
+	GetByID(ctx context.Context, id uint) (*entity.AccessRequest, error)
+	// List 按创建时间倒序分页
+	List(ctx context.Context, filter AccessRequestFilter, offset, limit int) ([]*entity.AccessRequest, int64, error)
+	HasPending(ctx context.Context, userID, roleID uint) (bool, error)
+	// Transition 仅当申请仍处于 from 状态时，保存 req 的状态与审批信息并记录事件；返回是否更新成功
+	Transition(ctx context.Context, req *entity.AccessRequest, from string, event *entity.AccessRequestEvent) (bool, error)
+	AddEvent(ctx context.Context, event *entity.AccessRequestEvent) error
+	ListEvents(ctx context.Context, requestID uint) ([]*entity.AccessRequestEvent, error)
+
+	// SetApprovers 以 userIDs 整体替换角色的审批人
+	SetApprovers(ctx context.Context, roleID uint, userIDs []uint) error
+	GetApprovers(ctx context.Context, roleID uint) ([]uint, error)
+	// GetApproverRoles 返回用户可审批的角色
+	GetApproverRoles(ctx context.Context, userID uint) ([]uint, error)
+}
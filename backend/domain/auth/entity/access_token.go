@@ -0,0 +1,35 @@
+package entity
+
+import (
+	"strings"
+	"time"
+)
+
+// PersonalAccessToken 个人访问令牌（供 CI / 内部服务调用 API，仅保存哈希）
+// Scopes 为逗号分隔的权限标识，实际生效权限为 Scopes 与所属用户当前权限的交集
+type PersonalAccessToken struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"userId" gorm:"index;not null"`
+	Name       string     `json:"name" gorm:"size:64;not null"`
+	Prefix     string     `json:"prefix" gorm:"size:16;not null"` // 明文前若干位，便于用户辨认
+	TokenHash  string     `json:"-" gorm:"size:64;uniqueIndex;not null"`
+	Scopes     string     `json:"scopes" gorm:"size:2048;not null"`
+	ExpiresAt  *time.Time `json:"expiresAt"` // 为空表示永不过期
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+func (PersonalAccessToken) TableName() string { return "personal_access_tokens" }
+
+// IsExpired 是否已过期
+func (t *PersonalAccessToken) IsExpired(now time.Time) bool {
+	return t.ExpiresAt != nil && now.After(*t.ExpiresAt)
+}
+
+// ScopeList 权限范围列表
+func (t *PersonalAccessToken) ScopeList() []string {
+	if t.Scopes == "" {
+		return nil
+	}
+	return strings.Split(t.Scopes, ",")
+}
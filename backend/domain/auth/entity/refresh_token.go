@@ -0,0 +1,21 @@
+package entity
+
+import "time"
+
+// RefreshToken 刷新令牌（仅保存哈希，明文只在签发时返回一次）
+// 同一次登录派生出的所有刷新令牌共享一个 FamilyID，用于重放检测时整体吊销
+type RefreshToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"userId" gorm:"index;not null"`
+	FamilyID  string     `json:"familyId" gorm:"size:64;index;not null"`
+	TokenHash string     `json:"-" gorm:"size:64;uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"expiresAt" gorm:"not null"`
+	UsedAt    *time.Time `json:"usedAt"`    // 已轮换（再次出现即视为重放）
+	RevokedAt *time.Time `json:"revokedAt"` // 已吊销
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+func (RefreshToken) TableName() string { return "refresh_tokens" }
+
+// IsExpired 是否已过期
+func (t *RefreshToken) IsExpired(now time.Time) bool { return now.After(t.ExpiresAt) }
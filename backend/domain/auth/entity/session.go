@@ -0,0 +1,25 @@
+package entity
+
+import "time"
+
+// Session 登录会话（设备）；SessionID 与刷新令牌的 FamilyID 相同，并写入访问令牌的 sid
+type Session struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	SessionID  string     `json:"sessionId" gorm:"size:64;uniqueIndex;not null"`
+	UserID     uint       `json:"userId" gorm:"index;not null"`
+	Username   string     `json:"username" gorm:"size:50"`
+	IP         string     `json:"ip" gorm:"size:64"`
+	UserAgent  string     `json:"userAgent" gorm:"size:512"`
+	Device     string     `json:"device" gorm:"size:100"`
+	ExpiresAt  time.Time  `json:"expiresAt" gorm:"not null"` // 随刷新令牌轮换顺延
+	LastSeenAt time.Time  `json:"lastSeenAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+func (Session) TableName() string { return "sessions" }
+
+// IsActive 未吊销且未过期
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
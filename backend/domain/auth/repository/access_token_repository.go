@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/sine-io/sinx/domain/auth/entity"
+)
+
+// AccessTokenRepository 个人访问令牌存储
+type AccessTokenRepository interface {
+	Create(ctx context.Context, token *entity.PersonalAccessToken) error
+	// GetByHash 不存在时返回 (nil, nil)
+	GetByHash(ctx context.Context, tokenHash string) (*entity.PersonalAccessToken, error)
+	ListByUser(ctx context.Context, userID uint) ([]*entity.PersonalAccessToken, error)
+	// Delete 仅删除属于该用户的令牌，返回是否存在
+	Delete(ctx context.Context, userID, id uint) (bool, error)
+	TouchLastUsed(ctx context.Context, id uint, at time.Time) error
+}
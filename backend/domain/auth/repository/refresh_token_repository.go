@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/sine-io/sinx/domain/auth/entity"
+)
+
+// RefreshTokenRepository 刷新令牌存储（Postgres / Redis 两种实现）
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *entity.RefreshToken) error
+	// GetByHash 不存在时返回 (nil, nil)；所属令牌族已吊销时 RevokedAt 非空
+	GetByHash(ctx context.Context, tokenHash string) (*entity.RefreshToken, error)
+	// MarkUsed 原子地标记为已使用，返回 false 表示此前已被使用（并发重放）
+	MarkUsed(ctx context.Context, tokenHash string) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeUser(ctx context.Context, userID uint) error
+}
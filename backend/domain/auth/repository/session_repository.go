@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/sine-io/sinx/domain/auth/entity"
+)
+
+// SessionRepository 登录会话存储
+type SessionRepository interface {
+	Create(ctx context.Context, session *entity.Session) error
+	// GetBySessionID 不存在时返回 (nil, nil)
+	GetBySessionID(ctx context.Context, sessionID string) (*entity.Session, error)
+	// ListActiveByUser 用户未吊销且未过期的会话，按最近活跃倒序
+	ListActiveByUser(ctx context.Context, userID uint, now time.Time) ([]*entity.Session, error)
+	// ListActive 全部在线会话（分页），按最近活跃倒序
+	ListActive(ctx context.Context, now time.Time, offset, limit int) ([]*entity.Session, int64, error)
+	// Touch 更新最近活跃时间；expiresAt 非零时同时顺延过期时间
+	Touch(ctx context.Context, sessionID string, lastSeen, expiresAt time.Time) error
+	Revoke(ctx context.Context, sessionID string) error
+	RevokeUser(ctx context.Context, userID uint) error
+}
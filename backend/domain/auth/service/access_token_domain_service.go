@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sine-io/sinx/domain/auth/entity"
+	"github.com/sine-io/sinx/domain/auth/repository"
+	"github.com/sine-io/sinx/pkg/auth"
+	"github.com/sine-io/sinx/pkg/errorx"
+	"github.com/sine-io/sinx/pkg/logger"
+	"github.com/sine-io/sinx/pkg/permissions"
+	"github.com/sine-io/sinx/pkg/utils"
+)
+
+// lastUsedInterval 最近使用时间的最小刷新间隔，避免每次请求都写库
+const lastUsedInterval = time.Minute
+
+type AccessTokenDomainService struct {
+	repo repository.AccessTokenRepository
+}
+
+func NewAccessTokenDomainService(repo repository.AccessTokenRepository) *AccessTokenDomainService {
+	return &AccessTokenDomainService{repo: repo}
+}
+
+// Create 创建个人访问令牌，scopes 必须是 AllPerms 的子集且不超出所属用户当前拥有的权限
+// 返回的明文令牌只在此时可见；ttl 为 0 表示永不过期
+func (s *AccessTokenDomainService) Create(ctx context.Context, userID uint, name string, scopes []string, ttl time.Duration, granted *permissions.PermSet) (string, *entity.PersonalAccessToken, error) {
+	normalized, err := normalizeScopes(scopes, granted)
+	if err != nil {
+		return "", nil, err
+	}
+
+	secret, err := utils.RandomToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+	raw := auth.APIKeyPrefix + secret
+
+	token := &entity.PersonalAccessToken{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    raw[:len(auth.APIKeyPrefix)+4],
+		TokenHash: utils.SHA256Hex(raw),
+		Scopes:    strings.Join(normalized, ","),
+	}
+	if ttl > 0 {
+		exp := time.Now().Add(ttl)
+		token.ExpiresAt = &exp
+	}
+	if err := s.repo.Create(ctx, token); err != nil {
+		return "", nil, err
+	}
+	return raw, token, nil
+}
+
+// Authenticate 校验明文令牌，返回令牌记录；顺带刷新最近使用时间
+func (s *AccessTokenDomainService) Authenticate(ctx context.Context, raw string) (*entity.PersonalAccessToken, error) {
+	if !strings.HasPrefix(raw, auth.APIKeyPrefix) {
+		return nil, errorx.NewWithCode(errorx.ErrUserInvalidToken)
+	}
+	token, err := s.repo.GetByHash(ctx, utils.SHA256Hex(raw))
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, errorx.NewWithCode(errorx.ErrUserInvalidToken)
+	}
+	now := time.Now()
+	if token.IsExpired(now) {
+		return nil, errorx.NewWithCode(errorx.ErrUserTokenExpired)
+	}
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= lastUsedInterval {
+		if err := s.repo.TouchLastUsed(ctx, token.ID, now); err != nil {
+			logger.Warn("access_token_touch_failed", "id", token.ID, "error", err)
+		}
+		token.LastUsedAt = &now
+	}
+	return token, nil
+}
+
+// List 列出用户的全部个人访问令牌
+func (s *AccessTokenDomainService) List(ctx context.Context, userID uint) ([]*entity.PersonalAccessToken, error) {
+	return s.repo.ListByUser(ctx, userID)
+}
+
+// Delete 删除用户自己的令牌
+func (s *AccessTokenDomainService) Delete(ctx context.Context, userID, id uint) error {
+	ok, err := s.repo.Delete(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errorx.NewWithCode(errorx.ErrNotFound)
+	}
+	return nil
+}
+
+// normalizeScopes 去重排序并校验权限范围
+func normalizeScopes(scopes []string, granted *permissions.PermSet) ([]string, error) {
+	known := make(map[string]struct{}, len(permissions.AllPerms))
+	for _, p := range permissions.AllPerms {
+		known[p] = struct{}{}
+	}
+	seen := make(map[string]struct{}, len(scopes))
+	res := make([]string, 0, len(scopes))
+	for _, sc := range scopes {
+		sc = strings.TrimSpace(sc)
+		if _, dup := seen[sc]; dup {
+			continue
+		}
+		if _, ok := known[sc]; !ok {
+			return nil, errorx.New(errorx.ErrInvalidParam, "unknown scope: "+sc)
+		}
+		if !granted.Has(sc) {
+			return nil, errorx.New(errorx.ErrForbidden, "scope exceeds owner permissions: "+sc)
+		}
+		seen[sc] = struct{}{}
+		res = append(res, sc)
+	}
+	if len(res) == 0 {
+		return nil, errorx.New(errorx.ErrInvalidParam, "at least one scope is required")
+	}
+	sort.Strings(res)
+	return res, nil
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sine-io/sinx/domain/auth/entity"
+	"github.com/sine-io/sinx/pkg/config"
+	"github.com/sine-io/sinx/pkg/errorx"
+	"github.com/sine-io/sinx/pkg/logger"
+	"github.com/sine-io/sinx/pkg/permissions"
+)
+
+type memAccessTokenRepo struct {
+	data   map[uint]*entity.PersonalAccessToken
+	nextID uint
+}
+
+func (m *memAccessTokenRepo) Create(_ context.Context, t *entity.PersonalAccessToken) error {
+	m.nextID++
+	t.ID = m.nextID
+	m.data[t.ID] = t
+	return nil
+}
+func (m *memAccessTokenRepo) GetByHash(_ context.Context, hash string) (*entity.PersonalAccessToken, error) {
+	for _, t := range m.data {
+		if t.TokenHash == hash {
+			return t, nil
+		}
+	}
+	return nil, nil
+}
+func (m *memAccessTokenRepo) ListByUser(_ context.Context, userID uint) ([]*entity.PersonalAccessToken, error) {
+	var res []*entity.PersonalAccessToken
+	for _, t := range m.data {
+		if t.UserID == userID {
+			res = append(res, t)
+		}
+	}
+	return res, nil
+}
+func (m *memAccessTokenRepo) Delete(_ context.Context, userID, id uint) (bool, error) {
+	t, ok := m.data[id]
+	if !ok || t.UserID != userID {
+		return false, nil
+	}
+	delete(m.data, id)
+	return true, nil
+}
+func (m *memAccessTokenRepo) TouchLastUsed(_ context.Context, id uint, at time.Time) error {
+	m.data[id].LastUsedAt = &at
+	return nil
+}
+
+func TestAccessTokenScopesAndExpiry(t *testing.T) {
+	_ = config.LoadEnv()
+	_ = logger.Init()
+	ctx := context.Background()
+	repo := &memAccessTokenRepo{data: map[uint]*entity.PersonalAccessToken{}}
+	svc := NewAccessTokenDomainService(repo)
+	granted := permissions.NewPermSet(map[string]struct{}{"user:list": {}, "role:list": {}})
+
+	// 超出用户自身权限
+	_, _, err := svc.Create(ctx, 1, "ci", []string{"user:list", "user:delete"}, 0, granted)
+	if appErr, ok := err.(*errorx.Error); !ok || appErr.Code != errorx.ErrForbidden {
+		t.Fatalf("expected forbidden scope, got %v", err)
+	}
+	// 未知权限标识
+	_, _, err = svc.Create(ctx, 1, "ci", []string{"nope"}, 0, granted)
+	if appErr, ok := err.(*errorx.Error); !ok || appErr.Code != errorx.ErrInvalidParam {
+		t.Fatalf("expected invalid scope, got %v", err)
+	}
+
+	raw, tok, err := svc.Create(ctx, 1, "ci", []string{"user:list", "user:list"}, time.Hour, granted)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if tok.Scopes != "user:list" {
+		t.Fatalf("scopes not normalized: %q", tok.Scopes)
+	}
+	got, err := svc.Authenticate(ctx, raw)
+	if err != nil || got.ID != tok.ID || got.LastUsedAt == nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	if _, err := svc.Authenticate(ctx, raw+"x"); err == nil {
+		t.Fatalf("tampered token accepted")
+	}
+
+	past := time.Now().Add(-time.Minute)
+	tok.ExpiresAt = &past
+	_, err = svc.Authenticate(ctx, raw)
+	if appErr, ok := err.(*errorx.Error); !ok || appErr.Code != errorx.ErrUserTokenExpired {
+		t.Fatalf("expected expired, got %v", err)
+	}
+}
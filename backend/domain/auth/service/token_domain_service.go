@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/sine-io/sinx/domain/auth/entity"
+	"github.com/sine-io/sinx/domain/auth/repository"
+	userEntity "github.com/sine-io/sinx/domain/user/entity"
+	userRepo "github.com/sine-io/sinx/domain/user/repository"
+	"github.com/sine-io/sinx/pkg/auth"
+	"github.com/sine-io/sinx/pkg/errorx"
+	"github.com/sine-io/sinx/pkg/logger"
+	"github.com/sine-io/sinx/pkg/utils"
+)
+
+// TokenPair 访问令牌 + 刷新令牌
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int64 // 访问令牌剩余秒数
+}
+
+// sessionTouchInterval 会话最近活跃时间的最小更新间隔，避免每个请求都写库
+const sessionTouchInterval = time.Minute
+
+type TokenDomainService struct {
+	refreshRepo repository.RefreshTokenRepository
+	sessionRepo repository.SessionRepository
+	userRepo    userRepo.UserRepository
+	revocations auth.RevocationStore
+	refreshTTL  time.Duration
+}
+
+func NewTokenDomainService(refreshRepo repository.RefreshTokenRepository, sessionRepo repository.SessionRepository, userRepo userRepo.UserRepository, revocations auth.RevocationStore, refreshTTL time.Duration) *TokenDomainService {
+	return &TokenDomainService{refreshRepo: refreshRepo, sessionRepo: sessionRepo, userRepo: userRepo, revocations: revocations, refreshTTL: refreshTTL}
+}
+
+// IssueTokenPair 登录成功后签发令牌对：开启新的令牌族并记录登录会话
+func (s *TokenDomainService) IssueTokenPair(ctx context.Context, user *userEntity.User, ip, userAgent string) (*TokenPair, error) {
+	familyID, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, errorx.New(errorx.ErrInternalServer, "failed to generate token")
+	}
+	if r := []rune(userAgent); len(r) > 512 {
+		userAgent = string(r[:512])
+	}
+	now := time.Now()
+	session := &entity.Session{
+		SessionID:  familyID,
+		UserID:     user.ID,
+		Username:   user.Username,
+		IP:         ip,
+		UserAgent:  userAgent,
+		Device:     utils.DeviceName(userAgent),
+		ExpiresAt:  now.Add(s.refreshTTL),
+		LastSeenAt: now,
+	}
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+	return s.issue(ctx, user, familyID)
+}
+
+// Refresh 使用刷新令牌换取新令牌对，旧刷新令牌随即失效（轮换）
+// 已使用过的刷新令牌再次出现说明可能被窃取，整个令牌族会被吊销
+func (s *TokenDomainService) Refresh(ctx context.Context, rawToken string) (*TokenPair, error) {
+	hash := utils.SHA256Hex(rawToken)
+	token, err := s.refreshRepo.GetByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if token == nil || token.RevokedAt != nil || token.IsExpired(time.Now()) {
+		return nil, errorx.NewWithCode(errorx.ErrRefreshTokenInvalid)
+	}
+	if token.UsedAt != nil {
+		return nil, s.revokeOnReuse(ctx, token)
+	}
+	ok, err := s.refreshRepo.MarkUsed(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if !ok { // 并发场景下被抢先使用
+		return nil, s.revokeOnReuse(ctx, token)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, token.UserID)
+	if err != nil || user == nil || user.Status != 0 {
+		_ = s.refreshRepo.RevokeFamily(ctx, token.FamilyID)
+		return nil, errorx.NewWithCode(errorx.ErrRefreshTokenInvalid)
+	}
+	pair, err := s.issue(ctx, user, token.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	// 刷新即视为活跃，并随刷新令牌顺延会话有效期
+	now := time.Now()
+	if err := s.sessionRepo.Touch(ctx, token.FamilyID, now, now.Add(s.refreshTTL)); err != nil {
+		logger.Warn("session_touch_failed", "sessionId", token.FamilyID, "error", err)
+	}
+	return pair, nil
+}
+
+// IsRevoked 访问令牌是否已被吊销（单令牌或用户级）
+func (s *TokenDomainService) IsRevoked(ctx context.Context, claims *auth.Claims) (bool, error) {
+	return auth.IsClaimsRevoked(ctx, s.revocations, claims)
+}
+
+// RevokeAccessToken 吊销单个访问令牌（保留到其自然过期）
+func (s *TokenDomainService) RevokeAccessToken(ctx context.Context, claims *auth.Claims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+	return s.revocations.RevokeToken(ctx, claims.ID, claims.ExpiresAt.Time)
+}
+
+// RevokeRefreshToken 吊销指定刷新令牌所在的令牌族及其会话（仅限本人令牌）
+func (s *TokenDomainService) RevokeRefreshToken(ctx context.Context, userID uint, rawToken string) error {
+	token, err := s.refreshRepo.GetByHash(ctx, utils.SHA256Hex(rawToken))
+	if err != nil {
+		return err
+	}
+	if token == nil || token.UserID != userID {
+		return nil
+	}
+	return s.revokeSession(ctx, token.FamilyID)
+}
+
+// RevokeUserTokens 吊销用户全部访问令牌、刷新令牌与会话（退出所有设备 / 禁用 / 删除）
+func (s *TokenDomainService) RevokeUserTokens(ctx context.Context, userID uint) error {
+	if err := s.revocations.RevokeUser(ctx, userID, time.Now()); err != nil {
+		return err
+	}
+	if err := s.refreshRepo.RevokeUser(ctx, userID); err != nil {
+		return err
+	}
+	return s.sessionRepo.RevokeUser(ctx, userID)
+}
+
+// ListSessions 用户当前在线的会话
+func (s *TokenDomainService) ListSessions(ctx context.Context, userID uint) ([]*entity.Session, error) {
+	return s.sessionRepo.ListActiveByUser(ctx, userID, time.Now())
+}
+
+// ListOnlineSessions 全部在线会话（分页）
+func (s *TokenDomainService) ListOnlineSessions(ctx context.Context, offset, limit int) ([]*entity.Session, int64, error) {
+	return s.sessionRepo.ListActive(ctx, time.Now(), offset, limit)
+}
+
+// RevokeSession 吊销会话：其刷新令牌与已签发的访问令牌立即失效。userID 非 0 时只允许吊销本人会话
+func (s *TokenDomainService) RevokeSession(ctx context.Context, userID uint, sessionID string) (*entity.Session, error) {
+	session, err := s.sessionRepo.GetBySessionID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil || (userID != 0 && session.UserID != userID) {
+		return nil, errorx.New(errorx.ErrNotFound, "session not found")
+	}
+	if err := s.revokeSession(ctx, sessionID); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// GetSession 会话不存在时返回 (nil, nil)
+func (s *TokenDomainService) GetSession(ctx context.Context, sessionID string) (*entity.Session, error) {
+	return s.sessionRepo.GetBySessionID(ctx, sessionID)
+}
+
+// SessionActive 校验访问令牌所属会话仍然有效，并按间隔更新最近活跃时间
+func (s *TokenDomainService) SessionActive(ctx context.Context, claims *auth.Claims) (bool, error) {
+	if claims.SessionID == "" {
+		return true, nil
+	}
+	session, err := s.sessionRepo.GetBySessionID(ctx, claims.SessionID)
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+	if session == nil || !session.IsActive(now) {
+		return false, nil
+	}
+	if now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		if err := s.sessionRepo.Touch(ctx, claims.SessionID, now, time.Time{}); err != nil {
+			logger.Warn("session_touch_failed", "sessionId", claims.SessionID, "error", err)
+		}
+	}
+	return true, nil
+}
+
+// revokeSession 吊销令牌族、会话记录及该会话已签发的访问令牌
+func (s *TokenDomainService) revokeSession(ctx context.Context, sessionID string) error {
+	if err := s.refreshRepo.RevokeFamily(ctx, sessionID); err != nil {
+		return err
+	}
+	if err := s.sessionRepo.Revoke(ctx, sessionID); err != nil {
+		return err
+	}
+	return auth.RevokeSession(ctx, s.revocations, sessionID)
+}
+
+func (s *TokenDomainService) revokeOnReuse(ctx context.Context, token *entity.RefreshToken) error {
+	if err := s.revokeSession(ctx, token.FamilyID); err != nil {
+		return err
+	}
+	logger.Warn("audit:refresh_token_reuse", "userId", token.UserID, "familyId", token.FamilyID)
+	return errorx.NewWithCode(errorx.ErrRefreshTokenReused)
+}
+
+func (s *TokenDomainService) issue(ctx context.Context, user *userEntity.User, familyID string) (*TokenPair, error) {
+	accessToken, err := auth.GenerateSessionToken(user.ID, user.Username, familyID)
+	if err != nil {
+		return nil, errorx.New(errorx.ErrInternalServer, "failed to generate token")
+	}
+	raw, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, errorx.New(errorx.ErrInternalServer, "failed to generate token")
+	}
+	refresh := &entity.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: utils.SHA256Hex(raw),
+		ExpiresAt: time.Now().Add(s.refreshTTL),
+	}
+	if err := s.refreshRepo.Create(ctx, refresh); err != nil {
+		return nil, err
+	}
+	return &TokenPair{AccessToken: accessToken, RefreshToken: raw, ExpiresIn: int64(auth.AccessTokenTTL().Seconds())}, nil
+}
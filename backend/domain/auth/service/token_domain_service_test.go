@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sine-io/sinx/domain/auth/entity"
+	userEntity "github.com/sine-io/sinx/domain/user/entity"
+	"github.com/sine-io/sinx/domain/user/repository/usertest"
+	"github.com/sine-io/sinx/pkg/auth"
+	"github.com/sine-io/sinx/pkg/config"
+	"github.com/sine-io/sinx/pkg/errorx"
+	"github.com/sine-io/sinx/pkg/logger"
+)
+
+// In-memory implementations -------------------------------------------------
+
+type memRefreshRepo struct {
+	data map[string]*entity.RefreshToken
+}
+
+func (m *memRefreshRepo) Create(_ context.Context, t *entity.RefreshToken) error {
+	m.data[t.TokenHash] = t
+	return nil
+}
+func (m *memRefreshRepo) GetByHash(_ context.Context, hash string) (*entity.RefreshToken, error) {
+	return m.data[hash], nil
+}
+func (m *memRefreshRepo) MarkUsed(_ context.Context, hash string) (bool, error) {
+	t, ok := m.data[hash]
+	if !ok || t.UsedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	t.UsedAt = &now
+	return true, nil
+}
+func (m *memRefreshRepo) RevokeFamily(_ context.Context, familyID string) error {
+	now := time.Now()
+	for _, t := range m.data {
+		if t.FamilyID == familyID {
+			t.RevokedAt = &now
+		}
+	}
+	return nil
+}
+func (m *memRefreshRepo) RevokeUser(_ context.Context, userID uint) error {
+	now := time.Now()
+	for _, t := range m.data {
+		if t.UserID == userID {
+			t.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+type memSessionRepo struct {
+	data map[string]*entity.Session
+}
+
+func (m *memSessionRepo) Create(_ context.Context, s *entity.Session) error {
+	m.data[s.SessionID] = s
+	return nil
+}
+func (m *memSessionRepo) GetBySessionID(_ context.Context, sid string) (*entity.Session, error) {
+	return m.data[sid], nil
+}
+func (m *memSessionRepo) ListActiveByUser(_ context.Context, userID uint, now time.Time) ([]*entity.Session, error) {
+	var res []*entity.Session
+	for _, s := range m.data {
+		if s.UserID == userID && s.IsActive(now) {
+			res = append(res, s)
+		}
+	}
+	return res, nil
+}
+func (m *memSessionRepo) ListActive(_ context.Context, now time.Time, offset, limit int) ([]*entity.Session, int64, error) {
+	return nil, 0, nil
+}
+func (m *memSessionRepo) Touch(_ context.Context, sid string, lastSeen, expiresAt time.Time) error {
+	if s, ok := m.data[sid]; ok {
+		s.LastSeenAt = lastSeen
+		if !expiresAt.IsZero() {
+			s.ExpiresAt = expiresAt
+		}
+	}
+	return nil
+}
+func (m *memSessionRepo) Revoke(_ context.Context, sid string) error {
+	if s, ok := m.data[sid]; ok {
+		now := time.Now()
+		s.RevokedAt = &now
+	}
+	return nil
+}
+func (m *memSessionRepo) RevokeUser(_ context.Context, userID uint) error {
+	now := time.Now()
+	for _, s := range m.data {
+		if s.UserID == userID {
+			s.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+// Test ----------------------------------------------------------------------
+func newTestTokenService() (*TokenDomainService, *usertest.UserRepo) {
+	_ = config.LoadEnv()
+	_ = logger.Init()
+	users := usertest.NewUserRepo(&userEntity.User{ID: 1, Username: "u1"}, &userEntity.User{ID: 2, Username: "u2"})
+	svc := NewTokenDomainService(&memRefreshRepo{data: map[string]*entity.RefreshToken{}}, &memSessionRepo{data: map[string]*entity.Session{}},
+		users, auth.NewMemoryRevocationStore(time.Hour), time.Hour)
+	return svc, users
+}
+
+func TestRefreshRotationAndReuse(t *testing.T) {
+	ctx := context.Background()
+	svc, users := newTestTokenService()
+
+	first, err := svc.IssueTokenPair(ctx, users.Data[1], "127.0.0.1", "curl/8.5.0")
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	second, err := svc.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatalf("refresh token not rotated")
+	}
+
+	// 重放旧令牌：应报重放并吊销整个令牌族
+	_, err = svc.Refresh(ctx, first.RefreshToken)
+	if appErr, ok := err.(*errorx.Error); !ok || appErr.Code != errorx.ErrRefreshTokenReused {
+		t.Fatalf("expected reuse error, got %v", err)
+	}
+	_, err = svc.Refresh(ctx, second.RefreshToken)
+	if appErr, ok := err.(*errorx.Error); !ok || appErr.Code != errorx.ErrRefreshTokenInvalid {
+		t.Fatalf("expected family revoked, got %v", err)
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	ctx := context.Background()
+	svc, users := newTestTokenService()
+
+	pair, err := svc.IssueTokenPair(ctx, users.Data[1], "127.0.0.1", "curl/8.5.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := svc.IssueTokenPair(ctx, users.Data[1], "10.0.0.1", "curl/8.5.0")
+	claims, err := auth.ParseToken(pair.AccessToken)
+	if err != nil || claims.SessionID == "" {
+		t.Fatalf("access token should carry sid: %v", err)
+	}
+	if sessions, _ := svc.ListSessions(ctx, 1); len(sessions) != 2 || sessions[0].Device != "curl" {
+		t.Fatalf("sessions = %+v", sessions)
+	}
+
+	// 只能吊销本人的会话
+	if _, err := svc.RevokeSession(ctx, 2, claims.SessionID); err == nil {
+		t.Fatal("expected other user's session to be hidden")
+	}
+	if _, err := svc.RevokeSession(ctx, 1, claims.SessionID); err != nil {
+		t.Fatal(err)
+	}
+
+	// 访问令牌与刷新令牌立即失效，同一用户的其他会话不受影响
+	if revoked, _ := svc.IsRevoked(ctx, claims); !revoked {
+		t.Fatal("access token of revoked session should be rejected")
+	}
+	if active, _ := svc.SessionActive(ctx, claims); active {
+		t.Fatal("revoked session should be inactive")
+	}
+	if _, err := svc.Refresh(ctx, pair.RefreshToken); err == nil {
+		t.Fatal("refresh token of revoked session should be rejected")
+	}
+	if _, err := svc.Refresh(ctx, other.RefreshToken); err != nil {
+		t.Fatalf("other session should stay valid: %v", err)
+	}
+}
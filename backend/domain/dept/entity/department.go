@@ -0,0 +1,23 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Department 部门实体，用户通过 User.DeptID 归属部门
+type Department struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	Name      string         `json:"name" gorm:"size:50;not null"`
+	ParentID  uint           `json:"parentId" gorm:"index;default:0"` // 上级部门，0 表示顶级
+	OrderNum  int            `json:"orderNum" gorm:"default:1"`
+	Leader    string         `json:"leader" gorm:"size:50"`
+	Status    int16          `json:"status" gorm:"default:0"` // 0正常 1禁用
+	Remark    string         `json:"remark" gorm:"size:100"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func (Department) TableName() string { return "departments" }
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/sine-io/sinx/domain/dept/entity"
+)
+
+type DepartmentRepository interface {
+	Create(ctx context.Context, dept *entity.Department) error
+	Update(ctx context.Context, dept *entity.Department) error
+	Delete(ctx context.Context, id uint) error
+	// GetByID 不存在时返回 (nil, nil)
+	GetByID(ctx context.Context, id uint) (*entity.Department, error)
+	ListAll(ctx context.Context) ([]*entity.Department, error)
+	// HasUsers 是否有状态正常的用户归属该部门
+	HasUsers(ctx context.Context, id uint) (bool, error)
+}
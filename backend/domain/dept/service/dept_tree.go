@@ -0,0 +1,59 @@
+package service
+
+import "github.com/sine-io/sinx/domain/dept/entity"
+
+// DeptTree 部门层级关系（由全部未删除部门构建的只读快照）
+type DeptTree struct {
+	depts    map[uint]*entity.Department
+	children map[uint][]uint
+}
+
+func NewDeptTree(depts []*entity.Department) *DeptTree {
+	t := &DeptTree{depts: make(map[uint]*entity.Department, len(depts)), children: map[uint][]uint{}}
+	for _, d := range depts {
+		t.depts[d.ID] = d
+	}
+	for _, d := range depts {
+		if d.ParentID != 0 {
+			t.children[d.ParentID] = append(t.children[d.ParentID], d.ID)
+		}
+	}
+	return t
+}
+
+// Get 返回部门，不存在（含已删除）时返回 nil
+func (t *DeptTree) Get(id uint) *entity.Department { return t.depts[id] }
+
+// HasChildren 是否存在下级部门
+func (t *DeptTree) HasChildren(id uint) bool { return len(t.children[id]) > 0 }
+
+// Descendants 返回部门自身及全部下级部门 ID；部门不存在时返回空
+func (t *DeptTree) Descendants(id uint) []uint {
+	if t.depts[id] == nil {
+		return nil
+	}
+	res := []uint{id}
+	seen := map[uint]struct{}{id: {}}
+	for i := 0; i < len(res); i++ {
+		for _, c := range t.children[res[i]] {
+			if _, ok := seen[c]; !ok {
+				seen[c] = struct{}{}
+				res = append(res, c)
+			}
+		}
+	}
+	return res
+}
+
+// WouldCycle 将 id 的上级设为 parentID 是否会形成环（含指向自身）
+func (t *DeptTree) WouldCycle(id, parentID uint) bool {
+	if id == 0 || parentID == 0 {
+		return false
+	}
+	for _, d := range t.Descendants(id) {
+		if d == parentID {
+			return true
+		}
+	}
+	return false
+}
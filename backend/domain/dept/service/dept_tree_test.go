@@ -0,0 +1,65 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sine-io/sinx/domain/dept/entity"
+)
+
+func dept(id, parentID uint) *entity.Department {
+	return &entity.Department{ID: id, ParentID: parentID}
+}
+
+// hq(1) -> sales(2) -> east(3)、west(5)；hq(1) -> rnd(4)
+func newTestTree() *DeptTree {
+	return NewDeptTree([]*entity.Department{dept(1, 0), dept(2, 1), dept(3, 2), dept(4, 1), dept(5, 2)})
+}
+
+func TestDeptTreeDescendants(t *testing.T) {
+	tree := newTestTree()
+	cases := []struct {
+		id   uint
+		want []uint
+	}{
+		{1, []uint{1, 2, 4, 3, 5}},
+		{2, []uint{2, 3, 5}},
+		{3, []uint{3}},
+		{99, nil},
+	}
+	for _, c := range cases {
+		if got := tree.Descendants(c.id); !reflect.DeepEqual(got, c.want) {
+			t.Fatalf("Descendants(%d) = %v, want %v", c.id, got, c.want)
+		}
+	}
+	if !tree.HasChildren(2) || tree.HasChildren(3) {
+		t.Fatal("unexpected HasChildren result")
+	}
+
+	// 上级已删除（不在快照中）的部门不会被计入其他部门的下级
+	orphan := NewDeptTree([]*entity.Department{dept(1, 0), dept(3, 2)})
+	if got := orphan.Descendants(1); !reflect.DeepEqual(got, []uint{1}) {
+		t.Fatalf("orphan must not be a descendant of hq, got %v", got)
+	}
+}
+
+func TestDeptTreeWouldCycle(t *testing.T) {
+	tree := newTestTree()
+	cases := []struct {
+		id, parentID uint
+		want         bool
+	}{
+		{2, 2, true},  // 指向自身
+		{1, 3, true},  // 挂到自己的孙部门下
+		{2, 5, true},  // 挂到自己的子部门下
+		{3, 4, false}, // 移到兄弟分支
+		{4, 3, false},
+		{2, 0, false}, // 设为顶级
+		{0, 1, false}, // 新建部门
+	}
+	for _, c := range cases {
+		if got := tree.WouldCycle(c.id, c.parentID); got != c.want {
+			t.Fatalf("WouldCycle(%d, %d) = %v, want %v", c.id, c.parentID, got, c.want)
+		}
+	}
+}
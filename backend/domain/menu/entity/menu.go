@@ -21,7 +21,7 @@ type Menu struct {
 	IsHidden  int16          `json:"isHidden" gorm:"default:0"`
 	Perms     string         `json:"perms" gorm:"size:100"`
 	Icon      string         `json:"icon" gorm:"size:100"`
-	Status    int16          `json:"status" gorm:"default:0"`
+	Status    int16          `json:"status" gorm:"default:0"` // 0正常 1禁用
 	Remark    string         `json:"remark" gorm:"size:100"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+// AuthorizationCode 授权码（仅存哈希，一次性使用）
+type AuthorizationCode struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	CodeHash      string     `json:"-" gorm:"size:64;uniqueIndex;not null"`
+	ClientID      string     `json:"clientId" gorm:"size:64;index;not null"`
+	UserID        uint       `json:"userId" gorm:"not null"`
+	RedirectURI   string     `json:"redirectUri" gorm:"size:512;not null"`
+	Scope         string     `json:"scope" gorm:"size:255"`
+	Nonce         string     `json:"-" gorm:"size:255"`
+	CodeChallenge string     `json:"-" gorm:"size:128;not null"` // PKCE S256
+	AuthTime      time.Time  `json:"authTime"`
+	ExpiresAt     time.Time  `json:"expiresAt" gorm:"index;not null"`
+	UsedAt        *time.Time `json:"usedAt"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+func (AuthorizationCode) TableName() string { return "oauth_authorization_codes" }
+
+// IsExpired 是否已过期
+func (c *AuthorizationCode) IsExpired(now time.Time) bool { return now.After(c.ExpiresAt) }
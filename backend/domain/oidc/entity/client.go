@@ -0,0 +1,41 @@
+package entity
+
+import (
+	"strings"
+	"time"
+)
+
+// OAuthClient 接入 sinx 登录的 OIDC 客户端（内部 Web 应用）
+// SecretHash 为空表示公开客户端（SPA / 原生应用），仅依赖 PKCE
+type OAuthClient struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	ClientID     string    `json:"clientId" gorm:"size:64;uniqueIndex;not null"`
+	Name         string    `json:"name" gorm:"size:100;not null"`
+	SecretHash   string    `json:"-" gorm:"size:64"`
+	RedirectURIs string    `json:"redirectUris" gorm:"size:2048;not null"` // 逗号分隔，精确匹配
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+func (OAuthClient) TableName() string { return "oauth_clients" }
+
+// IsPublic 是否为公开客户端
+func (c *OAuthClient) IsPublic() bool { return c.SecretHash == "" }
+
+// RedirectURIList 已登记的回调地址
+func (c *OAuthClient) RedirectURIList() []string {
+	if c.RedirectURIs == "" {
+		return nil
+	}
+	return strings.Split(c.RedirectURIs, ",")
+}
+
+// AllowsRedirect 回调地址是否已登记（精确匹配）
+func (c *OAuthClient) AllowsRedirect(uri string) bool {
+	for _, u := range c.RedirectURIList() {
+		if u == uri {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/sine-io/sinx/domain/oidc/entity"
+)
+
+type ClientRepository interface {
+	Create(ctx context.Context, client *entity.OAuthClient) error
+	// GetByClientID 不存在时返回 (nil, nil)
+	GetByClientID(ctx context.Context, clientID string) (*entity.OAuthClient, error)
+	List(ctx context.Context) ([]*entity.OAuthClient, error)
+	Delete(ctx context.Context, clientID string) error
+}
+
+type AuthCodeRepository interface {
+	Create(ctx context.Context, code *entity.AuthorizationCode) error
+	// Consume 原子地标记授权码为已使用并返回；不存在或已使用时返回 (nil, nil)
+	Consume(ctx context.Context, codeHash string) (*entity.AuthorizationCode, error)
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sine-io/sinx/domain/oidc/entity"
+	"github.com/sine-io/sinx/domain/oidc/repository"
+	"github.com/sine-io/sinx/pkg/errorx"
+	"github.com/sine-io/sinx/pkg/utils"
+)
+
+// authCodeTTL 授权码有效期
+const authCodeTTL = 2 * time.Minute
+
+// OAuthError RFC 6749 协议错误（token / userinfo 端点按协议格式返回）
+type OAuthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *OAuthError) Error() string { return e.Code + ": " + e.Description }
+
+func oauthError(code, desc string) *OAuthError { return &OAuthError{Code: code, Description: desc} }
+
+// AuthorizeParams 授权请求参数（已通过客户端 / 回调地址校验）
+type AuthorizeParams struct {
+	RedirectURI         string
+	Scope               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	AuthTime            time.Time
+}
+
+type OIDCDomainService struct {
+	clients repository.ClientRepository
+	codes   repository.AuthCodeRepository
+}
+
+func NewOIDCDomainService(clients repository.ClientRepository, codes repository.AuthCodeRepository) *OIDCDomainService {
+	return &OIDCDomainService{clients: clients, codes: codes}
+}
+
+// RegisterClient 登记客户端；public 为 true 时不生成密钥，返回的明文密钥仅此一次可见
+func (s *OIDCDomainService) RegisterClient(ctx context.Context, name string, redirectURIs []string, public bool) (*entity.OAuthClient, string, error) {
+	for _, u := range redirectURIs {
+		if !validRedirectURI(u) {
+			return nil, "", errorx.New(errorx.ErrInvalidParam, "invalid redirect uri: "+u)
+		}
+		if strings.Contains(u, ",") {
+			return nil, "", errorx.New(errorx.ErrInvalidParam, "redirect uri must not contain ','")
+		}
+	}
+	clientID, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, "", err
+	}
+	client := &entity.OAuthClient{ClientID: clientID, Name: name, RedirectURIs: strings.Join(redirectURIs, ",")}
+	var secret string
+	if !public {
+		if secret, err = utils.RandomToken(32); err != nil {
+			return nil, "", err
+		}
+		client.SecretHash = utils.SHA256Hex(secret)
+	}
+	if err := s.clients.Create(ctx, client); err != nil {
+		return nil, "", err
+	}
+	return client, secret, nil
+}
+
+func (s *OIDCDomainService) ListClients(ctx context.Context) ([]*entity.OAuthClient, error) {
+	return s.clients.List(ctx)
+}
+
+func (s *OIDCDomainService) DeleteClient(ctx context.Context, clientID string) error {
+	return s.clients.Delete(ctx, clientID)
+}
+
+// ResolveClient 校验客户端与回调地址；失败时不得重定向（防止开放重定向）
+func (s *OIDCDomainService) ResolveClient(ctx context.Context, clientID, redirectURI string) (*entity.OAuthClient, error) {
+	client, err := s.clients.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, errorx.New(errorx.ErrInvalidParam, "unknown client_id")
+	}
+	if !client.AllowsRedirect(redirectURI) {
+		return nil, errorx.New(errorx.ErrInvalidParam, "redirect_uri not registered")
+	}
+	return client, nil
+}
+
+// IssueCode 为已登录用户签发授权码；强制 openid scope 与 PKCE(S256)
+func (s *OIDCDomainService) IssueCode(ctx context.Context, client *entity.OAuthClient, userID uint, p AuthorizeParams) (string, error) {
+	if !HasScope(p.Scope, "openid") {
+		return "", oauthError("invalid_scope", "openid scope is required")
+	}
+	if p.CodeChallenge == "" || p.CodeChallengeMethod != "S256" {
+		return "", oauthError("invalid_request", "PKCE with code_challenge_method=S256 is required")
+	}
+	raw, err := utils.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	code := &entity.AuthorizationCode{
+		CodeHash:      utils.SHA256Hex(raw),
+		ClientID:      client.ClientID,
+		UserID:        userID,
+		RedirectURI:   p.RedirectURI,
+		Scope:         p.Scope,
+		Nonce:         p.Nonce,
+		CodeChallenge: p.CodeChallenge,
+		AuthTime:      p.AuthTime,
+		ExpiresAt:     time.Now().Add(authCodeTTL),
+	}
+	if err := s.codes.Create(ctx, code); err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// AuthenticateClient token 端点客户端认证：机密客户端校验密钥，公开客户端仅凭 PKCE
+func (s *OIDCDomainService) AuthenticateClient(ctx context.Context, clientID, secret string) (*entity.OAuthClient, error) {
+	client, err := s.clients.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, oauthError("invalid_client", "unknown client")
+	}
+	if client.IsPublic() {
+		return client, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(utils.SHA256Hex(secret)), []byte(client.SecretHash)) != 1 {
+		return nil, oauthError("invalid_client", "client authentication failed")
+	}
+	return client, nil
+}
+
+// ExchangeCode 消费授权码并校验客户端、回调地址与 PKCE
+func (s *OIDCDomainService) ExchangeCode(ctx context.Context, client *entity.OAuthClient, rawCode, redirectURI, verifier string) (*entity.AuthorizationCode, error) {
+	code, err := s.codes.Consume(ctx, utils.SHA256Hex(rawCode))
+	if err != nil {
+		return nil, err
+	}
+	if code == nil || code.IsExpired(time.Now()) {
+		return nil, oauthError("invalid_grant", "authorization code is invalid or expired")
+	}
+	if code.ClientID != client.ClientID || code.RedirectURI != redirectURI {
+		return nil, oauthError("invalid_grant", "authorization code was issued to another client or redirect_uri")
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+	if verifier == "" || subtle.ConstantTimeCompare([]byte(challenge), []byte(code.CodeChallenge)) != 1 {
+		return nil, oauthError("invalid_grant", "code_verifier does not match")
+	}
+	return code, nil
+}
+
+// HasScope scope 列表（空格分隔）中是否包含指定项
+func HasScope(scope, want string) bool {
+	for _, s := range strings.Fields(scope) {
+		if s == want {
+			return true
+		}
+	}
+	return false
+}
+
+// validRedirectURI 绝对 http(s) 地址且不含 fragment
+func validRedirectURI(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "https" || u.Scheme == "http") && u.Host != "" && u.Fragment == ""
+}
@@ -2,17 +2,36 @@ package entity
 
 import "time"
 
-// UserRole 用户角色关联
+// UserRole 用户角色关联；ValidFrom / ValidUntil 为空表示不限
 type UserRole struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	UserID    uint      `json:"userId" gorm:"index;not null"`
-	RoleID    uint      `json:"roleId" gorm:"index;not null"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"userId" gorm:"index;not null"`
+	RoleID     uint       `json:"roleId" gorm:"index;not null"`
+	ValidFrom  *time.Time `json:"validFrom,omitempty" gorm:"index"`
+	ValidUntil *time.Time `json:"validUntil,omitempty" gorm:"index"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
 }
 
 func (UserRole) TableName() string { return "user_roles" }
 
+// Widen 将有效期扩展为同时覆盖 [from, until) 的区间（取更早的生效时间与更晚的失效时间，空表示不限），返回是否有变化
+func (ur *UserRole) Widen(from, until *time.Time) bool {
+	changed := false
+	if ur.ValidFrom != nil && (from == nil || from.Before(*ur.ValidFrom)) {
+		ur.ValidFrom, changed = from, true
+	}
+	if ur.ValidUntil != nil && (until == nil || until.After(*ur.ValidUntil)) {
+		ur.ValidUntil, changed = until, true
+	}
+	return changed
+}
+
+// ActiveAt 绑定在 t 时刻是否处于有效期内
+func (ur *UserRole) ActiveAt(t time.Time) bool {
+	return (ur.ValidFrom == nil || !ur.ValidFrom.After(t)) && (ur.ValidUntil == nil || ur.ValidUntil.After(t))
+}
+
 // RoleMenu 角色菜单关联
 type RoleMenu struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
@@ -23,3 +42,14 @@ type RoleMenu struct {
 }
 
 func (RoleMenu) TableName() string { return "role_menus" }
+
+// RoleMenuDeny 角色拒绝菜单：拥有该角色（含后代角色）的用户不再获得菜单的权限标识，优先于任何授予
+type RoleMenuDeny struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	RoleID    uint      `json:"roleId" gorm:"uniqueIndex:idx_role_menu_deny;not null"`
+	MenuID    uint      `json:"menuId" gorm:"uniqueIndex:idx_role_menu_deny;index;not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (RoleMenuDeny) TableName() string { return "role_menu_denies" }
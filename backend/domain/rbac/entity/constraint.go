@@ -0,0 +1,50 @@
+package entity
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RoleConstraint 职责分离约束：用户最多同时拥有 Roles 中的 MaxRoles 个角色，MaxRoles 为 1 即互斥
+// 绑定子角色视为同时拥有其全部祖先角色
+type RoleConstraint struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"size:100;not null"`
+	Roles     string    `json:"roles" gorm:"size:1024;not null"` // 逗号分隔的角色ID
+	MaxRoles  int       `json:"maxRoles" gorm:"not null;default:1"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (RoleConstraint) TableName() string { return "role_constraints" }
+
+// RoleIDList 约束涉及的角色ID
+func (c *RoleConstraint) RoleIDList() []uint {
+	var ids []uint
+	for _, s := range strings.Split(c.Roles, ",") {
+		if id, err := strconv.ParseUint(s, 10, 64); err == nil && id > 0 {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
+
+// SetRoleIDs 去重排序后保存
+func (c *RoleConstraint) SetRoleIDs(ids []uint) {
+	seen := map[uint]struct{}{}
+	uniq := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; !ok && id > 0 {
+			seen[id] = struct{}{}
+			uniq = append(uniq, id)
+		}
+	}
+	sort.Slice(uniq, func(i, j int) bool { return uniq[i] < uniq[j] })
+	parts := make([]string, 0, len(uniq))
+	for _, id := range uniq {
+		parts = append(parts, strconv.FormatUint(uint64(id), 10))
+	}
+	c.Roles = strings.Join(parts, ",")
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	menuEntity "github.com/sine-io/sinx/domain/menu/entity"
 	rbacEntity "github.com/sine-io/sinx/domain/rbac/entity"
@@ -9,18 +10,44 @@ import (
 )
 
 type RBACRepository interface {
-	BindUserRoles(ctx context.Context, userID uint, roleIDs []uint) (added int, skipped int, err error)
+	// BindUserRoles 已存在的绑定按新的有效期更新，计为 skipped
+	BindUserRoles(ctx context.Context, userID uint, roleIDs []uint, validFrom, validUntil *time.Time) (added int, skipped int, err error)
+	// GrantUserRoles 已存在的绑定只放宽有效期（见 UserRole.Widen），不会缩短，计为 skipped
+	GrantUserRoles(ctx context.Context, userID uint, roleIDs []uint, validFrom, validUntil *time.Time) (added int, skipped int, err error)
 	UnbindUserRoles(ctx context.Context, userID uint, roleIDs []uint) error
+	// GetUserRoles 仅返回当前处于有效期内的绑定角色
 	GetUserRoles(ctx context.Context, userID uint) ([]*roleEntity.Role, error)
+	// GetUserRoleBindings 返回用户全部角色绑定（含未生效的）
+	GetUserRoleBindings(ctx context.Context, userID uint) ([]*rbacEntity.UserRole, error)
+	// DeleteExpiredUserRoles 删除在 now 之前已过期的绑定，返回涉及的用户
+	DeleteExpiredUserRoles(ctx context.Context, now time.Time) ([]uint, error)
+	// GetUsersActivatedBetween 返回绑定在 (from, to] 内开始生效的用户
+	GetUsersActivatedBetween(ctx context.Context, from, to time.Time) ([]uint, error)
 	BindRoleMenus(ctx context.Context, roleID uint, menuIDs []uint) (added int, skipped int, err error)
 	UnbindRoleMenus(ctx context.Context, roleID uint, menuIDs []uint) error
 	GetRoleMenus(ctx context.Context, roleID uint) ([]*menuEntity.Menu, error)
-	GetUserMenus(ctx context.Context, userID uint) ([]*menuEntity.Menu, error)
+	GetRolesMenus(ctx context.Context, roleIDs []uint) ([]*menuEntity.Menu, error)
 	GetMenuRoles(ctx context.Context, menuID uint) ([]*roleEntity.Role, error)
 	GetRoleUsers(ctx context.Context, roleID uint) ([]uint, error)
 	GetMenuIDsByRole(ctx context.Context, roleID uint) ([]uint, error)
+	BindRoleDenyMenus(ctx context.Context, roleID uint, menuIDs []uint) (added int, skipped int, err error)
+	UnbindRoleDenyMenus(ctx context.Context, roleID uint, menuIDs []uint) error
+	GetRolesDenyMenus(ctx context.Context, roleIDs []uint) ([]*menuEntity.Menu, error)
+	GetMenuDenyRoles(ctx context.Context, menuID uint) ([]uint, error)
+	// GetUserRolesByRoles 返回绑定了 roleIDs 中任一角色的全部绑定（含未生效的）
+	GetUserRolesByRoles(ctx context.Context, roleIDs []uint) ([]*rbacEntity.UserRole, error)
+
+	// 职责分离约束
+	SaveRoleConstraint(ctx context.Context, c *rbacEntity.RoleConstraint) error
+	DeleteRoleConstraint(ctx context.Context, id uint) error
+	GetRoleConstraint(ctx context.Context, id uint) (*rbacEntity.RoleConstraint, error)
+	ListRoleConstraints(ctx context.Context) ([]*rbacEntity.RoleConstraint, error)
+	// LockSoD 在持有职责分离锁的事务中执行 fn（fn 须使用传入的 ctx），使校验与随后的写入在多实例间串行：
+	// userID 非 0 时锁定该用户的角色绑定，为 0 时锁定全部（父角色变更、约束保存）
+	LockSoD(ctx context.Context, userID uint, fn func(ctx context.Context) error) error
 }
 
 // 复用实体定义，避免循环引用
 type UserRole = rbacEntity.UserRole
 type RoleMenu = rbacEntity.RoleMenu
+type RoleMenuDeny = rbacEntity.RoleMenuDeny
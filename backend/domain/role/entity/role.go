@@ -1,6 +1,9 @@
 package entity
 
 import (
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -11,10 +14,51 @@ type Role struct {
 	ID        uint           `json:"id" gorm:"primaryKey"`
 	Name      string         `json:"name" gorm:"uniqueIndex;size:50;not null"`
 	Remark    string         `json:"remark" gorm:"size:100"`
-	Status    int16          `json:"status" gorm:"default:0"` // 0正常 1禁用
+	ParentID  uint           `json:"parentId" gorm:"index;default:0"` // 父角色，0 表示顶级；继承全部祖先角色的菜单
+	Status    int16          `json:"status" gorm:"default:0"`         // 0正常 1禁用
+	DataScope int16          `json:"dataScope" gorm:"default:1"`      // 数据范围，见 DataScope* 常量
+	ScopeDept string         `json:"scopeDept" gorm:"size:1024"`      // 自定义数据范围的部门ID，逗号分隔
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// 数据范围（Role.DataScope），用户拥有多个角色时取并集
+const (
+	DataScopeAll          int16 = 1 // 全部数据
+	DataScopeCustom       int16 = 2 // 自定义部门
+	DataScopeDept         int16 = 3 // 本部门
+	DataScopeDeptAndChild int16 = 4 // 本部门及以下
+	DataScopeSelf         int16 = 5 // 仅本人
+)
+
 func (Role) TableName() string { return "roles" }
+
+// ScopeDeptIDs 自定义数据范围的部门ID
+func (r *Role) ScopeDeptIDs() []uint {
+	var ids []uint
+	for _, s := range strings.Split(r.ScopeDept, ",") {
+		if id, err := strconv.ParseUint(s, 10, 64); err == nil && id > 0 {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
+
+// SetScopeDeptIDs 去重排序后保存
+func (r *Role) SetScopeDeptIDs(ids []uint) {
+	seen := map[uint]struct{}{}
+	uniq := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; !ok && id > 0 {
+			seen[id] = struct{}{}
+			uniq = append(uniq, id)
+		}
+	}
+	sort.Slice(uniq, func(i, j int) bool { return uniq[i] < uniq[j] })
+	parts := make([]string, 0, len(uniq))
+	for _, id := range uniq {
+		parts = append(parts, strconv.FormatUint(uint64(id), 10))
+	}
+	r.ScopeDept = strings.Join(parts, ",")
+}
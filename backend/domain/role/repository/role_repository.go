@@ -13,4 +13,5 @@ type RoleRepository interface {
 	GetByID(ctx context.Context, id uint) (*entity.Role, error)
 	List(ctx context.Context, offset, limit int) ([]*entity.Role, error)
 	Count(ctx context.Context) (int64, error)
+	ListAll(ctx context.Context) ([]*entity.Role, error)
 }
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"sort"
+
+	"github.com/sine-io/sinx/domain/role/entity"
+)
+
+// RoleHierarchy 角色继承关系（由全部未删除角色构建的只读快照）
+type RoleHierarchy struct {
+	roles    map[uint]*entity.Role
+	children map[uint][]uint
+}
+
+func NewRoleHierarchy(roles []*entity.Role) *RoleHierarchy {
+	h := &RoleHierarchy{roles: make(map[uint]*entity.Role, len(roles)), children: map[uint][]uint{}}
+	for _, r := range roles {
+		h.roles[r.ID] = r
+	}
+	for _, r := range roles {
+		if r.ParentID != 0 {
+			h.children[r.ParentID] = append(h.children[r.ParentID], r.ID)
+		}
+	}
+	return h
+}
+
+// Get 返回角色，不存在（含已删除）时返回 nil
+func (h *RoleHierarchy) Get(id uint) *entity.Role { return h.roles[id] }
+
+// HasChildren 是否存在子角色
+func (h *RoleHierarchy) HasChildren(id uint) bool { return len(h.children[id]) > 0 }
+
+// Ancestors 自近及远返回角色的祖先链，不含自身；遇到缺失的父角色或环时停止
+func (h *RoleHierarchy) Ancestors(id uint) []*entity.Role {
+	var res []*entity.Role
+	seen := map[uint]struct{}{id: {}}
+	r := h.roles[id]
+	for r != nil && r.ParentID != 0 {
+		if _, ok := seen[r.ParentID]; ok {
+			break
+		}
+		seen[r.ParentID] = struct{}{}
+		r = h.roles[r.ParentID]
+		if r != nil {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+// Descendants 返回角色自身及全部后代角色 ID
+func (h *RoleHierarchy) Descendants(id uint) []uint {
+	res := []uint{id}
+	seen := map[uint]struct{}{id: {}}
+	for i := 0; i < len(res); i++ {
+		for _, c := range h.children[res[i]] {
+			if _, ok := seen[c]; !ok {
+				seen[c] = struct{}{}
+				res = append(res, c)
+			}
+		}
+	}
+	return res
+}
+
+// WithParent 返回将 id 的父角色改为 parentID 后的快照（原快照不变），用于变更前校验
+func (h *RoleHierarchy) WithParent(id, parentID uint) *RoleHierarchy {
+	roles := make([]*entity.Role, 0, len(h.roles))
+	for _, r := range h.roles {
+		if r.ID == id {
+			moved := *r
+			moved.ParentID = parentID
+			r = &moved
+		}
+		roles = append(roles, r)
+	}
+	return NewRoleHierarchy(roles)
+}
+
+// WouldCycle 将 id 的父角色设为 parentID 是否会形成环（含指向自身）
+func (h *RoleHierarchy) WouldCycle(id, parentID uint) bool {
+	if id == 0 || parentID == 0 {
+		return false
+	}
+	if id == parentID {
+		return true
+	}
+	for _, a := range h.Ancestors(parentID) {
+		if a.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Effective 返回一组直接绑定角色的生效角色 ID（含继承的祖先，按 ID 升序）
+// 禁用的角色不生效，也不再向上继承
+func (h *RoleHierarchy) Effective(roleIDs []uint) []uint {
+	set := map[uint]struct{}{}
+	for _, id := range roleIDs {
+		r := h.roles[id]
+		if r == nil || r.Status != 0 {
+			continue
+		}
+		set[id] = struct{}{}
+		for _, a := range h.Ancestors(id) {
+			if a.Status != 0 {
+				break
+			}
+			set[a.ID] = struct{}{}
+		}
+	}
+	res := make([]uint, 0, len(set))
+	for id := range set {
+		res = append(res, id)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res
+}
@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+// UserIdentity 本地用户绑定的外部身份（上游 IdP 的 provider + sub 唯一）
+type UserIdentity struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	UserID      uint       `json:"userId" gorm:"index;not null"`
+	Provider    string     `json:"provider" gorm:"size:50;not null;uniqueIndex:idx_identity_provider_subject"`
+	Subject     string     `json:"subject" gorm:"size:255;not null;uniqueIndex:idx_identity_provider_subject"`
+	Email       string     `json:"email" gorm:"size:100"`
+	LastLoginAt *time.Time `json:"lastLoginAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+func (UserIdentity) TableName() string { return "user_identities" }
@@ -0,0 +1,39 @@
+package entity
+
+import "time"
+
+// LoginLog 登录记录（成功与失败均记录）；用户名不存在时 UserID 为 0
+type LoginLog struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"userId" gorm:"index"`
+	Username  string    `json:"username" gorm:"size:50;index"`
+	Method    string    `json:"method" gorm:"size:20"` // password / mfa / federated
+	Success   bool      `json:"success" gorm:"index"`
+	Reason    string    `json:"reason" gorm:"size:50"` // 失败原因，见 LoginReason*
+	IP        string    `json:"ip" gorm:"size:64;index"`
+	UserAgent string    `json:"userAgent" gorm:"size:512"`
+	Nation    string    `json:"nation" gorm:"size:100"`
+	Province  string    `json:"province" gorm:"size:100"`
+	City      string    `json:"city" gorm:"size:100"`
+	CreatedAt time.Time `json:"createdAt" gorm:"index"`
+}
+
+func (LoginLog) TableName() string { return "login_logs" }
+
+// 登录方式
+const (
+	LoginMethodPassword  = "password"
+	LoginMethodMFA       = "mfa"
+	LoginMethodFederated = "federated"
+)
+
+// 登录失败原因
+const (
+	LoginReasonUserNotFound     = "user_not_found"
+	LoginReasonInvalidPassword  = "invalid_password"
+	LoginReasonInvalidMFACode   = "invalid_mfa_code"
+	LoginReasonLocked           = "locked"
+	LoginReasonThrottled        = "throttled"
+	LoginReasonEmailNotVerified = "email_not_verified"
+	LoginReasonError            = "error"
+)
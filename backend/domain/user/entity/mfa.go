@@ -0,0 +1,28 @@
+package entity
+
+import "time"
+
+// UserMFA 用户两步验证（TOTP）配置，Enabled 为 false 表示登记中尚未确认
+type UserMFA struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	UserID       uint       `json:"userId" gorm:"uniqueIndex;not null"`
+	Secret       string     `json:"-" gorm:"size:64;not null"`
+	Enabled      bool       `json:"enabled" gorm:"default:false"`
+	LastUsedStep int64      `json:"-" gorm:"default:0"` // 最近一次通过校验的时间步，防止验证码重放
+	EnabledAt    *time.Time `json:"enabledAt"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+}
+
+func (UserMFA) TableName() string { return "user_mfa" }
+
+// UserRecoveryCode 一次性恢复码（仅存哈希）
+type UserRecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"userId" gorm:"index;not null"`
+	CodeHash  string     `json:"-" gorm:"size:64;not null"`
+	UsedAt    *time.Time `json:"usedAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+func (UserRecoveryCode) TableName() string { return "user_recovery_codes" }
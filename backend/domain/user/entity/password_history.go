@@ -0,0 +1,13 @@
+package entity
+
+import "time"
+
+// PasswordHistory 历史密码哈希，用于禁止重复使用最近的密码
+type PasswordHistory struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"userId" gorm:"index;not null"`
+	Hash      string    `json:"-" gorm:"size:255;not null"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (PasswordHistory) TableName() string { return "password_histories" }
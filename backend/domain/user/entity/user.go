@@ -7,25 +7,48 @@ import (
 )
 
 type User struct {
-	ID                uint           `json:"id" gorm:"primaryKey"`
-	Username          string         `json:"username" gorm:"uniqueIndex;not null;size:50"`
-	Password          string         `json:"-" gorm:"not null;size:255"`
-	Avatar            string         `json:"avatar" gorm:"size:255"`
-	Nickname          string         `json:"nickname" gorm:"size:50"`
-	UserType          int16          `json:"userType" gorm:"default:0"` // 0 普通 1 超管
-	Email             string         `json:"email" gorm:"size:100"`
-	Mobile            string         `json:"mobile" gorm:"size:30"`
-	Sort              int            `json:"sort" gorm:"default:1"`
-	Status            int16          `json:"status" gorm:"default:0"` // 0 正常 1 禁用
-	LastLoginIP       string         `json:"lastLoginIp" gorm:"size:30"`
-	LastLoginNation   string         `json:"lastLoginNation" gorm:"size:100"`
-	LastLoginProvince string         `json:"lastLoginProvince" gorm:"size:100"`
-	LastLoginCity     string         `json:"lastLoginCity" gorm:"size:100"`
-	LastLoginDate     *time.Time     `json:"lastLoginDate"`
-	Salt              string         `json:"-" gorm:"size:30"`
-	CreatedAt         time.Time      `json:"createdAt"`
-	UpdatedAt         time.Time      `json:"updatedAt"`
-	DeletedAt         gorm.DeletedAt `json:"-" gorm:"index"`
+	ID                 uint           `json:"id" gorm:"primaryKey"`
+	Username           string         `json:"username" gorm:"uniqueIndex;not null;size:50"`
+	Password           string         `json:"-" gorm:"not null;size:255"`
+	Avatar             string         `json:"avatar" gorm:"size:255"`
+	Nickname           string         `json:"nickname" gorm:"size:50"`
+	UserType           int16          `json:"userType" gorm:"default:0"` // 0 普通 1 超管
+	Email              string         `json:"email" gorm:"size:100"`
+	Mobile             string         `json:"mobile" gorm:"size:30"`
+	Sort               int            `json:"sort" gorm:"default:1"`
+	Status             int16          `json:"status" gorm:"default:0"`       // 0 正常 1 禁用
+	DeptID             uint           `json:"deptId" gorm:"index;default:0"` // 所属部门，0 表示未分配
+	LastLoginIP        string         `json:"lastLoginIp" gorm:"size:64"`
+	LastLoginNation    string         `json:"lastLoginNation" gorm:"size:100"`
+	LastLoginProvince  string         `json:"lastLoginProvince" gorm:"size:100"`
+	LastLoginCity      string         `json:"lastLoginCity" gorm:"size:100"`
+	LastLoginDate      *time.Time     `json:"lastLoginDate"`
+	AuthSource         string         `json:"authSource" gorm:"size:20"`
+	EmailVerifiedAt    *time.Time     `json:"emailVerifiedAt"`
+	PasswordChangedAt  *time.Time     `json:"passwordChangedAt"`
+	MustChangePassword bool           `json:"mustChangePassword" gorm:"default:false"` // 管理员重置后须在下次登录时修改密码
+	Salt               string         `json:"-" gorm:"size:30"`                        // 已弃用：盐值内嵌于 Password 的 PHC 哈希串
+	CreatedAt          time.Time      `json:"createdAt"`
+	UpdatedAt          time.Time      `json:"updatedAt"`
+	DeletedAt          gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// 用户类型（User.UserType）
+const (
+	UserTypeNormal     int16 = 0
+	UserTypeSuperAdmin int16 = 1 // 超级管理员：跳过权限校验，可见全部菜单
+)
+
+// IsSuperAdmin 是否为超级管理员
+func (u *User) IsSuperAdmin() bool {
+	return u.UserType == UserTypeSuperAdmin
+}
+
+// 认证来源（User.AuthSource），为空时跟随全局配置 LDAP_DEFAULT_AUTH
+const (
+	AuthSourceLocal = "local"
+	AuthSourceLDAP  = "ldap"
+	AuthSourceOIDC  = "oidc" // 外部身份首次登录时创建，没有可用的本地密码；设置本地密码后转为 local
+)
+
 func (User) TableName() string { return "users" }
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/sine-io/sinx/domain/user/entity"
+)
+
+type IdentityRepository interface {
+	// GetByProviderSubject 未绑定时返回 (nil, nil)
+	GetByProviderSubject(ctx context.Context, provider, subject string) (*entity.UserIdentity, error)
+	ListByUser(ctx context.Context, userID uint) ([]*entity.UserIdentity, error)
+	Create(ctx context.Context, identity *entity.UserIdentity) error
+	// Delete 仅删除属于该用户的身份，返回是否删除
+	Delete(ctx context.Context, userID, id uint) (bool, error)
+	TouchLastLogin(ctx context.Context, id uint, at time.Time) error
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/sine-io/sinx/domain/user/entity"
+)
+
+// LoginLogFilter 登录记录查询条件，零值字段不参与过滤
+type LoginLogFilter struct {
+	UserID   uint
+	Username string
+	IP       string
+	Success  *bool
+	Since    time.Time
+	Until    time.Time
+}
+
+type LoginLogRepository interface {
+	Create(ctx context.Context, log *entity.LoginLog) error
+	// List 按时间倒序分页
+	List(ctx context.Context, filter LoginLogFilter, offset, limit int) ([]*entity.LoginLog, int64, error)
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/sine-io/sinx/domain/user/entity"
+)
+
+type MFARepository interface {
+	// GetByUserID 未登记时返回 (nil, nil)
+	GetByUserID(ctx context.Context, userID uint) (*entity.UserMFA, error)
+	Save(ctx context.Context, mfa *entity.UserMFA) error
+	// AdvanceStep 原子地推进最近使用的时间步，返回 false 表示该步已被使用（重放）
+	AdvanceStep(ctx context.Context, userID uint, step int64) (bool, error)
+	// Delete 删除 TOTP 配置及全部恢复码
+	Delete(ctx context.Context, userID uint) error
+	ReplaceRecoveryCodes(ctx context.Context, userID uint, codeHashes []string) error
+	// UseRecoveryCode 原子地消费一个未使用的恢复码，返回是否成功
+	UseRecoveryCode(ctx context.Context, userID uint, codeHash string) (bool, error)
+	CountRecoveryCodes(ctx context.Context, userID uint) (int64, error)
+}
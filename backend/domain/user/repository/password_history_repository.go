@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/sine-io/sinx/domain/user/entity"
+)
+
+type PasswordHistoryRepository interface {
+	// Recent 最近 n 条，按时间倒序
+	Recent(ctx context.Context, userID uint, n int) ([]*entity.PasswordHistory, error)
+	// Add 记录新密码并只保留最近 keep 条
+	Add(ctx context.Context, userID uint, hash string, keep int) error
+}
@@ -12,7 +12,14 @@ type UserRepository interface {
 	GetByUsername(ctx context.Context, username string) (*entity.User, error)
 	GetByEmail(ctx context.Context, email string) (*entity.User, error)
 	Update(ctx context.Context, user *entity.User) error
+	// UpdateLastLogin 仅更新 last_login_* 列，避免以过期的用户快照覆盖并发修改
+	UpdateLastLogin(ctx context.Context, user *entity.User) error
 	Delete(ctx context.Context, id uint) error
 	List(ctx context.Context, offset, limit int) ([]*entity.User, error)
 	Count(ctx context.Context) (int64, error)
+	// CountSuperAdmins 统计状态正常的超级管理员数量
+	CountSuperAdmins(ctx context.Context) (int64, error)
+	// LockSuperAdmins 在持有超级管理员锁的事务中执行 fn（fn 须使用传入的 ctx），
+	// 使“还有其他超级管理员”的校验与随后的降级 / 禁用 / 删除在多实例间串行
+	LockSuperAdmins(ctx context.Context, fn func(ctx context.Context) error) error
 }
@@ -0,0 +1,97 @@
+package usertest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sine-io/sinx/domain/user/entity"
+	"github.com/sine-io/sinx/domain/user/repository"
+)
+
+var _ repository.MFARepository = (*MFARepo)(nil)
+
+// MFARepo 内存版 MFARepository：GetByUserID 返回副本，AdvanceStep / UseRecoveryCode 与数据库实现一样原子地条件更新
+type MFARepo struct {
+	mu    sync.Mutex
+	Data  map[uint]*entity.UserMFA
+	codes map[uint][]*entity.UserRecoveryCode
+}
+
+func NewMFARepo() *MFARepo {
+	return &MFARepo{Data: map[uint]*entity.UserMFA{}, codes: map[uint][]*entity.UserRecoveryCode{}}
+}
+
+func (m *MFARepo) GetByUserID(_ context.Context, userID uint) (*entity.UserMFA, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mfa, ok := m.Data[userID]
+	if !ok {
+		return nil, nil
+	}
+	cp := *mfa
+	return &cp, nil
+}
+
+func (m *MFARepo) Save(_ context.Context, mfa *entity.UserMFA) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cp := *mfa
+	m.Data[mfa.UserID] = &cp
+	return nil
+}
+
+func (m *MFARepo) AdvanceStep(_ context.Context, userID uint, step int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mfa, ok := m.Data[userID]
+	if !ok || mfa.LastUsedStep >= step {
+		return false, nil
+	}
+	mfa.LastUsedStep = step
+	return true, nil
+}
+
+func (m *MFARepo) Delete(_ context.Context, userID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.Data, userID)
+	delete(m.codes, userID)
+	return nil
+}
+
+func (m *MFARepo) ReplaceRecoveryCodes(_ context.Context, userID uint, codeHashes []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	codes := make([]*entity.UserRecoveryCode, 0, len(codeHashes))
+	for _, h := range codeHashes {
+		codes = append(codes, &entity.UserRecoveryCode{UserID: userID, CodeHash: h})
+	}
+	m.codes[userID] = codes
+	return nil
+}
+
+func (m *MFARepo) UseRecoveryCode(_ context.Context, userID uint, codeHash string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range m.codes[userID] {
+		if c.CodeHash == codeHash && c.UsedAt == nil {
+			now := time.Now()
+			c.UsedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MFARepo) CountRecoveryCodes(_ context.Context, userID uint) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for _, c := range m.codes[userID] {
+		if c.UsedAt == nil {
+			n++
+		}
+	}
+	return n, nil
+}
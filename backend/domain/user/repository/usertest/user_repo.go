@@ -0,0 +1,159 @@
+// Package usertest 内存版 UserRepository，仅用于测试
+// 行为与数据库实现保持一致：查询不到时返回 gorm.ErrRecordNotFound，删除为置为禁用，列表只含正常用户并按数据范围过滤
+package usertest
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/sine-io/sinx/domain/user/entity"
+	"github.com/sine-io/sinx/domain/user/repository"
+	"github.com/sine-io/sinx/pkg/datascope"
+
+	"gorm.io/gorm"
+)
+
+var _ repository.UserRepository = (*UserRepo)(nil)
+
+// UserRepo Data 可在测试中直接读写
+type UserRepo struct {
+	mu     sync.Mutex
+	Data   map[uint]*entity.User
+	nextID uint
+
+	superAdminMu sync.Mutex
+}
+
+// NewUserRepo 预置的用户须带 ID；之后 Create 的用户 ID 从已有最大值递增
+func NewUserRepo(users ...*entity.User) *UserRepo {
+	m := &UserRepo{Data: map[uint]*entity.User{}}
+	for _, u := range users {
+		m.Data[u.ID] = u
+		m.nextID = max(m.nextID, u.ID)
+	}
+	return m
+}
+
+func (m *UserRepo) Create(_ context.Context, u *entity.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if u.ID == 0 {
+		m.nextID++
+		u.ID = m.nextID
+	}
+	m.nextID = max(m.nextID, u.ID)
+	m.Data[u.ID] = u
+	return nil
+}
+
+func (m *UserRepo) GetByID(_ context.Context, id uint) (*entity.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if u, ok := m.Data[id]; ok {
+		return u, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *UserRepo) GetByUsername(_ context.Context, username string) (*entity.User, error) {
+	return m.find(func(u *entity.User) bool { return u.Username == username })
+}
+
+func (m *UserRepo) GetByEmail(_ context.Context, email string) (*entity.User, error) {
+	return m.find(func(u *entity.User) bool { return u.Email == email })
+}
+
+func (m *UserRepo) find(match func(*entity.User) bool) (*entity.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range m.sorted() {
+		if match(u) {
+			return u, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *UserRepo) Update(_ context.Context, u *entity.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Data[u.ID] = u
+	return nil
+}
+
+func (m *UserRepo) UpdateLastLogin(_ context.Context, u *entity.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.Data[u.ID]
+	if !ok {
+		return nil
+	}
+	stored.LastLoginIP, stored.LastLoginDate = u.LastLoginIP, u.LastLoginDate
+	stored.LastLoginNation, stored.LastLoginProvince, stored.LastLoginCity = u.LastLoginNation, u.LastLoginProvince, u.LastLoginCity
+	return nil
+}
+
+func (m *UserRepo) Delete(_ context.Context, id uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if u, ok := m.Data[id]; ok {
+		u.Status = 1
+	}
+	return nil
+}
+
+func (m *UserRepo) List(ctx context.Context, offset, limit int) ([]*entity.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res := m.visible(ctx)
+	if offset >= len(res) {
+		return []*entity.User{}, nil
+	}
+	return res[offset:min(offset+limit, len(res))], nil
+}
+
+func (m *UserRepo) Count(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return int64(len(m.visible(ctx))), nil
+}
+
+func (m *UserRepo) CountSuperAdmins(_ context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for _, u := range m.Data {
+		if u.Status == 0 && u.IsSuperAdmin() {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *UserRepo) LockSuperAdmins(ctx context.Context, fn func(ctx context.Context) error) error {
+	m.superAdminMu.Lock()
+	defer m.superAdminMu.Unlock()
+	return fn(ctx)
+}
+
+// visible 状态正常且在 ctx 数据范围内的用户，按 ID 升序
+func (m *UserRepo) visible(ctx context.Context) []*entity.User {
+	scope := datascope.FromContext(ctx)
+	res := []*entity.User{}
+	for _, u := range m.sorted() {
+		if u.Status == 0 && scope.Allows(u.DeptID, u.ID) {
+			res = append(res, u)
+		}
+	}
+	return res
+}
+
+func (m *UserRepo) sorted() []*entity.User {
+	res := make([]*entity.User, 0, len(m.Data))
+	for _, u := range m.Data {
+		res = append(res, u)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/sine-io/sinx/domain/user/entity"
+	"github.com/sine-io/sinx/domain/user/repository"
+	"github.com/sine-io/sinx/pkg/geoip"
+	"github.com/sine-io/sinx/pkg/logger"
+)
+
+// GeoResolver IP 归属地解析
+type GeoResolver interface {
+	Lookup(ip string) geoip.Location
+}
+
+// LoginAttempt 一次登录尝试
+type LoginAttempt struct {
+	UserID    uint
+	Username  string
+	Method    string
+	Success   bool
+	Reason    string
+	IP        string
+	UserAgent string
+}
+
+type LoginLogDomainService struct {
+	logRepo  repository.LoginLogRepository
+	userRepo repository.UserRepository
+	geo      GeoResolver
+}
+
+// NewLoginLogDomainService geo 为 nil 时不解析归属地
+func NewLoginLogDomainService(logRepo repository.LoginLogRepository, userRepo repository.UserRepository, geo GeoResolver) *LoginLogDomainService {
+	return &LoginLogDomainService{logRepo: logRepo, userRepo: userRepo, geo: geo}
+}
+
+// Record 写入登录记录；登录成功时同时更新用户的最近登录信息。失败只记日志，不影响登录结果
+func (s *LoginLogDomainService) Record(ctx context.Context, attempt LoginAttempt, user *entity.User) {
+	var loc geoip.Location
+	if s.geo != nil {
+		loc = s.geo.Lookup(attempt.IP)
+	}
+	if user != nil {
+		attempt.UserID, attempt.Username = user.ID, user.Username
+	}
+	log := &entity.LoginLog{
+		UserID:    attempt.UserID,
+		Username:  truncate(attempt.Username, 50),
+		Method:    attempt.Method,
+		Success:   attempt.Success,
+		Reason:    attempt.Reason,
+		IP:        attempt.IP,
+		UserAgent: truncate(attempt.UserAgent, 512),
+		Nation:    loc.Nation,
+		Province:  loc.Province,
+		City:      loc.City,
+	}
+	if err := s.logRepo.Create(ctx, log); err != nil {
+		logger.Warn("login_log_save_failed", "username", attempt.Username, "error", err)
+	}
+	if !attempt.Success || user == nil {
+		return
+	}
+
+	now := time.Now()
+	user.LastLoginIP = attempt.IP
+	user.LastLoginNation, user.LastLoginProvince, user.LastLoginCity = loc.Nation, loc.Province, loc.City
+	user.LastLoginDate = &now
+	if err := s.userRepo.UpdateLastLogin(ctx, user); err != nil {
+		logger.Warn("last_login_update_failed", "userId", user.ID, "error", err)
+	}
+}
+
+// List 分页查询登录记录
+func (s *LoginLogDomainService) List(ctx context.Context, filter repository.LoginLogFilter, offset, limit int) ([]*entity.LoginLog, int64, error) {
+	return s.logRepo.List(ctx, filter, offset, limit)
+}
+
+// truncate 按字符截断，避免超出列长度
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
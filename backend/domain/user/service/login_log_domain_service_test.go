@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sine-io/sinx/domain/user/entity"
+	"github.com/sine-io/sinx/domain/user/repository"
+	"github.com/sine-io/sinx/domain/user/repository/usertest"
+	"github.com/sine-io/sinx/pkg/config"
+	"github.com/sine-io/sinx/pkg/geoip"
+	"github.com/sine-io/sinx/pkg/logger"
+)
+
+type memLoginLogRepo struct{ logs []*entity.LoginLog }
+
+func (m *memLoginLogRepo) Create(_ context.Context, log *entity.LoginLog) error {
+	log.ID = uint(len(m.logs) + 1)
+	m.logs = append(m.logs, log)
+	return nil
+}
+func (m *memLoginLogRepo) List(_ context.Context, _ repository.LoginLogFilter, _, _ int) ([]*entity.LoginLog, int64, error) {
+	return m.logs, int64(len(m.logs)), nil
+}
+
+type stubGeo map[string]geoip.Location
+
+func (g stubGeo) Lookup(ip string) geoip.Location { return g[ip] }
+
+// 登录成功写入记录并只更新最近登录信息；失败只写记录
+func TestLoginLogRecord(t *testing.T) {
+	_ = config.LoadEnv()
+	_ = logger.Init()
+	ctx := context.Background()
+	stored := &entity.User{ID: 1, Username: "alice", Nickname: "Alice"}
+	users := usertest.NewUserRepo(stored)
+	logs := &memLoginLogRepo{}
+	geo := stubGeo{"8.8.8.8": {Nation: "美国", Province: "加利福尼亚州", City: "山景城"}}
+	svc := NewLoginLogDomainService(logs, users, geo)
+
+	// 登录期间用户资料被并发修改：记录最近登录信息不能以旧快照覆盖
+	snapshot := *stored
+	stored.Nickname = "Alice Liddell"
+	svc.Record(ctx, LoginAttempt{Method: "password", Success: true, IP: "8.8.8.8", UserAgent: strings.Repeat("x", 600)}, &snapshot)
+
+	if len(logs.logs) != 1 {
+		t.Fatalf("expected one log, got %d", len(logs.logs))
+	}
+	l := logs.logs[0]
+	if l.UserID != 1 || l.Username != "alice" || !l.Success || l.City != "山景城" || len([]rune(l.UserAgent)) != 512 {
+		t.Fatalf("unexpected log: %+v", l)
+	}
+	if stored.LastLoginIP != "8.8.8.8" || stored.LastLoginCity != "山景城" || stored.LastLoginDate == nil {
+		t.Fatalf("last login not updated: %+v", stored)
+	}
+	if stored.Nickname != "Alice Liddell" {
+		t.Fatalf("concurrent profile change was overwritten: %q", stored.Nickname)
+	}
+
+	// 失败的登录：用户名来自请求，不更新最近登录信息
+	last := stored.LastLoginDate
+	svc.Record(ctx, LoginAttempt{Username: "alice", Method: "password", Reason: "invalid_password", IP: "1.1.1.1"}, nil)
+	if l := logs.logs[1]; l.Success || l.Username != "alice" || l.Reason != "invalid_password" || l.Nation != "" {
+		t.Fatalf("unexpected failure log: %+v", l)
+	}
+	if stored.LastLoginDate != last || stored.LastLoginIP != "8.8.8.8" {
+		t.Fatal("failed login must not update last login")
+	}
+}
+
+// 未配置归属地库（nil 接口或空的 *geoip.DB）时归属地留空，记录照常写入
+func TestLoginLogGeoFallback(t *testing.T) {
+	_ = config.LoadEnv()
+	_ = logger.Init()
+	ctx := context.Background()
+	var emptyDB *geoip.DB
+	for name, geo := range map[string]GeoResolver{"nil resolver": nil, "nil db": emptyDB} {
+		user := &entity.User{ID: 1, Username: "alice"}
+		logs := &memLoginLogRepo{}
+		svc := NewLoginLogDomainService(logs, usertest.NewUserRepo(user), geo)
+		svc.Record(ctx, LoginAttempt{Method: "password", Success: true, IP: "8.8.8.8"}, user)
+		if len(logs.logs) != 1 {
+			t.Fatalf("%s: log not written", name)
+		}
+		if l := logs.logs[0]; l.Nation != "" || l.Province != "" || l.City != "" {
+			t.Fatalf("%s: expected empty location, got %+v", name, l)
+		}
+		if user.LastLoginIP != "8.8.8.8" || user.LastLoginDate == nil {
+			t.Fatalf("%s: last login not updated", name)
+		}
+	}
+}
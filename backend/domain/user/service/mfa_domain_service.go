@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"strings"
+	"time"
+
+	"github.com/sine-io/sinx/domain/user/entity"
+	"github.com/sine-io/sinx/domain/user/repository"
+	"github.com/sine-io/sinx/pkg/errorx"
+	"github.com/sine-io/sinx/pkg/totp"
+	"github.com/sine-io/sinx/pkg/utils"
+)
+
+const (
+	recoveryCodeCount    = 10
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789" // 去掉易混淆字符
+)
+
+// MFADomainService TOTP 两步验证
+type MFADomainService struct {
+	mfaRepo repository.MFARepository
+	issuer  string
+}
+
+func NewMFADomainService(mfaRepo repository.MFARepository, issuer string) *MFADomainService {
+	return &MFADomainService{mfaRepo: mfaRepo, issuer: issuer}
+}
+
+// IsEnabled 用户是否已启用两步验证
+func (s *MFADomainService) IsEnabled(ctx context.Context, userID uint) (bool, error) {
+	m, err := s.mfaRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return m != nil && m.Enabled, nil
+}
+
+// BeginEnrollment 生成新密钥并返回 otpauth URI；需调用 ConfirmEnrollment 校验后才生效
+func (s *MFADomainService) BeginEnrollment(ctx context.Context, user *entity.User) (secret, uri string, err error) {
+	m, err := s.mfaRepo.GetByUserID(ctx, user.ID)
+	if err != nil {
+		return "", "", err
+	}
+	if m != nil && m.Enabled {
+		return "", "", errorx.NewWithCode(errorx.ErrMFAAlreadyEnabled)
+	}
+	if m == nil {
+		m = &entity.UserMFA{UserID: user.ID}
+	}
+	secret, err = totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	m.Secret = secret
+	m.LastUsedStep = 0
+	if err := s.mfaRepo.Save(ctx, m); err != nil {
+		return "", "", err
+	}
+	return secret, totp.URI(s.issuer, user.Username, secret), nil
+}
+
+// ConfirmEnrollment 校验首个验证码后启用两步验证，返回一次性恢复码明文（仅此一次）
+func (s *MFADomainService) ConfirmEnrollment(ctx context.Context, userID uint, code string) ([]string, error) {
+	m, err := s.mfaRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, errorx.NewWithCode(errorx.ErrMFANotEnrolled)
+	}
+	if m.Enabled {
+		return nil, errorx.NewWithCode(errorx.ErrMFAAlreadyEnabled)
+	}
+	step, ok := totp.Validate(m.Secret, code, time.Now(), m.LastUsedStep)
+	if !ok {
+		return nil, errorx.NewWithCode(errorx.ErrMFAInvalidCode)
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfaRepo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	m.Enabled = true
+	m.EnabledAt = &now
+	m.LastUsedStep = step
+	if err := s.mfaRepo.Save(ctx, m); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify 校验 TOTP 验证码或恢复码（恢复码使用后即失效）
+func (s *MFADomainService) Verify(ctx context.Context, userID uint, code string) error {
+	m, err := s.mfaRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if m == nil || !m.Enabled {
+		return errorx.NewWithCode(errorx.ErrMFANotEnrolled)
+	}
+	if step, ok := totp.Validate(m.Secret, code, time.Now(), m.LastUsedStep); ok {
+		advanced, err := s.mfaRepo.AdvanceStep(ctx, userID, step)
+		if err != nil {
+			return err
+		}
+		if advanced {
+			return nil
+		}
+		return errorx.NewWithCode(errorx.ErrMFAInvalidCode)
+	}
+	used, err := s.mfaRepo.UseRecoveryCode(ctx, userID, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return errorx.NewWithCode(errorx.ErrMFAInvalidCode)
+	}
+	return nil
+}
+
+// RemainingRecoveryCodes 剩余可用恢复码数量
+func (s *MFADomainService) RemainingRecoveryCodes(ctx context.Context, userID uint) (int64, error) {
+	return s.mfaRepo.CountRecoveryCodes(ctx, userID)
+}
+
+// Reset 清除用户的两步验证配置（管理员操作，用户需重新登记）
+func (s *MFADomainService) Reset(ctx context.Context, userID uint) error {
+	return s.mfaRepo.Delete(ctx, userID)
+}
+
+// generateRecoveryCodes 生成恢复码（xxxxx-xxxxx）及其哈希
+func generateRecoveryCodes() (codes, hashes []string, err error) {
+	buf := make([]byte, 10)
+	for i := 0; i < recoveryCodeCount; i++ {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		var sb strings.Builder
+		for j, b := range buf {
+			if j == 5 {
+				sb.WriteByte('-')
+			}
+			sb.WriteByte(recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)])
+		}
+		code := sb.String()
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode 忽略大小写与分隔符后计算哈希
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return utils.SHA256Hex(normalized)
+}
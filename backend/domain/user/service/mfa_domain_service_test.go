@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sine-io/sinx/domain/user/entity"
+	"github.com/sine-io/sinx/domain/user/repository/usertest"
+	"github.com/sine-io/sinx/pkg/errorx"
+	"github.com/sine-io/sinx/pkg/totp"
+)
+
+// staleMFARepo 模拟并发请求：GetByUserID 返回 AdvanceStep 之前读取的快照
+type staleMFARepo struct {
+	*usertest.MFARepo
+	snapshot *entity.UserMFA
+}
+
+func (r *staleMFARepo) GetByUserID(_ context.Context, _ uint) (*entity.UserMFA, error) {
+	cp := *r.snapshot
+	return &cp, nil
+}
+
+func expectCode(t *testing.T, err error, code errorx.ErrorCode) {
+	t.Helper()
+	if appErr, ok := err.(*errorx.Error); !ok || appErr.Code != code {
+		t.Fatalf("expected error code %d, got %v", code, err)
+	}
+}
+
+func enrollMFA(t *testing.T, svc *MFADomainService, user *entity.User) (secret string, recovery []string) {
+	t.Helper()
+	ctx := context.Background()
+	secret, _, err := svc.BeginEnrollment(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := totp.CodeAt(secret, totp.Step(time.Now()))
+	recovery, err = svc.ConfirmEnrollment(ctx, user.ID, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return secret, recovery
+}
+
+// 登记后须以验证码确认才生效
+func TestMFAEnrollmentRequiresConfirmation(t *testing.T) {
+	ctx := context.Background()
+	svc := NewMFADomainService(usertest.NewMFARepo(), "sinx")
+	user := &entity.User{ID: 1, Username: "alice"}
+
+	secret, uri, err := svc.BeginEnrollment(ctx, user)
+	if err != nil || secret == "" || uri == "" {
+		t.Fatalf("begin enrollment: %v", err)
+	}
+	if enabled, _ := svc.IsEnabled(ctx, user.ID); enabled {
+		t.Fatal("enrollment must not be active before confirmation")
+	}
+	code, _ := totp.CodeAt(secret, totp.Step(time.Now()))
+	expectCode(t, svc.Verify(ctx, user.ID, code), errorx.ErrMFANotEnrolled)
+	if _, err := svc.ConfirmEnrollment(ctx, user.ID, "000000x"); err == nil {
+		t.Fatal("invalid code must not confirm enrollment")
+	}
+
+	codes, err := svc.ConfirmEnrollment(ctx, user.ID, code)
+	if err != nil || len(codes) != recoveryCodeCount {
+		t.Fatalf("confirm enrollment: %v (%d codes)", err, len(codes))
+	}
+	if enabled, _ := svc.IsEnabled(ctx, user.ID); !enabled {
+		t.Fatal("enrollment must be active after confirmation")
+	}
+	_, _, err = svc.BeginEnrollment(ctx, user)
+	expectCode(t, err, errorx.ErrMFAAlreadyEnabled)
+}
+
+// 恢复码只能使用一次，且忽略大小写与分隔符
+func TestMFARecoveryCodeSingleUse(t *testing.T) {
+	ctx := context.Background()
+	svc := NewMFADomainService(usertest.NewMFARepo(), "sinx")
+	user := &entity.User{ID: 1, Username: "alice"}
+	_, recovery := enrollMFA(t, svc, user)
+
+	if err := svc.Verify(ctx, user.ID, recovery[0]); err != nil {
+		t.Fatalf("recovery code rejected: %v", err)
+	}
+	expectCode(t, svc.Verify(ctx, user.ID, recovery[0]), errorx.ErrMFAInvalidCode)
+	if n, _ := svc.RemainingRecoveryCodes(ctx, user.ID); n != recoveryCodeCount-1 {
+		t.Fatalf("expected %d remaining codes, got %d", recoveryCodeCount-1, n)
+	}
+	if err := svc.Verify(ctx, user.ID, " "+strings.ToUpper(recovery[1][:5])+recovery[1][6:]); err != nil {
+		t.Fatalf("normalized recovery code rejected: %v", err)
+	}
+}
+
+// 并发请求读取到同一时间步之前的状态时，由 AdvanceStep 拒绝重放
+func TestMFAReplayRejectedByAdvanceStep(t *testing.T) {
+	ctx := context.Background()
+	repo := usertest.NewMFARepo()
+	svc := NewMFADomainService(repo, "sinx")
+	user := &entity.User{ID: 1, Username: "alice"}
+	secret, _ := enrollMFA(t, svc, user)
+
+	snapshot, _ := repo.GetByUserID(ctx, user.ID)
+	code, _ := totp.CodeAt(secret, snapshot.LastUsedStep+1)
+	if err := svc.Verify(ctx, user.ID, code); err != nil {
+		t.Fatalf("fresh code rejected: %v", err)
+	}
+	expectCode(t, svc.Verify(ctx, user.ID, code), errorx.ErrMFAInvalidCode)
+
+	// 旧快照下 totp.Validate 会通过，只有 AdvanceStep 能发现该步已被使用
+	stale := NewMFADomainService(&staleMFARepo{MFARepo: repo, snapshot: snapshot}, "sinx")
+	expectCode(t, stale.Verify(ctx, user.ID, code), errorx.ErrMFAInvalidCode)
+}
+
+// 管理员重置后配置与恢复码一并清除，用户须重新登记
+func TestMFAReset(t *testing.T) {
+	ctx := context.Background()
+	svc := NewMFADomainService(usertest.NewMFARepo(), "sinx")
+	user := &entity.User{ID: 1, Username: "alice"}
+	_, recovery := enrollMFA(t, svc, user)
+
+	if err := svc.Reset(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if enabled, _ := svc.IsEnabled(ctx, user.ID); enabled {
+		t.Fatal("mfa must be disabled after reset")
+	}
+	if n, _ := svc.RemainingRecoveryCodes(ctx, user.ID); n != 0 {
+		t.Fatalf("recovery codes must be removed, %d left", n)
+	}
+	expectCode(t, svc.Verify(ctx, user.ID, recovery[0]), errorx.ErrMFANotEnrolled)
+	enrollMFA(t, svc, user)
+}
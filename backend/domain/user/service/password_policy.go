@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/sine-io/sinx/domain/user/entity"
+	"github.com/sine-io/sinx/domain/user/repository"
+	"github.com/sine-io/sinx/pkg/errorx"
+	"github.com/sine-io/sinx/pkg/logger"
+	"github.com/sine-io/sinx/pkg/passwordpolicy"
+	"github.com/sine-io/sinx/pkg/utils"
+)
+
+// PasswordPolicyService 密码策略：强度校验、历史密码与有效期。nil 表示不启用任何策略
+type PasswordPolicyService struct {
+	policy      *passwordpolicy.Policy
+	historyRepo repository.PasswordHistoryRepository
+}
+
+// NewPasswordPolicyService historyRepo 为 nil 时不检查历史密码
+func NewPasswordPolicyService(policy *passwordpolicy.Policy, historyRepo repository.PasswordHistoryRepository) *PasswordPolicyService {
+	return &PasswordPolicyService{policy: policy, historyRepo: historyRepo}
+}
+
+// Validate 校验新密码，违反时返回 ErrPasswordPolicy 并附带字段错误；user 为 nil 表示新建用户
+func (s *PasswordPolicyService) Validate(ctx context.Context, field string, user *entity.User, username, password string) error {
+	if s == nil || s.policy == nil {
+		return nil
+	}
+	violations := s.policy.Check(password, username)
+	if user != nil && s.reused(ctx, user, password) {
+		violations = append(violations, s.policy.ReusedViolation())
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	fields := make([]*errorx.FieldError, 0, len(violations))
+	for _, v := range violations {
+		fields = append(fields, &errorx.FieldError{Field: field, Rule: v.Rule, Message: v.Message})
+	}
+	return errorx.New(errorx.ErrPasswordPolicy, violations[0].Message, fields)
+}
+
+// reused 是否与当前密码或最近 HistorySize 个历史密码相同
+func (s *PasswordPolicyService) reused(ctx context.Context, user *entity.User, password string) bool {
+	if s.policy.HistorySize <= 0 {
+		return false
+	}
+	if user.Password != "" && utils.CheckPassword(password, user.Password) {
+		return true
+	}
+	if s.historyRepo == nil {
+		return false
+	}
+	list, err := s.historyRepo.Recent(ctx, user.ID, s.policy.HistorySize)
+	if err != nil {
+		logger.Warn("password_history_load_failed", "userId", user.ID, "error", err)
+		return false
+	}
+	for _, h := range list {
+		if utils.CheckPassword(password, h.Hash) {
+			return true
+		}
+	}
+	return false
+}
+
+// Remember 记录新密码哈希，仅保留最近 HistorySize 条；失败不影响修改密码
+func (s *PasswordPolicyService) Remember(ctx context.Context, userID uint, hash string) {
+	if s == nil || s.policy == nil || s.historyRepo == nil || s.policy.HistorySize <= 0 {
+		return
+	}
+	if err := s.historyRepo.Add(ctx, userID, hash, s.policy.HistorySize); err != nil {
+		logger.Warn("password_history_save_failed", "userId", userID, "error", err)
+	}
+}
+
+// Expired 密码是否已过期；从未修改过密码的用户以创建时间计算
+func (s *PasswordPolicyService) Expired(user *entity.User) bool {
+	if s == nil || s.policy == nil {
+		return false
+	}
+	changedAt := user.CreatedAt
+	if user.PasswordChangedAt != nil {
+		changedAt = *user.PasswordChangedAt
+	}
+	return s.policy.Expired(changedAt, time.Now())
+}
+
+// Generate 生成满足策略的随机临时密码
+func (s *PasswordPolicyService) Generate() (string, error) {
+	policy := &passwordpolicy.Policy{}
+	if s != nil && s.policy != nil {
+		policy = s.policy
+	}
+	return policy.Generate(16)
+}
@@ -2,20 +2,35 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/sine-io/sinx/domain/user/entity"
 	"github.com/sine-io/sinx/domain/user/repository"
 	"github.com/sine-io/sinx/pkg/errorx"
+	"github.com/sine-io/sinx/pkg/logger"
 	"github.com/sine-io/sinx/pkg/utils"
 )
 
+// Directory 外部目录认证（LDAP / AD bind）；凭据错误返回 (false, nil)，目录不可用返回 error
+type Directory interface {
+	Authenticate(ctx context.Context, username, password string) (bool, error)
+}
+
 type UserDomainService struct {
-	userRepo repository.UserRepository
+	userRepo          repository.UserRepository
+	directory         Directory
+	defaultAuthSource string
+	passwords         *PasswordPolicyService
 }
 
-func NewUserDomainService(userRepo repository.UserRepository) *UserDomainService {
+// NewUserDomainService directory 为 nil 表示未启用目录认证；defaultAuthSource 为 AuthSource 为空的用户使用的认证方式；
+// passwords 为 nil 表示不启用密码策略
+func NewUserDomainService(userRepo repository.UserRepository, directory Directory, defaultAuthSource string, passwords *PasswordPolicyService) *UserDomainService {
 	return &UserDomainService{
-		userRepo: userRepo,
+		userRepo:          userRepo,
+		directory:         directory,
+		defaultAuthSource: defaultAuthSource,
+		passwords:         passwords,
 	}
 }
 
@@ -33,17 +48,23 @@ func (s *UserDomainService) CreateUser(ctx context.Context, username, email, pas
 		return nil, errorx.NewWithCode(errorx.ErrUserAlreadyExists)
 	}
 
+	if err := s.passwords.Validate(ctx, "password", nil, username, password); err != nil {
+		return nil, err
+	}
+
 	// 对密码进行哈希处理
 	hashedPassword, err := utils.HashPassword(password)
 	if err != nil {
 		return nil, errorx.New(errorx.ErrInternalServer, "failed to hash password")
 	}
 
-	user := &entity.User{Username: username, Email: email, Password: hashedPassword, Status: 0}
+	now := time.Now()
+	user := &entity.User{Username: username, Email: email, Password: hashedPassword, PasswordChangedAt: &now, Status: 0}
 
 	if err := s.userRepo.Create(ctx, user); err != nil {
 		return nil, err
 	}
+	s.passwords.Remember(ctx, user.ID, hashedPassword)
 
 	return user, nil
 }
@@ -59,13 +80,70 @@ func (s *UserDomainService) AuthenticateUser(ctx context.Context, username, pass
 		return nil, errorx.NewWithCode(errorx.ErrUserNotFound)
 	}
 
+	if s.IsDirectoryUser(user) {
+		return s.authenticateDirectory(ctx, user, password)
+	}
+
 	if !utils.CheckPassword(password, user.Password) {
 		return nil, errorx.NewWithCode(errorx.ErrUserInvalidPassword)
 	}
 
+	// 旧算法（MD5）或参数过时的哈希：借助本次明文密码透明升级
+	if utils.NeedsRehash(user.Password) {
+		s.rehashPassword(ctx, user, password)
+	}
+
+	return user, nil
+}
+
+// IsDirectoryUser 是否由 LDAP 目录校验密码（本地密码不可用）
+func (s *UserDomainService) IsDirectoryUser(user *entity.User) bool {
+	return s.authSource(user) == entity.AuthSourceLDAP
+}
+
+// authSource 用户实际使用的认证方式
+func (s *UserDomainService) authSource(user *entity.User) string {
+	if user.AuthSource != "" {
+		return user.AuthSource
+	}
+	if s.defaultAuthSource != "" {
+		return s.defaultAuthSource
+	}
+	return entity.AuthSourceLocal
+}
+
+// authenticateDirectory 目录 bind 校验密码；目录故障不计入登录失败次数
+func (s *UserDomainService) authenticateDirectory(ctx context.Context, user *entity.User, password string) (*entity.User, error) {
+	if s.directory == nil {
+		logger.Warn("ldap_not_configured", "userId", user.ID)
+		return nil, errorx.NewWithCode(errorx.ErrUserInvalidPassword)
+	}
+	ok, err := s.directory.Authenticate(ctx, user.Username, password)
+	if err != nil {
+		logger.Error("ldap_authenticate_failed", "userId", user.ID, "error", err)
+		return nil, errorx.New(errorx.ErrInternalServer, "directory service unavailable")
+	}
+	if !ok {
+		return nil, errorx.NewWithCode(errorx.ErrUserInvalidPassword)
+	}
 	return user, nil
 }
 
+// rehashPassword 使用当前算法重新哈希，失败不影响本次登录
+func (s *UserDomainService) rehashPassword(ctx context.Context, user *entity.User, password string) {
+	hashed, err := utils.HashPassword(password)
+	if err != nil {
+		logger.Warn("password_rehash_failed", "userId", user.ID, "error", err)
+		return
+	}
+	user.Password = hashed
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		logger.Warn("password_rehash_failed", "userId", user.ID, "error", err)
+		return
+	}
+	logger.Info("audit:password_rehashed", "userId", user.ID)
+}
+
 // GetUserByID 根据ID获取用户
 func (s *UserDomainService) GetUserByID(ctx context.Context, id uint) (*entity.User, error) {
 	user, err := s.userRepo.GetByID(ctx, id)
@@ -79,3 +157,157 @@ func (s *UserDomainService) GetUserByID(ctx context.Context, id uint) (*entity.U
 
 	return user, nil
 }
+
+// ResetPassword 设置新的本地密码；目录用户的密码由目录管理
+func (s *UserDomainService) ResetPassword(ctx context.Context, user *entity.User, password string) error {
+	return s.SetPassword(ctx, user, "new_password", password)
+}
+
+// SetPassword 按密码策略校验后设置本地密码，field 为校验失败时返回的字段名
+func (s *UserDomainService) SetPassword(ctx context.Context, user *entity.User, field, password string) error {
+	if s.IsDirectoryUser(user) {
+		return errorx.New(errorx.ErrInvalidParam, "password is managed by the directory")
+	}
+	if err := s.passwords.Validate(ctx, field, user, user.Username, password); err != nil {
+		return err
+	}
+	hashed, err := utils.HashPassword(password)
+	if err != nil {
+		return errorx.New(errorx.ErrInternalServer, "failed to hash password")
+	}
+	now := time.Now()
+	user.Password = hashed
+	user.PasswordChangedAt = &now
+	user.MustChangePassword = false
+	enableLocalPassword(user)
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+	s.passwords.Remember(ctx, user.ID, hashed)
+	return nil
+}
+
+// SetTemporaryPassword 管理员设置临时密码，用户下次登录后须先修改密码；password 为空时随机生成并返回。
+// 临时密码只使用一次，不检查也不记入历史密码
+func (s *UserDomainService) SetTemporaryPassword(ctx context.Context, user *entity.User, password string) (string, error) {
+	if s.IsDirectoryUser(user) {
+		return "", errorx.New(errorx.ErrInvalidParam, "password is managed by the directory")
+	}
+	if password == "" {
+		generated, err := s.passwords.Generate()
+		if err != nil {
+			return "", errorx.New(errorx.ErrInternalServer, "failed to generate password")
+		}
+		password = generated
+	} else if err := s.passwords.Validate(ctx, "password", nil, user.Username, password); err != nil {
+		return "", err
+	}
+	hashed, err := utils.HashPassword(password)
+	if err != nil {
+		return "", errorx.New(errorx.ErrInternalServer, "failed to hash password")
+	}
+	now := time.Now()
+	user.Password = hashed
+	user.PasswordChangedAt = &now
+	user.MustChangePassword = true
+	enableLocalPassword(user)
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return "", err
+	}
+	return password, nil
+}
+
+// PasswordExpired 本地密码是否已过期或须在登录后修改；目录用户与外部身份创建的用户没有可用的本地密码，不在此判断
+func (s *UserDomainService) PasswordExpired(user *entity.User) bool {
+	switch s.authSource(user) {
+	case entity.AuthSourceLDAP, entity.AuthSourceOIDC:
+		return false
+	}
+	return user.MustChangePassword || s.passwords.Expired(user)
+}
+
+// enableLocalPassword 外部身份创建的用户设置了本地密码后，改为本地认证并受密码有效期约束
+func enableLocalPassword(user *entity.User) {
+	if user.AuthSource == entity.AuthSourceOIDC {
+		user.AuthSource = entity.AuthSourceLocal
+	}
+}
+
+// MarkEmailVerified 标记当前邮箱已验证
+func (s *UserDomainService) MarkEmailVerified(ctx context.Context, user *entity.User) error {
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	return s.userRepo.Update(ctx, user)
+}
+
+// GetUserByEmail 根据邮箱获取正常状态的用户
+func (s *UserDomainService) GetUserByEmail(ctx context.Context, email string) (*entity.User, error) {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil || user.Status != 0 {
+		return nil, errorx.NewWithCode(errorx.ErrUserNotFound)
+	}
+	return user, nil
+}
+
+// CreateFederatedUser 外部身份首次登录时创建本地用户，用户名冲突时追加随机后缀
+func (s *UserDomainService) CreateFederatedUser(ctx context.Context, username, email, nickname string) (*entity.User, error) {
+	if r := []rune(username); len(r) > 40 {
+		username = string(r[:40])
+	}
+	candidate := username
+	for i := 0; ; i++ {
+		existing, _ := s.userRepo.GetByUsername(ctx, candidate)
+		if existing == nil {
+			break
+		}
+		if i == 5 {
+			return nil, errorx.NewWithCode(errorx.ErrUserAlreadyExists)
+		}
+		suffix, err := utils.RandomToken(3)
+		if err != nil {
+			return nil, err
+		}
+		candidate = username + "_" + suffix
+	}
+	return s.createExternalUser(ctx, candidate, email, nickname, entity.AuthSourceOIDC)
+}
+
+// CreateDirectoryUser 目录同步导入用户：用户名与目录一致，认证来源为 ldap
+func (s *UserDomainService) CreateDirectoryUser(ctx context.Context, username, email, nickname string) (*entity.User, error) {
+	if existing, _ := s.userRepo.GetByUsername(ctx, username); existing != nil {
+		return nil, errorx.NewWithCode(errorx.ErrUserAlreadyExists)
+	}
+	return s.createExternalUser(ctx, username, email, nickname, entity.AuthSourceLDAP)
+}
+
+// createExternalUser 创建由外部系统认证的用户：邮箱已被占用时留空，本地密码为不可猜测的随机值
+func (s *UserDomainService) createExternalUser(ctx context.Context, username, email, nickname, authSource string) (*entity.User, error) {
+	if email != "" {
+		if existing, _ := s.userRepo.GetByEmail(ctx, email); existing != nil {
+			email = ""
+		}
+	}
+
+	random, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := utils.HashPassword(random)
+	if err != nil {
+		return nil, errorx.New(errorx.ErrInternalServer, "failed to hash password")
+	}
+	if r := []rune(nickname); len(r) > 50 {
+		nickname = string(r[:50])
+	}
+
+	user := &entity.User{Username: username, Email: email, Nickname: nickname, Password: hashedPassword, AuthSource: authSource, Status: 0}
+	// 目录中的邮箱由目录管理员维护，视为已验证
+	if authSource == entity.AuthSourceLDAP && email != "" {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -22,7 +22,9 @@ func InitRedis() error {
 	defer cancel()
 	if err := rdb.Ping(ctx).Err(); err != nil {
 		logger.Warn("redis_ping_failed", "err", err)
-		// 可容忍: 返回 nil 表示继续运行(使用内存缓存 fallback) 也可直接返回错误
+		// 可容忍: 置空客户端，调用方据此回退到内存实现
+		_ = rdb.Close()
+		rdb = nil
 		return err
 	}
 	logger.Info("redis_connected")
@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	authEntity "github.com/sine-io/sinx/domain/auth/entity"
+	authRepo "github.com/sine-io/sinx/domain/auth/repository"
+)
+
+// RedisRefreshTokenStore 基于 Redis 的刷新令牌存储
+//
+//	refresh_token:<hash>          hash: user_id / family_id / expires_at / created_at / used_at
+//	refresh_family:<familyID>     set:  该族下的令牌哈希
+//	refresh_family_revoked:<id>   string: 令牌族吊销标记
+//	refresh_user:<userID>         set:  用户的令牌族
+type RedisRefreshTokenStore struct {
+	cli    *redis.Client
+	ttl    time.Duration
+	prefix string
+}
+
+func NewRedisRefreshTokenStore(cli *redis.Client, ttl time.Duration) authRepo.RefreshTokenRepository {
+	return &RedisRefreshTokenStore{cli: cli, ttl: ttl, prefix: "refresh_"}
+}
+
+func (s *RedisRefreshTokenStore) tokenKey(hash string) string {
+	return s.prefix + "token:" + hash
+}
+
+func (s *RedisRefreshTokenStore) familyKey(familyID string) string {
+	return s.prefix + "family:" + familyID
+}
+
+func (s *RedisRefreshTokenStore) revokedKey(familyID string) string {
+	return s.prefix + "family_revoked:" + familyID
+}
+
+func (s *RedisRefreshTokenStore) userKey(userID uint) string {
+	return s.prefix + "user:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+func (s *RedisRefreshTokenStore) Create(ctx context.Context, token *authEntity.RefreshToken) error {
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	_, err := s.cli.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		key := s.tokenKey(token.TokenHash)
+		p.HSet(ctx, key,
+			"user_id", token.UserID,
+			"family_id", token.FamilyID,
+			"expires_at", token.ExpiresAt.Unix(),
+			"created_at", token.CreatedAt.Unix(),
+		)
+		p.ExpireAt(ctx, key, token.ExpiresAt)
+		p.SAdd(ctx, s.familyKey(token.FamilyID), token.TokenHash)
+		p.ExpireAt(ctx, s.familyKey(token.FamilyID), token.ExpiresAt)
+		p.SAdd(ctx, s.userKey(token.UserID), token.FamilyID)
+		p.ExpireAt(ctx, s.userKey(token.UserID), token.ExpiresAt)
+		return nil
+	})
+	return err
+}
+
+func (s *RedisRefreshTokenStore) GetByHash(ctx context.Context, tokenHash string) (*authEntity.RefreshToken, error) {
+	vals, err := s.cli.HGetAll(ctx, s.tokenKey(tokenHash)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(vals) == 0 {
+		return nil, nil
+	}
+	userID, _ := strconv.ParseUint(vals["user_id"], 10, 64)
+	token := &authEntity.RefreshToken{
+		UserID:    uint(userID),
+		FamilyID:  vals["family_id"],
+		TokenHash: tokenHash,
+		ExpiresAt: parseUnix(vals["expires_at"]),
+		CreatedAt: parseUnix(vals["created_at"]),
+	}
+	if v, ok := vals["used_at"]; ok {
+		t := parseUnix(v)
+		token.UsedAt = &t
+	}
+	revoked, err := s.cli.Get(ctx, s.revokedKey(token.FamilyID)).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	if err == nil {
+		t := parseUnix(revoked)
+		token.RevokedAt = &t
+	}
+	return token, nil
+}
+
+// markUsedScript 令牌仍存在时才写入 used_at：单独的 HSETNX 会把已过期的键重建为不带 TTL 的残缺记录
+var markUsedScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+return redis.call("HSETNX", KEYS[1], "used_at", ARGV[1])
+`)
+
+func (s *RedisRefreshTokenStore) MarkUsed(ctx context.Context, tokenHash string) (bool, error) {
+	n, err := markUsedScript.Run(ctx, s.cli, []string{s.tokenKey(tokenHash)}, time.Now().Unix()).Int()
+	return n == 1, err
+}
+
+func (s *RedisRefreshTokenStore) RevokeFamily(ctx context.Context, familyID string) error {
+	// 吊销标记需至少存活到族内最后一个令牌过期
+	ttl, err := s.cli.TTL(ctx, s.familyKey(familyID)).Result()
+	if err != nil || ttl <= 0 {
+		ttl = s.ttl
+	}
+	return s.cli.Set(ctx, s.revokedKey(familyID), time.Now().Unix(), ttl).Err()
+}
+
+func (s *RedisRefreshTokenStore) RevokeUser(ctx context.Context, userID uint) error {
+	families, err := s.cli.SMembers(ctx, s.userKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+	for _, f := range families {
+		if err := s.RevokeFamily(ctx, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseUnix(v string) time.Time {
+	sec, _ := strconv.ParseInt(v, 10, 64)
+	return time.Unix(sec, 0)
+}
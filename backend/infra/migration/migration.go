@@ -1,7 +1,11 @@
 package migration
 
 import (
+	accessEntity "github.com/sine-io/sinx/domain/access/entity"
+	authEntity "github.com/sine-io/sinx/domain/auth/entity"
+	deptEntity "github.com/sine-io/sinx/domain/dept/entity"
 	menuEntity "github.com/sine-io/sinx/domain/menu/entity"
+	oidcEntity "github.com/sine-io/sinx/domain/oidc/entity"
 	rbacEntity "github.com/sine-io/sinx/domain/rbac/entity"
 	roleEntity "github.com/sine-io/sinx/domain/role/entity"
 	userEntity "github.com/sine-io/sinx/domain/user/entity"
@@ -17,8 +21,24 @@ func AutoMigrate(db *gorm.DB) error {
 		&userEntity.User{},
 		&roleEntity.Role{},
 		&menuEntity.Menu{},
+		&deptEntity.Department{},
 		&rbacEntity.UserRole{},
 		&rbacEntity.RoleMenu{},
+		&rbacEntity.RoleMenuDeny{},
+		&rbacEntity.RoleConstraint{},
+		&accessEntity.AccessRequest{},
+		&accessEntity.AccessRequestEvent{},
+		&accessEntity.RoleApprover{},
+		&authEntity.RefreshToken{},
+		&authEntity.PersonalAccessToken{},
+		&authEntity.Session{},
+		&oidcEntity.OAuthClient{},
+		&oidcEntity.AuthorizationCode{},
+		&userEntity.UserMFA{},
+		&userEntity.UserRecoveryCode{},
+		&userEntity.UserIdentity{},
+		&userEntity.PasswordHistory{},
+		&userEntity.LoginLog{},
 	)
 
 	if err != nil {
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	accessEntity "github.com/sine-io/sinx/domain/access/entity"
+	accessRepo "github.com/sine-io/sinx/domain/access/repository"
+	"gorm.io/gorm"
+)
+
+type accessRequestRepositoryImpl struct{ db *gorm.DB }
+
+func NewAccessRequestRepository(db *gorm.DB) accessRepo.AccessRequestRepository {
+	return &accessRequestRepositoryImpl{db: db}
+}
+
+func (r *accessRequestRepositoryImpl) Create(ctx context.Context, req *accessEntity.AccessRequest, event *accessEntity.AccessRequestEvent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(req).Error; err != nil {
+			return err
+		}
+		event.RequestID = req.ID
+		return tx.Create(event).Error
+	})
+}
+
+func (r *accessRequestRepositoryImpl) GetByID(ctx context.Context, id uint) (*accessEntity.AccessRequest, error) {
+	var req accessEntity.AccessRequest
+	err := r.db.WithContext(ctx).First(&req, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+func (r *accessRequestRepositoryImpl) List(ctx context.Context, filter accessRepo.AccessRequestFilter, offset, limit int) ([]*accessEntity.AccessRequest, int64, error) {
+	var total int64
+	if err := r.filtered(ctx, filter).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var list []*accessEntity.AccessRequest
+	err := r.filtered(ctx, filter).Order("id DESC").Offset(offset).Limit(limit).Find(&list).Error
+	return list, total, err
+}
+
+func (r *accessRequestRepositoryImpl) filtered(ctx context.Context, filter accessRepo.AccessRequestFilter) *gorm.DB {
+	q := r.db.WithContext(ctx).Model(&accessEntity.AccessRequest{})
+	if filter.UserID != 0 {
+		q = q.Where("user_id = ?", filter.UserID)
+	}
+	if filter.RoleIDs != nil {
+		q = q.Where("role_id IN ?", filter.RoleIDs)
+	}
+	if filter.Status != "" {
+		q = q.Where("status = ?", filter.Status)
+	}
+	return q
+}
+
+func (r *accessRequestRepositoryImpl) HasPending(ctx context.Context, userID, roleID uint) (bool, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&accessEntity.AccessRequest{}).
+		Where("user_id = ? AND role_id = ? AND status = ?", userID, roleID, accessEntity.AccessStatusPending).Count(&n).Error
+	return n > 0, err
+}
+
+func (r *accessRequestRepositoryImpl) Transition(ctx context.Context, req *accessEntity.AccessRequest, from string, event *accessEntity.AccessRequestEvent) (bool, error) {
+	ok := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&accessEntity.AccessRequest{}).Where("id = ? AND status = ?", req.ID, from).Updates(map[string]interface{}{
+			"status":      req.Status,
+			"valid_until": req.ValidUntil,
+			"decider_id":  req.DeciderID,
+			"decided_at":  req.DecidedAt,
+		})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		ok = true
+		event.RequestID = req.ID
+		return tx.Create(event).Error
+	})
+	return ok && err == nil, err
+}
+
+func (r *accessRequestRepositoryImpl) AddEvent(ctx context.Context, event *accessEntity.AccessRequestEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+func (r *accessRequestRepositoryImpl) ListEvents(ctx context.Context, requestID uint) ([]*accessEntity.AccessRequestEvent, error) {
+	var list []*accessEntity.AccessRequestEvent
+	err := r.db.WithContext(ctx).Where("request_id = ?", requestID).Order("id").Find(&list).Error
+	return list, err
+}
+
+func (r *accessRequestRepositoryImpl) SetApprovers(ctx context.Context, roleID uint, userIDs []uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role_id = ?", roleID).Delete(&accessEntity.RoleApprover{}).Error; err != nil {
+			return err
+		}
+		for _, uid := range userIDs {
+			if err := tx.Create(&accessEntity.RoleApprover{RoleID: roleID, UserID: uid}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *accessRequestRepositoryImpl) GetApprovers(ctx context.Context, roleID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).Model(&accessEntity.RoleApprover{}).Where("role_id = ?", roleID).Order("user_id").Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+func (r *accessRequestRepositoryImpl) GetApproverRoles(ctx context.Context, userID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).Model(&accessEntity.RoleApprover{}).Where("user_id = ?", userID).Order("role_id").Pluck("role_id", &ids).Error
+	return ids, err
+}
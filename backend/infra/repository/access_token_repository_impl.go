@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	authEntity "github.com/sine-io/sinx/domain/auth/entity"
+	authRepo "github.com/sine-io/sinx/domain/auth/repository"
+	"gorm.io/gorm"
+)
+
+type accessTokenRepositoryImpl struct{ db *gorm.DB }
+
+func NewAccessTokenRepository(db *gorm.DB) authRepo.AccessTokenRepository {
+	return &accessTokenRepositoryImpl{db: db}
+}
+
+func (r *accessTokenRepositoryImpl) Create(ctx context.Context, token *authEntity.PersonalAccessToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *accessTokenRepositoryImpl) GetByHash(ctx context.Context, tokenHash string) (*authEntity.PersonalAccessToken, error) {
+	var t authEntity.PersonalAccessToken
+	err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&t).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *accessTokenRepositoryImpl) ListByUser(ctx context.Context, userID uint) ([]*authEntity.PersonalAccessToken, error) {
+	var list []*authEntity.PersonalAccessToken
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id DESC").Find(&list).Error
+	return list, err
+}
+
+func (r *accessTokenRepositoryImpl) Delete(ctx context.Context, userID, id uint) (bool, error) {
+	res := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&authEntity.PersonalAccessToken{})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func (r *accessTokenRepositoryImpl) TouchLastUsed(ctx context.Context, id uint, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&authEntity.PersonalAccessToken{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"github.com/sine-io/sinx/pkg/datascope"
+	"gorm.io/gorm"
+)
+
+// dataScope 按 ctx 中调用者的数据范围过滤（见 datascope.WithScope），未设置范围时不限制
+// deptColumn 为记录所属部门列，userColumn 为记录所属用户列，为空时不参与过滤
+func dataScope(ctx context.Context, deptColumn, userColumn string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		s := datascope.FromContext(ctx)
+		if s == nil || s.All {
+			return db
+		}
+		var conds []string
+		var args []any
+		if deptColumn != "" && len(s.DeptIDs) > 0 {
+			conds = append(conds, deptColumn+" IN ?")
+			args = append(args, s.DeptIDs)
+		}
+		if userColumn != "" && s.UserID != 0 {
+			conds = append(conds, userColumn+" = ?")
+			args = append(args, s.UserID)
+		}
+		if len(conds) == 0 {
+			return db.Where("1 = 0")
+		}
+		return db.Where("("+strings.Join(conds, " OR ")+")", args...)
+	}
+}
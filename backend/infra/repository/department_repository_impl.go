@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	deptEntity "github.com/sine-io/sinx/domain/dept/entity"
+	deptRepo "github.com/sine-io/sinx/domain/dept/repository"
+	userEntity "github.com/sine-io/sinx/domain/user/entity"
+	"gorm.io/gorm"
+)
+
+type departmentRepositoryImpl struct{ db *gorm.DB }
+
+func NewDepartmentRepository(db *gorm.DB) deptRepo.DepartmentRepository {
+	return &departmentRepositoryImpl{db: db}
+}
+
+func (r *departmentRepositoryImpl) Create(ctx context.Context, dept *deptEntity.Department) error {
+	return r.db.WithContext(ctx).Create(dept).Error
+}
+func (r *departmentRepositoryImpl) Update(ctx context.Context, dept *deptEntity.Department) error {
+	return r.db.WithContext(ctx).Save(dept).Error
+}
+func (r *departmentRepositoryImpl) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&deptEntity.Department{}, id).Error
+}
+func (r *departmentRepositoryImpl) GetByID(ctx context.Context, id uint) (*deptEntity.Department, error) {
+	var d deptEntity.Department
+	if err := r.db.WithContext(ctx).First(&d, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &d, nil
+}
+func (r *departmentRepositoryImpl) ListAll(ctx context.Context) ([]*deptEntity.Department, error) {
+	var list []*deptEntity.Department
+	err := r.db.WithContext(ctx).Order("order_num ASC, id ASC").Find(&list).Error
+	return list, err
+}
+func (r *departmentRepositoryImpl) HasUsers(ctx context.Context, id uint) (bool, error) {
+	var c int64
+	if err := r.db.WithContext(ctx).Model(&userEntity.User{}).Where("dept_id = ? AND status = 0", id).Count(&c).Error; err != nil {
+		return false, err
+	}
+	return c > 0, nil
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sine-io/sinx/domain/user/entity"
+	"github.com/sine-io/sinx/domain/user/repository"
+
+	"gorm.io/gorm"
+)
+
+type identityRepositoryImpl struct{ db *gorm.DB }
+
+func NewIdentityRepository(db *gorm.DB) repository.IdentityRepository {
+	return &identityRepositoryImpl{db: db}
+}
+
+func (r *identityRepositoryImpl) GetByProviderSubject(ctx context.Context, provider, subject string) (*entity.UserIdentity, error) {
+	var i entity.UserIdentity
+	err := r.db.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&i).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+func (r *identityRepositoryImpl) ListByUser(ctx context.Context, userID uint) ([]*entity.UserIdentity, error) {
+	var list []*entity.UserIdentity
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id").Find(&list).Error
+	return list, err
+}
+
+func (r *identityRepositoryImpl) Create(ctx context.Context, identity *entity.UserIdentity) error {
+	return r.db.WithContext(ctx).Create(identity).Error
+}
+
+func (r *identityRepositoryImpl) Delete(ctx context.Context, userID, id uint) (bool, error) {
+	res := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&entity.UserIdentity{})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *identityRepositoryImpl) TouchLastLogin(ctx context.Context, id uint, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&entity.UserIdentity{}).Where("id = ?", id).Update("last_login_at", at).Error
+}
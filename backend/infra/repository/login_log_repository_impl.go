@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/sine-io/sinx/domain/user/entity"
+	"github.com/sine-io/sinx/domain/user/repository"
+
+	"gorm.io/gorm"
+)
+
+type loginLogRepositoryImpl struct{ db *gorm.DB }
+
+func NewLoginLogRepository(db *gorm.DB) repository.LoginLogRepository {
+	return &loginLogRepositoryImpl{db: db}
+}
+
+func (r *loginLogRepositoryImpl) Create(ctx context.Context, log *entity.LoginLog) error {
+	return r.db.WithContext(ctx).Create(log).Error
+}
+
+func (r *loginLogRepositoryImpl) List(ctx context.Context, filter repository.LoginLogFilter, offset, limit int) ([]*entity.LoginLog, int64, error) {
+	var total int64
+	if err := r.filtered(ctx, filter).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var list []*entity.LoginLog
+	err := r.filtered(ctx, filter).Order("id DESC").Offset(offset).Limit(limit).Find(&list).Error
+	return list, total, err
+}
+
+func (r *loginLogRepositoryImpl) filtered(ctx context.Context, filter repository.LoginLogFilter) *gorm.DB {
+	q := r.db.WithContext(ctx).Model(&entity.LoginLog{})
+	if filter.UserID != 0 {
+		q = q.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Username != "" {
+		q = q.Where("username = ?", filter.Username)
+	}
+	if filter.IP != "" {
+		q = q.Where("ip = ?", filter.IP)
+	}
+	if filter.Success != nil {
+		q = q.Where("success = ?", *filter.Success)
+	}
+	if !filter.Since.IsZero() {
+		q = q.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		q = q.Where("created_at < ?", filter.Until)
+	}
+	return q
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sine-io/sinx/domain/user/entity"
+	"github.com/sine-io/sinx/domain/user/repository"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// dryRunDB 只生成 SQL，不连接数据库
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// 零值字段不参与过滤，其余条件按 AND 组合
+func TestLoginLogFilter(t *testing.T) {
+	repo := &loginLogRepositoryImpl{db: dryRunDB(t)}
+	failed := false
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := since.Add(24 * time.Hour)
+
+	cases := []struct {
+		name   string
+		filter repository.LoginLogFilter
+		sql    string
+		vars   []interface{}
+	}{
+		{"empty", repository.LoginLogFilter{}, `SELECT * FROM "login_logs"`, nil},
+		{
+			"all",
+			repository.LoginLogFilter{UserID: 7, Username: "alice", IP: "10.0.0.1", Success: &failed, Since: since, Until: until},
+			`SELECT * FROM "login_logs" WHERE user_id = $1 AND username = $2 AND ip = $3 AND success = $4 AND created_at >= $5 AND created_at < $6`,
+			[]interface{}{uint(7), "alice", "10.0.0.1", false, since, until},
+		},
+		{"failures only", repository.LoginLogFilter{Success: &failed}, `SELECT * FROM "login_logs" WHERE success = $1`, []interface{}{false}},
+	}
+	for _, c := range cases {
+		var list []*entity.LoginLog
+		stmt := repo.filtered(context.Background(), c.filter).Find(&list).Statement
+		if got := stmt.SQL.String(); got != c.sql {
+			t.Fatalf("%s: sql = %s", c.name, got)
+		}
+		if len(c.vars) != 0 && !reflect.DeepEqual(stmt.Vars, c.vars) {
+			t.Fatalf("%s: vars = %v", c.name, stmt.Vars)
+		}
+	}
+}
+
+// 更新最近登录信息只写 last_login_* 列
+func TestUserUpdateLastLoginColumns(t *testing.T) {
+	db := dryRunDB(t)
+	repo := &userRepositoryImpl{db: db}
+	now := time.Now()
+	user := &entity.User{ID: 3, Nickname: "stale", LastLoginIP: "1.2.3.4", LastLoginDate: &now}
+
+	var captured string
+	_ = db.Callback().Update().After("gorm:update").Register("test:capture", func(tx *gorm.DB) {
+		captured = tx.Statement.SQL.String()
+	})
+	if err := repo.UpdateLastLogin(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	want := `UPDATE "users" SET "last_login_city"=$1,"last_login_date"=$2,"last_login_ip"=$3,"last_login_nation"=$4,"last_login_province"=$5 WHERE id = $6 AND "users"."deleted_at" IS NULL`
+	if captured != want {
+		t.Fatalf("unexpected sql: %s", captured)
+	}
+}
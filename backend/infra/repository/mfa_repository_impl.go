@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sine-io/sinx/domain/user/entity"
+	"github.com/sine-io/sinx/domain/user/repository"
+
+	"gorm.io/gorm"
+)
+
+type mfaRepositoryImpl struct{ db *gorm.DB }
+
+func NewMFARepository(db *gorm.DB) repository.MFARepository { return &mfaRepositoryImpl{db: db} }
+
+func (r *mfaRepositoryImpl) GetByUserID(ctx context.Context, userID uint) (*entity.UserMFA, error) {
+	var m entity.UserMFA
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&m).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *mfaRepositoryImpl) Save(ctx context.Context, mfa *entity.UserMFA) error {
+	return r.db.WithContext(ctx).Save(mfa).Error
+}
+
+func (r *mfaRepositoryImpl) AdvanceStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&entity.UserMFA{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func (r *mfaRepositoryImpl) Delete(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&entity.UserRecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&entity.UserMFA{}).Error
+	})
+}
+
+func (r *mfaRepositoryImpl) ReplaceRecoveryCodes(ctx context.Context, userID uint, codeHashes []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&entity.UserRecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]*entity.UserRecoveryCode, 0, len(codeHashes))
+		for _, h := range codeHashes {
+			codes = append(codes, &entity.UserRecoveryCode{UserID: userID, CodeHash: h})
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *mfaRepositoryImpl) UseRecoveryCode(ctx context.Context, userID uint, codeHash string) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&entity.UserRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func (r *mfaRepositoryImpl) CountRecoveryCodes(ctx context.Context, userID uint) (int64, error) {
+	var c int64
+	err := r.db.WithContext(ctx).Model(&entity.UserRecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&c).Error
+	return c, err
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sine-io/sinx/domain/oidc/entity"
+	"github.com/sine-io/sinx/domain/oidc/repository"
+
+	"gorm.io/gorm"
+)
+
+type oauthClientRepositoryImpl struct{ db *gorm.DB }
+
+func NewOAuthClientRepository(db *gorm.DB) repository.ClientRepository {
+	return &oauthClientRepositoryImpl{db: db}
+}
+
+func (r *oauthClientRepositoryImpl) Create(ctx context.Context, client *entity.OAuthClient) error {
+	return r.db.WithContext(ctx).Create(client).Error
+}
+
+func (r *oauthClientRepositoryImpl) GetByClientID(ctx context.Context, clientID string) (*entity.OAuthClient, error) {
+	var c entity.OAuthClient
+	err := r.db.WithContext(ctx).Where("client_id = ?", clientID).First(&c).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *oauthClientRepositoryImpl) List(ctx context.Context) ([]*entity.OAuthClient, error) {
+	var list []*entity.OAuthClient
+	err := r.db.WithContext(ctx).Order("id ASC").Find(&list).Error
+	return list, err
+}
+
+func (r *oauthClientRepositoryImpl) Delete(ctx context.Context, clientID string) error {
+	return r.db.WithContext(ctx).Where("client_id = ?", clientID).Delete(&entity.OAuthClient{}).Error
+}
+
+type authCodeRepositoryImpl struct{ db *gorm.DB }
+
+func NewAuthCodeRepository(db *gorm.DB) repository.AuthCodeRepository {
+	return &authCodeRepositoryImpl{db: db}
+}
+
+func (r *authCodeRepositoryImpl) Create(ctx context.Context, code *entity.AuthorizationCode) error {
+	return r.db.WithContext(ctx).Create(code).Error
+}
+
+func (r *authCodeRepositoryImpl) Consume(ctx context.Context, codeHash string) (*entity.AuthorizationCode, error) {
+	var code entity.AuthorizationCode
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&entity.AuthorizationCode{}).Where("code_hash = ? AND used_at IS NULL", codeHash).Update("used_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Where("code_hash = ?", codeHash).First(&code).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/sine-io/sinx/domain/user/entity"
+	"github.com/sine-io/sinx/domain/user/repository"
+
+	"gorm.io/gorm"
+)
+
+type passwordHistoryRepositoryImpl struct{ db *gorm.DB }
+
+func NewPasswordHistoryRepository(db *gorm.DB) repository.PasswordHistoryRepository {
+	return &passwordHistoryRepositoryImpl{db: db}
+}
+
+func (r *passwordHistoryRepositoryImpl) Recent(ctx context.Context, userID uint, n int) ([]*entity.PasswordHistory, error) {
+	var list []*entity.PasswordHistory
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id DESC").Limit(n).Find(&list).Error
+	return list, err
+}
+
+func (r *passwordHistoryRepositoryImpl) Add(ctx context.Context, userID uint, hash string, keep int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&entity.PasswordHistory{UserID: userID, Hash: hash}).Error; err != nil {
+			return err
+		}
+		// 删除超出保留数量的旧记录
+		keepIDs := tx.Model(&entity.PasswordHistory{}).Select("id").Where("user_id = ?", userID).Order("id DESC").Limit(keep)
+		return tx.Where("user_id = ? AND id NOT IN (?)", userID, keepIDs).Delete(&entity.PasswordHistory{}).Error
+	})
+}
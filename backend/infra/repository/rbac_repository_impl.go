@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	menuEntity "github.com/sine-io/sinx/domain/menu/entity"
 	rbacEntity "github.com/sine-io/sinx/domain/rbac/entity"
@@ -16,20 +17,52 @@ type rbacRepositoryImpl struct{ db *gorm.DB }
 
 func NewRBACRepository(db *gorm.DB) rbacRepo.RBACRepository { return &rbacRepositoryImpl{db: db} }
 
-func (r *rbacRepositoryImpl) BindUserRoles(ctx context.Context, userID uint, roleIDs []uint) (int, int, error) {
+func (r *rbacRepositoryImpl) BindUserRoles(ctx context.Context, userID uint, roleIDs []uint, validFrom, validUntil *time.Time) (int, int, error) {
 	added := 0
 	skipped := 0
-	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
 		for _, rid := range roleIDs {
-			ur := &rbacEntity.UserRole{UserID: userID, RoleID: rid}
-			res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(ur)
+			res := tx.Model(&rbacEntity.UserRole{}).Where("user_id = ? AND role_id = ?", userID, rid).
+				Updates(map[string]interface{}{"valid_from": validFrom, "valid_until": validUntil})
 			if res.Error != nil {
 				return res.Error
 			}
-			if res.RowsAffected == 0 {
+			if res.RowsAffected > 0 {
 				skipped++
-			} else {
+				continue
+			}
+			if err := tx.Create(&rbacEntity.UserRole{UserID: userID, RoleID: rid, ValidFrom: validFrom, ValidUntil: validUntil}).Error; err != nil {
+				return err
+			}
+			added++
+		}
+		return nil
+	})
+	return added, skipped, err
+}
+
+func (r *rbacRepositoryImpl) GrantUserRoles(ctx context.Context, userID uint, roleIDs []uint, validFrom, validUntil *time.Time) (int, int, error) {
+	added := 0
+	skipped := 0
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		for _, rid := range roleIDs {
+			var ur rbacEntity.UserRole
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ? AND role_id = ?", userID, rid).First(&ur).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				if err := tx.Create(&rbacEntity.UserRole{UserID: userID, RoleID: rid, ValidFrom: validFrom, ValidUntil: validUntil}).Error; err != nil {
+					return err
+				}
 				added++
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			skipped++
+			if ur.Widen(validFrom, validUntil) {
+				if err := tx.Model(&ur).Updates(map[string]interface{}{"valid_from": ur.ValidFrom, "valid_until": ur.ValidUntil}).Error; err != nil {
+					return err
+				}
 			}
 		}
 		return nil
@@ -38,19 +71,46 @@ func (r *rbacRepositoryImpl) BindUserRoles(ctx context.Context, userID uint, rol
 }
 
 func (r *rbacRepositoryImpl) UnbindUserRoles(ctx context.Context, userID uint, roleIDs []uint) error {
-	return r.db.WithContext(ctx).Where("user_id = ? AND role_id IN ?", userID, roleIDs).Delete(&rbacEntity.UserRole{}).Error
+	return conn(ctx, r.db).Where("user_id = ? AND role_id IN ?", userID, roleIDs).Delete(&rbacEntity.UserRole{}).Error
 }
 
 func (r *rbacRepositoryImpl) GetUserRoles(ctx context.Context, userID uint) ([]*roleEntity.Role, error) {
 	var roles []*roleEntity.Role
-	err := r.db.WithContext(ctx).Table("roles r").Select("r.*").Joins("JOIN user_roles ur ON ur.role_id = r.id").Where("ur.user_id = ?", userID).Scan(&roles).Error
+	now := time.Now()
+	err := conn(ctx, r.db).Table("roles r").Select("r.*").Joins("JOIN user_roles ur ON ur.role_id = r.id").
+		Where("ur.user_id = ? AND r.deleted_at IS NULL AND (ur.valid_from IS NULL OR ur.valid_from <= ?) AND (ur.valid_until IS NULL OR ur.valid_until > ?)", userID, now, now).
+		Scan(&roles).Error
 	return roles, err
 }
 
+func (r *rbacRepositoryImpl) GetUserRoleBindings(ctx context.Context, userID uint) ([]*rbacEntity.UserRole, error) {
+	var list []*rbacEntity.UserRole
+	err := conn(ctx, r.db).Where("user_id = ?", userID).Order("id").Find(&list).Error
+	return list, err
+}
+
+func (r *rbacRepositoryImpl) DeleteExpiredUserRoles(ctx context.Context, now time.Time) ([]uint, error) {
+	var ids []uint
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		expired := tx.Model(&rbacEntity.UserRole{}).Where("valid_until IS NOT NULL AND valid_until <= ?", now)
+		if err := expired.Distinct().Pluck("user_id", &ids).Error; err != nil {
+			return err
+		}
+		return tx.Where("valid_until IS NOT NULL AND valid_until <= ?", now).Delete(&rbacEntity.UserRole{}).Error
+	})
+	return ids, err
+}
+
+func (r *rbacRepositoryImpl) GetUsersActivatedBetween(ctx context.Context, from, to time.Time) ([]uint, error) {
+	var ids []uint
+	err := conn(ctx, r.db).Model(&rbacEntity.UserRole{}).Where("valid_from > ? AND valid_from <= ?", from, to).Distinct().Pluck("user_id", &ids).Error
+	return ids, err
+}
+
 func (r *rbacRepositoryImpl) BindRoleMenus(ctx context.Context, roleID uint, menuIDs []uint) (int, int, error) {
 	added := 0
 	skipped := 0
-	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
 		for _, mid := range menuIDs {
 			rm := &rbacEntity.RoleMenu{RoleID: roleID, MenuID: mid}
 			res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(rm)
@@ -69,38 +129,123 @@ func (r *rbacRepositoryImpl) BindRoleMenus(ctx context.Context, roleID uint, men
 }
 
 func (r *rbacRepositoryImpl) UnbindRoleMenus(ctx context.Context, roleID uint, menuIDs []uint) error {
-	return r.db.WithContext(ctx).Where("role_id = ? AND menu_id IN ?", roleID, menuIDs).Delete(&rbacEntity.RoleMenu{}).Error
+	return conn(ctx, r.db).Where("role_id = ? AND menu_id IN ?", roleID, menuIDs).Delete(&rbacEntity.RoleMenu{}).Error
 }
 
 func (r *rbacRepositoryImpl) GetRoleMenus(ctx context.Context, roleID uint) ([]*menuEntity.Menu, error) {
 	var menus []*menuEntity.Menu
-	err := r.db.WithContext(ctx).Table("menus m").Select("m.*").Joins("JOIN role_menus rm ON rm.menu_id = m.id").Where("rm.role_id = ?", roleID).Scan(&menus).Error
+	err := conn(ctx, r.db).Table("menus m").Select("m.*").Joins("JOIN role_menus rm ON rm.menu_id = m.id").Where("rm.role_id = ?", roleID).Scan(&menus).Error
 	return menus, err
 }
 
-func (r *rbacRepositoryImpl) GetUserMenus(ctx context.Context, userID uint) ([]*menuEntity.Menu, error) {
+// GetRolesMenus 返回绑定到指定角色的正常状态菜单（已删除的菜单同样排除）；角色的状态与继承由调用方处理
+func (r *rbacRepositoryImpl) GetRolesMenus(ctx context.Context, roleIDs []uint) ([]*menuEntity.Menu, error) {
 	var menus []*menuEntity.Menu
-	err := r.db.WithContext(ctx).Table("menus m").Select("DISTINCT m.*").Joins("JOIN role_menus rm ON rm.menu_id = m.id").Joins("JOIN user_roles ur ON ur.role_id = rm.role_id").Where("ur.user_id = ?", userID).Scan(&menus).Error
+	if len(roleIDs) == 0 {
+		return menus, nil
+	}
+	err := conn(ctx, r.db).Table("menus m").Select("DISTINCT m.*").
+		Joins("JOIN role_menus rm ON rm.menu_id = m.id").
+		Where("rm.role_id IN ? AND m.status = 0 AND m.deleted_at IS NULL", roleIDs).
+		Scan(&menus).Error
 	return menus, err
 }
 
 func (r *rbacRepositoryImpl) GetMenuRoles(ctx context.Context, menuID uint) ([]*roleEntity.Role, error) {
 	var roles []*roleEntity.Role
-	err := r.db.WithContext(ctx).Table("roles r").Select("r.*").Joins("JOIN role_menus rm ON rm.role_id = r.id").Where("rm.menu_id = ?", menuID).Scan(&roles).Error
+	err := conn(ctx, r.db).Table("roles r").Select("r.*").Joins("JOIN role_menus rm ON rm.role_id = r.id").Where("rm.menu_id = ?", menuID).Scan(&roles).Error
 	return roles, err
 }
 
 func (r *rbacRepositoryImpl) GetRoleUsers(ctx context.Context, roleID uint) ([]uint, error) {
 	var ids []uint
-	err := r.db.WithContext(ctx).Model(&rbacEntity.UserRole{}).Where("role_id = ?", roleID).Pluck("user_id", &ids).Error
+	err := conn(ctx, r.db).Model(&rbacEntity.UserRole{}).Where("role_id = ?", roleID).Pluck("user_id", &ids).Error
 	return ids, err
 }
 
 func (r *rbacRepositoryImpl) GetMenuIDsByRole(ctx context.Context, roleID uint) ([]uint, error) {
 	var ids []uint
-	err := r.db.WithContext(ctx).Model(&rbacEntity.RoleMenu{}).Where("role_id = ?", roleID).Pluck("menu_id", &ids).Error
+	err := conn(ctx, r.db).Model(&rbacEntity.RoleMenu{}).Where("role_id = ?", roleID).Pluck("menu_id", &ids).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return []uint{}, nil
 	}
 	return ids, err
 }
+
+func (r *rbacRepositoryImpl) BindRoleDenyMenus(ctx context.Context, roleID uint, menuIDs []uint) (int, int, error) {
+	added := 0
+	skipped := 0
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		for _, mid := range menuIDs {
+			res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rbacEntity.RoleMenuDeny{RoleID: roleID, MenuID: mid})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				skipped++
+			} else {
+				added++
+			}
+		}
+		return nil
+	})
+	return added, skipped, err
+}
+
+func (r *rbacRepositoryImpl) UnbindRoleDenyMenus(ctx context.Context, roleID uint, menuIDs []uint) error {
+	return conn(ctx, r.db).Where("role_id = ? AND menu_id IN ?", roleID, menuIDs).Delete(&rbacEntity.RoleMenuDeny{}).Error
+}
+
+// GetRolesDenyMenus 返回指定角色拒绝的菜单；禁用的菜单同样生效，已删除的菜单排除
+func (r *rbacRepositoryImpl) GetRolesDenyMenus(ctx context.Context, roleIDs []uint) ([]*menuEntity.Menu, error) {
+	var menus []*menuEntity.Menu
+	if len(roleIDs) == 0 {
+		return menus, nil
+	}
+	err := conn(ctx, r.db).Table("menus m").Select("DISTINCT m.*").
+		Joins("JOIN role_menu_denies d ON d.menu_id = m.id").
+		Where("d.role_id IN ? AND m.deleted_at IS NULL", roleIDs).
+		Scan(&menus).Error
+	return menus, err
+}
+
+func (r *rbacRepositoryImpl) GetMenuDenyRoles(ctx context.Context, menuID uint) ([]uint, error) {
+	var ids []uint
+	err := conn(ctx, r.db).Model(&rbacEntity.RoleMenuDeny{}).Where("menu_id = ?", menuID).Pluck("role_id", &ids).Error
+	return ids, err
+}
+
+func (r *rbacRepositoryImpl) GetUserRolesByRoles(ctx context.Context, roleIDs []uint) ([]*rbacEntity.UserRole, error) {
+	var list []*rbacEntity.UserRole
+	if len(roleIDs) == 0 {
+		return list, nil
+	}
+	err := conn(ctx, r.db).Where("role_id IN ?", roleIDs).Order("user_id, role_id").Find(&list).Error
+	return list, err
+}
+
+func (r *rbacRepositoryImpl) SaveRoleConstraint(ctx context.Context, c *rbacEntity.RoleConstraint) error {
+	return conn(ctx, r.db).Save(c).Error
+}
+
+func (r *rbacRepositoryImpl) DeleteRoleConstraint(ctx context.Context, id uint) error {
+	return conn(ctx, r.db).Delete(&rbacEntity.RoleConstraint{}, id).Error
+}
+
+func (r *rbacRepositoryImpl) GetRoleConstraint(ctx context.Context, id uint) (*rbacEntity.RoleConstraint, error) {
+	var c rbacEntity.RoleConstraint
+	err := conn(ctx, r.db).First(&c, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *rbacRepositoryImpl) ListRoleConstraints(ctx context.Context) ([]*rbacEntity.RoleConstraint, error) {
+	var list []*rbacEntity.RoleConstraint
+	err := conn(ctx, r.db).Order("id").Find(&list).Error
+	return list, err
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	authEntity "github.com/sine-io/sinx/domain/auth/entity"
+	authRepo "github.com/sine-io/sinx/domain/auth/repository"
+	"gorm.io/gorm"
+)
+
+type refreshTokenRepositoryImpl struct{ db *gorm.DB }
+
+func NewRefreshTokenRepository(db *gorm.DB) authRepo.RefreshTokenRepository {
+	return &refreshTokenRepositoryImpl{db: db}
+}
+
+func (r *refreshTokenRepositoryImpl) Create(ctx context.Context, token *authEntity.RefreshToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *refreshTokenRepositoryImpl) GetByHash(ctx context.Context, tokenHash string) (*authEntity.RefreshToken, error) {
+	var t authEntity.RefreshToken
+	err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&t).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *refreshTokenRepositoryImpl) MarkUsed(ctx context.Context, tokenHash string) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&authEntity.RefreshToken{}).Where("token_hash = ? AND used_at IS NULL", tokenHash).Update("used_at", time.Now())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (r *refreshTokenRepositoryImpl) RevokeFamily(ctx context.Context, familyID string) error {
+	return r.db.WithContext(ctx).Model(&authEntity.RefreshToken{}).Where("family_id = ? AND revoked_at IS NULL", familyID).Update("revoked_at", time.Now()).Error
+}
+
+func (r *refreshTokenRepositoryImpl) RevokeUser(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Model(&authEntity.RefreshToken{}).Where("user_id = ? AND revoked_at IS NULL", userID).Update("revoked_at", time.Now()).Error
+}
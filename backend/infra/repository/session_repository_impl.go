@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	authEntity "github.com/sine-io/sinx/domain/auth/entity"
+	authRepo "github.com/sine-io/sinx/domain/auth/repository"
+	"gorm.io/gorm"
+)
+
+type sessionRepositoryImpl struct{ db *gorm.DB }
+
+func NewSessionRepository(db *gorm.DB) authRepo.SessionRepository {
+	return &sessionRepositoryImpl{db: db}
+}
+
+func (r *sessionRepositoryImpl) Create(ctx context.Context, session *authEntity.Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *sessionRepositoryImpl) GetBySessionID(ctx context.Context, sessionID string) (*authEntity.Session, error) {
+	var s authEntity.Session
+	err := r.db.WithContext(ctx).Where("session_id = ?", sessionID).First(&s).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *sessionRepositoryImpl) active(ctx context.Context, now time.Time) *gorm.DB {
+	return r.db.WithContext(ctx).Model(&authEntity.Session{}).Where("revoked_at IS NULL AND expires_at > ?", now)
+}
+
+func (r *sessionRepositoryImpl) ListActiveByUser(ctx context.Context, userID uint, now time.Time) ([]*authEntity.Session, error) {
+	var list []*authEntity.Session
+	err := r.active(ctx, now).Where("user_id = ?", userID).Order("last_seen_at DESC").Find(&list).Error
+	return list, err
+}
+
+func (r *sessionRepositoryImpl) ListActive(ctx context.Context, now time.Time, offset, limit int) ([]*authEntity.Session, int64, error) {
+	var total int64
+	if err := r.active(ctx, now).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var list []*authEntity.Session
+	err := r.active(ctx, now).Order("last_seen_at DESC").Offset(offset).Limit(limit).Find(&list).Error
+	return list, total, err
+}
+
+func (r *sessionRepositoryImpl) Touch(ctx context.Context, sessionID string, lastSeen, expiresAt time.Time) error {
+	updates := map[string]interface{}{"last_seen_at": lastSeen}
+	if !expiresAt.IsZero() {
+		updates["expires_at"] = expiresAt
+	}
+	return r.db.WithContext(ctx).Model(&authEntity.Session{}).Where("session_id = ? AND revoked_at IS NULL", sessionID).Updates(updates).Error
+}
+
+func (r *sessionRepositoryImpl) Revoke(ctx context.Context, sessionID string) error {
+	return r.db.WithContext(ctx).Model(&authEntity.Session{}).Where("session_id = ? AND revoked_at IS NULL", sessionID).Update("revoked_at", time.Now()).Error
+}
+
+func (r *sessionRepositoryImpl) RevokeUser(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Model(&authEntity.Session{}).Where("user_id = ? AND revoked_at IS NULL", userID).Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// LockSoD 开启事务并获取职责分离咨询锁后执行 fn，锁随事务结束释放，对多实例同样有效
+// 绑定持全局共享锁与该用户的独占锁；父角色变更 / 约束保存（userID 为 0）持全局独占锁
+func (r *rbacRepositoryImpl) LockSoD(ctx context.Context, userID uint, fn func(ctx context.Context) error) error {
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if userID == 0 {
+			if err := advisoryXactLock(tx, lockClassSoD, 0); err != nil {
+				return err
+			}
+		} else {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock_shared(?, 0)", lockClassSoD).Error; err != nil {
+				return err
+			}
+			if err := advisoryXactLock(tx, lockClassSoDUser, int32(userID)); err != nil {
+				return err
+			}
+		}
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
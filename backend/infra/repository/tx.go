@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// 咨询锁命名空间（pg_advisory_xact_lock 双参数形式的第一个参数）
+const (
+	lockClassSoD        int32 = 0x50d0 // 职责分离：全局
+	lockClassSoDUser    int32 = 0x50d1 // 职责分离：单个用户的角色绑定
+	lockClassSuperAdmin int32 = 0x50d2 // 超级管理员的降级 / 禁用 / 删除
+)
+
+type txKey struct{}
+
+// conn 返回 ctx 中由 LockSoD / LockSuperAdmins 开启的事务，否则返回 db；使锁内的校验与写入在同一事务中执行
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+// advisoryXactLock 在事务内获取咨询锁，随事务结束释放
+func advisoryXactLock(tx *gorm.DB, class, key int32) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", class, key).Error
+}
@@ -20,12 +20,12 @@ func NewUserRepository(db *gorm.DB) repository.UserRepository {
 }
 
 func (r *userRepositoryImpl) Create(ctx context.Context, user *entity.User) error {
-	return r.db.WithContext(ctx).Create(user).Error
+	return conn(ctx, r.db).Create(user).Error
 }
 
 func (r *userRepositoryImpl) GetByID(ctx context.Context, id uint) (*entity.User, error) {
 	var user entity.User
-	err := r.db.WithContext(ctx).Where("id = ?", id).First(&user).Error
+	err := conn(ctx, r.db).Where("id = ?", id).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -34,7 +34,7 @@ func (r *userRepositoryImpl) GetByID(ctx context.Context, id uint) (*entity.User
 
 func (r *userRepositoryImpl) GetByUsername(ctx context.Context, username string) (*entity.User, error) {
 	var user entity.User
-	err := r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error
+	err := conn(ctx, r.db).Where("username = ?", username).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -43,7 +43,7 @@ func (r *userRepositoryImpl) GetByUsername(ctx context.Context, username string)
 
 func (r *userRepositoryImpl) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
 	var user entity.User
-	err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error
+	err := conn(ctx, r.db).Where("email = ?", email).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -51,21 +51,46 @@ func (r *userRepositoryImpl) GetByEmail(ctx context.Context, email string) (*ent
 }
 
 func (r *userRepositoryImpl) Update(ctx context.Context, user *entity.User) error {
-	return r.db.WithContext(ctx).Save(user).Error
+	return conn(ctx, r.db).Save(user).Error
+}
+
+func (r *userRepositoryImpl) UpdateLastLogin(ctx context.Context, user *entity.User) error {
+	return conn(ctx, r.db).Model(&entity.User{}).Where("id = ?", user.ID).UpdateColumns(map[string]interface{}{
+		"last_login_ip":       user.LastLoginIP,
+		"last_login_nation":   user.LastLoginNation,
+		"last_login_province": user.LastLoginProvince,
+		"last_login_city":     user.LastLoginCity,
+		"last_login_date":     user.LastLoginDate,
+	}).Error
 }
 
 func (r *userRepositoryImpl) Delete(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).Model(&entity.User{}).Where("id = ?", id).Update("status", 1).Error
+	return conn(ctx, r.db).Model(&entity.User{}).Where("id = ?", id).Update("status", 1).Error
 }
 
 func (r *userRepositoryImpl) List(ctx context.Context, offset, limit int) ([]*entity.User, error) {
 	var users []*entity.User
-	err := r.db.WithContext(ctx).Where("status = 0").Offset(offset).Limit(limit).Order("created_at DESC").Find(&users).Error
+	err := conn(ctx, r.db).Scopes(dataScope(ctx, "dept_id", "id")).Where("status = 0").Offset(offset).Limit(limit).Order("created_at DESC").Find(&users).Error
 	return users, err
 }
 
 func (r *userRepositoryImpl) Count(ctx context.Context) (int64, error) {
 	var count int64
-	err := r.db.WithContext(ctx).Model(&entity.User{}).Where("status = 0").Count(&count).Error
+	err := conn(ctx, r.db).Model(&entity.User{}).Scopes(dataScope(ctx, "dept_id", "id")).Where("status = 0").Count(&count).Error
+	return count, err
+}
+
+func (r *userRepositoryImpl) CountSuperAdmins(ctx context.Context) (int64, error) {
+	var count int64
+	err := conn(ctx, r.db).Model(&entity.User{}).Where("status = 0 AND user_type = ?", entity.UserTypeSuperAdmin).Count(&count).Error
 	return count, err
 }
+
+func (r *userRepositoryImpl) LockSuperAdmins(ctx context.Context, fn func(ctx context.Context) error) error {
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := advisoryXactLock(tx, lockClassSuperAdmin, 0); err != nil {
+			return err
+		}
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
@@ -40,6 +40,9 @@ func main() {
 	if err := logger.Init(); err != nil {
 		log.Fatalf("Failed to initialize logger: %v", err)
 	}
+	for env, replacement := range config.Get().Deprecated {
+		logger.Warn("config_deprecated", "env", env, "replacement", replacement)
+	}
 
 	// 创建上下文
 	ctx := context.Background()
@@ -5,41 +5,111 @@ import (
 	"time"
 
 	"github.com/sine-io/sinx/pkg/config"
+	"github.com/sine-io/sinx/pkg/utils"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// TokenUseMFAPending 密码校验通过、等待两步验证的临时令牌
+const TokenUseMFAPending = "mfa_pending"
+
+// TokenUseAPIKey 由个人访问令牌解析出的身份（非 JWT，不会被签发）
+const TokenUseAPIKey = "api_key"
+
+// TokenUseOIDC 签发给 OIDC 客户端的访问令牌，仅可用于 userinfo 端点
+const TokenUseOIDC = "oidc"
+
+// TokenUsePasswordReset 找回密码邮件中的一次性令牌
+const TokenUsePasswordReset = "password_reset"
+
+// TokenUseEmailVerify 邮箱验证邮件中的一次性令牌
+const TokenUseEmailVerify = "email_verify"
+
+// APIKeyPrefix 个人访问令牌明文前缀，用于与 JWT 区分
+const APIKeyPrefix = "sinx_pat_"
+
+// mfaTokenTTL 两步验证临时令牌有效期
+const mfaTokenTTL = 5 * time.Minute
+
+// Claims 访问令牌声明，RegisteredClaims.ID 即 jti（用于单令牌吊销）
+// TokenUse 非空表示受限用途令牌，不能用于访问业务接口
 type Claims struct {
 	UserID   uint   `json:"user_id"`
 	Username string `json:"username"`
+	TokenUse string `json:"token_use,omitempty"`
+	Scope    string `json:"scope,omitempty"` // OIDC 访问令牌的授权范围
+	// SessionID 登录会话标识（与刷新令牌族相同），会话被吊销后其访问令牌立即失效
+	SessionID string `json:"sid,omitempty"`
+	// Fingerprint 邮件令牌签发时的账号状态摘要（密码哈希 / 邮箱），状态变化后令牌即失效
+	Fingerprint string `json:"fp,omitempty"`
+	// Actor 代登录令牌的实际操作人（RFC 8693 act 声明），普通令牌为 nil
+	Actor *Actor `json:"act,omitempty"`
+	// IssuedAtNano 纳秒级签发时间：iat 只有秒级精度，用户级吊销按此判断同一秒内签发的令牌
+	IssuedAtNano int64 `json:"iat_ns,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Actor 代登录的管理员身份
+type Actor struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+}
+
+// AccessTokenTTL 访问令牌有效期
+func AccessTokenTTL() time.Duration {
+	return time.Duration(config.Get().JWTExpireMinutes) * time.Minute
+}
+
 func GenerateToken(userID uint, username string) (string, error) {
-	cfg := config.Get()
+	return GenerateSessionToken(userID, username, "")
+}
 
-	claims := Claims{
-		UserID:   userID,
-		Username: username,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(cfg.JWTExpireHours) * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    cfg.JWTIssuer,
-		},
-	}
+// GenerateSessionToken 签发属于指定登录会话的访问令牌
+func GenerateSessionToken(userID uint, username, sessionID string) (string, error) {
+	return generate(&Claims{UserID: userID, Username: username, SessionID: sessionID}, AccessTokenTTL())
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(cfg.JWTSecret))
+// GenerateImpersonationToken 签发以目标用户身份访问、携带 act 声明的代登录令牌（不关联会话，不可刷新）
+func GenerateImpersonationToken(userID uint, username string, actor Actor, ttl time.Duration) (string, *Claims, error) {
+	claims := Claims{UserID: userID, Username: username, Actor: &actor}
+	token, err := generate(&claims, ttl)
+	return token, &claims, err
 }
 
-func ParseToken(tokenString string) (*Claims, error) {
+// GenerateMFAToken 签发两步验证临时令牌
+func GenerateMFAToken(userID uint, username string) (string, error) {
+	return generate(&Claims{UserID: userID, Username: username, TokenUse: TokenUseMFAPending}, mfaTokenTTL)
+}
+
+// GenerateActionToken 签发邮件链接中的一次性令牌（找回密码 / 邮箱验证）
+func GenerateActionToken(userID uint, username, use, fingerprint string, ttl time.Duration) (string, error) {
+	return generate(&Claims{UserID: userID, Username: username, TokenUse: use, Fingerprint: fingerprint}, ttl)
+}
+
+// generate 补全 jti / 有效期 / 签发者后签名
+func generate(claims *Claims, ttl time.Duration) (string, error) {
 	cfg := config.Get()
 
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(cfg.JWTSecret), nil
-	})
+	jti, err := utils.RandomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims.IssuedAtNano = now.UnixNano()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        jti,
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		Issuer:    cfg.JWTIssuer,
+	}
 
+	return SignClaims(claims)
+}
+
+func ParseToken(tokenString string) (*Claims, error) {
+	token, err := ParseWithKeys(tokenString, &Claims{})
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,242 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/sine-io/sinx/pkg/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey 一把签名密钥；Private 为空表示仅用于验签（已停止签发、等待退役的旧密钥）
+type SigningKey struct {
+	KID     string
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+// KeySet 按 kid 索引的密钥集合，Active 指定当前用于签发的密钥
+type KeySet struct {
+	Active string
+	keys   map[string]*SigningKey
+}
+
+// JWK 公钥的 JSON Web Key 表示（RFC 7517 / RFC 8037）
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet /.well-known/jwks.json 响应体
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// keySet 当前生效的密钥集合；为 nil 时退回 HS256 + JWTSecret
+var keySet atomic.Pointer[KeySet]
+
+// InitSigningKeys 按配置加载非对称签名密钥；未配置 JWT_SIGNING_KEYS 时沿用 HS256
+func InitSigningKeys() error {
+	cfg := config.Get()
+	if strings.TrimSpace(cfg.JWTSigningKeys) == "" {
+		keySet.Store(nil)
+		return nil
+	}
+	ks, err := LoadKeySet(cfg.JWTSigningKeys, cfg.JWTActiveKID)
+	if err != nil {
+		return err
+	}
+	keySet.Store(ks)
+	return nil
+}
+
+// SetKeySet 替换当前密钥集合（nil 表示使用 HS256）
+func SetKeySet(ks *KeySet) { keySet.Store(ks) }
+
+// LoadKeySet 解析 "kid:path,kid:path" 形式的配置并读取 PEM 文件
+// 私钥文件可签发与验签，公钥文件仅用于验签；activeKID 为空时取第一把私钥
+func LoadKeySet(spec, activeKID string) (*KeySet, error) {
+	ks := &KeySet{keys: map[string]*SigningKey{}}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kid, path, ok := strings.Cut(item, ":")
+		if !ok || kid == "" || path == "" {
+			return nil, fmt.Errorf("invalid signing key entry %q, expected kid:path", item)
+		}
+		if _, dup := ks.keys[kid]; dup {
+			return nil, fmt.Errorf("duplicate signing key id %q", kid)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read signing key %s: %w", kid, err)
+		}
+		key, err := ParseKeyPEM(kid, data)
+		if err != nil {
+			return nil, err
+		}
+		ks.keys[kid] = key
+		if activeKID == "" && key.Private != nil {
+			activeKID = kid
+		}
+	}
+	active, ok := ks.keys[activeKID]
+	if !ok {
+		return nil, fmt.Errorf("active signing key %q not found", activeKID)
+	}
+	if active.Private == nil {
+		return nil, fmt.Errorf("active signing key %q has no private key", activeKID)
+	}
+	ks.Active = activeKID
+	return ks, nil
+}
+
+// ParseKeyPEM 解析 RSA（RS256）或 Ed25519（EdDSA）的私钥 / 公钥 PEM
+func ParseKeyPEM(kid string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("signing key %s: no PEM block found", kid)
+	}
+	var parsed any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("signing key %s: unsupported PEM type %q", kid, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("signing key %s: %w", kid, err)
+	}
+
+	key := &SigningKey{KID: kid}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("signing key %s: unsupported key type %T", kid, parsed)
+	}
+	if rk, ok := key.Public.(*rsa.PublicKey); ok && rk.N.BitLen() < 2048 {
+		return nil, fmt.Errorf("signing key %s: RSA key must be at least 2048 bits", kid)
+	}
+	return key, nil
+}
+
+// ActiveKey 当前签发使用的密钥
+func (ks *KeySet) ActiveKey() *SigningKey { return ks.keys[ks.Active] }
+
+// Lookup 按 kid 查找验签密钥
+func (ks *KeySet) Lookup(kid string) (*SigningKey, bool) {
+	k, ok := ks.keys[kid]
+	return k, ok
+}
+
+// JWKS 导出全部公钥（含仅验签的旧密钥），按 kid 排序
+func (ks *KeySet) JWKS() JWKSet {
+	kids := make([]string, 0, len(ks.keys))
+	for kid := range ks.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	set := JWKSet{Keys: make([]JWK, 0, len(kids))}
+	for _, kid := range kids {
+		k := ks.keys[kid]
+		jwk := JWK{Kid: kid, Use: "sig", Alg: k.Method.Alg()}
+		switch pub := k.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// CurrentJWKS 当前公钥集合；HS256 模式下为空集合
+func CurrentJWKS() JWKSet {
+	if ks := keySet.Load(); ks != nil {
+		return ks.JWKS()
+	}
+	return JWKSet{Keys: []JWK{}}
+}
+
+// ActiveAlg 当前签发使用的算法
+func ActiveAlg() string {
+	if ks := keySet.Load(); ks != nil {
+		return ks.ActiveKey().Method.Alg()
+	}
+	return jwt.SigningMethodHS256.Alg()
+}
+
+// SignClaims 使用当前活动密钥签名（未配置时使用 HS256 共享密钥）
+func SignClaims(claims jwt.Claims) (string, error) {
+	ks := keySet.Load()
+	if ks == nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString([]byte(config.Get().JWTSecret))
+	}
+	key := ks.ActiveKey()
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.KID
+	return token.SignedString(key.Private)
+}
+
+// ParseWithKeys 按 kid 选择验签密钥，并限定算法防止算法混淆
+func ParseWithKeys(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	ks := keySet.Load()
+	if ks == nil {
+		return jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+			return []byte(config.Get().JWTSecret), nil
+		}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	}
+	return jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := ks.Lookup(kid)
+		if !ok {
+			return nil, errors.New("unknown signing key")
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, errors.New("signing method mismatch")
+		}
+		return key.Public, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}))
+}
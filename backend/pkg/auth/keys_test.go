@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sine-io/sinx/pkg/config"
+)
+
+func writePEM(t *testing.T, dir, name, typ string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// 轮换流程：新密钥开始签发后，旧密钥签发的令牌仍可验证；旧密钥移除后失效
+func TestKeyRotation(t *testing.T) {
+	_ = config.LoadEnv()
+	defer SetKeySet(nil)
+	dir := t.TempDir()
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rsaPath := writePEM(t, dir, "old.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+	_, edPriv, _ := ed25519.GenerateKey(rand.Reader)
+	edDER, _ := x509.MarshalPKCS8PrivateKey(edPriv)
+	edPath := writePEM(t, dir, "new.pem", "PRIVATE KEY", edDER)
+
+	ks, err := LoadKeySet("old:"+rsaPath, "old")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	SetKeySet(ks)
+	oldToken, err := GenerateToken(1, "u1")
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	// 新密钥上线并开始签发，旧密钥保留验签
+	ks, err = LoadKeySet("old:"+rsaPath+",new:"+edPath, "new")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	SetKeySet(ks)
+	newToken, _ := GenerateToken(2, "u2")
+	if c, err := ParseToken(oldToken); err != nil || c.UserID != 1 {
+		t.Fatalf("old token should verify: %v", err)
+	}
+	if c, err := ParseToken(newToken); err != nil || c.UserID != 2 {
+		t.Fatalf("new token should verify: %v", err)
+	}
+	if jwks := CurrentJWKS(); len(jwks.Keys) != 2 || jwks.Keys[0].Kid != "new" || jwks.Keys[0].Kty != "OKP" || jwks.Keys[1].Kty != "RSA" {
+		t.Fatalf("unexpected jwks: %+v", jwks)
+	}
+
+	// 旧密钥退役
+	ks, _ = LoadKeySet("new:"+edPath, "")
+	SetKeySet(ks)
+	if _, err := ParseToken(oldToken); err == nil {
+		t.Fatalf("token signed by retired key accepted")
+	}
+
+	// HS256 令牌不能混入非对称模式
+	SetKeySet(nil)
+	hsToken, _ := GenerateToken(3, "u3")
+	SetKeySet(ks)
+	if _, err := ParseToken(hsToken); err == nil {
+		t.Fatalf("HS256 token accepted in asymmetric mode")
+	}
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// legacyMilliBound 用户级吊销时间原以毫秒存储，现为纳秒；小于该值的按毫秒解析
+const legacyMilliBound = 1e15
+
+// RedisRevocationStore 基于 Redis 的令牌吊销存储，记录随令牌有效期自动过期
+type RedisRevocationStore struct {
+	cli    *redis.Client
+	ttl    time.Duration
+	prefix string
+}
+
+func NewRedisRevocationStore(cli *redis.Client, ttl time.Duration) *RedisRevocationStore {
+	return &RedisRevocationStore{cli: cli, ttl: ttl, prefix: "revoked_"}
+}
+
+func (s *RedisRevocationStore) tokenKey(jti string) string { return s.prefix + "jti:" + jti }
+
+func (s *RedisRevocationStore) userKey(userID uint) string {
+	return s.prefix + "user:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+func (s *RedisRevocationStore) RevokeToken(ctx context.Context, jti string, exp time.Time) error {
+	ttl := time.Until(exp)
+	if ttl <= 0 {
+		return nil
+	}
+	return s.cli.Set(ctx, s.tokenKey(jti), 1, ttl).Err()
+}
+
+func (s *RedisRevocationStore) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	n, err := s.cli.Exists(ctx, s.tokenKey(jti)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (s *RedisRevocationStore) RevokeUser(ctx context.Context, userID uint, at time.Time) error {
+	return s.cli.Set(ctx, s.userKey(userID), at.UnixNano(), s.ttl).Err()
+}
+
+func (s *RedisRevocationStore) UserRevokedAt(ctx context.Context, userID uint) (time.Time, error) {
+	val, err := s.cli.Get(ctx, s.userKey(userID)).Int64()
+	if err != nil {
+		if err == redis.Nil {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	// 兼容旧版本写入的毫秒时间戳
+	if val < legacyMilliBound {
+		return time.UnixMilli(val), nil
+	}
+	return time.Unix(0, val), nil
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RevocationStore 访问令牌吊销存储
+// 单令牌按 jti 吊销；用户级吊销记录一个时间点，此前签发的令牌全部失效
+type RevocationStore interface {
+	RevokeToken(ctx context.Context, jti string, exp time.Time) error
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+	RevokeUser(ctx context.Context, userID uint, at time.Time) error
+	// UserRevokedAt 返回用户级吊销时间点，未吊销返回零值
+	UserRevokedAt(ctx context.Context, userID uint) (time.Time, error)
+}
+
+// IsClaimsRevoked 综合判断令牌是否已被吊销（单令牌 / 所属会话 / 用户级）
+func IsClaimsRevoked(ctx context.Context, store RevocationStore, claims *Claims) (bool, error) {
+	if claims.ID != "" {
+		revoked, err := store.IsTokenRevoked(ctx, claims.ID)
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+	if claims.SessionID != "" {
+		revoked, err := store.IsTokenRevoked(ctx, sessionKey(claims.SessionID))
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+	// 代登录令牌在目标用户或操作人任一方被整体吊销（如退出全部设备）后失效
+	if claims.Actor != nil {
+		revoked, err := userRevoked(ctx, store, claims.Actor.UserID, claims)
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+	return userRevoked(ctx, store, claims.UserID, claims)
+}
+
+// userRevoked 令牌是否签发于用户级吊销时间之前
+func userRevoked(ctx context.Context, store RevocationStore, userID uint, claims *Claims) (bool, error) {
+	at, err := store.UserRevokedAt(ctx, userID)
+	if err != nil || at.IsZero() {
+		return false, err
+	}
+	if claims.IssuedAtNano != 0 {
+		return time.Unix(0, claims.IssuedAtNano).Before(at), nil
+	}
+	// 缺少 iat_ns 的令牌只有秒级 iat：吊销时间向上取整到秒，同一秒内签发的令牌一并失效
+	if claims.IssuedAt == nil {
+		return true, nil
+	}
+	cutoff := at.Truncate(time.Second)
+	if cutoff.Before(at) {
+		cutoff = cutoff.Add(time.Second)
+	}
+	return claims.IssuedAt.Time.Before(cutoff), nil
+}
+
+// RevokeSession 吊销会话下已签发的全部访问令牌；记录保留到这些令牌全部过期
+func RevokeSession(ctx context.Context, store RevocationStore, sessionID string) error {
+	return store.RevokeToken(ctx, sessionKey(sessionID), time.Now().Add(AccessTokenTTL()))
+}
+
+// sessionKey 会话吊销与单令牌吊销共用存储，加前缀避免与 jti 冲突
+func sessionKey(sessionID string) string { return "sid:" + sessionID }
+
+type userRevocation struct {
+	at  time.Time
+	exp time.Time
+}
+
+// MemoryRevocationStore 进程内吊销存储（Redis 不可用时的回退实现）
+type MemoryRevocationStore struct {
+	ttl    time.Duration // 用户级吊销保留时长（不短于访问令牌有效期）
+	mu     sync.RWMutex
+	tokens map[string]time.Time
+	users  map[uint]userRevocation
+}
+
+func NewMemoryRevocationStore(ttl time.Duration) *MemoryRevocationStore {
+	return &MemoryRevocationStore{ttl: ttl, tokens: make(map[string]time.Time), users: make(map[uint]userRevocation)}
+}
+
+func (s *MemoryRevocationStore) RevokeToken(_ context.Context, jti string, exp time.Time) error {
+	s.mu.Lock()
+	s.tokens[jti] = exp
+	s.gcLocked(time.Now())
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryRevocationStore) IsTokenRevoked(_ context.Context, jti string) (bool, error) {
+	s.mu.RLock()
+	exp, ok := s.tokens[jti]
+	s.mu.RUnlock()
+	return ok && time.Now().Before(exp), nil
+}
+
+func (s *MemoryRevocationStore) RevokeUser(_ context.Context, userID uint, at time.Time) error {
+	s.mu.Lock()
+	s.users[userID] = userRevocation{at: at, exp: at.Add(s.ttl)}
+	s.gcLocked(time.Now())
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryRevocationStore) UserRevokedAt(_ context.Context, userID uint) (time.Time, error) {
+	s.mu.RLock()
+	item, ok := s.users[userID]
+	s.mu.RUnlock()
+	if !ok || time.Now().After(item.exp) {
+		return time.Time{}, nil
+	}
+	return item.at, nil
+}
+
+// gcLocked 清理过期记录（调用方需持有写锁）
+func (s *MemoryRevocationStore) gcLocked(now time.Time) {
+	for jti, exp := range s.tokens {
+		if now.After(exp) {
+			delete(s.tokens, jti)
+		}
+	}
+	for id, item := range s.users {
+		if now.After(item.exp) {
+			delete(s.users, id)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sine-io/sinx/pkg/config"
+)
+
+// 代登录令牌：act 声明可往返解析；操作人被整体吊销后令牌随之失效
+func TestImpersonationRevocation(t *testing.T) {
+	_ = config.LoadEnv()
+	ctx := context.Background()
+
+	raw, _, err := GenerateImpersonationToken(2, "john", Actor{UserID: 1, Username: "admin"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := ParseToken(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserID != 2 || claims.Actor == nil || claims.Actor.UserID != 1 || claims.SessionID != "" {
+		t.Fatalf("unexpected claims: %+v", claims)
+	}
+
+	store := NewMemoryRevocationStore(time.Hour)
+	if revoked, _ := IsClaimsRevoked(ctx, store, claims); revoked {
+		t.Fatal("fresh token must be valid")
+	}
+	_ = store.RevokeUser(ctx, 1, time.Now())
+	if revoked, _ := IsClaimsRevoked(ctx, store, claims); !revoked {
+		t.Fatal("revoking the actor must revoke the impersonation token")
+	}
+}
+
+// 用户级吊销精确到纳秒：同一秒内早于吊销签发的令牌失效，之后签发的令牌有效
+func TestUserRevocationSubSecond(t *testing.T) {
+	_ = config.LoadEnv()
+	ctx := context.Background()
+	store := NewMemoryRevocationStore(time.Hour)
+	second := time.Now().Truncate(time.Second).Add(time.Second)
+	at := second.Add(500 * time.Millisecond)
+	_ = store.RevokeUser(ctx, 1, at)
+
+	token := func(issued time.Time, withNano bool) *Claims {
+		c := &Claims{UserID: 1}
+		c.IssuedAt = jwt.NewNumericDate(issued)
+		if withNano {
+			c.IssuedAtNano = issued.UnixNano()
+		}
+		return c
+	}
+	cases := []struct {
+		name    string
+		claims  *Claims
+		revoked bool
+	}{
+		{"same second, before revoke", token(at.Add(-time.Millisecond), true), true},
+		{"same second, after revoke", token(at.Add(time.Millisecond), true), false},
+		{"previous second", token(second.Add(-time.Millisecond), true), true},
+		// 无 iat_ns 的旧令牌：吊销时间向上取整到秒
+		{"legacy, same second", token(at.Add(time.Millisecond), false), true},
+		{"legacy, next second", token(second.Add(time.Second), false), false},
+		{"no iat", &Claims{UserID: 1}, true},
+	}
+	for _, c := range cases {
+		if revoked, _ := IsClaimsRevoked(ctx, store, c.claims); revoked != c.revoked {
+			t.Fatalf("%s: revoked = %v, want %v", c.name, revoked, c.revoked)
+		}
+	}
+
+	// 刚签发的令牌带有 iat_ns，并在吊销后立即失效
+	raw, err := GenerateToken(2, "john")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := ParseToken(raw)
+	if err != nil || claims.IssuedAtNano == 0 {
+		t.Fatalf("expected iat_ns, got %+v (%v)", claims, err)
+	}
+	_ = store.RevokeUser(ctx, 2, time.Now())
+	if revoked, _ := IsClaimsRevoked(ctx, store, claims); !revoked {
+		t.Fatal("token issued before revoke in the same second must be revoked")
+	}
+}
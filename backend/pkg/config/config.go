@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -11,6 +12,11 @@ type Config struct {
 	AppEnv     string
 	ListenAddr string
 	LogLevel   string
+	// TrustedProxies 可信反向代理（IP / CIDR），仅信任其转发的 X-Forwarded-For；为空时客户端 IP 取连接地址
+	TrustedProxies []string
+
+	// Deprecated 仍被读取的已废弃环境变量 -> 替代变量，启动时输出告警
+	Deprecated map[string]string
 
 	// Database
 	DBHost     string
@@ -21,9 +27,91 @@ type Config struct {
 	DBSSLMode  string
 
 	// JWT
-	JWTSecret      string
-	JWTExpireHours int
-	JWTIssuer      string
+	JWTSecret               string
+	JWTExpireMinutes        int
+	JWTIssuer               string
+	JWTSigningKeys          string // kid:path[,kid:path...]，为空时使用 HS256 + JWTSecret
+	JWTActiveKID            string // 当前签发使用的 kid，其余密钥仅验签
+	ImpersonationTTLMinutes int    // 代登录令牌有效期
+
+	// 超级管理员初始化：系统中没有超级管理员时，启动时将该用户名提升为超级管理员
+	BootstrapSuperAdmin string
+
+	// 限时角色：清理过期绑定、失效权限缓存的间隔（秒），0 表示不启用
+	RoleSweepIntervalSeconds int
+
+	// Refresh Token
+	RefreshExpireHours int
+	RefreshTokenStore  string // postgres | redis
+
+	// Password
+	PasswordHasher         string   // argon2id | bcrypt
+	PasswordMinLength      int      // 最小长度
+	PasswordRequireClasses []string // 必须包含的字符类别：lower / upper / digit / symbol
+	PasswordDenyCommon     bool     // 拒绝常见 / 泄露密码
+	PasswordDenyListFile   string   // 追加的拒绝密码表（每行一个）
+	PasswordDenyUsername   bool     // 拒绝包含用户名的密码
+	PasswordHistory        int      // 不得与最近 N 个密码相同
+	PasswordMaxAgeDays     int      // 密码有效期，0 表示永不过期
+
+	// Login throttling
+	LoginWindowMinutes     int
+	LoginMaxUserFailures   int
+	LoginMaxIPFailures     int
+	LoginLockoutMinutes    int
+	LoginBackoffMaxSeconds int
+
+	// 登录日志
+	GeoIPDBPath string // MaxMind mmdb（GeoLite2-City 等），为空表示不解析归属地
+
+	// MFA
+	MFAIssuer string // otpauth URI 中显示的发行方
+
+	// OIDC Provider
+	OIDCIssuer   string // 对外可访问的基础地址，作为 iss 与各端点前缀
+	OIDCLoginURL string // 前端登录 / 授权页
+
+	// Federation（上游 OIDC 身份提供方）
+	FederationProviders   []FederationProvider
+	FederationRedirectURL string // 外部登录完成后跳转的前端页面，结果放在 URL fragment 中
+
+	// LDAP / Active Directory
+	LDAPURL                 string // 为空表示不启用
+	LDAPStartTLS            bool
+	LDAPInsecureSkipVerify  bool
+	LDAPBindDN              string
+	LDAPBindPassword        string
+	LDAPBaseDN              string
+	LDAPUserFilter          string
+	LDAPUsernameAttr        string
+	LDAPEmailAttr           string
+	LDAPNameAttr            string
+	LDAPGroupAttr           string
+	LDAPGroupRoles          map[string]uint // 目录组(cn) -> 本地角色ID
+	LDAPDefaultRoleID       uint            // 同步导入用户时绑定的角色
+	LDAPDefaultAuth         string          // AuthSource 为空的用户使用的认证方式：local / ldap
+	LDAPSyncIntervalMinutes int             // 定时同步间隔，0 表示仅手动触发
+
+	// Mail
+	MailDriver      string // log | file | smtp
+	MailFrom        string
+	MailOutboxDir   string // file 驱动写入目录
+	MailTemplateDir string // 自定义模板目录，同名文件覆盖内置模板
+	SMTPHost        string
+	SMTPPort        int
+	SMTPUsername    string
+	SMTPPassword    string
+	SMTPTLS         string // starttls | tls | none
+
+	// 找回密码 / 邮箱验证
+	PasswordResetURL         string // 前端重置密码页，邮件链接为 <url>?token=...
+	PasswordResetTTLMinutes  int
+	EmailVerifyURL           string // 前端邮箱验证页
+	EmailVerifyTTLHours      int
+	RequireEmailVerification bool // 邮箱未验证的用户禁止密码登录
+	MailRateWindowMinutes    int  // 找回密码 / 重发验证邮件限流窗口
+	MailRateMaxPerEmail      int  // 窗口内单邮箱请求上限，0 表示不限
+	MailRateMaxPerIP         int  // 窗口内单 IP 请求上限，0 表示不限
 
 	// Redis
 	RedisHost     string
@@ -32,6 +120,20 @@ type Config struct {
 	RedisDB       int
 }
 
+// FederationProvider 上游身份提供方配置，环境变量前缀 FEDERATION_<NAME>_
+type FederationProvider struct {
+	Name          string
+	DisplayName   string
+	Issuer        string
+	ClientID      string
+	ClientSecret  string
+	Scopes        []string
+	GroupsClaim   string          // 组声明名称，支持 "." 访问嵌套字段
+	DefaultRoleID uint            // 首次登录自动创建用户时绑定的角色，0 表示不绑定
+	GroupRoles    map[string]uint // 上游组 -> 本地角色ID
+	LinkByEmail   bool            // 按已验证邮箱关联已有本地用户
+}
+
 var cfg *Config
 
 func LoadEnv() error {
@@ -40,11 +142,15 @@ func LoadEnv() error {
 		// .env文件不存在也不是错误，可能使用系统环境变量
 	}
 
+	deprecated := map[string]string{}
 	cfg = &Config{
+		Deprecated: deprecated,
 		AppEnv:     getEnv("APP_ENV", "development"),
 		ListenAddr: getEnv("LISTEN_ADDR", ":8080"),
 		LogLevel:   getEnv("LOG_LEVEL", "info"),
 
+		TrustedProxies: splitList(getEnv("TRUSTED_PROXIES", "")),
+
 		// Database
 		DBHost:     getEnv("DB_HOST", "localhost"),
 		DBPort:     getEnv("DB_PORT", "5432"),
@@ -55,9 +161,89 @@ func LoadEnv() error {
 		DBSSLMode: getEnv("DB_SSL_MODE", "disable"),
 
 		// JWT
-		JWTSecret:      getEnv("JWT_SECRET", "your-super-secret-jwt-key"),
-		JWTExpireHours: getEnvAsInt("JWT_EXPIRE_HOURS", 24),
-		JWTIssuer:      getEnv("JWT_ISSUER", "github.com/sine-io/sinx"),
+		JWTSecret:               getEnv("JWT_SECRET", "your-super-secret-jwt-key"),
+		JWTExpireMinutes:        jwtExpireMinutes(deprecated),
+		JWTIssuer:               getEnv("JWT_ISSUER", "github.com/sine-io/sinx"),
+		JWTSigningKeys:          getEnv("JWT_SIGNING_KEYS", ""),
+		JWTActiveKID:            getEnv("JWT_ACTIVE_KID", ""),
+		ImpersonationTTLMinutes: getEnvAsInt("IMPERSONATION_TTL_MINUTES", 30),
+
+		BootstrapSuperAdmin: getEnv("BOOTSTRAP_SUPER_ADMIN", ""),
+
+		RoleSweepIntervalSeconds: getEnvAsInt("ROLE_SWEEP_INTERVAL_SECONDS", 60),
+
+		// Refresh Token
+		RefreshExpireHours: getEnvAsInt("JWT_REFRESH_EXPIRE_HOURS", 168),
+		RefreshTokenStore:  getEnv("REFRESH_TOKEN_STORE", "postgres"),
+
+		// Password
+		PasswordHasher:         getEnv("PASSWORD_HASHER", "argon2id"),
+		PasswordMinLength:      getEnvAsInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordRequireClasses: splitList(getEnv("PASSWORD_REQUIRE_CLASSES", "")),
+		PasswordDenyCommon:     getEnv("PASSWORD_DENY_COMMON", "true") == "true",
+		PasswordDenyListFile:   getEnv("PASSWORD_DENY_LIST_FILE", ""),
+		PasswordDenyUsername:   getEnv("PASSWORD_DENY_USERNAME", "true") == "true",
+		PasswordHistory:        getEnvAsInt("PASSWORD_HISTORY", 0),
+		PasswordMaxAgeDays:     getEnvAsInt("PASSWORD_MAX_AGE_DAYS", 0),
+
+		// Login throttling
+		LoginWindowMinutes:     getEnvAsInt("LOGIN_WINDOW_MINUTES", 15),
+		LoginMaxUserFailures:   getEnvAsInt("LOGIN_MAX_USER_FAILURES", 5),
+		LoginMaxIPFailures:     getEnvAsInt("LOGIN_MAX_IP_FAILURES", 50),
+		LoginLockoutMinutes:    getEnvAsInt("LOGIN_LOCKOUT_MINUTES", 15),
+		LoginBackoffMaxSeconds: getEnvAsInt("LOGIN_BACKOFF_MAX_SECONDS", 30),
+
+		// 登录日志
+		GeoIPDBPath: getEnv("GEOIP_DB_PATH", ""),
+
+		// MFA
+		MFAIssuer: getEnv("MFA_ISSUER", "Sinx"),
+
+		// OIDC Provider
+		OIDCIssuer:   getEnv("OIDC_ISSUER", "http://localhost:8080"),
+		OIDCLoginURL: getEnv("OIDC_LOGIN_URL", "/login"),
+
+		// Federation
+		FederationProviders:   loadFederationProviders(),
+		FederationRedirectURL: getEnv("FEDERATION_REDIRECT_URL", "/login/callback"),
+
+		// LDAP
+		LDAPURL:                 getEnv("LDAP_URL", ""),
+		LDAPStartTLS:            getEnv("LDAP_START_TLS", "false") == "true",
+		LDAPInsecureSkipVerify:  getEnv("LDAP_INSECURE_SKIP_VERIFY", "false") == "true",
+		LDAPBindDN:              getEnv("LDAP_BIND_DN", ""),
+		LDAPBindPassword:        getEnv("LDAP_BIND_PASSWORD", ""),
+		LDAPBaseDN:              getEnv("LDAP_BASE_DN", ""),
+		LDAPUserFilter:          getEnv("LDAP_USER_FILTER", "(objectClass=person)"),
+		LDAPUsernameAttr:        getEnv("LDAP_USERNAME_ATTR", "uid"),
+		LDAPEmailAttr:           getEnv("LDAP_EMAIL_ATTR", "mail"),
+		LDAPNameAttr:            getEnv("LDAP_NAME_ATTR", "cn"),
+		LDAPGroupAttr:           getEnv("LDAP_GROUP_ATTR", "memberOf"),
+		LDAPGroupRoles:          parseRoleMap(os.Getenv("LDAP_GROUP_ROLES")),
+		LDAPDefaultRoleID:       uint(getEnvAsInt("LDAP_DEFAULT_ROLE_ID", 0)),
+		LDAPDefaultAuth:         getEnv("LDAP_DEFAULT_AUTH", "local"),
+		LDAPSyncIntervalMinutes: getEnvAsInt("LDAP_SYNC_INTERVAL_MINUTES", 0),
+
+		// Mail
+		MailDriver:      getEnv("MAIL_DRIVER", "log"),
+		MailFrom:        getEnv("MAIL_FROM", "Sinx <no-reply@localhost>"),
+		MailOutboxDir:   getEnv("MAIL_OUTBOX_DIR", "tmp/mail"),
+		MailTemplateDir: getEnv("MAIL_TEMPLATE_DIR", ""),
+		SMTPHost:        getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:        getEnvAsInt("SMTP_PORT", 587),
+		SMTPUsername:    getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:    getEnv("SMTP_PASSWORD", ""),
+		SMTPTLS:         getEnv("SMTP_TLS", "starttls"),
+
+		// 找回密码 / 邮箱验证
+		PasswordResetURL:         getEnv("PASSWORD_RESET_URL", "http://localhost:8091/reset-password"),
+		PasswordResetTTLMinutes:  getEnvAsInt("PASSWORD_RESET_TTL_MINUTES", 30),
+		EmailVerifyURL:           getEnv("EMAIL_VERIFY_URL", "http://localhost:8091/verify-email"),
+		EmailVerifyTTLHours:      getEnvAsInt("EMAIL_VERIFY_TTL_HOURS", 48),
+		RequireEmailVerification: getEnv("REQUIRE_EMAIL_VERIFICATION", "false") == "true",
+		MailRateWindowMinutes:    getEnvAsInt("MAIL_RATE_WINDOW_MINUTES", 15),
+		MailRateMaxPerEmail:      getEnvAsInt("MAIL_RATE_MAX_PER_EMAIL", 3),
+		MailRateMaxPerIP:         getEnvAsInt("MAIL_RATE_MAX_PER_IP", 20),
 
 		// Redis
 		RedisHost:     getEnv("REDIS_HOST", "localhost"),
@@ -88,3 +274,70 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// jwtExpireMinutes 访问令牌有效期；未设置 JWT_EXPIRE_MINUTES 时兼容旧的 JWT_EXPIRE_HOURS
+func jwtExpireMinutes(deprecated map[string]string) int {
+	if os.Getenv("JWT_EXPIRE_HOURS") == "" {
+		return getEnvAsInt("JWT_EXPIRE_MINUTES", 15)
+	}
+	deprecated["JWT_EXPIRE_HOURS"] = "JWT_EXPIRE_MINUTES"
+	if os.Getenv("JWT_EXPIRE_MINUTES") != "" {
+		return getEnvAsInt("JWT_EXPIRE_MINUTES", 15)
+	}
+	if hours := getEnvAsInt("JWT_EXPIRE_HOURS", 0); hours > 0 {
+		return hours * 60
+	}
+	return 15
+}
+
+// loadFederationProviders 读取 FEDERATION_PROVIDERS=keycloak,azure 及各自的 FEDERATION_<NAME>_* 配置
+func loadFederationProviders() []FederationProvider {
+	var list []FederationProvider
+	for _, name := range strings.Split(os.Getenv("FEDERATION_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "FEDERATION_" + strings.ToUpper(name) + "_"
+		p := FederationProvider{
+			Name:          name,
+			DisplayName:   getEnv(prefix+"DISPLAY_NAME", name),
+			Issuer:        os.Getenv(prefix + "ISSUER"),
+			ClientID:      os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret:  os.Getenv(prefix + "CLIENT_SECRET"),
+			Scopes:        strings.Fields(getEnv(prefix+"SCOPES", "openid profile email")),
+			GroupsClaim:   getEnv(prefix+"GROUPS_CLAIM", "groups"),
+			DefaultRoleID: uint(getEnvAsInt(prefix+"DEFAULT_ROLE_ID", 0)),
+			GroupRoles:    parseRoleMap(os.Getenv(prefix + "GROUP_ROLES")),
+			LinkByEmail:   getEnv(prefix+"LINK_BY_EMAIL", "false") == "true",
+		}
+		list = append(list, p)
+	}
+	return list
+}
+
+// parseRoleMap 解析 group=roleId,group=roleId 形式的组到角色映射
+func parseRoleMap(value string) map[string]uint {
+	m := map[string]uint{}
+	for _, item := range strings.Split(value, ",") {
+		group, roleID, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			continue
+		}
+		if id, err := strconv.Atoi(roleID); err == nil && id > 0 {
+			m[group] = uint(id)
+		}
+	}
+	return m
+}
+
+// splitList 解析逗号分隔的列表，忽略空项
+func splitList(value string) []string {
+	var res []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			res = append(res, item)
+		}
+	}
+	return res
+}
@@ -0,0 +1,30 @@
+package config
+
+import "testing"
+
+// 旧的 JWT_EXPIRE_HOURS 仍生效（按小时换算），同时记入已废弃变量；新变量优先
+func TestJWTExpireFallback(t *testing.T) {
+	cases := []struct {
+		hours, minutes string
+		want           int
+		deprecated     bool
+	}{
+		{"", "", 15, false},
+		{"", "30", 30, false},
+		{"2", "", 120, true},
+		{"2", "30", 30, true},
+	}
+	for _, c := range cases {
+		t.Setenv("JWT_EXPIRE_HOURS", c.hours)
+		t.Setenv("JWT_EXPIRE_MINUTES", c.minutes)
+		if err := LoadEnv(); err != nil {
+			t.Fatal(err)
+		}
+		if got := Get().JWTExpireMinutes; got != c.want {
+			t.Fatalf("hours=%q minutes=%q: got %d, want %d", c.hours, c.minutes, got, c.want)
+		}
+		if _, ok := Get().Deprecated["JWT_EXPIRE_HOURS"]; ok != c.deprecated {
+			t.Fatalf("hours=%q minutes=%q: deprecated = %v", c.hours, c.minutes, ok)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package datascope
+
+import "context"
+
+// Scope 调用者可访问的数据范围：All 为 true 时不限制，否则仅可访问
+// 归属 DeptIDs 中部门的数据，以及 UserID 非 0 时本人的数据
+type Scope struct {
+	All     bool
+	DeptIDs []uint
+	UserID  uint
+}
+
+// Allows 归属 deptID、属于 userID 的数据是否在范围内
+func (s *Scope) Allows(deptID, userID uint) bool {
+	if s == nil || s.All {
+		return true
+	}
+	if s.UserID != 0 && s.UserID == userID {
+		return true
+	}
+	for _, id := range s.DeptIDs {
+		if id == deptID && deptID != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+type ctxKey struct{}
+
+// WithScope 将数据范围附加到 ctx，仓储查询据此自动过滤
+func WithScope(ctx context.Context, s *Scope) context.Context {
+	return context.WithValue(ctx, ctxKey{}, s)
+}
+
+// FromContext 未设置时返回 nil（系统内部调用），不做限制
+func FromContext(ctx context.Context) *Scope {
+	s, _ := ctx.Value(ctxKey{}).(*Scope)
+	return s
+}
@@ -16,6 +16,8 @@ const (
 	ErrForbidden      ErrorCode = 10004
 	ErrNotFound       ErrorCode = 10005
 	ErrHasChildren    ErrorCode = 10006
+	ErrTooManyRequest ErrorCode = 10007
+	ErrRoleConflict   ErrorCode = 10008
 
 	// 用户相关错误码 20000-29999
 	ErrUserNotFound        ErrorCode = 20001
@@ -23,8 +25,28 @@ const (
 	ErrUserInvalidPassword ErrorCode = 20003
 	ErrUserInvalidToken    ErrorCode = 20004
 	ErrUserTokenExpired    ErrorCode = 20005
+	ErrRefreshTokenInvalid ErrorCode = 20006
+	ErrRefreshTokenReused  ErrorCode = 20007
+	ErrUserTokenRevoked    ErrorCode = 20008
+	ErrUserLocked          ErrorCode = 20009
+	ErrMFAInvalidCode      ErrorCode = 20010
+	ErrMFAAlreadyEnabled   ErrorCode = 20011
+	ErrMFANotEnrolled      ErrorCode = 20012
+	ErrFederationFailed    ErrorCode = 20013
+	ErrIdentityLinked      ErrorCode = 20014
+	ErrEmailNotVerified    ErrorCode = 20015
+	ErrPasswordPolicy      ErrorCode = 20016
+	ErrPasswordExpired     ErrorCode = 20017
+	ErrLastSuperAdmin      ErrorCode = 20018
 )
 
+// FieldError 字段级校验错误，放在 Error.Data 中返回
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
 type Error struct {
 	Code    ErrorCode   `json:"code"`
 	Message string      `json:"message"`
@@ -50,16 +72,18 @@ func (e *Error) HTTPStatus() int {
 	switch e.Code {
 	case ErrSuccess:
 		return http.StatusOK
-	case ErrInvalidParam:
+	case ErrInvalidParam, ErrMFANotEnrolled, ErrPasswordPolicy:
 		return http.StatusBadRequest
-	case ErrUnauthorized, ErrUserInvalidToken, ErrUserTokenExpired, ErrUserInvalidPassword:
+	case ErrUnauthorized, ErrUserInvalidToken, ErrUserTokenExpired, ErrUserInvalidPassword, ErrRefreshTokenInvalid, ErrRefreshTokenReused, ErrUserTokenRevoked, ErrMFAInvalidCode, ErrFederationFailed:
 		return http.StatusUnauthorized
-	case ErrForbidden:
+	case ErrForbidden, ErrEmailNotVerified, ErrPasswordExpired:
 		return http.StatusForbidden
 	case ErrNotFound, ErrUserNotFound:
 		return http.StatusNotFound
-	case ErrUserAlreadyExists:
+	case ErrUserAlreadyExists, ErrMFAAlreadyEnabled, ErrIdentityLinked, ErrLastSuperAdmin, ErrRoleConflict:
 		return http.StatusConflict
+	case ErrTooManyRequest, ErrUserLocked:
+		return http.StatusTooManyRequests
 	default:
 		return http.StatusInternalServerError
 	}
@@ -74,11 +98,26 @@ var errorMessages = map[ErrorCode]string{
 	ErrForbidden:           "forbidden",
 	ErrNotFound:            "not found",
 	ErrHasChildren:         "resource has children",
+	ErrTooManyRequest:      "too many requests",
+	ErrRoleConflict:        "role assignment violates separation of duties",
 	ErrUserNotFound:        "user not found",
 	ErrUserAlreadyExists:   "user already exists",
 	ErrUserInvalidPassword: "invalid password",
 	ErrUserInvalidToken:    "invalid token",
 	ErrUserTokenExpired:    "token expired",
+	ErrRefreshTokenInvalid: "invalid refresh token",
+	ErrRefreshTokenReused:  "refresh token reused, session revoked",
+	ErrUserTokenRevoked:    "token revoked",
+	ErrUserLocked:          "account temporarily locked",
+	ErrMFAInvalidCode:      "invalid verification code",
+	ErrMFAAlreadyEnabled:   "two-factor authentication already enabled",
+	ErrMFANotEnrolled:      "two-factor authentication not enrolled",
+	ErrFederationFailed:    "federated login failed",
+	ErrIdentityLinked:      "identity already linked to another account",
+	ErrEmailNotVerified:    "email address not verified",
+	ErrPasswordPolicy:      "password does not meet the policy",
+	ErrPasswordExpired:     "password expired, please change it",
+	ErrLastSuperAdmin:      "cannot remove the last super admin",
 }
 
 func GetErrorMessage(code ErrorCode) string {
@@ -0,0 +1,104 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// LoginState 跳转上游前保存的登录上下文，回调时按 state 一次性取回
+type LoginState struct {
+	Provider   string `json:"provider"`
+	Nonce      string `json:"nonce"`
+	Verifier   string `json:"verifier"`
+	Redirect   string `json:"redirect,omitempty"`
+	LinkUserID uint   `json:"linkUserId,omitempty"` // 非 0 表示已登录用户绑定外部身份
+}
+
+// StateStore 登录上下文存储
+type StateStore interface {
+	Save(ctx context.Context, state string, s *LoginState, ttl time.Duration) error
+	// Take 取出并删除；不存在或已过期返回 (nil, nil)
+	Take(ctx context.Context, state string) (*LoginState, error)
+}
+
+type memoryState struct {
+	s   *LoginState
+	exp time.Time
+}
+
+// MemoryStateStore 进程内存储（Redis 不可用时的回退实现）
+type MemoryStateStore struct {
+	mu    sync.Mutex
+	items map[string]memoryState
+}
+
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{items: make(map[string]memoryState)}
+}
+
+func (m *MemoryStateStore) Save(_ context.Context, state string, s *LoginState, ttl time.Duration) error {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for k, v := range m.items {
+		if now.After(v.exp) {
+			delete(m.items, k)
+		}
+	}
+	m.items[state] = memoryState{s: s, exp: now.Add(ttl)}
+	return nil
+}
+
+func (m *MemoryStateStore) Take(_ context.Context, state string) (*LoginState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[state]
+	if !ok {
+		return nil, nil
+	}
+	delete(m.items, state)
+	if time.Now().After(item.exp) {
+		return nil, nil
+	}
+	return item.s, nil
+}
+
+// RedisStateStore 基于 Redis 的存储（多实例共享）
+//
+//	federation_state:<state>  string JSON
+type RedisStateStore struct {
+	cli    *redis.Client
+	prefix string
+}
+
+func NewRedisStateStore(cli *redis.Client) *RedisStateStore {
+	return &RedisStateStore{cli: cli, prefix: "federation_state:"}
+}
+
+func (r *RedisStateStore) Save(ctx context.Context, state string, s *LoginState, ttl time.Duration) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return r.cli.Set(ctx, r.prefix+state, data, ttl).Err()
+}
+
+func (r *RedisStateStore) Take(ctx context.Context, state string) (*LoginState, error) {
+	data, err := r.cli.GetDel(ctx, r.prefix+state).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s LoginState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
@@ -0,0 +1,77 @@
+// Package geoip 基于离线 MaxMind mmdb 数据库（GeoLite2-City / GeoIP2-City 等）解析 IP 归属地
+package geoip
+
+import (
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Location IP 归属地，无法解析的字段为空
+type Location struct {
+	Nation   string
+	Province string
+	City     string
+}
+
+// record mmdb City 数据库中用到的字段
+type record struct {
+	Country struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+// DB 只读 mmdb 数据库，可并发使用
+type DB struct {
+	reader *maxminddb.Reader
+	langs  []string
+}
+
+// Open 打开 mmdb 文件；langs 为名称语言的优先级，如 zh-CN、en
+func Open(path string, langs ...string) (*DB, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(langs) == 0 {
+		langs = []string{"zh-CN", "en"}
+	}
+	return &DB{reader: reader, langs: langs}, nil
+}
+
+// Lookup 解析 IP 归属地；内网 / 回环地址及查询失败时返回零值
+func (d *DB) Lookup(ip string) Location {
+	parsed := net.ParseIP(ip)
+	if d == nil || parsed == nil || parsed.IsLoopback() || parsed.IsPrivate() || parsed.IsUnspecified() {
+		return Location{}
+	}
+	var r record
+	if err := d.reader.Lookup(parsed, &r); err != nil {
+		return Location{}
+	}
+	loc := Location{Nation: d.name(r.Country.Names), City: d.name(r.City.Names)}
+	if len(r.Subdivisions) > 0 {
+		loc.Province = d.name(r.Subdivisions[0].Names)
+	}
+	return loc
+}
+
+// Close 释放数据库文件
+func (d *DB) Close() error {
+	return d.reader.Close()
+}
+
+func (d *DB) name(names map[string]string) string {
+	for _, lang := range d.langs {
+		if n := names[lang]; n != "" {
+			return n
+		}
+	}
+	return ""
+}
@@ -0,0 +1,183 @@
+package ldapdir
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// adAccountDisabled Active Directory userAccountControl 中的 ACCOUNTDISABLE 标志位
+const adAccountDisabled = 0x2
+
+// Config LDAP / Active Directory 连接与属性映射
+type Config struct {
+	URL                string // ldap://host:389 或 ldaps://host:636
+	StartTLS           bool
+	InsecureSkipVerify bool
+	BindDN             string // 服务账号，用于查找用户与同步
+	BindPassword       string
+	BaseDN             string
+	UserFilter         string // 用户对象过滤条件，如 (objectClass=person)
+	UsernameAttr       string // 登录名属性：OpenLDAP 常用 uid，AD 常用 sAMAccountName
+	EmailAttr          string
+	NameAttr           string
+	GroupAttr          string // 用户所属组属性（memberOf）
+	Timeout            time.Duration
+}
+
+// Entry 目录中的用户
+type Entry struct {
+	DN       string
+	Username string
+	Email    string
+	Name     string
+	Groups   []string // 组 DN 的首个 RDN 值（通常为 cn）
+	Disabled bool     // AD 中已禁用的账号
+}
+
+// Client 每次操作新建连接，避免长连接被服务端超时断开；属性名按大小写不敏感读取
+type Client struct {
+	cfg Config
+}
+
+func New(cfg Config) *Client {
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = "(objectClass=person)"
+	}
+	if cfg.UsernameAttr == "" {
+		cfg.UsernameAttr = "uid"
+	}
+	if cfg.EmailAttr == "" {
+		cfg.EmailAttr = "mail"
+	}
+	if cfg.NameAttr == "" {
+		cfg.NameAttr = "cn"
+	}
+	if cfg.GroupAttr == "" {
+		cfg.GroupAttr = "memberOf"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	return &Client{cfg: cfg}
+}
+
+// Authenticate 以服务账号查找用户 DN，再使用用户密码 bind；凭据错误返回 (false, nil)
+func (c *Client) Authenticate(_ context.Context, username, password string) (bool, error) {
+	// 空密码会被多数服务器当作匿名 bind 而"成功"
+	if username == "" || password == "" {
+		return false, nil
+	}
+	conn, err := c.connect()
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	res, err := conn.Search(ldap.NewSearchRequest(
+		c.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(c.cfg.Timeout.Seconds()), false,
+		c.userFilter(username), []string{"dn"}, nil,
+	))
+	if err != nil {
+		return false, fmt.Errorf("ldap search user: %w", err)
+	}
+	if len(res.Entries) != 1 {
+		return false, nil
+	}
+
+	if err := conn.Bind(res.Entries[0].DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return false, nil
+		}
+		return false, fmt.Errorf("ldap bind user: %w", err)
+	}
+	return true, nil
+}
+
+// ListUsers 分页读取全部用户
+func (c *Client) ListUsers(_ context.Context) ([]*Entry, error) {
+	conn, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	attrs := []string{c.cfg.UsernameAttr, c.cfg.EmailAttr, c.cfg.NameAttr, c.cfg.GroupAttr, "userAccountControl"}
+	res, err := conn.SearchWithPaging(ldap.NewSearchRequest(
+		c.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		c.cfg.UserFilter, attrs, nil,
+	), 500)
+	if err != nil {
+		return nil, fmt.Errorf("ldap list users: %w", err)
+	}
+
+	list := make([]*Entry, 0, len(res.Entries))
+	for _, e := range res.Entries {
+		username := e.GetEqualFoldAttributeValue(c.cfg.UsernameAttr)
+		if username == "" {
+			continue
+		}
+		entry := &Entry{
+			DN:       e.DN,
+			Username: username,
+			Email:    e.GetEqualFoldAttributeValue(c.cfg.EmailAttr),
+			Name:     e.GetEqualFoldAttributeValue(c.cfg.NameAttr),
+		}
+		for _, g := range e.GetEqualFoldAttributeValues(c.cfg.GroupAttr) {
+			entry.Groups = append(entry.Groups, groupName(g))
+		}
+		if uac, err := strconv.Atoi(e.GetEqualFoldAttributeValue("userAccountControl")); err == nil {
+			entry.Disabled = uac&adAccountDisabled != 0
+		}
+		list = append(list, entry)
+	}
+	return list, nil
+}
+
+func (c *Client) connect() (*ldap.Conn, error) {
+	u, err := url.Parse(c.cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ldap url: %w", err)
+	}
+	tlsCfg := &tls.Config{ServerName: u.Hostname(), InsecureSkipVerify: c.cfg.InsecureSkipVerify}
+	conn, err := ldap.DialURL(c.cfg.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: c.cfg.Timeout}),
+		ldap.DialWithTLSConfig(tlsCfg),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ldap dial: %w", err)
+	}
+	conn.SetTimeout(c.cfg.Timeout)
+	if c.cfg.StartTLS {
+		if err := conn.StartTLS(tlsCfg); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap starttls: %w", err)
+		}
+	}
+	if c.cfg.BindDN != "" {
+		if err := conn.Bind(c.cfg.BindDN, c.cfg.BindPassword); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap service bind: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+func (c *Client) userFilter(username string) string {
+	return fmt.Sprintf("(&%s(%s=%s))", c.cfg.UserFilter, c.cfg.UsernameAttr, ldap.EscapeFilter(username))
+}
+
+// groupName 取组 DN 的首个 RDN 值，无法解析时原样返回
+func groupName(dn string) string {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) == 0 || len(parsed.RDNs[0].Attributes) == 0 {
+		return dn
+	}
+	return parsed.RDNs[0].Attributes[0].Value
+}
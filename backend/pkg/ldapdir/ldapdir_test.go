@@ -0,0 +1,80 @@
+package ldapdir
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sine-io/sinx/pkg/ldapdir/ldaptest"
+)
+
+func newTestDirectory(t *testing.T) (*ldaptest.Server, *Client) {
+	srv, err := ldaptest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	srv.Add("cn=svc,dc=corp,dc=test", map[string][]string{"userPassword": {"svc-pass"}})
+	srv.Add("uid=alice,ou=people,dc=corp,dc=test", map[string][]string{
+		"objectClass": {"person"}, "uid": {"alice"}, "mail": {"alice@corp.test"}, "cn": {"Alice"},
+		"memberOf":     {"cn=admins,ou=groups,dc=corp,dc=test", "cn=dev,ou=groups,dc=corp,dc=test"},
+		"userPassword": {"alice-pass"},
+	})
+	srv.Add("uid=bob,ou=people,dc=corp,dc=test", map[string][]string{
+		"objectClass": {"person"}, "uid": {"bob"}, "userAccountControl": {"514"}, "userPassword": {"bob-pass"},
+	})
+	return srv, New(Config{URL: srv.URL, BindDN: "cn=svc,dc=corp,dc=test", BindPassword: "svc-pass", BaseDN: "ou=people,dc=corp,dc=test"})
+}
+
+func TestAuthenticate(t *testing.T) {
+	_, c := newTestDirectory(t)
+	ctx := context.Background()
+
+	cases := []struct {
+		username, password string
+		want               bool
+	}{
+		{"alice", "alice-pass", true},
+		{"alice", "wrong", false},
+		{"alice", "", false}, // 空密码不得退化为匿名 bind
+		{"nobody", "alice-pass", false},
+		{"*", "alice-pass", false}, // 过滤条件注入
+	}
+	for _, tc := range cases {
+		ok, err := c.Authenticate(ctx, tc.username, tc.password)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.username, err)
+		}
+		if ok != tc.want {
+			t.Fatalf("Authenticate(%q, %q) = %v, want %v", tc.username, tc.password, ok, tc.want)
+		}
+	}
+
+	// 服务账号密码错误属于配置问题，应返回错误而不是凭据错误
+	bad := New(Config{URL: c.cfg.URL, BindDN: c.cfg.BindDN, BindPassword: "x", BaseDN: c.cfg.BaseDN})
+	if _, err := bad.Authenticate(ctx, "alice", "alice-pass"); err == nil {
+		t.Fatal("expected service bind error")
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	_, c := newTestDirectory(t)
+
+	list, err := c.ListUsers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := map[string]*Entry{}
+	for _, e := range list {
+		users[e.Username] = e
+	}
+	if len(users) != 2 {
+		t.Fatalf("expected 2 users, got %d", len(users))
+	}
+	alice := users["alice"]
+	if alice.Email != "alice@corp.test" || alice.Name != "Alice" || len(alice.Groups) != 2 || alice.Groups[0] != "admins" {
+		t.Fatalf("unexpected alice entry: %+v", alice)
+	}
+	if alice.Disabled || !users["bob"].Disabled {
+		t.Fatal("expected only bob to be disabled")
+	}
+}
@@ -0,0 +1,215 @@
+// Package ldaptest 进程内最小 LDAP 服务器，仅用于测试
+// 支持 simple bind、search（and / or / not / equality / present 过滤）与 unbind
+package ldaptest
+
+import (
+	"net"
+	"strings"
+	"sync"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+)
+
+const (
+	opBindRequest   = 0
+	opBindResponse  = 1
+	opUnbindRequest = 2
+	opSearchRequest = 3
+	opSearchEntry   = 4
+	opSearchDone    = 5
+
+	resultSuccess            = 0
+	resultInsufficientAccess = 50
+	resultInvalidCredentials = 49
+
+	filterAnd      = 0
+	filterOr       = 1
+	filterNot      = 2
+	filterEquality = 3
+	filterPresent  = 7
+)
+
+// Server 内存目录；属性名大小写不敏感，userPassword 属性作为 bind 密码
+type Server struct {
+	URL string
+
+	ln      net.Listener
+	mu      sync.RWMutex
+	entries map[string]map[string][]string // dn -> attr(lower) -> values
+}
+
+// NewServer 在 127.0.0.1 随机端口启动
+func NewServer() (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{URL: "ldap://" + ln.Addr().String(), ln: ln, entries: map[string]map[string][]string{}}
+	go s.serve()
+	return s, nil
+}
+
+func (s *Server) Close() error { return s.ln.Close() }
+
+// Add 添加或覆盖条目
+func (s *Server) Add(dn string, attrs map[string][]string) {
+	lower := make(map[string][]string, len(attrs))
+	for k, v := range attrs {
+		lower[strings.ToLower(k)] = v
+	}
+	s.mu.Lock()
+	s.entries[strings.ToLower(dn)] = lower
+	s.mu.Unlock()
+}
+
+// Delete 删除条目
+func (s *Server) Delete(dn string) {
+	s.mu.Lock()
+	delete(s.entries, strings.ToLower(dn))
+	s.mu.Unlock()
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	bound := false
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		msgID, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+		switch op.Tag {
+		case opBindRequest:
+			dn := op.Children[1].Data.String()
+			password := op.Children[2].Data.String()
+			code := int64(resultInvalidCredentials)
+			if s.checkPassword(dn, password) {
+				code, bound = resultSuccess, true
+			}
+			conn.Write(result(msgID, opBindResponse, code).Bytes())
+		case opSearchRequest:
+			if !bound {
+				conn.Write(result(msgID, opSearchDone, resultInsufficientAccess).Bytes())
+				continue
+			}
+			base := strings.ToLower(op.Children[0].Data.String())
+			for dn, attrs := range s.search(base, op.Children[6]) {
+				conn.Write(entry(msgID, dn, attrs).Bytes())
+			}
+			conn.Write(result(msgID, opSearchDone, resultSuccess).Bytes())
+		case opUnbindRequest:
+			return
+		}
+	}
+}
+
+func (s *Server) checkPassword(dn, password string) bool {
+	if password == "" {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	attrs, ok := s.entries[strings.ToLower(dn)]
+	if !ok {
+		return false
+	}
+	for _, p := range attrs["userpassword"] {
+		if p == password {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) search(base string, filter *ber.Packet) map[string]map[string][]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	res := map[string]map[string][]string{}
+	for dn, attrs := range s.entries {
+		if (dn == base || strings.HasSuffix(dn, ","+base)) && match(filter, attrs) {
+			res[dn] = attrs
+		}
+	}
+	return res
+}
+
+func match(f *ber.Packet, attrs map[string][]string) bool {
+	switch f.Tag {
+	case filterAnd:
+		for _, c := range f.Children {
+			if !match(c, attrs) {
+				return false
+			}
+		}
+		return true
+	case filterOr:
+		for _, c := range f.Children {
+			if match(c, attrs) {
+				return true
+			}
+		}
+		return false
+	case filterNot:
+		return len(f.Children) == 1 && !match(f.Children[0], attrs)
+	case filterEquality:
+		name := strings.ToLower(f.Children[0].Data.String())
+		want := f.Children[1].Data.String()
+		for _, v := range attrs[name] {
+			if strings.EqualFold(v, want) {
+				return true
+			}
+		}
+		return false
+	case filterPresent:
+		_, ok := attrs[strings.ToLower(f.Data.String())]
+		return ok
+	}
+	return false
+}
+
+func envelope(msgID int64, op *ber.Packet) *ber.Packet {
+	p := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, msgID, "MessageID"))
+	p.AppendChild(op)
+	return p
+}
+
+func result(msgID int64, tag ber.Tag, code int64) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "resultCode"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "diagnosticMessage"))
+	return envelope(msgID, op)
+}
+
+func entry(msgID int64, dn string, attrs map[string][]string) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, opSearchEntry, nil, "Search Result Entry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, dn, "objectName"))
+	list := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attributes")
+	for name, values := range attrs {
+		if name == "userpassword" {
+			continue
+		}
+		attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "vals")
+		for _, v := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "value"))
+		}
+		attr.AppendChild(set)
+		list.AppendChild(attr)
+	}
+	op.AppendChild(list)
+	return envelope(msgID, op)
+}
@@ -0,0 +1,156 @@
+package loginguard
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+const (
+	KindUser = "user"
+	KindIP   = "ip"
+)
+
+// Policy 登录节流策略
+type Policy struct {
+	Window          time.Duration // 失败计数滑动窗口
+	MaxUserFailures int           // 窗口内单账号失败次数达到后锁定账号
+	MaxIPFailures   int           // 窗口内单 IP 失败次数达到后锁定 IP
+	LockoutDuration time.Duration // 锁定时长
+	BaseDelay       time.Duration // 账号失败后的退避基数，每次失败翻倍
+	MaxDelay        time.Duration // 退避上限
+}
+
+// Decision 节流判定结果
+type Decision struct {
+	Allowed    bool
+	Locked     bool // true 表示被锁定，false 且 !Allowed 表示处于退避期
+	RetryAfter time.Duration
+}
+
+// Lockout 当前生效的锁定记录
+type Lockout struct {
+	Kind     string    `json:"kind"`
+	Key      string    `json:"key"`
+	Failures int       `json:"failures"`
+	Until    time.Time `json:"until"`
+}
+
+// Store 计数与锁定的底层存储（内存 / Redis）
+type Store interface {
+	// AddFailure 记录一次失败并返回窗口内失败次数
+	AddFailure(ctx context.Context, key string, now time.Time, window time.Duration) (int, error)
+	// Failures 返回窗口内失败次数与最近一次失败时间
+	Failures(ctx context.Context, key string, now time.Time, window time.Duration) (int, time.Time, error)
+	Reset(ctx context.Context, key string) error
+	Lock(ctx context.Context, key string, failures int, until time.Time) error
+	LockedUntil(ctx context.Context, key string) (time.Time, error)
+	Unlock(ctx context.Context, key string) error
+	Lockouts(ctx context.Context, now time.Time) ([]Lockout, error)
+}
+
+// Guard 按账号与客户端 IP 进行登录节流
+type Guard struct {
+	policy Policy
+	store  Store
+}
+
+func NewGuard(policy Policy, store Store) *Guard {
+	return &Guard{policy: policy, store: store}
+}
+
+func subjectKey(kind, value string) string { return kind + ":" + value }
+
+// Check 登录前检查账号 / IP 是否被锁定或处于退避期
+func (g *Guard) Check(ctx context.Context, username, ip string) (Decision, error) {
+	now := time.Now()
+	for _, key := range g.keys(username, ip) {
+		until, err := g.store.LockedUntil(ctx, key)
+		if err != nil {
+			return Decision{Allowed: true}, err
+		}
+		if until.After(now) {
+			return Decision{Locked: true, RetryAfter: until.Sub(now)}, nil
+		}
+	}
+	if username == "" {
+		return Decision{Allowed: true}, nil
+	}
+	n, last, err := g.store.Failures(ctx, subjectKey(KindUser, normalize(username)), now, g.policy.Window)
+	if err != nil || n == 0 {
+		return Decision{Allowed: true}, err
+	}
+	if next := last.Add(g.backoff(n)); next.After(now) {
+		return Decision{RetryAfter: next.Sub(now)}, nil
+	}
+	return Decision{Allowed: true}, nil
+}
+
+// RecordFailure 记录失败；达到阈值时锁定账号或 IP
+func (g *Guard) RecordFailure(ctx context.Context, username, ip string) (Decision, error) {
+	now := time.Now()
+	decision := Decision{Allowed: true}
+	limits := map[string]int{KindUser: g.policy.MaxUserFailures, KindIP: g.policy.MaxIPFailures}
+	for _, key := range g.keys(username, ip) {
+		n, err := g.store.AddFailure(ctx, key, now, g.policy.Window)
+		if err != nil {
+			return decision, err
+		}
+		limit := limits[strings.SplitN(key, ":", 2)[0]]
+		if limit > 0 && n >= limit {
+			until := now.Add(g.policy.LockoutDuration)
+			if err := g.store.Lock(ctx, key, n, until); err != nil {
+				return decision, err
+			}
+			decision = Decision{Locked: true, RetryAfter: g.policy.LockoutDuration}
+		}
+	}
+	return decision, nil
+}
+
+// RecordSuccess 登录成功后清空账号失败计数（IP 计数保留，防止撞库）
+func (g *Guard) RecordSuccess(ctx context.Context, username string) error {
+	return g.store.Reset(ctx, subjectKey(KindUser, normalize(username)))
+}
+
+// Lockouts 列出当前生效的锁定
+func (g *Guard) Lockouts(ctx context.Context) ([]Lockout, error) {
+	return g.store.Lockouts(ctx, time.Now())
+}
+
+// Unlock 解除锁定并清空失败计数
+func (g *Guard) Unlock(ctx context.Context, kind, value string) error {
+	if kind == KindUser {
+		value = normalize(value)
+	}
+	key := subjectKey(kind, value)
+	if err := g.store.Unlock(ctx, key); err != nil {
+		return err
+	}
+	return g.store.Reset(ctx, key)
+}
+
+func (g *Guard) keys(username, ip string) []string {
+	keys := make([]string, 0, 2)
+	if username != "" {
+		keys = append(keys, subjectKey(KindUser, normalize(username)))
+	}
+	if ip != "" {
+		keys = append(keys, subjectKey(KindIP, ip))
+	}
+	return keys
+}
+
+// backoff 第 n 次失败后的等待时长：BaseDelay * 2^(n-1)，不超过 MaxDelay
+func (g *Guard) backoff(n int) time.Duration {
+	d := g.policy.BaseDelay
+	for i := 1; i < n && d < g.policy.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > g.policy.MaxDelay {
+		d = g.policy.MaxDelay
+	}
+	return d
+}
+
+func normalize(username string) string { return strings.ToLower(strings.TrimSpace(username)) }
@@ -0,0 +1,84 @@
+package loginguard
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGuardBackoffAndLockout(t *testing.T) {
+	ctx := context.Background()
+	g := NewGuard(Policy{
+		Window:          time.Minute,
+		MaxUserFailures: 3,
+		MaxIPFailures:   10,
+		LockoutDuration: time.Minute,
+		BaseDelay:       time.Hour, // 足够大，保证测试期间处于退避期
+		MaxDelay:        time.Hour,
+	}, NewMemoryStore())
+
+	if d, _ := g.Check(ctx, "alice", "10.0.0.1"); !d.Allowed {
+		t.Fatalf("first attempt should be allowed")
+	}
+	if d, _ := g.RecordFailure(ctx, "alice", "10.0.0.1"); d.Locked {
+		t.Fatalf("locked too early")
+	}
+	d, _ := g.Check(ctx, "Alice", "10.0.0.2")
+	if d.Allowed || d.Locked || d.RetryAfter <= 0 {
+		t.Fatalf("expected backoff after failure, got %+v", d)
+	}
+
+	_, _ = g.RecordFailure(ctx, "alice", "10.0.0.1")
+	if d, _ := g.RecordFailure(ctx, "alice", "10.0.0.1"); !d.Locked {
+		t.Fatalf("expected lockout after %d failures", 3)
+	}
+	lockouts, _ := g.Lockouts(ctx)
+	if len(lockouts) != 1 || lockouts[0].Kind != KindUser || lockouts[0].Key != "alice" {
+		t.Fatalf("unexpected lockouts: %+v", lockouts)
+	}
+
+	// 解锁后清空计数，不再退避
+	if err := g.Unlock(ctx, KindUser, "alice"); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+	if d, _ := g.Check(ctx, "alice", "10.0.0.3"); !d.Allowed {
+		t.Fatalf("expected allowed after unlock, got %+v", d)
+	}
+}
+
+func TestLimiterPerEmailAndIP(t *testing.T) {
+	ctx := context.Background()
+	l := NewLimiter(NewMemoryStore(), "mail", time.Minute, 3, 2)
+
+	for i := 0; i < 2; i++ {
+		if d, _ := l.Allow(ctx, "alice@corp.test", "10.0.0.1"); !d.Allowed {
+			t.Fatalf("request %d should be allowed", i+1)
+		}
+	}
+	// 邮箱忽略大小写；换 IP 也不能绕过单邮箱上限
+	d, _ := l.Allow(ctx, " Alice@Corp.test", "10.0.0.2")
+	if d.Allowed || d.Locked || d.RetryAfter <= 0 {
+		t.Fatalf("expected email limit, got %+v", d)
+	}
+	// 被拒绝的请求不计数：10.0.0.1 仍剩一次
+	if d, _ := l.Allow(ctx, "bob@corp.test", "10.0.0.1"); !d.Allowed {
+		t.Fatal("ip should have one request left")
+	}
+	if d, _ := l.Allow(ctx, "carol@corp.test", "10.0.0.1"); d.Allowed {
+		t.Fatal("expected ip limit")
+	}
+
+	// 限流计数与登录失败计数、锁定列表互不影响
+	g := NewGuard(Policy{Window: time.Minute, MaxIPFailures: 1, LockoutDuration: time.Minute}, l.store)
+	if d, _ := g.Check(ctx, "alice@corp.test", "10.0.0.1"); !d.Allowed {
+		t.Fatalf("mail requests must not throttle login, got %+v", d)
+	}
+	if lockouts, _ := g.Lockouts(ctx); len(lockouts) != 0 {
+		t.Fatalf("unexpected lockouts: %+v", lockouts)
+	}
+
+	var none *Limiter
+	if d, _ := none.Allow(ctx, "alice@corp.test", "10.0.0.1"); !d.Allowed {
+		t.Fatal("nil limiter must allow")
+	}
+}
@@ -0,0 +1,117 @@
+package loginguard
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 失败记录条目过多时（如用户名喷洒）触发全量清理
+const memorySweepThreshold = 10000
+
+type memoryLock struct {
+	failures int
+	until    time.Time
+}
+
+// MemoryStore 进程内存储（Redis 不可用时的回退实现）
+type MemoryStore struct {
+	mu       sync.Mutex
+	failures map[string][]time.Time
+	locks    map[string]memoryLock
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{failures: make(map[string][]time.Time), locks: make(map[string]memoryLock)}
+}
+
+func (s *MemoryStore) AddFailure(_ context.Context, key string, now time.Time, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.failures) > memorySweepThreshold {
+		for k := range s.failures {
+			s.pruneLocked(k, now, window)
+		}
+	}
+	list := append(s.pruneLocked(key, now, window), now)
+	s.failures[key] = list
+	return len(list), nil
+}
+
+func (s *MemoryStore) Failures(_ context.Context, key string, now time.Time, window time.Duration) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := s.pruneLocked(key, now, window)
+	if len(list) == 0 {
+		return 0, time.Time{}, nil
+	}
+	return len(list), list[len(list)-1], nil
+}
+
+func (s *MemoryStore) Reset(_ context.Context, key string) error {
+	s.mu.Lock()
+	delete(s.failures, key)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore) Lock(_ context.Context, key string, failures int, until time.Time) error {
+	s.mu.Lock()
+	s.locks[key] = memoryLock{failures: failures, until: until}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore) LockedUntil(_ context.Context, key string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.locks[key]
+	if !ok {
+		return time.Time{}, nil
+	}
+	if time.Now().After(l.until) {
+		delete(s.locks, key)
+		return time.Time{}, nil
+	}
+	return l.until, nil
+}
+
+func (s *MemoryStore) Unlock(_ context.Context, key string) error {
+	s.mu.Lock()
+	delete(s.locks, key)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore) Lockouts(_ context.Context, now time.Time) ([]Lockout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make([]Lockout, 0, len(s.locks))
+	for key, l := range s.locks {
+		if now.After(l.until) {
+			delete(s.locks, key)
+			continue
+		}
+		kind, value, _ := strings.Cut(key, ":")
+		res = append(res, Lockout{Kind: kind, Key: value, Failures: l.failures, Until: l.until})
+	}
+	return res, nil
+}
+
+// pruneLocked 丢弃窗口外的失败记录（调用方需持有锁）
+func (s *MemoryStore) pruneLocked(key string, now time.Time, window time.Duration) []time.Time {
+	list := s.failures[key]
+	start := now.Add(-window)
+	i := 0
+	for i < len(list) && !list[i].After(start) {
+		i++
+	}
+	list = list[i:]
+	if len(list) == 0 {
+		delete(s.failures, key)
+		return nil
+	}
+	s.failures[key] = list
+	return list
+}
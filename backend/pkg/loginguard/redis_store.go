@@ -0,0 +1,100 @@
+package loginguard
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore 基于 Redis 的存储：失败记录使用有序集合（score 为毫秒时间戳）实现滑动窗口
+//
+//	login_fail:<kind>:<value>  zset
+//	login_lock:<kind>:<value>  string "<failures>:<untilUnixMilli>"
+type RedisStore struct {
+	cli        *redis.Client
+	failPrefix string
+	lockPrefix string
+}
+
+func NewRedisStore(cli *redis.Client) *RedisStore {
+	return &RedisStore{cli: cli, failPrefix: "login_fail:", lockPrefix: "login_lock:"}
+}
+
+func (s *RedisStore) AddFailure(ctx context.Context, key string, now time.Time, window time.Duration) (int, error) {
+	k := s.failPrefix + key
+	ms := now.UnixMilli()
+	pipe := s.cli.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, k, "-inf", strconv.FormatInt(now.Add(-window).UnixMilli(), 10))
+	// member 追加纳秒避免同一毫秒内的失败被合并
+	pipe.ZAdd(ctx, k, redis.Z{Score: float64(ms), Member: strconv.FormatInt(now.UnixNano(), 10)})
+	card := pipe.ZCard(ctx, k)
+	pipe.Expire(ctx, k, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return int(card.Val()), nil
+}
+
+func (s *RedisStore) Failures(ctx context.Context, key string, now time.Time, window time.Duration) (int, time.Time, error) {
+	k := s.failPrefix + key
+	min := strconv.FormatInt(now.Add(-window).UnixMilli()+1, 10)
+	list, err := s.cli.ZRangeByScoreWithScores(ctx, k, &redis.ZRangeBy{Min: min, Max: "+inf"}).Result()
+	if err != nil || len(list) == 0 {
+		return 0, time.Time{}, err
+	}
+	return len(list), time.UnixMilli(int64(list[len(list)-1].Score)), nil
+}
+
+func (s *RedisStore) Reset(ctx context.Context, key string) error {
+	return s.cli.Del(ctx, s.failPrefix+key).Err()
+}
+
+func (s *RedisStore) Lock(ctx context.Context, key string, failures int, until time.Time) error {
+	val := strconv.Itoa(failures) + ":" + strconv.FormatInt(until.UnixMilli(), 10)
+	return s.cli.Set(ctx, s.lockPrefix+key, val, time.Until(until)).Err()
+}
+
+func (s *RedisStore) LockedUntil(ctx context.Context, key string) (time.Time, error) {
+	val, err := s.cli.Get(ctx, s.lockPrefix+key).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	_, until := parseLock(val)
+	return until, nil
+}
+
+func (s *RedisStore) Unlock(ctx context.Context, key string) error {
+	return s.cli.Del(ctx, s.lockPrefix+key).Err()
+}
+
+func (s *RedisStore) Lockouts(ctx context.Context, now time.Time) ([]Lockout, error) {
+	res := []Lockout{}
+	iter := s.cli.Scan(ctx, 0, s.lockPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		k := iter.Val()
+		val, err := s.cli.Get(ctx, k).Result()
+		if err != nil {
+			continue // 扫描期间过期
+		}
+		failures, until := parseLock(val)
+		if now.After(until) {
+			continue
+		}
+		kind, value, _ := strings.Cut(strings.TrimPrefix(k, s.lockPrefix), ":")
+		res = append(res, Lockout{Kind: kind, Key: value, Failures: failures, Until: until})
+	}
+	return res, iter.Err()
+}
+
+func parseLock(val string) (int, time.Time) {
+	f, u, _ := strings.Cut(val, ":")
+	failures, _ := strconv.Atoi(f)
+	ms, _ := strconv.ParseInt(u, 10, 64)
+	return failures, time.UnixMilli(ms)
+}
@@ -0,0 +1,140 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"mime"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type resetData struct {
+	AppName, Username, Link, ExpiresIn string
+}
+
+func TestTemplates(t *testing.T) {
+	tpls, err := LoadTemplates("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := tpls.Render(TemplatePasswordReset, "john@example.com", resetData{"Sinx", "john", "https://x/reset?token=abc", "30 分钟"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "重置您的 Sinx 密码" || !strings.Contains(msg.Body, "https://x/reset?token=abc") {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+
+	// 目录中的同名文件覆盖内置模板
+	dir := t.TempDir()
+	custom := `{{define "subject"}}Reset{{end}}{{define "body"}}Go to {{.Link}}{{end}}`
+	if err := os.WriteFile(filepath.Join(dir, "password_reset.tmpl"), []byte(custom), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tpls, err = LoadTemplates(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, _ = tpls.Render(TemplatePasswordReset, "john@example.com", resetData{Link: "L"})
+	if msg.Subject != "Reset" || msg.Body != "Go to L" {
+		t.Fatalf("override not applied: %+v", msg)
+	}
+}
+
+func TestHeaderInjection(t *testing.T) {
+	m := NewFileMailer(t.TempDir(), "Sinx <no-reply@example.com>")
+	if err := m.Send(context.Background(), &Message{To: "a@example.com\r\nBcc: b@example.com", Subject: "x"}); err == nil {
+		t.Fatal("expected header injection to be rejected")
+	}
+}
+
+// fakeSMTP 最小 SMTP 服务端，记录收到的信封与报文
+func fakeSMTP(t *testing.T) (addr string, got chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	got = make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 fake")
+		var rcpt string
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 fake")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				rcpt = strings.TrimSpace(line[len("RCPT TO:"):])
+				reply("250 ok")
+			case cmd == "DATA":
+				reply("354 go")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				got <- rcpt + "\n" + data.String()
+				reply("250 queued")
+			case cmd == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return ln.Addr().String(), got
+}
+
+func TestSMTPMailer(t *testing.T) {
+	addr, got := fakeSMTP(t)
+	host, port, _ := net.SplitHostPort(addr)
+	p, _ := strconv.Atoi(port)
+	m := NewSMTPMailer(SMTPConfig{Host: host, Port: p, From: "Sinx <no-reply@example.com>", TLS: "none"})
+	if err := m.Send(context.Background(), &Message{To: "john@example.com", Subject: "你好", Body: "正文"}); err != nil {
+		t.Fatal(err)
+	}
+
+	rcpt, raw, _ := strings.Cut(<-got, "\n")
+	if rcpt != "<john@example.com>" {
+		t.Fatalf("rcpt = %q", rcpt)
+	}
+	parsed, err := mail.ReadMessage(strings.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if subject != "你好" {
+		t.Fatalf("subject = %q", subject)
+	}
+	var body strings.Builder
+	b := bufio.NewScanner(parsed.Body)
+	for b.Scan() {
+		body.WriteString(b.Text())
+	}
+	decoded, _ := base64.StdEncoding.DecodeString(body.String())
+	if string(decoded) != "正文" {
+		t.Fatalf("body = %q", decoded)
+	}
+}
@@ -0,0 +1,75 @@
+package mailer
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// 内置模板名
+const (
+	TemplatePasswordReset = "password_reset"
+	TemplateVerifyEmail   = "verify_email"
+	TemplateAccessRequest = "access_request"
+)
+
+//go:embed templates/*.tmpl
+var builtin embed.FS
+
+// Templates 邮件模板：每个模板文件需定义 subject 与 body 两个块
+type Templates struct {
+	set map[string]*template.Template
+}
+
+// LoadTemplates 加载内置模板；dir 非空时同名文件（<name>.tmpl）覆盖内置模板
+func LoadTemplates(dir string) (*Templates, error) {
+	entries, err := builtin.ReadDir("templates")
+	if err != nil {
+		return nil, err
+	}
+	t := &Templates{set: make(map[string]*template.Template, len(entries))}
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), ".tmpl")
+		src, err := builtin.ReadFile("templates/" + e.Name())
+		if err != nil {
+			return nil, err
+		}
+		if dir != "" {
+			custom, err := os.ReadFile(filepath.Join(dir, e.Name()))
+			if err == nil {
+				src = custom
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+		}
+		tpl, err := template.New(name).Option("missingkey=error").Parse(string(src))
+		if err != nil {
+			return nil, fmt.Errorf("mailer: parse template %s: %w", name, err)
+		}
+		if tpl.Lookup("subject") == nil || tpl.Lookup("body") == nil {
+			return nil, fmt.Errorf("mailer: template %s must define subject and body", name)
+		}
+		t.set[name] = tpl
+	}
+	return t, nil
+}
+
+// Render 渲染模板生成邮件
+func (t *Templates) Render(name, to string, data any) (*Message, error) {
+	tpl, ok := t.set[name]
+	if !ok {
+		return nil, fmt.Errorf("mailer: unknown template %s", name)
+	}
+	var subject, body strings.Builder
+	if err := tpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+	if err := tpl.ExecuteTemplate(&body, "body", data); err != nil {
+		return nil, err
+	}
+	return &Message{To: to, Subject: strings.TrimSpace(subject.String()), Body: strings.TrimLeft(body.String(), "\n")}, nil
+}
@@ -0,0 +1,205 @@
+// Package passwordpolicy 密码强度规则：长度、字符类别、常见密码、包含用户名与有效期
+package passwordpolicy
+
+import (
+	"bufio"
+	"crypto/rand"
+	_ "embed"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 规则标识，作为字段错误的 rule 返回给前端
+const (
+	RuleMinLength        = "min_length"
+	RuleMaxLength        = "max_length"
+	RuleLower            = "lower"
+	RuleUpper            = "upper"
+	RuleDigit            = "digit"
+	RuleSymbol           = "symbol"
+	RuleCommon           = "common"
+	RuleContainsUsername = "contains_username"
+	RuleReused           = "reused"
+)
+
+// maxLength 上限用于避免超长输入拖慢哈希
+const maxLength = 128
+
+//go:embed common.txt
+var bundled string
+
+// common 内置常见密码表（小写）
+var common = parseList(bufio.NewScanner(strings.NewReader(bundled)))
+
+// Policy 零值表示不做任何限制
+type Policy struct {
+	MinLength      int
+	RequireClasses []string // lower / upper / digit / symbol
+	DenyCommon     bool
+	DenyUsername   bool
+	HistorySize    int           // 不得与最近 N 个密码相同，0 表示不检查
+	MaxAge         time.Duration // 密码有效期，0 表示永不过期
+
+	extra map[string]struct{} // LoadDenyList 追加的密码
+}
+
+// Violation 违反的规则
+type Violation struct {
+	Rule    string
+	Message string
+}
+
+// LoadDenyList 在内置常见密码表之外追加文件中的密码（每行一个，如泄露密码库导出）
+func (p *Policy) LoadDenyList(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	p.extra = parseList(s)
+	return s.Err()
+}
+
+func parseList(s *bufio.Scanner) map[string]struct{} {
+	res := map[string]struct{}{}
+	for s.Scan() {
+		if w := strings.ToLower(strings.TrimSpace(s.Text())); w != "" {
+			res[w] = struct{}{}
+		}
+	}
+	return res
+}
+
+// Check 校验密码强度（不含历史记录），返回全部违反项
+func (p *Policy) Check(password, username string) []Violation {
+	var res []Violation
+	n := utf8.RuneCountInString(password)
+	if n < p.MinLength {
+		res = append(res, Violation{RuleMinLength, fmt.Sprintf("密码长度至少为 %d 位", p.MinLength)})
+	}
+	if n > maxLength {
+		res = append(res, Violation{RuleMaxLength, fmt.Sprintf("密码长度不能超过 %d 位", maxLength)})
+	}
+
+	has := map[string]bool{}
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			has[RuleLower] = true
+		case unicode.IsUpper(r):
+			has[RuleUpper] = true
+		case unicode.IsDigit(r):
+			has[RuleDigit] = true
+		default:
+			has[RuleSymbol] = true
+		}
+	}
+	for _, class := range p.RequireClasses {
+		if !has[class] {
+			res = append(res, Violation{class, "密码需包含" + classNames[class]})
+		}
+	}
+
+	if p.DenyCommon && p.isCommon(password) {
+		res = append(res, Violation{RuleCommon, "密码过于常见，容易被猜中"})
+	}
+	if p.DenyUsername && len(username) >= 3 && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		res = append(res, Violation{RuleContainsUsername, "密码不能包含用户名"})
+	}
+	return res
+}
+
+// Expired 密码是否已超过有效期
+func (p *Policy) Expired(changedAt, now time.Time) bool {
+	return p.MaxAge > 0 && now.Sub(changedAt) > p.MaxAge
+}
+
+// ReusedViolation 与历史密码重复
+func (p *Policy) ReusedViolation() Violation {
+	return Violation{RuleReused, fmt.Sprintf("不能使用最近 %d 次用过的密码", p.HistorySize)}
+}
+
+// isCommon 忽略大小写；去掉末尾数字与符号后再比对一次（如 Password123!）
+func (p *Policy) isCommon(password string) bool {
+	w := strings.ToLower(password)
+	if p.denied(w) {
+		return true
+	}
+	trimmed := strings.TrimRightFunc(w, func(r rune) bool { return !unicode.IsLetter(r) })
+	return trimmed != w && utf8.RuneCountInString(trimmed) >= 4 && p.denied(trimmed)
+}
+
+func (p *Policy) denied(w string) bool {
+	if _, ok := common[w]; ok {
+		return true
+	}
+	_, ok := p.extra[w]
+	return ok
+}
+
+var classNames = map[string]string{
+	RuleLower:  "小写字母",
+	RuleUpper:  "大写字母",
+	RuleDigit:  "数字",
+	RuleSymbol: "特殊字符",
+}
+
+// ValidClass 是否为支持的字符类别
+func ValidClass(class string) bool {
+	_, ok := classNames[class]
+	return ok
+}
+
+// 生成临时密码使用的字符集（去掉易混淆的 0 O 1 l I）
+var classChars = map[string]string{
+	RuleLower:  "abcdefghijkmnopqrstuvwxyz",
+	RuleUpper:  "ABCDEFGHJKLMNPQRSTUVWXYZ",
+	RuleDigit:  "23456789",
+	RuleSymbol: "!@#$%^&*-_=+?",
+}
+
+// Generate 生成包含全部字符类别的随机密码，长度不少于 n 与 MinLength
+func (p *Policy) Generate(n int) (string, error) {
+	n = max(n, p.MinLength, len(classChars))
+	classes := []string{RuleLower, RuleUpper, RuleDigit, RuleSymbol}
+	var all string
+	for _, class := range classes {
+		all += classChars[class]
+	}
+	res := make([]byte, n)
+	for i := range res {
+		// 前几位依次取自各字符类别，保证每类至少出现一次
+		set := all
+		if i < len(classes) {
+			set = classChars[classes[i]]
+		}
+		c, err := randomIndex(len(set))
+		if err != nil {
+			return "", err
+		}
+		res[i] = set[c]
+	}
+	// 打乱顺序，避免固定的类别位置
+	for i := len(res) - 1; i > 0; i-- {
+		j, err := randomIndex(i + 1)
+		if err != nil {
+			return "", err
+		}
+		res[i], res[j] = res[j], res[i]
+	}
+	return string(res), nil
+}
+
+func randomIndex(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(v.Int64()), nil
+}
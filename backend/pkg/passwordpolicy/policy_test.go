@@ -0,0 +1,88 @@
+package passwordpolicy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func rules(vs []Violation) map[string]bool {
+	res := map[string]bool{}
+	for _, v := range vs {
+		res[v.Rule] = true
+	}
+	return res
+}
+
+func TestCheck(t *testing.T) {
+	p := &Policy{MinLength: 10, RequireClasses: []string{RuleLower, RuleUpper, RuleDigit, RuleSymbol}, DenyCommon: true, DenyUsername: true}
+
+	cases := []struct {
+		password string
+		want     []string
+	}{
+		{"Correct-Horse-9", nil},
+		{"short", []string{RuleMinLength, RuleUpper, RuleDigit, RuleSymbol}},
+		{"Password123!", []string{RuleCommon}}, // 去掉末尾数字符号后命中常见密码
+		{"qwertyuiop", []string{RuleUpper, RuleDigit, RuleSymbol, RuleCommon}},
+		{"xx-John_Doe-9Z", []string{RuleContainsUsername}},
+	}
+	for _, tc := range cases {
+		got := rules(p.Check(tc.password, "john_doe"))
+		if len(got) != len(tc.want) {
+			t.Fatalf("%q: got %v, want %v", tc.password, got, tc.want)
+		}
+		for _, r := range tc.want {
+			if !got[r] {
+				t.Fatalf("%q: missing rule %s in %v", tc.password, r, got)
+			}
+		}
+	}
+
+	// 零值策略不做限制
+	if vs := (&Policy{}).Check("1", "u"); len(vs) != 0 {
+		t.Fatalf("zero policy should accept anything, got %v", vs)
+	}
+}
+
+func TestDenyListFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "deny.txt")
+	if err := os.WriteFile(file, []byte("Tr0ub4dor&3\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p := &Policy{DenyCommon: true}
+	if err := p.LoadDenyList(file); err != nil {
+		t.Fatal(err)
+	}
+	if !rules(p.Check("tr0ub4dor&3", ""))[RuleCommon] || !rules(p.Check("letmein", ""))[RuleCommon] {
+		t.Fatal("expected both bundled and file entries to be denied")
+	}
+}
+
+func TestExpired(t *testing.T) {
+	now := time.Now()
+	p := &Policy{MaxAge: 90 * 24 * time.Hour}
+	if p.Expired(now.Add(-89*24*time.Hour), now) || !p.Expired(now.Add(-91*24*time.Hour), now) {
+		t.Fatal("unexpected expiry result")
+	}
+	if (&Policy{}).Expired(time.Time{}, now) {
+		t.Fatal("zero MaxAge never expires")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	p := &Policy{MinLength: 20, RequireClasses: []string{RuleLower, RuleUpper, RuleDigit, RuleSymbol}, DenyCommon: true}
+	for i := 0; i < 50; i++ {
+		pw, err := p.Generate(12)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pw) != 20 {
+			t.Fatalf("len(%q) = %d", pw, len(pw))
+		}
+		if v := p.Check(pw, ""); len(v) != 0 {
+			t.Fatalf("generated password %q violates policy: %+v", pw, v)
+		}
+	}
+}
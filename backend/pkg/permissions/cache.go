@@ -7,11 +7,13 @@ import (
 
 // userPermCacheItem 缓存项
 type userPermCacheItem struct {
-	perms map[string]struct{}
-	exp   time.Time
+	perms   *PermSet
+	version int64
+	exp     time.Time
 }
 
-// UserPermCache 简单内存缓存（进程级，后续可替换为 Redis）
+// UserPermCache 简单内存缓存（进程级），缓存预编译好的权限集合，检查时无需重建匹配树
+// 缓存项记录编译时的版本戳（多实例下取自 Redis，单实例恒为 0），版本不一致视为失效
 type UserPermCache struct {
 	ttl   time.Duration
 	mu    sync.RWMutex
@@ -22,13 +24,13 @@ func NewUserPermCache(ttl time.Duration) *UserPermCache {
 	return &UserPermCache{ttl: ttl, store: make(map[uint]*userPermCacheItem)}
 }
 
-// Get 返回缓存的权限集合，若不存在或过期返回 nil
-func (c *UserPermCache) Get(userID uint) map[string]struct{} {
+// Get 返回指定版本的缓存权限集合，若不存在、过期或版本不一致返回 nil
+func (c *UserPermCache) Get(userID uint, version int64) *PermSet {
 	c.mu.RLock()
 	item, ok := c.store[userID]
 	c.mu.RUnlock()
-	if !ok || time.Now().After(item.exp) {
-		if ok { // 过期清理
+	if !ok || time.Now().After(item.exp) || item.version != version {
+		if ok { // 过期或版本落后，清理
 			c.mu.Lock()
 			delete(c.store, userID)
 			c.mu.Unlock()
@@ -38,10 +40,10 @@ func (c *UserPermCache) Get(userID uint) map[string]struct{} {
 	return item.perms
 }
 
-// Set 写入权限集合
-func (c *UserPermCache) Set(userID uint, perms map[string]struct{}) {
+// Set 写入权限集合及其编译时的版本戳
+func (c *UserPermCache) Set(userID uint, perms *PermSet, version int64) {
 	c.mu.Lock()
-	c.store[userID] = &userPermCacheItem{perms: perms, exp: time.Now().Add(c.ttl)}
+	c.store[userID] = &userPermCacheItem{perms: perms, version: version, exp: time.Now().Add(c.ttl)}
 	c.mu.Unlock()
 }
 
@@ -0,0 +1,30 @@
+package permissions
+
+import (
+	"testing"
+	"time"
+)
+
+// 缓存项按版本戳命中：其他实例失效后版本戳变化，旧集合不再返回
+func TestUserPermCacheVersion(t *testing.T) {
+	c := NewUserPermCache(time.Minute)
+	perms := set("user:list")
+	c.Set(1, perms, 100)
+
+	if got := c.Get(1, 100); got != perms {
+		t.Fatal("expected the compiled set for the same version")
+	}
+	if got := c.Get(1, 200); got != nil {
+		t.Fatal("stale version must miss")
+	}
+	// 版本落后的缓存项已被清理，即使版本戳回到旧值也不会复用
+	if got := c.Get(1, 100); got != nil {
+		t.Fatal("stale item must be dropped")
+	}
+
+	c.Set(2, perms, 0)
+	c.InvalidateUsers([]uint{2})
+	if got := c.Get(2, 0); got != nil {
+		t.Fatal("invalidated item must miss")
+	}
+}
@@ -0,0 +1,182 @@
+package permissions
+
+import "strings"
+
+// Wildcard 通配段；单独的 * 表示全部权限
+const Wildcard = "*"
+
+// PermSet 权限集合：保留原始权限标识（可含通配符），并预编译为按段（以 : 分隔）匹配的前缀树
+// 通配符只匹配一个完整的段，不跨段、也不匹配段内的一部分：user:* 匹配 user:list，不匹配 user:role:list 与 users:list
+// 拒绝规则优先于授予：与任一拒绝规则重叠的权限均不授予
+type PermSet struct {
+	perms  map[string]struct{}
+	denies map[string]struct{}
+	grant  permTrie
+	deny   permTrie
+}
+
+type permTrie struct {
+	root *permNode
+	all  bool
+}
+
+type permNode struct {
+	children map[string]*permNode
+	terminal bool
+}
+
+func NewPermSet(perms map[string]struct{}) *PermSet {
+	return NewPermSetWithDenies(perms, nil)
+}
+
+// NewPermSetWithDenies denies 为拒绝规则，同样支持通配段
+func NewPermSetWithDenies(perms, denies map[string]struct{}) *PermSet {
+	if denies == nil {
+		denies = map[string]struct{}{}
+	}
+	return &PermSet{perms: perms, denies: denies, grant: compile(perms), deny: compile(denies)}
+}
+
+func compile(perms map[string]struct{}) permTrie {
+	t := permTrie{root: &permNode{}}
+	for p := range perms {
+		if p == Wildcard {
+			t.all = true
+			continue
+		}
+		if p == "" {
+			continue
+		}
+		n := t.root
+		for _, seg := range strings.Split(p, ":") {
+			if n.children == nil {
+				n.children = map[string]*permNode{}
+			}
+			child, ok := n.children[seg]
+			if !ok {
+				child = &permNode{}
+				n.children[seg] = child
+			}
+			n = child
+		}
+		n.terminal = true
+	}
+	return t
+}
+
+// Has 判断是否授予 required 且未被拒绝；耗时只与 required 的段数有关，与集合大小无关
+// required 中的 * 按字面段处理，因此 Has("user:*") 表示集合是否覆盖整个 user:* 模式（其中任何部分被拒绝都不算覆盖）
+func (s *PermSet) Has(required string) bool {
+	if s == nil || required == "" {
+		return false
+	}
+	if !s.grant.all && !s.grant.root.match(required) {
+		return false
+	}
+	return !s.Denied(required)
+}
+
+// Denied 判断 required 是否与任一拒绝规则重叠
+func (s *PermSet) Denied(required string) bool {
+	if s == nil {
+		return false
+	}
+	if required == Wildcard { // 全部权限与任何拒绝规则都重叠
+		return len(s.denies) > 0
+	}
+	return s.deny.all || s.deny.root.overlap(required)
+}
+
+// Overlaps 判断授予标识（不考虑拒绝规则）是否与模式 p 有共同匹配的权限
+func (s *PermSet) Overlaps(p string) bool {
+	if s == nil || p == "" {
+		return false
+	}
+	if p == Wildcard {
+		return len(s.perms) > 0
+	}
+	return s.grant.all || s.grant.root.overlap(p)
+}
+
+// Perms 返回原始授予标识集合（只读，未扣除拒绝规则）
+func (s *PermSet) Perms() map[string]struct{} {
+	if s == nil {
+		return map[string]struct{}{}
+	}
+	return s.perms
+}
+
+// Denies 返回原始拒绝规则集合（只读）
+func (s *PermSet) Denies() map[string]struct{} {
+	if s == nil {
+		return map[string]struct{}{}
+	}
+	return s.denies
+}
+
+// Effective 返回实际生效的权限标识：未被拒绝的授予标识，加上 known 中被授予且未被拒绝的具体权限点
+// 用于对外列出权限（如令牌声明），通配授予因部分被拒绝而失效时仍能列出其余权限点
+func (s *PermSet) Effective(known []string) map[string]struct{} {
+	res := map[string]struct{}{}
+	for p := range s.Perms() {
+		if s.Has(p) {
+			res[p] = struct{}{}
+		}
+	}
+	for _, p := range known {
+		if s.Has(p) {
+			res[p] = struct{}{}
+		}
+	}
+	return res
+}
+
+func (n *permNode) match(rest string) bool {
+	seg, tail, more := strings.Cut(rest, ":")
+	if n.children[seg].follow(tail, more, (*permNode).match) {
+		return true
+	}
+	// 通配段不匹配空段
+	return seg != "" && seg != Wildcard && n.children[Wildcard].follow(tail, more, (*permNode).match)
+}
+
+// overlap 模式与树中任一模式是否有共同匹配的权限：两侧的 * 都可匹配对方的任意非空段
+func (n *permNode) overlap(rest string) bool {
+	seg, tail, more := strings.Cut(rest, ":")
+	if seg == Wildcard {
+		for key, child := range n.children {
+			if key != "" && child.follow(tail, more, (*permNode).overlap) {
+				return true
+			}
+		}
+		return false
+	}
+	if n.children[seg].follow(tail, more, (*permNode).overlap) {
+		return true
+	}
+	return seg != "" && n.children[Wildcard].follow(tail, more, (*permNode).overlap)
+}
+
+// follow 当前段已匹配到 n：最后一段要求 n 为完整标识，否则继续匹配剩余的段
+func (n *permNode) follow(tail string, more bool, next func(*permNode, string) bool) bool {
+	if n == nil {
+		return false
+	}
+	if !more {
+		return n.terminal
+	}
+	return next(n, tail)
+}
+
+// ValidPattern 校验权限标识：段不能为空，通配符只能作为完整的段出现
+func ValidPattern(p string) bool {
+	if p == Wildcard {
+		return true
+	}
+	for _, seg := range strings.Split(p, ":") {
+		if seg == "" || (seg != Wildcard && strings.Contains(seg, Wildcard)) {
+			return false
+		}
+	}
+	return true
+}
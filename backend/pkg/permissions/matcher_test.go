@@ -0,0 +1,116 @@
+package permissions
+
+import "testing"
+
+func set(perms ...string) *PermSet {
+	m := make(map[string]struct{}, len(perms))
+	for _, p := range perms {
+		m[p] = struct{}{}
+	}
+	return NewPermSet(m)
+}
+
+func TestPermSetMatch(t *testing.T) {
+	cases := []struct {
+		granted  []string
+		required string
+		want     bool
+	}{
+		{[]string{"user:create"}, "user:create", true},
+		{[]string{"user:create"}, "user:list", false},
+		{[]string{"user:*"}, "user:list", true},
+		{[]string{"*:list"}, "role:list", true},
+		{[]string{"*:list"}, "role:delete", false},
+		{[]string{"*"}, "menu:roleMenuTree", true},
+		{[]string{"*"}, "report:daily:export", true},
+		{[]string{"*:*"}, "user:list", true},
+		{nil, "user:list", false},
+		{[]string{"user:*"}, "", false},
+	}
+	for _, c := range cases {
+		if got := set(c.granted...).Has(c.required); got != c.want {
+			t.Errorf("%v Has(%q) = %v, want %v", c.granted, c.required, got, c.want)
+		}
+	}
+}
+
+// 通配段只匹配一个完整的段：不跨越 : 分隔，也不匹配段的前缀 / 后缀
+func TestPermSetWildcardSegmentBoundary(t *testing.T) {
+	cases := []struct {
+		granted  string
+		required string
+	}{
+		{"user:*", "user:role:list"},
+		{"user:*", "users:list"},
+		{"user:*", "user"},
+		{"user:*", "user:"},
+		{"*:list", "user:role:list"},
+		{"*:list", "user:listAll"},
+		{"*:list", "list"},
+		{"*:*", "user"},
+		{"*:*", "user:role:list"},
+		{"user*", "users:list"},
+		{"user*", "user:list"},
+		{"us*:list", "user:list"},
+	}
+	for _, c := range cases {
+		if set(c.granted).Has(c.required) {
+			t.Errorf("%q must not match %q", c.granted, c.required)
+		}
+	}
+}
+
+// 以模式为参数时按字面段比较，得到「是否覆盖整个模式」
+func TestPermSetCoversPattern(t *testing.T) {
+	if !set("*:*").Has("user:*") || !set("user:*").Has("user:*") || !set("*").Has("*") {
+		t.Fatal("broader pattern should cover narrower one")
+	}
+	if set("user:list").Has("user:*") || set("user:*").Has("*:*") || set("*:*").Has("*") {
+		t.Fatal("narrower set must not cover broader pattern")
+	}
+}
+
+func TestValidPattern(t *testing.T) {
+	for _, p := range []string{"user:list", "user:*", "*:list", "*", "*:*", "report:daily:export"} {
+		if !ValidPattern(p) {
+			t.Errorf("%q should be valid", p)
+		}
+	}
+	for _, p := range []string{"user*", "us*:list", "user:", ":list", "user::list", "**"} {
+		if ValidPattern(p) {
+			t.Errorf("%q should be invalid", p)
+		}
+	}
+}
+
+// 拒绝规则优先于授予（含通配）；拒绝同样按整段匹配
+func TestPermSetDenies(t *testing.T) {
+	s := NewPermSetWithDenies(map[string]struct{}{"*": {}}, map[string]struct{}{"user:delete": {}, "*:export": {}})
+	for _, p := range []string{"user:delete", "report:export"} {
+		if s.Has(p) {
+			t.Errorf("%q must be denied", p)
+		}
+	}
+	for _, p := range []string{"user:list", "user:delete:soft", "report:exportAll"} {
+		if !s.Has(p) {
+			t.Errorf("%q should still be granted", p)
+		}
+	}
+	// 部分被拒绝的模式不算被覆盖
+	if s.Has("user:*") || s.Has("role:*") || s.Has("*") {
+		t.Fatal("pattern overlapping a deny must not be covered")
+	}
+	if !NewPermSetWithDenies(map[string]struct{}{"*": {}}, map[string]struct{}{"user:delete": {}}).Has("role:*") {
+		t.Fatal("pattern disjoint from denies should be covered")
+	}
+	if !s.Overlaps("user:delete") || set("role:list").Overlaps("user:*") {
+		t.Fatal("unexpected overlap result")
+	}
+	eff := s.Effective(AllPerms)
+	if _, ok := eff[PermUserDelete]; ok {
+		t.Fatal("effective perms must exclude denied perms")
+	}
+	if _, ok := eff[PermUserList]; !ok {
+		t.Fatal("effective perms should expand the wildcard")
+	}
+}
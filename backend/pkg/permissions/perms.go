@@ -3,13 +3,17 @@ package permissions
 // 定义所有权限常量，便于集中管理与对外导出
 const (
 	// 用户相关
-	PermUserCreate     = "user:create"
-	PermUserList       = "user:list"
-	PermUserUpdate     = "user:update"
-	PermUserDelete     = "user:delete"
-	PermUserBindRole   = "user:bindRole"
-	PermUserUnbindRole = "user:unbindRole"
-	PermUserRoles      = "user:roles"
+	PermUserCreate      = "user:create"
+	PermUserList        = "user:list"
+	PermUserUpdate      = "user:update"
+	PermUserDelete      = "user:delete"
+	PermUserBindRole    = "user:bindRole"
+	PermUserUnbindRole  = "user:unbindRole"
+	PermUserRoles       = "user:roles"
+	PermUserResetMFA    = "user:resetMfa"
+	PermUserResetPwd    = "user:resetPassword"
+	PermUserImpersonate = "user:impersonate"
+	PermUserDeniedPerms = "user:deniedPerms"
 
 	// 角色相关
 	PermRoleCreate     = "role:create"
@@ -20,6 +24,10 @@ const (
 	PermRoleUnbindMenu = "role:unbindMenu"
 	PermRoleMenus      = "role:menus"
 	PermRoleUsers      = "role:users"
+	PermRolePerms      = "role:perms"
+	PermRoleApprovers  = "role:approvers"
+	PermRoleSoD        = "role:sod"
+	PermRoleSoDReport  = "role:sodReport"
 
 	// 菜单相关
 	PermMenuCreate       = "menu:create"
@@ -28,11 +36,38 @@ const (
 	PermMenuDelete       = "menu:delete"
 	PermMenuRoles        = "menu:roles"
 	PermMenuRoleMenuTree = "menu:roleMenuTree"
+
+	// 部门相关
+	PermDeptCreate = "dept:create"
+	PermDeptList   = "dept:list"
+	PermDeptUpdate = "dept:update"
+	PermDeptDelete = "dept:delete"
+
+	// OIDC 客户端
+	PermOIDCClientCreate = "oidcClient:create"
+	PermOIDCClientList   = "oidcClient:list"
+	PermOIDCClientDelete = "oidcClient:delete"
+
+	// 安全相关
+	PermSecurityLockouts = "security:lockouts"
+	PermSecurityUnlock   = "security:unlock"
+	PermSecurityLDAPSync = "security:ldapSync"
+	PermSecuritySessions = "security:sessions"
+	PermSecurityLogout   = "security:forceLogout"
+	PermSecurityLogins   = "security:loginLogs"
+
+	// 角色申请
+	PermAccessList = "access:list"
 )
 
 // AllPerms 导出全部权限列表（用于前端获取 / 同步 / 测试）
 var AllPerms = []string{
-	PermUserCreate, PermUserList, PermUserUpdate, PermUserDelete, PermUserBindRole, PermUserUnbindRole, PermUserRoles,
-	PermRoleCreate, PermRoleList, PermRoleUpdate, PermRoleDelete, PermRoleBindMenu, PermRoleUnbindMenu, PermRoleMenus, PermRoleUsers,
+	PermUserCreate, PermUserList, PermUserUpdate, PermUserDelete, PermUserBindRole, PermUserUnbindRole, PermUserRoles, PermUserResetMFA, PermUserResetPwd, PermUserImpersonate, PermUserDeniedPerms,
+	PermRoleCreate, PermRoleList, PermRoleUpdate, PermRoleDelete, PermRoleBindMenu, PermRoleUnbindMenu, PermRoleMenus, PermRoleUsers, PermRolePerms, PermRoleApprovers, PermRoleSoD, PermRoleSoDReport,
 	PermMenuCreate, PermMenuList, PermMenuUpdate, PermMenuDelete, PermMenuRoles, PermMenuRoleMenuTree,
+	PermDeptCreate, PermDeptList, PermDeptUpdate, PermDeptDelete,
+	PermOIDCClientCreate, PermOIDCClientList, PermOIDCClientDelete,
+	PermSecurityLockouts, PermSecurityUnlock, PermSecurityLDAPSync, PermSecuritySessions, PermSecurityLogout,
+	PermSecurityLogins,
+	PermAccessList,
 }
@@ -2,80 +2,56 @@ package permissions
 
 import (
 	"context"
-	"encoding/json"
 	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
-// RedisUserPermCache 基于 Redis 的权限缓存
+// RedisUserPermCache 基于 Redis 的权限缓存，多实例间失效各自内存中编译好的权限集合
+// 只保存版本戳、不再保存权限集合（原 Get / Set 已移除）：失效时写入新的纳秒时间戳，
+// 各实例比对版本戳决定是否沿用 UserPermCache 中的集合，检查权限时只需一次 GET
+//
+//	user_perms_ver:<userID>  string "<unixNano>"
 type RedisUserPermCache struct {
 	cli    *redis.Client
-	ttl    time.Duration
+	ttl    time.Duration // 须长于内存缓存有效期，过期后版本戳归零不会与仍存活的缓存项重合
 	prefix string
 }
 
+// NewRedisUserPermCache ttl 为内存缓存有效期
 func NewRedisUserPermCache(cli *redis.Client, ttl time.Duration) *RedisUserPermCache {
-	return &RedisUserPermCache{cli: cli, ttl: ttl, prefix: "user_perms:"}
+	return &RedisUserPermCache{cli: cli, ttl: 2 * ttl, prefix: "user_perms_ver:"}
 }
 
 func (c *RedisUserPermCache) key(userID uint) string {
 	return c.prefix + strconv.FormatUint(uint64(userID), 10)
 }
 
-// Get 返回权限集合；若无或解析失败返回 nil
-func (c *RedisUserPermCache) Get(ctx context.Context, userID uint) (map[string]struct{}, error) {
-	if c.cli == nil {
-		return nil, nil
+// Version 返回用户当前版本戳，从未失效过返回 0
+func (c *RedisUserPermCache) Version(ctx context.Context, userID uint) (int64, error) {
+	val, err := c.cli.Get(ctx, c.key(userID)).Int64()
+	if err == redis.Nil {
+		return 0, nil
 	}
-	val, err := c.cli.Get(ctx, c.key(userID)).Result()
-	if err != nil {
-		if err == redis.Nil {
-			return nil, nil
-		}
-		return nil, err
-	}
-	var arr []string
-	if err := json.Unmarshal([]byte(val), &arr); err != nil {
-		return nil, err
-	}
-	m := make(map[string]struct{}, len(arr))
-	for _, p := range arr {
-		m[p] = struct{}{}
-	}
-	return m, nil
-}
-
-// Set 写入权限集合
-func (c *RedisUserPermCache) Set(ctx context.Context, userID uint, perms map[string]struct{}) error {
-	if c.cli == nil {
-		return nil
-	}
-	arr := make([]string, 0, len(perms))
-	for p := range perms {
-		arr = append(arr, p)
-	}
-	b, _ := json.Marshal(arr)
-	return c.cli.Set(ctx, c.key(userID), b, c.ttl).Err()
+	return val, err
 }
 
-// Invalidate 删除指定用户权限缓存
-func (c *RedisUserPermCache) Invalidate(ctx context.Context, userID uint) {
-	if c.cli == nil {
-		return
-	}
-	_ = c.cli.Del(ctx, c.key(userID)).Err()
+// Invalidate 使指定用户在各实例的权限缓存失效
+func (c *RedisUserPermCache) Invalidate(ctx context.Context, userID uint) error {
+	return c.InvalidateUsers(ctx, []uint{userID})
 }
 
-// InvalidateUsers 批量删除
-func (c *RedisUserPermCache) InvalidateUsers(ctx context.Context, userIDs []uint) {
-	if c.cli == nil || len(userIDs) == 0 {
-		return
+// InvalidateUsers 批量失效：更新版本戳
+func (c *RedisUserPermCache) InvalidateUsers(ctx context.Context, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
 	}
-	keys := make([]string, 0, len(userIDs))
+	stamp := time.Now().UnixNano()
+	pipe := c.cli.Pipeline()
 	for _, id := range userIDs {
-		keys = append(keys, c.key(id))
+		pipe.Set(ctx, c.key(id), stamp, c.ttl)
 	}
-	_ = c.cli.Del(ctx, keys...).Err()
+	_, err := pipe.Exec(ctx)
+	return err
 }
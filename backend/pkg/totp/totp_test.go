@@ -0,0 +1,41 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录 B 测试向量（SHA1，8 位取后 6 位）
+func TestRFC6238Vectors(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	cases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for ts, want := range cases {
+		got, err := CodeAt(secret, Step(time.Unix(ts, 0)))
+		if err != nil {
+			t.Fatalf("code at %d: %v", ts, err)
+		}
+		if got != want {
+			t.Fatalf("code at %d: got %s want %s", ts, got, want)
+		}
+	}
+}
+
+func TestValidateRejectsReplay(t *testing.T) {
+	secret, _ := GenerateSecret()
+	now := time.Now()
+	code, _ := CodeAt(secret, Step(now))
+	step, ok := Validate(secret, code, now, 0)
+	if !ok {
+		t.Fatalf("valid code rejected")
+	}
+	if _, ok := Validate(secret, code, now, step); ok {
+		t.Fatalf("replayed code accepted")
+	}
+}
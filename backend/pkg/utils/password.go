@@ -2,20 +2,101 @@ package utils
 
 import (
 	"crypto/md5"
+	"crypto/subtle"
 	"fmt"
+	"strings"
+	"sync"
 )
 
-// HashPassword 对密码进行哈希处理 (简单实现，生产环境建议使用bcrypt)
+// PasswordHasher 可插拔的密码哈希算法
+// 产出的哈希串自描述（PHC / Modular Crypt 格式），据此即可判断所用算法与参数
+type PasswordHasher interface {
+	// Name 算法标识，如 argon2id / bcrypt
+	Name() string
+	Hash(password string) (string, error)
+	// Match 判断哈希串是否由该算法生成
+	Match(encoded string) bool
+	Verify(password, encoded string) (bool, error)
+	// NeedsRehash 哈希串参数是否落后于当前配置
+	NeedsRehash(encoded string) bool
+}
+
+var (
+	hasherMu      sync.RWMutex
+	defaultHasher PasswordHasher = NewArgon2idHasher()
+	hashers                      = []PasswordHasher{NewArgon2idHasher(), NewBcryptHasher(0)}
+)
+
+// SetDefaultPasswordHasher 按名称切换新密码使用的哈希算法（argon2id / bcrypt）
+func SetDefaultPasswordHasher(name string) error {
+	hasherMu.Lock()
+	defer hasherMu.Unlock()
+	for _, h := range hashers {
+		if h.Name() == name {
+			defaultHasher = h
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown password hasher: %s", name)
+}
+
+func currentHasher() PasswordHasher {
+	hasherMu.RLock()
+	defer hasherMu.RUnlock()
+	return defaultHasher
+}
+
+func findHasher(encoded string) PasswordHasher {
+	hasherMu.RLock()
+	defer hasherMu.RUnlock()
+	for _, h := range hashers {
+		if h.Match(encoded) {
+			return h
+		}
+	}
+	return nil
+}
+
+// HashPassword 使用当前默认算法对密码进行哈希处理
 func HashPassword(password string) (string, error) {
-	hash := md5.Sum([]byte(password))
-	return fmt.Sprintf("%x", hash), nil
+	return currentHasher().Hash(password)
 }
 
-// CheckPassword 验证密码
+// CheckPassword 验证密码，兼容历史遗留的无盐 MD5 哈希
 func CheckPassword(password, hash string) bool {
-	hashedPassword, err := HashPassword(password)
-	if err != nil {
+	if isLegacyMD5(hash) {
+		sum := md5.Sum([]byte(password))
+		return subtle.ConstantTimeCompare([]byte(fmt.Sprintf("%x", sum)), []byte(strings.ToLower(hash))) == 1
+	}
+	h := findHasher(hash)
+	if h == nil {
 		return false
 	}
-	return hashedPassword == hash
+	ok, err := h.Verify(password, hash)
+	return err == nil && ok
+}
+
+// NeedsRehash 哈希串是否需要用当前算法重新生成（遗留 MD5、算法切换或参数升级）
+func NeedsRehash(hash string) bool {
+	if isLegacyMD5(hash) {
+		return true
+	}
+	cur := currentHasher()
+	if !cur.Match(hash) {
+		return true
+	}
+	return cur.NeedsRehash(hash)
+}
+
+// isLegacyMD5 旧版本存储的 32 位十六进制 MD5
+func isLegacyMD5(hash string) bool {
+	if len(hash) != 32 {
+		return false
+	}
+	for _, c := range hash {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"crypto/md5"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPasswordHashers(t *testing.T) {
+	defer func() { _ = SetDefaultPasswordHasher("argon2id") }()
+
+	for _, name := range []string{"argon2id", "bcrypt"} {
+		if err := SetDefaultPasswordHasher(name); err != nil {
+			t.Fatalf("set hasher %s: %v", name, err)
+		}
+		hash, err := HashPassword("s3cret-pass")
+		if err != nil {
+			t.Fatalf("%s hash: %v", name, err)
+		}
+		if !CheckPassword("s3cret-pass", hash) {
+			t.Fatalf("%s: correct password rejected", name)
+		}
+		if CheckPassword("wrong-pass", hash) {
+			t.Fatalf("%s: wrong password accepted", name)
+		}
+		if NeedsRehash(hash) {
+			t.Fatalf("%s: fresh hash should not need rehash", name)
+		}
+	}
+
+	// 切换算法后，旧算法生成的哈希仍可验证但需要重新哈希
+	bcryptHash, _ := NewBcryptHasher(0).Hash("s3cret-pass")
+	_ = SetDefaultPasswordHasher("argon2id")
+	if !CheckPassword("s3cret-pass", bcryptHash) || !NeedsRehash(bcryptHash) {
+		t.Fatalf("bcrypt hash should verify and need rehash under argon2id")
+	}
+	if err := SetDefaultPasswordHasher("md5"); err == nil {
+		t.Fatalf("unknown hasher accepted")
+	}
+}
+
+func TestLegacyMD5Password(t *testing.T) {
+	legacy := fmt.Sprintf("%x", md5.Sum([]byte("admin123")))
+	if !CheckPassword("admin123", legacy) || !CheckPassword("admin123", strings.ToUpper(legacy)) {
+		t.Fatalf("legacy md5 hash rejected")
+	}
+	if CheckPassword("admin124", legacy) {
+		t.Fatalf("wrong password accepted for legacy hash")
+	}
+	if !NeedsRehash(legacy) {
+		t.Fatalf("legacy md5 hash should need rehash")
+	}
+}
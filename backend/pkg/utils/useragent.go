@@ -0,0 +1,39 @@
+package utils
+
+import "strings"
+
+// uaRule 按顺序匹配 User-Agent 中的关键字（Edge / Opera 的 UA 同时包含 Chrome，需排在前面）
+type uaRule struct{ token, name string }
+
+var browserRules = []uaRule{
+	{"edg/", "Edge"}, {"opr/", "Opera"}, {"firefox/", "Firefox"}, {"chrome/", "Chrome"},
+	{"safari/", "Safari"}, {"curl/", "curl"}, {"postman", "Postman"}, {"go-http-client", "Go"},
+}
+
+var osRules = []uaRule{
+	{"windows", "Windows"}, {"iphone", "iOS"}, {"ipad", "iPadOS"}, {"android", "Android"},
+	{"mac os x", "macOS"}, {"cros", "ChromeOS"}, {"linux", "Linux"},
+}
+
+// DeviceName 从 User-Agent 粗略识别浏览器与操作系统，如 "Chrome on Windows"；无法识别时返回空
+func DeviceName(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	browser, system := matchUA(ua, browserRules), matchUA(ua, osRules)
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	default:
+		return system
+	}
+}
+
+func matchUA(ua string, rules []uaRule) string {
+	for _, r := range rules {
+		if strings.Contains(ua, r.token) {
+			return r.name
+		}
+	}
+	return ""
+}